@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/iMookatayou/homeservice-backend/internal/bills"
+	"github.com/iMookatayou/homeservice-backend/internal/files"
+	"github.com/iMookatayou/homeservice-backend/internal/media"
+	"github.com/iMookatayou/homeservice-backend/internal/medicine"
+	"github.com/iMookatayou/homeservice-backend/internal/notes"
+	"github.com/iMookatayou/homeservice-backend/internal/scheduler"
+	"github.com/iMookatayou/homeservice-backend/internal/stocks"
+)
+
+type jobDeps struct {
+	quotes   *stocks.QuotesWorker
+	rss      *media.RSSWorker
+	bills    *bills.Generator
+	medicine *medicine.AlertWorker
+	doses    *medicine.DoseWorker
+	notes    *notes.ReminderWorker
+	files    *files.Processor
+	filesGC  *files.GC
+}
+
+// jobFilesProcess ชื่องานประมวลผลไฟล์ (อัปโหลดแล้วปลุกด้วย scheduler.Trigger)
+const jobFilesProcess = "files.process"
+
+// registerJobs — ตารางงานเบื้องหลังทั้งหมด (ชื่อ, cron, timeout)
+func registerJobs(s *scheduler.Scheduler, d jobDeps) error {
+	jobs := []struct {
+		name    string
+		spec    string
+		timeout time.Duration
+		run     func(ctx context.Context) error
+	}{
+		{"stocks.quotes", "@every 5s", 30 * time.Second, d.quotes.RunOnce},
+		{"media.rss", "@every 3m", 2 * time.Minute, d.rss.RunOnce},
+		{"bills.generate", "@hourly", 5 * time.Minute, d.bills.RunOnce},
+		{"medicine.alerts", "0 7 * * *", 10 * time.Minute, d.medicine.RunAll}, // ทุกวัน 07:00 ทุกบ้าน
+		{"medicine.doses", "@every 15m", 2 * time.Minute, d.doses.RunOnce},
+		{jobFilesProcess, "@every 30s", 5 * time.Minute, d.files.RunOnce},
+		{"files.gc", "30 3 * * *", 30 * time.Minute, d.filesGC.RunOnce}, // ทุกวัน 03:30
+		{"notes.reminders", "@every 1m", time.Minute, func(ctx context.Context) error {
+			return d.notes.RunOnce(ctx, time.Now())
+		}},
+	}
+	for _, j := range jobs {
+		if err := s.Register(j.name, j.spec, j.timeout, j.run); err != nil {
+			return err
+		}
+	}
+	return nil
+}
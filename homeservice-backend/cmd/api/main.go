@@ -2,30 +2,42 @@ package main
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 
 	"github.com/iMookatayou/homeservice-backend/internal/auth"
+	"github.com/iMookatayou/homeservice-backend/internal/authz"
 	"github.com/iMookatayou/homeservice-backend/internal/config"
 	"github.com/iMookatayou/homeservice-backend/internal/db"
 	"github.com/iMookatayou/homeservice-backend/internal/health"
+	"github.com/iMookatayou/homeservice-backend/internal/household"
 	"github.com/iMookatayou/homeservice-backend/internal/httpx"
+	"github.com/iMookatayou/homeservice-backend/internal/migrate"
 	"github.com/iMookatayou/homeservice-backend/internal/notes"
+	"github.com/iMookatayou/homeservice-backend/internal/notifications"
+	"github.com/iMookatayou/homeservice-backend/internal/session"
 	"github.com/iMookatayou/homeservice-backend/internal/user"
 	"github.com/iMookatayou/homeservice-backend/internal/weather"
 
 	"github.com/iMookatayou/homeservice-backend/internal/bills"
+	"github.com/iMookatayou/homeservice-backend/internal/chores"
 	"github.com/iMookatayou/homeservice-backend/internal/contractors"
 	"github.com/iMookatayou/homeservice-backend/internal/files"
 	"github.com/iMookatayou/homeservice-backend/internal/medicine"
 	"github.com/iMookatayou/homeservice-backend/internal/purchases"
+	"github.com/iMookatayou/homeservice-backend/internal/scheduler"
 	"github.com/iMookatayou/homeservice-backend/internal/stocks"
 	"github.com/iMookatayou/homeservice-backend/internal/storage"
 
 	"github.com/iMookatayou/homeservice-backend/internal/media"
+	"github.com/iMookatayou/homeservice-backend/migrations"
 )
 
 func main() {
@@ -33,24 +45,61 @@ func main() {
 	logger, _ := zap.NewProduction()
 	defer logger.Sync()
 
-	ctx := context.Background()
+	// SIGINT/SIGTERM ยกเลิก ctx → เริ่มขั้นตอนปิดระบบท้าย main
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	pool, err := db.Connect(ctx, cfg.DSN)
 	if err != nil {
 		logger.Fatal("db connect", zap.Error(err))
 	}
 	defer pool.Close()
-	
+
+	mig := migrate.New(pool, migrations.FS)
+	mig.Logf = logger.Sugar().Infof
+
+	// subcommand: api migrate up|down|status|to <version>
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(ctx, mig, os.Args[2:]); err != nil {
+			logger.Fatal("migrate", zap.Error(err))
+		}
+		return
+	}
+	if cfg.MigrateOnStart {
+		if err := mig.Up(ctx); err != nil {
+			logger.Fatal("migrate on start", zap.Error(err))
+		}
+	}
+
+	sessRepo := session.Repo{DB: pool}
+	sessSvc := session.Service{
+		Repo:       sessRepo,
+		JWTSecret:  cfg.JWTSecret,
+		AccessTTL:  cfg.AccessTokenTTL,
+		RefreshTTL: cfg.RefreshTokenTTL,
+	}
+	sessHandler := session.Handler{Svc: sessSvc}
+
 	uRepo := user.Repo{DB: pool}
-	uHandler := user.Handler{Repo: uRepo, JWTSecret: cfg.JWTSecret}
+	uHandler := user.Handler{Repo: uRepo, Sessions: sessSvc}
+
+	hhRepo := household.Repo{DB: pool}
+	hhHandler := household.Handler{Repo: hhRepo}
 
 	nRepo := notes.Repo{DB: pool}
 	nHandler := notes.Handler{Repo: nRepo}
 
 	wHandler := weather.Handler{}
 
-	st := storage.New(cfg)
+	st, err := storage.New(cfg)
+	if err != nil {
+		logger.Fatal("storage", zap.Error(err))
+	}
 	fRepo := files.Repo{DB: pool}
-	fHandler := files.Handler{Repo: fRepo, Storage: st, JWTSecret: cfg.JWTSecret}
+	fHandler := files.Handler{
+		Repo: fRepo, Storage: st, JWTSecret: cfg.JWTSecret,
+		Quota: files.Quota{UserBytes: cfg.QuotaUserBytes, HouseholdBytes: cfg.QuotaHouseholdBytes},
+	}
 
 	pRepo := purchases.NewRepo(pool)
 	pSvc := purchases.NewService(pRepo)
@@ -58,10 +107,36 @@ func main() {
 	pRegistrar := purchases.Registrar{H: pHandler}
 
 	httpClient := &http.Client{Timeout: 15 * time.Second}
-	ctrRepo := contractors.NewRepo(10 * time.Minute)          
-	ctrSvc := contractors.NewService(httpClient, ctrRepo, "") 
+	ctrRepo := contractors.NewRepo(10 * time.Minute)
+	ctrSvc := contractors.NewService(httpClient, ctrRepo, "")
 	ctrH := contractors.Handler{Svc: ctrSvc}
 
+	// notifications: in-app feed + email/webhook/webpush
+	// ปลายทางเป็น URL ที่ผู้ใช้กำหนด จึงใช้ client ที่กันการยิงเข้า address ภายใน
+	hookClient := notifications.NewHTTPClient(15 * time.Second)
+	notifier := &notifications.Dispatcher{
+		Repo: notifications.Repo{DB: pool},
+		Senders: map[string]notifications.Sender{
+			notifications.ChannelWebhook: notifications.WebhookSender{Client: hookClient},
+		},
+		Logf: logger.Sugar().Warnf,
+	}
+	if cfg.SMTPHost != "" {
+		notifier.Senders[notifications.ChannelEmail] = notifications.EmailSender{
+			Host: cfg.SMTPHost, Port: cfg.SMTPPort,
+			Username: cfg.SMTPUser, Password: cfg.SMTPPassword, From: cfg.SMTPFrom,
+		}
+	}
+	ntHandler := notifications.Handler{D: notifier}
+	if cfg.VAPIDPrivate != "" {
+		wp, err := notifications.NewWebPushSender(hookClient, cfg.VAPIDSubject, cfg.VAPIDPrivate)
+		if err != nil {
+			logger.Fatal("web push", zap.Error(err))
+		}
+		notifier.Senders[notifications.ChannelWebPush] = wp
+		ntHandler.VAPIDPublicKey = wp.PublicKey
+	}
+
 	bRepo := bills.Repo{DB: pool}
 	bSvc := bills.NewService(bRepo)
 	bHandler := bills.Handler{Svc: bSvc}
@@ -70,75 +145,117 @@ func main() {
 	stkRepo := stocks.NewPgRepo(pool)
 	stkSvc := &stocks.Service{
 		Repo:       stkRepo,
-		Prov:       stocks.NewMockProvider(), 
+		Prov:       stocks.NewMockProvider(),
 		StaleAfter: 3 * time.Minute,
 	}
 
 	mRepo := medicine.NewPGRepo(pool)
-	mSvc := &medicine.Service{Repo: mRepo, Now: time.Now}
+	mCatalog := &medicine.PGCatalog{DB: pool}
+	mSvc := &medicine.Service{Repo: mRepo, Now: time.Now, Location: cfg.SchedulerLocation(), Catalog: mCatalog}
+	pSvc.Stock = medicineRestock{svc: mSvc}
 
-	acqMedia, err := pool.Acquire(ctx)
-	if err != nil {
-		logger.Fatal("acquire media conn", zap.Error(err))
-	}
-	defer acqMedia.Release()
-
-	mdRepo := media.NewPGRepo(acqMedia.Conn())
+	mdRepo := media.NewPGRepo(pool)
 	mdSvc := media.NewService(mdRepo)
 	mdH := media.NewHandler(mdSvc)
 
 	wRepo := media.NewWorkerRepo(pool)
+	rssWorker := media.NewRSSWorker(wRepo, 3*time.Minute, 5*time.Second, 100)
+	rssWorker.Notifier = notifier
+
+	fileGC := &files.GC{
+		Repo: fRepo, Storage: st, Grace: cfg.FilesGCGrace, Apply: cfg.FilesGCApply,
+		Logf: logger.Sugar().Infof,
+	}
+
+	sched := scheduler.New(pool)
+	sched.Location = cfg.SchedulerLocation()
+	sched.Logf = logger.Sugar().Warnf
+	if err := registerJobs(sched, jobDeps{
+		quotes:   &stocks.QuotesWorker{Repo: stkSvc.Repo, Prov: stkSvc.Prov},
+		rss:      rssWorker,
+		bills:    &bills.Generator{Svc: bSvc, Notifier: notifier, DueWithinDays: 3, Logf: logger.Sugar().Infof},
+		medicine: &medicine.AlertWorker{Svc: mSvc, Notifier: notifier},
+		doses:    &medicine.DoseWorker{Svc: mSvc, Notifier: notifier},
+		notes:    &notes.ReminderWorker{Repo: nRepo, Notifier: notifier},
+		files:    &files.Processor{Repo: fRepo, Storage: st, Logf: logger.Sugar().Infof},
+		filesGC:  fileGC,
+	}); err != nil {
+		logger.Fatal("scheduler jobs", zap.Error(err))
+	}
+	fHandler.Kick = func(ctx context.Context) { _ = sched.Trigger(ctx, jobFilesProcess) }
+	schedHandler := scheduler.Handler{S: sched}
 
 	r := chi.NewRouter()
 	for _, m := range httpx.CommonMiddlewares(cfg.CorsOrigin) {
 		r.Use(m)
 	}
 
-	if cfg.StorageBackend == "local" && cfg.LocalDir != "" {
-		fs := http.StripPrefix("/static/", http.FileServer(http.Dir(cfg.LocalDir)))
-		r.Handle("/static/*", fs)
+	// local storage: เสิร์ฟเฉพาะลิงก์ที่ sign แล้ว (GET /api/v1/files/{id}/url) ไม่เปิดโฟลเดอร์ให้ทุกคน
+	if l, ok := st.(*storage.Local); ok {
+		r.Handle("/static/*", http.StripPrefix("/static/", l.SignedHandler()))
 	}
 
 	r.Get("/healthz", health.Live)
-	r.Get("/readyz", health.Ready)
+	r.Get("/readyz", readiness(pool, st, sched).Ready)
 	r.Route("/api/v1", func(api chi.Router) {
-		// public 
+		// public
 		api.Post("/auth/register", uHandler.Register)
 		api.Post("/auth/login", uHandler.Login)
+		sessHandler.RegisterPublic(api)
 		api.Get("/weather/today", wHandler.Today)
 
-		// contractors search 
+		// contractors search
 		ctrH.RegisterRoutes(api)
 
 		// auth-required
 		api.Group(func(pr chi.Router) {
 			pr.Use(auth.RequireAuth(cfg.JWTSecret, auth.NewClaims))
+			pr.Use(auth.RequireSession(sessRepo))
 			pr.Get("/me", uHandler.Me)
+			sessHandler.RegisterRoutes(pr)
+			ntHandler.RegisterRoutes(pr)
 
-			nHandler.RegisterRoutes(pr)
+			// households: สร้าง/เข้าร่วม/จัดการสมาชิก (ยังไม่ต้องเลือกบ้าน)
+			hhHandler.RegisterRoutes(pr)
 			fHandler.RegisterRoutes(pr)
 
-			// purchases
-			pRegistrar.Register(pr)
+			// household-scoped: ต้องเป็นสมาชิกบ้าน (X-Household-ID หรือบ้านแรก)
+			pr.Group(func(hr chi.Router) {
+				hr.Use(auth.RequireHousehold(hhRepo))
 
-			// bills
-			bRegistrar.Register(pr)
+				nHandler.RegisterRoutes(hr)
 
-			// stocks
-			stocks.RegisterRoutes(pr, &stocks.Handler{SVC: stkSvc})
+				// purchases
+				pRegistrar.Register(hr)
 
-			// medicine
-			pr.Route("/medicine", func(r chi.Router) {
-				medicine.MountHTTP(r, mSvc)
-			})
+				// bills
+				bRegistrar.Register(hr)
+
+				// chores
+				chores.Handler{Repo: chores.Repo{DB: pool}}.RegisterRoutes(hr)
+
+				// stocks
+				stocks.RegisterRoutes(hr, &stocks.Handler{SVC: stkSvc})
+
+				// medicine
+				hr.Route("/medicine", func(r chi.Router) {
+					medicine.MountHTTP(r, mSvc)
+				})
 
-			// media
-			mdH.Mount(pr)
+				// media
+				mdH.Mount(hr)
+			})
 		})
 
 		// admin-only
 		api.Group(func(ad chi.Router) {
-			ad.Use(auth.RequireAdmin(cfg.JWTSecret, auth.NewClaims))
+			ad.Use(auth.RequireAuth(cfg.JWTSecret, auth.NewClaims))
+			ad.Use(auth.RequireSession(sessRepo))
+			ad.Use(authz.Require(authz.ActionManage, authz.KindUser))
+			uHandler.RegisterAdminRoutes(ad)
+			schedHandler.RegisterAdminRoutes(ad)
+			files.GCHandler{GC: fileGC}.RegisterAdminRoutes(ad)
+			medicine.CatalogHandler{Catalog: mCatalog}.RegisterAdminRoutes(ad)
 		})
 	})
 
@@ -148,18 +265,16 @@ func main() {
 		return nil
 	})
 
-	// quotes worker (mock)
+	// งานเบื้องหลังทั้งหมดผ่าน scheduler (รันได้หลาย replica; lock ต่องาน)
+	// ใช้ ctx แยกจากสัญญาณ: ให้ HTTP drain ก่อน แล้วค่อยยกเลิกงานเบื้องหลัง
+	bgCtx, cancelBg := context.WithCancel(context.Background())
+	defer cancelBg()
+	schedDone := make(chan struct{})
 	go func() {
-		_ = (&stocks.QuotesWorker{
-			Repo:  stkSvc.Repo,
-			Prov:  stkSvc.Prov,
-			Every: 5 * time.Second,
-		}).Run(context.Background())
-	}()
-
-	go func() {
-		worker := media.NewRSSWorker(wRepo, 3*time.Minute, 5*time.Second, 100)
-		_ = worker.Run(context.Background())
+		defer close(schedDone)
+		if err := sched.Run(bgCtx); err != nil && !errors.Is(err, context.Canceled) {
+			logger.Error("scheduler", zap.Error(err))
+		}
 	}()
 
 	srv := &http.Server{
@@ -167,8 +282,51 @@ func main() {
 		Handler:           r,
 		ReadHeaderTimeout: 10 * time.Second,
 	}
-	logger.Info("listening", zap.String("addr", srv.Addr))
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		logger.Fatal("server", zap.Error(err))
+	srvErr := make(chan error, 1)
+	go func() {
+		logger.Info("listening", zap.String("addr", srv.Addr))
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			srvErr <- err
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		logger.Info("shutdown signal received")
+	case err := <-srvErr:
+		logger.Error("server", zap.Error(err))
+	}
+	stop() // สัญญาณครั้งที่สอง = ปิดทันทีตามค่าเริ่มต้นของ Go
+	if !shutdown(logger, cfg, srv, cancelBg, schedDone) {
+		// งานค้างยังถือ connection อยู่: pool.Close จะรอไม่จบ จึงออกเลย
+		_ = logger.Sync()
+		os.Exit(1)
+	}
+}
+
+// shutdown: /readyz → 503, รอ load balancer ถอด instance, drain HTTP, ยกเลิกงานเบื้องหลังแล้วรอให้จบ
+// ทั้งหมดภายใน cfg.ShutdownTimeout; คืน false ถ้างานเบื้องหลังไม่จบทันเวลา (pool ปิดด้วย defer ใน main)
+func shutdown(logger *zap.Logger, cfg config.Config, srv *http.Server, cancelBg context.CancelFunc, schedDone <-chan struct{}) bool {
+	health.SetDraining(true)
+	deadline, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	select {
+	case <-time.After(cfg.DrainDelay):
+	case <-deadline.Done():
+	}
+	if err := srv.Shutdown(deadline); err != nil {
+		logger.Warn("http drain incomplete; closing connections", zap.Error(err))
+		_ = srv.Close()
+	}
+
+	cancelBg()
+	select {
+	case <-schedDone:
+		logger.Info("background jobs stopped")
+		return true
+	case <-deadline.Done():
+		logger.Warn("background jobs still running at shutdown deadline")
+		return false
 	}
 }
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/iMookatayou/homeservice-backend/internal/migrate"
+)
+
+const migrateUsage = `usage: api migrate <command>
+
+commands:
+  up              รันทุก migration ที่ยังไม่ได้รัน
+  down            ย้อน migration ล่าสุด 1 ตัว
+  status          แสดงสถานะทุก migration
+  to <version>    ขึ้น/ลงให้อยู่ที่ version ที่ระบุ (-1 = ย้อนทุกตัว)
+  baseline <ver>  บันทึกว่ารันถึง version นี้แล้ว (ฐานข้อมูลเดิมที่เคยรันด้วย psql)`
+
+// runMigrate: go run ./cmd/api migrate up|down|status|to <version>|baseline <version>
+func runMigrate(ctx context.Context, m *migrate.Migrator, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	version := func() (int64, error) {
+		if len(args) < 2 {
+			return 0, errors.New(migrateUsage)
+		}
+		return strconv.ParseInt(args[1], 10, 64)
+	}
+
+	switch args[0] {
+	case "up":
+		return m.Up(ctx)
+	case "down":
+		return m.Down(ctx)
+	case "to":
+		v, err := version()
+		if err != nil {
+			return err
+		}
+		return m.To(ctx, v)
+	case "baseline":
+		v, err := version()
+		if err != nil {
+			return err
+		}
+		return m.Baseline(ctx, v)
+	case "status":
+		list, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range list {
+			at := "pending"
+			if s.AppliedAt != nil {
+				at = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(os.Stdout, "%04d  %-28s %s\n", s.Version, s.Name, at)
+		}
+		return nil
+	default:
+		return errors.New(migrateUsage)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/iMookatayou/homeservice-backend/internal/health"
+	"github.com/iMookatayou/homeservice-backend/internal/scheduler"
+	"github.com/iMookatayou/homeservice-backend/internal/storage"
+)
+
+// readiness — dependency ที่ /readyz ตรวจ
+// postgres/storage เป็น critical; งานเบื้องหลังแค่รายงาน (รันบน replica เดียว ล้มแล้วไม่ควรถอดทุกตัว)
+func readiness(pool *pgxpool.Pool, st storage.Service, s *scheduler.Scheduler) health.Readiness {
+	return health.Readiness{
+		Timeout: 2 * time.Second,
+		Checks: []health.Check{
+			{Name: "postgres", Critical: true, Run: func(ctx context.Context) (map[string]any, error) {
+				stat := pool.Stat()
+				detail := map[string]any{
+					"total_conns":    stat.TotalConns(),
+					"acquired_conns": stat.AcquiredConns(),
+					"max_conns":      stat.MaxConns(),
+				}
+				return detail, pool.Ping(ctx)
+			}},
+			{Name: "storage", Critical: true, Run: func(ctx context.Context) (map[string]any, error) {
+				return nil, st.Check(ctx)
+			}},
+			jobCheck(s, "stocks.quotes", time.Minute),
+			jobCheck(s, "media.rss", 15*time.Minute),
+		},
+	}
+}
+
+// jobCheck รายงานเวลาสำเร็จล่าสุดของงาน; เก่ากว่า maxAge หรือไม่เคยสำเร็จ = fail
+func jobCheck(s *scheduler.Scheduler, name string, maxAge time.Duration) health.Check {
+	return health.Check{Name: "job:" + name, Run: func(ctx context.Context) (map[string]any, error) {
+		last, err := s.LastSuccess(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if last == nil {
+			return map[string]any{"last_success_at": nil}, fmt.Errorf("never succeeded")
+		}
+		age := time.Since(*last)
+		detail := map[string]any{"last_success_at": *last, "age_seconds": int64(age.Seconds())}
+		if age > maxAge {
+			return detail, fmt.Errorf("stale: last success %s ago", age.Round(time.Second))
+		}
+		return detail, nil
+	}}
+}
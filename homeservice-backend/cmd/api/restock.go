@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/iMookatayou/homeservice-backend/internal/medicine"
+	"github.com/iMookatayou/homeservice-backend/internal/purchases"
+)
+
+// medicineRestock ต่อยาที่ต่ำกว่าขั้นต่ำเข้ากับรายการซื้อ (purchases.RestockSource)
+type medicineRestock struct {
+	svc *medicine.Service
+}
+
+func (m medicineRestock) RestockItems(ctx context.Context, householdID string, ids []string) ([]purchases.Item, error) {
+	needs, err := m.svc.RestockNeeds(ctx, householdID, ids)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]purchases.Item, 0, len(needs))
+	for _, n := range needs {
+		note := []string{fmt.Sprintf("คงเหลือ %g/%g", n.TotalQty, n.MinQty)}
+		if n.Item.Strength != nil && *n.Item.Strength != "" {
+			note = append([]string{*n.Item.Strength}, note...)
+		}
+		items = append(items, purchases.Item{
+			Name: n.Item.Name, Qty: n.Suggested, Unit: n.Item.Unit, Note: strings.Join(note, " · "),
+		})
+	}
+	return items, nil
+}
@@ -8,9 +8,12 @@ type Claims struct {
 	UserID string `json:"uid"`
 	Email  string `json:"email"`
 	Role   string `json:"role"` // "user" | "admin"
+	// HouseholdID บ้านที่ token ผูกไว้ (optional; header X-Household-ID มีผลก่อน)
+	HouseholdID string `json:"hid,omitempty"`
+	// SessionID อุปกรณ์/refresh-token family ที่ออก token นี้ (ใช้ตรวจการ revoke)
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
 func (c *Claims) GetUserID() string { return c.UserID }
-func (c *Claims) IsAdmin() bool     { return c.Role == "admin" }
 func NewClaims() *Claims            { return &Claims{} }
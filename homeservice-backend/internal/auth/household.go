@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// HouseholdHeader: client เลือกบ้านที่จะทำงานด้วย header นี้ (ถ้าไม่ส่ง ใช้บ้านแรกที่เป็นสมาชิก)
+const HouseholdHeader = "X-Household-ID"
+
+var ErrNotMember = errors.New("not a household member")
+
+// Membership คือสิ่งที่ middleware ต้องใช้ตรวจสมาชิกภาพ (household.Repo implement ให้)
+type Membership interface {
+	MemberRole(ctx context.Context, householdID, userID string) (string, error)
+	DefaultHousehold(ctx context.Context, userID string) (string, error)
+}
+
+type householdCtx struct {
+	ID   string
+	Role string
+}
+
+const householdCtxKey ctxKey = iota + 1
+
+// RequireHousehold — ต้องใช้ต่อจาก RequireAuth; ผูก household_id + role ของผู้ใช้ลง context
+func RequireHousehold(m Membership) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c := ClaimsFrom(r)
+			if c == nil || c.UserID == "" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			hid := strings.TrimSpace(r.Header.Get(HouseholdHeader))
+			if hid == "" {
+				hid = c.HouseholdID
+			}
+			if hid == "" {
+				def, err := m.DefaultHousehold(r.Context(), c.UserID)
+				if err != nil {
+					log.Printf("[AUTH] 🚫 user=%s has no household path=%s", c.UserID, r.URL.Path)
+					http.Error(w, "no household: create or join one first", http.StatusForbidden)
+					return
+				}
+				hid = def
+			}
+
+			role, err := m.MemberRole(r.Context(), hid, c.UserID)
+			if err != nil {
+				log.Printf("[AUTH] 🚫 user=%s not member of household=%s path=%s", c.UserID, hid, r.URL.Path)
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), householdCtxKey, householdCtx{ID: hid, Role: role})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func HouseholdIDFrom(r *http.Request) (string, bool) {
+	if v, ok := r.Context().Value(householdCtxKey).(householdCtx); ok && v.ID != "" {
+		return v.ID, true
+	}
+	return "", false
+}
+
+func HouseholdRoleFrom(r *http.Request) string {
+	if v, ok := r.Context().Value(householdCtxKey).(householdCtx); ok {
+		return v.Role
+	}
+	return ""
+}
@@ -11,11 +11,17 @@ func SignJWT(secret, uid, email string, ttl time.Duration) (string, error) {
 }
 
 func SignJWTWithRole(secret, uid, email, role string, ttl time.Duration) (string, error) {
+	return SignJWTWithSession(secret, uid, email, role, "", ttl)
+}
+
+// SignJWTWithSession เซ็น access token ที่ผูกกับ session (sid) เพื่อให้ยกเลิกได้ก่อนหมดอายุ
+func SignJWTWithSession(secret, uid, email, role, sid string, ttl time.Duration) (string, error) {
 	now := time.Now().UTC()
 	claims := Claims{
-		UserID: uid,
-		Email:  email,
-		Role:   role,
+		UserID:    uid,
+		Email:     email,
+		Role:      role,
+		SessionID: sid,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    Issuer,
 			Subject:   uid,
@@ -113,18 +113,3 @@ func UserIDFrom(r *http.Request) (string, bool) {
 	}
 	return "", false
 }
-
-// RequireAdmin — auth + log 
-func RequireAdmin(secret string, newClaims func() *Claims) func(http.Handler) http.Handler {
-	base := RequireAuth(secret, newClaims)
-	return func(next http.Handler) http.Handler {
-		return base(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if c := ClaimsFrom(r); c == nil || c.Role != "admin" {
-				log.Printf("[AUTH] 🚫 forbidden non-admin user=%v path=%s", c, r.URL.Path)
-				http.Error(w, "forbidden", http.StatusForbidden)
-				return
-			}
-			next.ServeHTTP(w, r)
-		}))
-	}
-}
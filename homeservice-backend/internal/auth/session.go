@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"net/http"
+)
+
+// SessionStore ใช้ตรวจว่า session ของ access token ยังไม่ถูก revoke (session.Repo implement ให้)
+type SessionStore interface {
+	SessionActive(ctx context.Context, sessionID string) (bool, error)
+}
+
+// RequireSession — ต้องใช้ต่อจาก RequireAuth; ปฏิเสธ access token ของ session ที่ logout/ถูกยกเลิกแล้ว
+// token เก่าที่ไม่มี sid (ออกก่อนมีระบบ session) ปล่อยผ่านจนหมดอายุเอง
+func RequireSession(s SessionStore) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c := ClaimsFrom(r)
+			if c == nil {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if c.SessionID != "" {
+				ok, err := s.SessionActive(r.Context(), c.SessionID)
+				if err != nil {
+					log.Printf("[AUTH] ⚠️ session check failed sid=%s: %v", c.SessionID, err)
+					http.Error(w, "internal error", http.StatusInternalServerError)
+					return
+				}
+				if !ok {
+					log.Printf("[AUTH] 🚫 revoked session user=%s sid=%s path=%s", c.UserID, c.SessionID, r.URL.Path)
+					http.Error(w, "session revoked", http.StatusUnauthorized)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func SessionIDFrom(r *http.Request) string {
+	if c := ClaimsFrom(r); c != nil {
+		return c.SessionID
+	}
+	return ""
+}
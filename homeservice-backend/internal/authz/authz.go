@@ -0,0 +1,130 @@
+// Package authz รวมกติกาสิทธิ์ (บทบาทในบ้าน × ชนิด resource × action) ไว้ที่เดียว
+// service เรียก Can(...) แทนการเทียบ requester/owner เองในแต่ละโมดูล
+package authz
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/iMookatayou/homeservice-backend/internal/auth"
+)
+
+type Action string
+
+const (
+	ActionRead     Action = "read"
+	ActionCreate   Action = "create"
+	ActionUpdate   Action = "update"
+	ActionDelete   Action = "delete"
+	ActionManage   Action = "manage" // จัดการสมาชิก/บทบาท
+	ActionClaim    Action = "claim"
+	ActionProgress Action = "progress"
+	ActionCancel   Action = "cancel"
+	ActionAttach   Action = "attach"
+	ActionComplete Action = "complete"
+	ActionSkip     Action = "skip"
+	ActionUnclaim  Action = "unclaim"
+	ActionPay      Action = "pay"
+	ActionApprove  Action = "approve"
+	ActionComment  Action = "comment"
+)
+
+type Kind string
+
+const (
+	KindHousehold Kind = "household"
+	KindMember    Kind = "household_member"
+	KindPurchase  Kind = "purchase"
+	KindTemplate  Kind = "purchase_template"
+	KindBill      Kind = "bill"
+	KindFile      Kind = "file"
+	KindChore     Kind = "chore"
+	KindUser      Kind = "user" // ระดับระบบ (ไม่ผูกบ้าน)
+)
+
+// บทบาทในบ้าน (ตรงกับ household.Role*) และบทบาทระดับระบบ (users.role)
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+
+	SystemAdmin = "admin"
+	SystemUser  = "user"
+)
+
+// Subject = ผู้กระทำ
+type Subject struct {
+	UserID        string
+	SystemRole    string // users.role: user|admin
+	HouseholdID   string // บ้านที่เลือกอยู่ (RequireHousehold)
+	HouseholdRole string // owner|admin|member
+}
+
+// Resource = สิ่งที่ถูกกระทำ; OwnerID = ผู้สร้าง/ผู้ขอ, Participants = ผู้เกี่ยวข้องอื่น (เช่น buyer)
+type Resource struct {
+	Kind         Kind
+	HouseholdID  string
+	OwnerID      string
+	Participants []string
+}
+
+// SubjectFrom ประกอบ Subject จาก claims + household context ของ request
+func SubjectFrom(r *http.Request) Subject {
+	s := Subject{HouseholdRole: auth.HouseholdRoleFrom(r)}
+	if c := auth.ClaimsFrom(r); c != nil {
+		s.UserID = c.UserID
+		s.SystemRole = c.Role
+	}
+	s.HouseholdID, _ = auth.HouseholdIDFrom(r)
+	return s
+}
+
+// Can ตอบว่า subject ทำ action กับ resource ได้หรือไม่ ตาม Policies
+func Can(ctx context.Context, s Subject, a Action, res Resource) bool {
+	rule, ok := Policies[res.Kind][a]
+	if !ok || s.UserID == "" {
+		return false
+	}
+	if rule.System {
+		return contains(rule.Roles, s.SystemRole)
+	}
+	// resource ในบ้าน: ต้องเป็นบ้านเดียวกับที่ subject เลือกอยู่เสมอ
+	if res.HouseholdID != "" && res.HouseholdID != s.HouseholdID {
+		return false
+	}
+	if res.HouseholdID != "" && contains(rule.Roles, s.HouseholdRole) {
+		return true
+	}
+	if rule.Owner && res.OwnerID != "" && res.OwnerID == s.UserID {
+		return true
+	}
+	if rule.Participant && contains(res.Participants, s.UserID) {
+		return true
+	}
+	return false
+}
+
+func contains(list []string, v string) bool {
+	if v == "" {
+		return false
+	}
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Require — middleware สำหรับ action ระดับระบบ (เช่น route กลุ่ม admin) ใช้ต่อจาก RequireAuth
+func Require(a Action, k Kind) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !Can(r.Context(), SubjectFrom(r), a, Resource{Kind: k}) {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
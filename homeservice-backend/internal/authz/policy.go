@@ -0,0 +1,70 @@
+package authz
+
+// Rule อนุญาตเมื่อ (บทบาทอยู่ใน Roles) หรือ (Owner และเป็นเจ้าของ) หรือ (Participant และอยู่ใน Participants)
+// System=true: Roles คือบทบาทระดับระบบ (users.role) แทนบทบาทในบ้าน
+type Rule struct {
+	Roles       []string
+	Owner       bool
+	Participant bool
+	System      bool
+}
+
+var (
+	anyMember = []string{RoleOwner, RoleAdmin, RoleMember}
+	managers  = []string{RoleOwner, RoleAdmin}
+)
+
+// Policies — ตารางสิทธิ์ทั้งหมดของระบบ; kind/action ที่ไม่อยู่ในตาราง = ไม่อนุญาต
+var Policies = map[Kind]map[Action]Rule{
+	KindHousehold: {
+		ActionRead:   {Roles: anyMember},
+		ActionManage: {Roles: managers}, // จัดการโค้ดเชิญ
+	},
+	KindMember: { // OwnerID = user ของสมาชิกคนนั้น
+		ActionUpdate: {Roles: []string{RoleOwner}},   // เปลี่ยนบทบาท
+		ActionDelete: {Owner: true, Roles: managers}, // ออกจากบ้านเอง หรือผู้ดูแลเอาออก
+	},
+	KindPurchase: {
+		ActionRead:     {Roles: anyMember},
+		ActionCreate:   {Roles: anyMember},
+		ActionClaim:    {Roles: anyMember},
+		ActionComment:  {Roles: anyMember},
+		ActionUpdate:   {Owner: true},                  // requester แก้ภายในช่วงที่แก้ได้
+		ActionDelete:   {Owner: true, Roles: managers}, // requester หรือผู้ดูแลบ้าน
+		ActionCancel:   {Owner: true, Participant: true, Roles: managers},
+		ActionAttach:   {Owner: true, Participant: true},
+		ActionProgress: {Participant: true}, // buyer เท่านั้น
+		ActionApprove:  {Roles: managers},   // อนุมัติ/ปฏิเสธคำขอที่เกินเกณฑ์
+		ActionManage:   {Roles: managers},   // เกณฑ์อนุมัติและงบรายเดือนต่อหมวด
+	},
+	KindTemplate: { // OwnerID = ผู้สร้าง template
+		ActionRead:   {Roles: anyMember},
+		ActionCreate: {Roles: anyMember},
+		ActionUpdate: {Owner: true, Roles: managers},
+		ActionDelete: {Owner: true, Roles: managers},
+	},
+	KindBill: {
+		ActionRead:   {Roles: anyMember},
+		ActionCreate: {Roles: managers},
+		ActionUpdate: {Owner: true, Roles: managers},
+		ActionDelete: {Owner: true, Roles: managers},
+		ActionPay:    {Roles: anyMember},
+		ActionManage: {Roles: managers}, // แม่แบบบิลประจำ
+	},
+	KindChore: { // Participants = คนที่รับงานอยู่ (claimed_by); complete/skip นับคนที่ถึงตา (assigned_to) ด้วย
+		ActionRead:     {Roles: anyMember},
+		ActionCreate:   {Roles: anyMember},
+		ActionClaim:    {Roles: anyMember},
+		ActionComplete: {Participant: true, Roles: managers}, // งานที่ยังไม่มีเจ้าของ: สมาชิกทุกคน (ดู chores.authorize)
+		ActionSkip:     {Participant: true, Roles: managers},
+		ActionUnclaim:  {Participant: true, Roles: managers},
+	},
+	KindFile: { // Participants = สมาชิกบ้านที่มีรายการอ้างถึงไฟล์นี้ (แนบคำขอซื้อ, สลิปบิล, รูปยา)
+		ActionRead:   {Owner: true, Participant: true},
+		ActionDelete: {Owner: true},
+	},
+	KindUser: {
+		ActionRead:   {System: true, Roles: []string{SystemAdmin}},
+		ActionManage: {System: true, Roles: []string{SystemAdmin}},
+	},
+}
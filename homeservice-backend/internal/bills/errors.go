@@ -0,0 +1,17 @@
+package bills
+
+import "errors"
+
+var (
+	// สิทธิ์ไม่พอตาม authz.Policies
+	ErrForbidden = errors.New("forbidden")
+
+	// ไม่พบบิล/แม่แบบในบ้านนี้
+	ErrNotFound = errors.New("bill not found")
+
+	// สถานะไม่อนุญาต เช่น จ่ายบิลที่จ่ายไปแล้ว
+	ErrConflict = errors.New("conflict")
+
+	// payload ไม่ถูกต้อง เช่น ใบเสร็จไม่ใช่ไฟล์ของสมาชิกบ้าน หรือช่วงวันที่ผิด
+	ErrBadRequest = errors.New("bad request")
+)
@@ -1,6 +1,8 @@
 package bills
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -10,6 +12,7 @@ import (
 
 	// ปรับ import ให้ตรงกับโมดูล auth ของคุณ
 	"github.com/iMookatayou/homeservice-backend/internal/auth"
+	"github.com/iMookatayou/homeservice-backend/internal/authz"
 )
 
 type Handler struct {
@@ -18,8 +21,16 @@ type Handler struct {
 
 func (h Handler) RegisterRoutes(r chi.Router) {
 	r.Post("/bills", h.createBill)
-	r.Get("/bills", h.listBills)
-	r.Get("/bills/summary", h.summary)
+	r.Get("/bills", h.listBills)       // ?status=unpaid|paid|overdue&type=&month=YYYY-MM|from=&to=
+	r.Get("/bills/summary", h.summary) // ?month=YYYY-MM หรือ from=YYYY-MM-DD&to=YYYY-MM-DD
+	r.Get("/bills/templates", h.listTemplates)
+	r.Post("/bills/templates", h.createTemplate)
+	r.Patch("/bills/templates/{id}", h.updateTemplate)
+	r.Delete("/bills/templates/{id}", h.deleteTemplate)
+	r.Get("/bills/{id}", h.getBill)
+	r.Patch("/bills/{id}", h.updateBill)
+	r.Delete("/bills/{id}", h.deleteBill)
+	r.Post("/bills/{id}/pay", h.pay)
 }
 
 func (h Handler) createBill(w http.ResponseWriter, r *http.Request) {
@@ -46,36 +57,284 @@ func (h Handler) createBill(w http.ResponseWriter, r *http.Request) {
 	}
 	req.CreatedBy = userUUID
 
-	if req.Status == "paid" {
+	hid, ok := householdFrom(w, r)
+	if !ok {
+		return
+	}
+	req.HouseholdID = hid
+
+	// overdue คำนวณจาก due_date เสมอ จึงรับแค่ unpaid|paid
+	switch req.Status {
+	case "", StatusUnpaid:
+		req.Status = StatusUnpaid
+		req.PaidAt, req.PaidAmount, req.PaidBy = nil, nil, nil
+	case StatusPaid:
 		if req.PaidAt == nil {
 			t := now
 			req.PaidAt = &t
 		}
-	} else {
-		req.PaidAt = nil
+		if req.PaidAmount == nil {
+			req.PaidAmount = &req.Amount
+		}
+		req.PaidBy = &userUUID
+	default:
+		http.Error(w, "status must be unpaid or paid", http.StatusBadRequest)
+		return
 	}
+	req.ReceiptFileID, req.TemplateID, req.PaymentMethod = nil, nil, nil
 
-	if err := h.Svc.CreateBill(r.Context(), &req); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.Svc.CreateBill(r.Context(), authz.SubjectFrom(r), &req); err != nil {
+		writeErr(w, err)
 		return
 	}
 	render.JSON(w, r, req)
 }
 
 func (h Handler) listBills(w http.ResponseWriter, r *http.Request) {
-	list, err := h.Svc.ListBills(r.Context())
+	hid, ok := householdFrom(w, r)
+	if !ok {
+		return
+	}
+	p, err := parsePeriod(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f := ListFilter{Status: r.URL.Query().Get("status"), Type: r.URL.Query().Get("type"), Period: p}
+	switch f.Status {
+	case "", StatusUnpaid, StatusPaid, StatusOverdue:
+	default:
+		http.Error(w, "status must be unpaid, paid or overdue", http.StatusBadRequest)
+		return
+	}
+	list, err := h.Svc.ListBills(r.Context(), authz.SubjectFrom(r), hid, f)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeErr(w, err)
 		return
 	}
 	render.JSON(w, r, list)
 }
 
 func (h Handler) summary(w http.ResponseWriter, r *http.Request) {
-	res, err := h.Svc.Summarize(r.Context())
+	hid, ok := householdFrom(w, r)
+	if !ok {
+		return
+	}
+	p, err := parsePeriod(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	res, err := h.Svc.Summarize(r.Context(), authz.SubjectFrom(r), hid, p)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeErr(w, err)
 		return
 	}
 	render.JSON(w, r, res)
 }
+
+func (h Handler) getBill(w http.ResponseWriter, r *http.Request) {
+	hid, id, ok := householdAndID(w, r)
+	if !ok {
+		return
+	}
+	b, err := h.Svc.GetBill(r.Context(), authz.SubjectFrom(r), hid, id)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	render.JSON(w, r, b)
+}
+
+func (h Handler) updateBill(w http.ResponseWriter, r *http.Request) {
+	hid, id, ok := householdAndID(w, r)
+	if !ok {
+		return
+	}
+	var req UpdateBillReq
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	b, err := h.Svc.UpdateBill(r.Context(), authz.SubjectFrom(r), hid, id, req)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	render.JSON(w, r, b)
+}
+
+func (h Handler) deleteBill(w http.ResponseWriter, r *http.Request) {
+	hid, id, ok := householdAndID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Svc.DeleteBill(r.Context(), authz.SubjectFrom(r), hid, id); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// pay บันทึกการจ่าย {amount?, method, receipt_file_id?, paid_at?}
+func (h Handler) pay(w http.ResponseWriter, r *http.Request) {
+	hid, id, ok := householdAndID(w, r)
+	if !ok {
+		return
+	}
+	var req PayReq
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	b, err := h.Svc.Pay(r.Context(), authz.SubjectFrom(r), hid, id, req)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	render.JSON(w, r, b)
+}
+
+func (h Handler) listTemplates(w http.ResponseWriter, r *http.Request) {
+	hid, ok := householdFrom(w, r)
+	if !ok {
+		return
+	}
+	list, err := h.Svc.ListTemplates(r.Context(), authz.SubjectFrom(r), hid)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	render.JSON(w, r, list)
+}
+
+func (h Handler) createTemplate(w http.ResponseWriter, r *http.Request) {
+	hid, ok := householdFrom(w, r)
+	if !ok {
+		return
+	}
+	userIDStr, _ := auth.UserIDFrom(r)
+	uid, err := uuid.Parse(userIDStr)
+	if err != nil {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return
+	}
+	var req TemplateReq
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	t, err := h.Svc.CreateTemplate(r.Context(), authz.SubjectFrom(r), hid, uid, req)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, t)
+}
+
+func (h Handler) updateTemplate(w http.ResponseWriter, r *http.Request) {
+	hid, id, ok := householdAndID(w, r)
+	if !ok {
+		return
+	}
+	var req TemplateReq
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	t, err := h.Svc.UpdateTemplate(r.Context(), authz.SubjectFrom(r), hid, id, req)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	render.JSON(w, r, t)
+}
+
+func (h Handler) deleteTemplate(w http.ResponseWriter, r *http.Request) {
+	hid, id, ok := householdAndID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Svc.DeleteTemplate(r.Context(), authz.SubjectFrom(r), hid, id); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeErr(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrForbidden):
+		code = http.StatusForbidden
+	case errors.Is(err, ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, ErrConflict):
+		code = http.StatusConflict
+	case errors.Is(err, ErrBadRequest):
+		code = http.StatusBadRequest
+	}
+	http.Error(w, err.Error(), code)
+}
+
+// parsePeriod อ่าน ?month=YYYY-MM หรือ ?from=YYYY-MM-DD&to=YYYY-MM-DD (ว่าง = ทั้งหมด)
+func parsePeriod(r *http.Request) (Period, error) {
+	var p Period
+	q := r.URL.Query()
+	if m := q.Get("month"); m != "" {
+		start, err := time.Parse("2006-01", m)
+		if err != nil {
+			return p, fmt.Errorf("month must be YYYY-MM")
+		}
+		end := start.AddDate(0, 1, -1)
+		p.From, p.To = &start, &end
+		return p, nil
+	}
+	for _, it := range []struct {
+		key string
+		dst **time.Time
+	}{{"from", &p.From}, {"to", &p.To}} {
+		v := q.Get(it.key)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return p, fmt.Errorf("%s must be YYYY-MM-DD", it.key)
+		}
+		*it.dst = &t
+	}
+	if p.From != nil && p.To != nil && p.To.Before(*p.From) {
+		return p, fmt.Errorf("to must not be before from")
+	}
+	return p, nil
+}
+
+func householdAndID(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	hid, ok := householdFrom(w, r)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return hid, id, true
+}
+
+// householdFrom ดึง household จาก context (RequireHousehold) แล้วแปลงเป็น UUID
+func householdFrom(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	hidStr, ok := auth.HouseholdIDFrom(r)
+	if !ok {
+		http.Error(w, "household required", http.StatusForbidden)
+		return uuid.Nil, false
+	}
+	hid, err := uuid.Parse(hidStr)
+	if err != nil {
+		http.Error(w, "invalid household ID", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return hid, true
+}
@@ -6,16 +6,29 @@ import (
 	"github.com/google/uuid"
 )
 
+// สถานะบิล: เก็บจริงแค่ unpaid|paid, overdue คำนวณจาก due_date ตอนอ่าน
+const (
+	StatusUnpaid  = "unpaid"
+	StatusPaid    = "paid"
+	StatusOverdue = "overdue"
+)
+
 type Bill struct {
 	ID                 uuid.UUID  `json:"id"`
+	HouseholdID        uuid.UUID  `json:"household_id"`
 	Type               string     `json:"type"`
 	Title              string     `json:"title"`
 	Amount             float64    `json:"amount"`
 	BillingPeriodStart *time.Time `json:"billing_period_start,omitempty"`
 	BillingPeriodEnd   *time.Time `json:"billing_period_end,omitempty"`
 	DueDate            time.Time  `json:"due_date"`
-	Status             string     `json:"status"`
+	Status             string     `json:"status"` // unpaid|paid|overdue
 	PaidAt             *time.Time `json:"paid_at,omitempty"`
+	PaidAmount         *float64   `json:"paid_amount,omitempty"`
+	PaymentMethod      *string    `json:"payment_method,omitempty"`
+	PaidBy             *uuid.UUID `json:"paid_by,omitempty"`
+	ReceiptFileID      *uuid.UUID `json:"receipt_file_id,omitempty"`
+	TemplateID         *uuid.UUID `json:"template_id,omitempty"` // สร้างจากแม่แบบบิลประจำ
 	Note               *string    `json:"note,omitempty"`
 	CreatedBy          uuid.UUID  `json:"created_by"`
 	CreatedAt          time.Time  `json:"created_at"`
@@ -23,9 +36,99 @@ type Bill struct {
 }
 
 type Summary struct {
-	Type        string  `json:"type"`
-	TotalAmount float64 `json:"total_amount"`
-	TotalPaid   float64 `json:"total_paid"`
-	TotalUnpaid float64 `json:"total_unpaid"`
-	Count       int     `json:"count"`
+	Type         string  `json:"type"`
+	TotalAmount  float64 `json:"total_amount"`
+	TotalPaid    float64 `json:"total_paid"`
+	TotalUnpaid  float64 `json:"total_unpaid"`
+	TotalOverdue float64 `json:"total_overdue"` // ส่วนหนึ่งของ total_unpaid ที่เลยกำหนดแล้ว
+	Count        int     `json:"count"`
+}
+
+// Period ช่วง due_date (รวมทั้งสองฝั่ง); ค่า nil = ไม่จำกัด
+type Period struct {
+	From *time.Time
+	To   *time.Time
+}
+
+type ListFilter struct {
+	Status string // unpaid|paid|overdue
+	Type   string
+	Period Period
+}
+
+// UpdateBillReq PATCH: ส่งเฉพาะฟิลด์ที่ต้องการแก้
+type UpdateBillReq struct {
+	Type               *string    `json:"type,omitempty"`
+	Title              *string    `json:"title,omitempty"`
+	Amount             *float64   `json:"amount,omitempty"`
+	BillingPeriodStart *time.Time `json:"billing_period_start,omitempty"`
+	BillingPeriodEnd   *time.Time `json:"billing_period_end,omitempty"`
+	DueDate            *time.Time `json:"due_date,omitempty"`
+	Note               *string    `json:"note,omitempty"`
+}
+
+// PayReq บันทึกการจ่าย; amount ว่าง = จ่ายเต็มยอดบิล
+type PayReq struct {
+	Amount        *float64   `json:"amount,omitempty"`
+	Method        string     `json:"method"` // cash|transfer|card|promptpay|...
+	ReceiptFileID *uuid.UUID `json:"receipt_file_id,omitempty"`
+	PaidAt        *time.Time `json:"paid_at,omitempty"`
+}
+
+// Template = แม่แบบบิลประจำ (ค่าไฟ/ค่าน้ำ/อินเทอร์เน็ต) ที่ระบบสร้างบิลรอบถัดไปให้เอง
+type Template struct {
+	ID             uuid.UUID `json:"id"`
+	HouseholdID    uuid.UUID `json:"household_id"`
+	Type           string    `json:"type"`
+	Title          string    `json:"title"`
+	Amount         float64   `json:"amount"`
+	DayOfMonth     int       `json:"day_of_month"`    // วันครบกำหนด (เดือนที่สั้นกว่าใช้วันสุดท้าย)
+	IntervalMonths int       `json:"interval_months"` // 1 = ทุกเดือน
+	LeadDays       int       `json:"lead_days"`       // สร้างบิลล่วงหน้ากี่วัน
+	NextDue        time.Time `json:"next_due"`
+	Active         bool      `json:"active"`
+	Note           *string   `json:"note,omitempty"`
+	CreatedBy      uuid.UUID `json:"created_by"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type TemplateReq struct {
+	Type           *string    `json:"type,omitempty"`
+	Title          *string    `json:"title,omitempty"`
+	Amount         *float64   `json:"amount,omitempty"`
+	DayOfMonth     *int       `json:"day_of_month,omitempty"`
+	IntervalMonths *int       `json:"interval_months,omitempty"`
+	LeadDays       *int       `json:"lead_days,omitempty"`
+	NextDue        *time.Time `json:"next_due,omitempty"` // ว่าง = วันครบกำหนดถัดไปนับจากวันนี้
+	Active         *bool      `json:"active,omitempty"`
+	Note           *string    `json:"note,omitempty"`
+}
+
+// dueIn วันที่ day ของเดือน (year, month); เดือนที่ไม่มีวันนั้นใช้วันสุดท้ายของเดือน
+func dueIn(year int, month time.Month, day int) time.Time {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if day > last {
+		day = last
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// advance เลื่อนวันครบกำหนดไป months เดือน โดยยึด day_of_month ของแม่แบบ (31 ม.ค. -> 28/29 ก.พ. -> 31 มี.ค.)
+func advance(due time.Time, day, months int) time.Time {
+	return dueIn(due.Year(), due.Month()+time.Month(months), day)
+}
+
+// firstDue วันครบกำหนดแรกที่ไม่ก่อน today
+func firstDue(today time.Time, day int) time.Time {
+	d := dueIn(today.Year(), today.Month(), day)
+	if d.Before(dateOnly(today)) {
+		d = dueIn(today.Year(), today.Month()+1, day)
+	}
+	return d
+}
+
+func dateOnly(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
 }
@@ -2,7 +2,13 @@ package bills
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -10,70 +16,339 @@ type Repo struct {
 	DB *pgxpool.Pool
 }
 
+// statusExpr สถานะที่ client เห็น: unpaid ที่เลย due_date = overdue
+const statusExpr = `CASE WHEN status = 'unpaid' AND due_date < current_date THEN 'overdue' ELSE status END`
+
+const billCols = `id, household_id, type, title, amount, billing_period_start, billing_period_end,
+          due_date, ` + statusExpr + `, paid_at, paid_amount, payment_method, paid_by, receipt_file_id, template_id,
+          note, created_by, created_at, updated_at`
+
+func scanBill(row pgx.Row) (Bill, error) {
+	var b Bill
+	err := row.Scan(
+		&b.ID, &b.HouseholdID, &b.Type, &b.Title, &b.Amount,
+		&b.BillingPeriodStart, &b.BillingPeriodEnd,
+		&b.DueDate, &b.Status, &b.PaidAt, &b.PaidAmount, &b.PaymentMethod, &b.PaidBy, &b.ReceiptFileID, &b.TemplateID,
+		&b.Note, &b.CreatedBy, &b.CreatedAt, &b.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return b, ErrNotFound
+	}
+	return b, err
+}
+
 // CreateBill เพิ่มบิลใหม่
 func (r Repo) CreateBill(ctx context.Context, b *Bill) error {
 	_, err := r.DB.Exec(ctx, `
-        INSERT INTO bills (id, type, title, amount, billing_period_start, billing_period_end,
-          due_date, status, paid_at, note, created_by, created_at, updated_at)
-        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)`,
-		b.ID, b.Type, b.Title, b.Amount,
+        INSERT INTO bills (id, household_id, type, title, amount, billing_period_start, billing_period_end,
+          due_date, status, paid_at, paid_amount, paid_by, note, created_by, created_at, updated_at)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16)`,
+		b.ID, b.HouseholdID, b.Type, b.Title, b.Amount,
 		b.BillingPeriodStart, b.BillingPeriodEnd,
-		b.DueDate, b.Status, b.PaidAt, b.Note,
+		b.DueDate, b.Status, b.PaidAt, b.PaidAmount, b.PaidBy, b.Note,
 		b.CreatedBy, b.CreatedAt, b.UpdatedAt,
 	)
 	return err
 }
 
-// ListBills ดึงรายการบิลทั้งหมด
-func (r Repo) ListBills(ctx context.Context) ([]Bill, error) {
+func (r Repo) GetBill(ctx context.Context, householdID, id uuid.UUID) (Bill, error) {
+	return scanBill(r.DB.QueryRow(ctx, `
+        SELECT `+billCols+`
+        FROM bills WHERE id = $1 AND household_id = $2`, id, householdID))
+}
+
+// periodWhere ต่อเงื่อนไขช่วง due_date; args เริ่มที่ $n
+func periodWhere(p Period, args []any) (string, []any) {
+	var sb strings.Builder
+	if p.From != nil {
+		args = append(args, *p.From)
+		fmt.Fprintf(&sb, " AND due_date >= $%d", len(args))
+	}
+	if p.To != nil {
+		args = append(args, *p.To)
+		fmt.Fprintf(&sb, " AND due_date <= $%d", len(args))
+	}
+	return sb.String(), args
+}
+
+// ListBills ดึงรายการบิลของบ้าน ตามสถานะ/ประเภท/ช่วงวันครบกำหนด
+func (r Repo) ListBills(ctx context.Context, householdID uuid.UUID, f ListFilter) ([]Bill, error) {
+	where, args := periodWhere(f.Period, []any{householdID})
+	if f.Status != "" {
+		args = append(args, f.Status)
+		where += fmt.Sprintf(" AND %s = $%d", statusExpr, len(args))
+	}
+	if f.Type != "" {
+		args = append(args, f.Type)
+		where += fmt.Sprintf(" AND type = $%d", len(args))
+	}
 	rows, err := r.DB.Query(ctx, `
-        SELECT id, type, title, amount, billing_period_start, billing_period_end,
-          due_date, status, paid_at, note, created_by, created_at, updated_at
+        SELECT `+billCols+`
         FROM bills
-        ORDER BY due_date DESC`)
+        WHERE household_id = $1`+where+`
+        ORDER BY due_date DESC`, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var result []Bill
+	result := []Bill{}
 	for rows.Next() {
-		var b Bill
-		if err := rows.Scan(
-			&b.ID, &b.Type, &b.Title, &b.Amount,
-			&b.BillingPeriodStart, &b.BillingPeriodEnd,
-			&b.DueDate, &b.Status, &b.PaidAt, &b.Note,
-			&b.CreatedBy, &b.CreatedAt, &b.UpdatedAt,
-		); err != nil {
+		b, err := scanBill(rows)
+		if err != nil {
 			return nil, err
 		}
 		result = append(result, b)
 	}
-	return result, nil
+	return result, rows.Err()
 }
 
-// Summarize ดึงยอดรวมตามประเภทบิล
-func (r Repo) Summarize(ctx context.Context) ([]Summary, error) {
+// UpdateBill แก้เฉพาะฟิลด์ที่ส่งมา (COALESCE กับค่าเดิม)
+func (r Repo) UpdateBill(ctx context.Context, householdID, id uuid.UUID, in UpdateBillReq) (Bill, error) {
+	return scanBill(r.DB.QueryRow(ctx, `
+        UPDATE bills SET
+          type = COALESCE($3, type),
+          title = COALESCE($4, title),
+          amount = COALESCE($5, amount),
+          billing_period_start = COALESCE($6, billing_period_start),
+          billing_period_end = COALESCE($7, billing_period_end),
+          due_date = COALESCE($8, due_date),
+          note = COALESCE($9, note),
+          updated_at = now()
+        WHERE id = $1 AND household_id = $2
+        RETURNING `+billCols,
+		id, householdID, in.Type, in.Title, in.Amount,
+		in.BillingPeriodStart, in.BillingPeriodEnd, in.DueDate, in.Note))
+}
+
+func (r Repo) DeleteBill(ctx context.Context, householdID, id uuid.UUID) error {
+	ct, err := r.DB.Exec(ctx, `DELETE FROM bills WHERE id = $1 AND household_id = $2`, id, householdID)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Pay บันทึกการจ่าย; บิลที่จ่ายแล้วคืน ErrConflict
+func (r Repo) Pay(ctx context.Context, householdID, id, userID uuid.UUID, amount float64, method string,
+	receipt *uuid.UUID, paidAt time.Time) (Bill, error) {
+	b, err := scanBill(r.DB.QueryRow(ctx, `
+        UPDATE bills SET
+          status = 'paid', paid_at = $4, paid_amount = $5, payment_method = NULLIF($6, ''),
+          paid_by = $3, receipt_file_id = $7, updated_at = now()
+        WHERE id = $1 AND household_id = $2 AND status = 'unpaid'
+        RETURNING `+billCols,
+		id, householdID, userID, paidAt, amount, method, receipt))
+	if errors.Is(err, ErrNotFound) {
+		if _, gerr := r.GetBill(ctx, householdID, id); gerr != nil {
+			return b, gerr
+		}
+		return b, ErrConflict
+	}
+	return b, err
+}
+
+// DueBills บิลที่ยังไม่จ่ายและครบกำหนดไม่เกิน until (รวมที่เลยกำหนดแล้ว) ของทุกบ้าน
+func (r Repo) DueBills(ctx context.Context, until time.Time) ([]Bill, error) {
+	rows, err := r.DB.Query(ctx, `
+        SELECT `+billCols+`
+        FROM bills
+        WHERE status = 'unpaid' AND due_date <= $1::date AND household_id IS NOT NULL
+        ORDER BY due_date`, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []Bill{}
+	for rows.Next() {
+		b, err := scanBill(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, b)
+	}
+	return result, rows.Err()
+}
+
+// ReceiptUsable ใบเสร็จต้องเป็นไฟล์ที่อัปโหลดโดยสมาชิกของบ้านนี้
+func (r Repo) ReceiptUsable(ctx context.Context, householdID, fileID uuid.UUID) (bool, error) {
+	var ok bool
+	err := r.DB.QueryRow(ctx, `
+        SELECT EXISTS (
+          SELECT 1 FROM files f
+          JOIN household_members m ON m.user_id = f.owner_id
+          WHERE f.id = $1 AND m.household_id = $2
+        )`, fileID, householdID).Scan(&ok)
+	return ok, err
+}
+
+// Summarize ดึงยอดรวมตามประเภทบิลของบ้าน ในช่วง due_date ที่กำหนด
+func (r Repo) Summarize(ctx context.Context, householdID uuid.UUID, p Period) ([]Summary, error) {
+	where, args := periodWhere(p, []any{householdID})
 	rows, err := r.DB.Query(ctx, `
         SELECT type,
           COUNT(*) AS count,
           SUM(amount) AS total_amount,
-          SUM(CASE WHEN status = 'paid' THEN amount ELSE 0 END) AS total_paid,
-          SUM(CASE WHEN status != 'paid' THEN amount ELSE 0 END) AS total_unpaid
+          SUM(CASE WHEN status = 'paid' THEN COALESCE(paid_amount, amount) ELSE 0 END) AS total_paid,
+          SUM(CASE WHEN status != 'paid' THEN amount ELSE 0 END) AS total_unpaid,
+          SUM(CASE WHEN status != 'paid' AND due_date < current_date THEN amount ELSE 0 END) AS total_overdue
         FROM bills
-        GROUP BY type`)
+        WHERE household_id = $1`+where+`
+        GROUP BY type
+        ORDER BY type`, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var result []Summary
+	result := []Summary{}
 	for rows.Next() {
 		var s Summary
-		if err := rows.Scan(&s.Type, &s.Count, &s.TotalAmount, &s.TotalPaid, &s.TotalUnpaid); err != nil {
+		if err := rows.Scan(&s.Type, &s.Count, &s.TotalAmount, &s.TotalPaid, &s.TotalUnpaid, &s.TotalOverdue); err != nil {
 			return nil, err
 		}
 		result = append(result, s)
 	}
-	return result, nil
+	return result, rows.Err()
+}
+
+// --- templates ---
+
+const templateCols = `id, household_id, type, title, amount, day_of_month, interval_months, lead_days,
+          next_due, active, note, created_by, created_at, updated_at`
+
+func scanTemplate(row pgx.Row) (Template, error) {
+	var t Template
+	err := row.Scan(&t.ID, &t.HouseholdID, &t.Type, &t.Title, &t.Amount, &t.DayOfMonth, &t.IntervalMonths,
+		&t.LeadDays, &t.NextDue, &t.Active, &t.Note, &t.CreatedBy, &t.CreatedAt, &t.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return t, ErrNotFound
+	}
+	return t, err
+}
+
+func (r Repo) CreateTemplate(ctx context.Context, t *Template) error {
+	got, err := scanTemplate(r.DB.QueryRow(ctx, `
+        INSERT INTO bill_templates (household_id, type, title, amount, day_of_month, interval_months,
+          lead_days, next_due, active, note, created_by)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+        RETURNING `+templateCols,
+		t.HouseholdID, t.Type, t.Title, t.Amount, t.DayOfMonth, t.IntervalMonths,
+		t.LeadDays, t.NextDue, t.Active, t.Note, t.CreatedBy))
+	if err != nil {
+		return err
+	}
+	*t = got
+	return nil
+}
+
+func (r Repo) GetTemplate(ctx context.Context, householdID, id uuid.UUID) (Template, error) {
+	return scanTemplate(r.DB.QueryRow(ctx, `
+        SELECT `+templateCols+` FROM bill_templates WHERE id = $1 AND household_id = $2`, id, householdID))
+}
+
+func (r Repo) ListTemplates(ctx context.Context, householdID uuid.UUID) ([]Template, error) {
+	rows, err := r.DB.Query(ctx, `
+        SELECT `+templateCols+`
+        FROM bill_templates WHERE household_id = $1
+        ORDER BY active DESC, next_due`, householdID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Template{}
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// UpdateTemplate เขียนทับทั้งแถว (service merge ค่าเดิมกับ PATCH ให้แล้ว)
+func (r Repo) UpdateTemplate(ctx context.Context, t Template) (Template, error) {
+	return scanTemplate(r.DB.QueryRow(ctx, `
+        UPDATE bill_templates SET
+          type = $3, title = $4, amount = $5, day_of_month = $6, interval_months = $7,
+          lead_days = $8, next_due = $9, active = $10, note = $11
+        WHERE id = $1 AND household_id = $2
+        RETURNING `+templateCols,
+		t.ID, t.HouseholdID, t.Type, t.Title, t.Amount, t.DayOfMonth, t.IntervalMonths,
+		t.LeadDays, t.NextDue, t.Active, t.Note))
+}
+
+// DeleteTemplate ลบแม่แบบ; บิลที่สร้างไปแล้วยังอยู่ (template_id -> NULL)
+func (r Repo) DeleteTemplate(ctx context.Context, householdID, id uuid.UUID) error {
+	ct, err := r.DB.Exec(ctx, `DELETE FROM bill_templates WHERE id = $1 AND household_id = $2`, id, householdID)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GenerateDue สร้างบิลจากแม่แบบที่ใกล้ครบกำหนด (next_due - lead_days <= today) แล้วเลื่อน next_due
+// ใช้ SKIP LOCKED + unique (template_id, due_date) จึงรันพร้อมกันหลาย instance ได้โดยไม่เกิดบิลซ้ำ
+func (r Repo) GenerateDue(ctx context.Context, today time.Time) (int, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	rows, err := tx.Query(ctx, `
+        SELECT `+templateCols+`
+        FROM bill_templates
+        WHERE active AND next_due - lead_days <= $1::date
+        FOR UPDATE SKIP LOCKED`, today)
+	if err != nil {
+		return 0, err
+	}
+	var due []Template
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		due = append(due, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for _, t := range due {
+		next := t.NextDue
+		// ไล่ตามรอบที่ค้าง (เช่น server ปิดไปหลายเดือน) แต่จำกัดไว้ไม่ให้วนไม่จบ
+		for i := 0; i < 24 && !next.AddDate(0, 0, -t.LeadDays).After(today); i++ {
+			start := advance(next, t.DayOfMonth, -t.IntervalMonths)
+			end := next.AddDate(0, 0, -1)
+			ct, err := tx.Exec(ctx, `
+                INSERT INTO bills (household_id, type, title, amount, billing_period_start, billing_period_end,
+                  due_date, status, note, created_by, template_id)
+                VALUES ($1,$2,$3,$4,$5,$6,$7,'unpaid',$8,$9,$10)
+                ON CONFLICT (template_id, due_date) WHERE template_id IS NOT NULL DO NOTHING`,
+				t.HouseholdID, t.Type, t.Title, t.Amount, start, end, next, t.Note, t.CreatedBy, t.ID)
+			if err != nil {
+				return created, err
+			}
+			created += int(ct.RowsAffected())
+			next = advance(next, t.DayOfMonth, t.IntervalMonths)
+		}
+		if _, err := tx.Exec(ctx, `UPDATE bill_templates SET next_due = $2 WHERE id = $1`, t.ID, next); err != nil {
+			return created, err
+		}
+	}
+	return created, tx.Commit(ctx)
 }
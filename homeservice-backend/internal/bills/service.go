@@ -1,6 +1,13 @@
 package bills
 
-import "context"
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iMookatayou/homeservice-backend/internal/authz"
+)
 
 type Service struct {
 	repo Repo
@@ -10,14 +17,197 @@ func NewService(r Repo) Service {
 	return Service{repo: r}
 }
 
-func (s Service) CreateBill(ctx context.Context, b *Bill) error {
+func (s Service) can(ctx context.Context, sub authz.Subject, a authz.Action, householdID uuid.UUID) error {
+	if !authz.Can(ctx, sub, a, authz.Resource{Kind: authz.KindBill, HouseholdID: householdID.String()}) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// load ดึงบิลแล้วตรวจสิทธิ์โดยนับผู้สร้างบิลเป็นเจ้าของ
+func (s Service) load(ctx context.Context, sub authz.Subject, householdID, id uuid.UUID, a authz.Action) (Bill, error) {
+	b, err := s.repo.GetBill(ctx, householdID, id)
+	if err != nil {
+		return b, err
+	}
+	res := authz.Resource{Kind: authz.KindBill, HouseholdID: b.HouseholdID.String(), OwnerID: b.CreatedBy.String()}
+	if !authz.Can(ctx, sub, a, res) {
+		return b, ErrForbidden
+	}
+	return b, nil
+}
+
+// CreateBill — owner/admin ของบ้านเท่านั้น
+func (s Service) CreateBill(ctx context.Context, sub authz.Subject, b *Bill) error {
+	if err := s.can(ctx, sub, authz.ActionCreate, b.HouseholdID); err != nil {
+		return err
+	}
 	return s.repo.CreateBill(ctx, b)
 }
 
-func (s Service) ListBills(ctx context.Context) ([]Bill, error) {
-	return s.repo.ListBills(ctx)
+func (s Service) GetBill(ctx context.Context, sub authz.Subject, householdID, id uuid.UUID) (Bill, error) {
+	return s.load(ctx, sub, householdID, id, authz.ActionRead)
+}
+
+func (s Service) ListBills(ctx context.Context, sub authz.Subject, householdID uuid.UUID, f ListFilter) ([]Bill, error) {
+	if err := s.can(ctx, sub, authz.ActionRead, householdID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListBills(ctx, householdID, f)
+}
+
+func (s Service) UpdateBill(ctx context.Context, sub authz.Subject, householdID, id uuid.UUID, in UpdateBillReq) (Bill, error) {
+	if _, err := s.load(ctx, sub, householdID, id, authz.ActionUpdate); err != nil {
+		return Bill{}, err
+	}
+	if in.Amount != nil && *in.Amount < 0 {
+		return Bill{}, ErrBadRequest
+	}
+	return s.repo.UpdateBill(ctx, householdID, id, in)
+}
+
+func (s Service) DeleteBill(ctx context.Context, sub authz.Subject, householdID, id uuid.UUID) error {
+	if _, err := s.load(ctx, sub, householdID, id, authz.ActionDelete); err != nil {
+		return err
+	}
+	return s.repo.DeleteBill(ctx, householdID, id)
+}
+
+// Pay — สมาชิกคนไหนในบ้านก็บันทึกการจ่ายได้; ใบเสร็จต้องเป็นไฟล์ของสมาชิกบ้านเดียวกัน
+func (s Service) Pay(ctx context.Context, sub authz.Subject, householdID, id uuid.UUID, in PayReq) (Bill, error) {
+	b, err := s.load(ctx, sub, householdID, id, authz.ActionPay)
+	if err != nil {
+		return b, err
+	}
+	if b.Status == StatusPaid {
+		return b, ErrConflict
+	}
+	amount := b.Amount
+	if in.Amount != nil {
+		if *in.Amount < 0 {
+			return b, ErrBadRequest
+		}
+		amount = *in.Amount
+	}
+	if in.ReceiptFileID != nil {
+		ok, err := s.repo.ReceiptUsable(ctx, householdID, *in.ReceiptFileID)
+		if err != nil {
+			return b, err
+		}
+		if !ok {
+			return b, ErrBadRequest
+		}
+	}
+	paidAt := time.Now()
+	if in.PaidAt != nil {
+		paidAt = *in.PaidAt
+	}
+	uid, err := uuid.Parse(sub.UserID)
+	if err != nil {
+		return b, ErrForbidden
+	}
+	return s.repo.Pay(ctx, householdID, id, uid, amount, strings.TrimSpace(in.Method), in.ReceiptFileID, paidAt)
+}
+
+func (s Service) Summarize(ctx context.Context, sub authz.Subject, householdID uuid.UUID, p Period) ([]Summary, error) {
+	if err := s.can(ctx, sub, authz.ActionRead, householdID); err != nil {
+		return nil, err
+	}
+	return s.repo.Summarize(ctx, householdID, p)
+}
+
+// --- templates (owner/admin) ---
+
+func (s Service) ListTemplates(ctx context.Context, sub authz.Subject, householdID uuid.UUID) ([]Template, error) {
+	if err := s.can(ctx, sub, authz.ActionRead, householdID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListTemplates(ctx, householdID)
+}
+
+// CreateTemplate สร้างแม่แบบแล้วสร้างบิลรอบแรกทันทีถ้าอยู่ในช่วง lead_days
+func (s Service) CreateTemplate(ctx context.Context, sub authz.Subject, householdID, userID uuid.UUID, in TemplateReq) (Template, error) {
+	if err := s.can(ctx, sub, authz.ActionManage, householdID); err != nil {
+		return Template{}, err
+	}
+	t := Template{HouseholdID: householdID, CreatedBy: userID, IntervalMonths: 1, LeadDays: 7, Active: true}
+	if in.Type == nil || in.Title == nil || in.Amount == nil || in.DayOfMonth == nil {
+		return t, ErrBadRequest
+	}
+	apply(&t, in)
+	if in.NextDue == nil {
+		t.NextDue = firstDue(time.Now(), t.DayOfMonth)
+	}
+	if !t.valid() {
+		return t, ErrBadRequest
+	}
+	if err := s.repo.CreateTemplate(ctx, &t); err != nil {
+		return t, err
+	}
+	if _, err := s.repo.GenerateDue(ctx, dateOnly(time.Now())); err != nil {
+		return t, err
+	}
+	return s.repo.GetTemplate(ctx, householdID, t.ID)
+}
+
+func (s Service) UpdateTemplate(ctx context.Context, sub authz.Subject, householdID, id uuid.UUID, in TemplateReq) (Template, error) {
+	if err := s.can(ctx, sub, authz.ActionManage, householdID); err != nil {
+		return Template{}, err
+	}
+	t, err := s.repo.GetTemplate(ctx, householdID, id)
+	if err != nil {
+		return t, err
+	}
+	apply(&t, in)
+	if !t.valid() {
+		return t, ErrBadRequest
+	}
+	return s.repo.UpdateTemplate(ctx, t)
+}
+
+func (s Service) DeleteTemplate(ctx context.Context, sub authz.Subject, householdID, id uuid.UUID) error {
+	if err := s.can(ctx, sub, authz.ActionManage, householdID); err != nil {
+		return err
+	}
+	return s.repo.DeleteTemplate(ctx, householdID, id)
+}
+
+// GenerateDue สำหรับ worker (ไม่มี subject): สร้างบิลรอบถัดไปของทุกบ้าน
+func (s Service) GenerateDue(ctx context.Context, now time.Time) (int, error) {
+	return s.repo.GenerateDue(ctx, dateOnly(now))
+}
+
+func apply(t *Template, in TemplateReq) {
+	if in.Type != nil {
+		t.Type = *in.Type
+	}
+	if in.Title != nil {
+		t.Title = *in.Title
+	}
+	if in.Amount != nil {
+		t.Amount = *in.Amount
+	}
+	if in.DayOfMonth != nil {
+		t.DayOfMonth = *in.DayOfMonth
+	}
+	if in.IntervalMonths != nil {
+		t.IntervalMonths = *in.IntervalMonths
+	}
+	if in.LeadDays != nil {
+		t.LeadDays = *in.LeadDays
+	}
+	if in.NextDue != nil {
+		t.NextDue = dateOnly(*in.NextDue)
+	}
+	if in.Active != nil {
+		t.Active = *in.Active
+	}
+	if in.Note != nil {
+		t.Note = in.Note
+	}
 }
 
-func (s Service) Summarize(ctx context.Context) ([]Summary, error) {
-	return s.repo.Summarize(ctx)
+func (t Template) valid() bool {
+	return strings.TrimSpace(t.Type) != "" && strings.TrimSpace(t.Title) != "" && t.Amount >= 0 &&
+		t.DayOfMonth >= 1 && t.DayOfMonth <= 31 && t.IntervalMonths >= 1 && t.LeadDays >= 0
 }
@@ -0,0 +1,59 @@
+package bills
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Notifier ฝั่งระบบแจ้งเตือน (notifications.Dispatcher) — กันซ้ำ/คูลดาวน์อยู่ฝั่งนั้น
+type Notifier interface {
+	NotifyBillDue(ctx context.Context, householdID, billID, message string) error
+	NotifyBillOverdue(ctx context.Context, householdID, billID, message string) error
+}
+
+// Generator สร้างบิลรอบถัดไปจากแม่แบบ (scheduler เรียก RunOnce เป็นระยะ)
+// และถ้ามี Notifier จะเตือนบิลที่ใกล้ครบกำหนดภายใน DueWithinDays วัน / เลยกำหนดแล้ว
+type Generator struct {
+	Svc           Service
+	Notifier      Notifier
+	DueWithinDays int
+	Logf          func(format string, args ...any)
+}
+
+func (g *Generator) RunOnce(ctx context.Context) error {
+	n, err := g.Svc.GenerateDue(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	if n > 0 && g.Logf != nil {
+		g.Logf("bills: generated %d bill(s) from templates", n)
+	}
+	if g.Notifier != nil {
+		return g.remind(ctx)
+	}
+	return nil
+}
+
+func (g *Generator) remind(ctx context.Context) error {
+	now := time.Now()
+	list, err := g.Svc.repo.DueBills(ctx, dateOnly(now).AddDate(0, 0, g.DueWithinDays))
+	if err != nil {
+		return err
+	}
+	today := dateOnly(now)
+	for _, b := range list {
+		hid, id := b.HouseholdID.String(), b.ID.String()
+		due := dateOnly(b.DueDate)
+		if due.Before(today) {
+			days := int(today.Sub(due).Hours() / 24)
+			msg := fmt.Sprintf("“%s” %.2f บาท เลยกำหนดชำระมาแล้ว %d วัน (ครบกำหนด %s)", b.Title, b.Amount, days, due.Format("2006-01-02"))
+			_ = g.Notifier.NotifyBillOverdue(ctx, hid, id, msg)
+			continue
+		}
+		days := int(due.Sub(today).Hours() / 24)
+		msg := fmt.Sprintf("“%s” %.2f บาท ครบกำหนดใน %d วัน (%s)", b.Title, b.Amount, days, due.Format("2006-01-02"))
+		_ = g.Notifier.NotifyBillDue(ctx, hid, id, msg)
+	}
+	return nil
+}
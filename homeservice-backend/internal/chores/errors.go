@@ -0,0 +1,18 @@
+package chores
+
+import "errors"
+
+var (
+	ErrNotFound = errors.New("chore not found")
+
+	// สถานะปัจจุบันไม่อนุญาต เช่น claim งานที่มีคนรับแล้ว
+	ErrConflict = errors.New("chore state does not allow this action")
+
+	ErrForbidden = errors.New("forbidden")
+
+	// RRULE ที่ไม่รองรับ / ผิดรูปแบบ
+	ErrBadRule = errors.New("invalid recurrence rule")
+
+	// rotation มี user ที่ไม่ได้เป็นสมาชิกบ้าน
+	ErrNotMember = errors.New("rotation contains a non-member")
+)
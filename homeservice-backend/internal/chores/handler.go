@@ -1,10 +1,14 @@
 package chores
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/iMookatayou/homeservice-backend/internal/auth"
+	"github.com/iMookatayou/homeservice-backend/internal/authz"
 	"github.com/iMookatayou/homeservice-backend/internal/httpx"
 )
 
@@ -12,13 +16,59 @@ type Handler struct{ Repo Repo }
 
 func (h Handler) RegisterRoutes(r chi.Router) {
 	r.Route("/chores", func(r chi.Router) {
-		r.Post("/", h.Create)                // สร้างงานบ้าน
+		r.Post("/", h.Create)                // สร้างงานบ้าน (ทำซ้ำได้ด้วย recurrence)
+		r.Get("/report", h.Report)           // ความเป็นธรรมต่อสมาชิก ?days=30
 		r.Post("/{id}/claim", h.Claim)       // กดรับทำ
-		r.Post("/{id}/complete", h.Complete) // เสร็จงาน
+		r.Post("/{id}/unclaim", h.Unclaim)   // ปล่อยงานที่รับไว้
+		r.Post("/{id}/complete", h.Complete) // เสร็จงาน (งานทำซ้ำ -> รอบถัดไป)
+		r.Post("/{id}/skip", h.Skip)         // ข้ามรอบนี้
+		r.Get("/{id}/history", h.History)
 		r.Get("/", h.List)
 	})
 }
 
+func writeErr(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, ErrConflict):
+		code = http.StatusConflict
+	case errors.Is(err, ErrForbidden):
+		code = http.StatusForbidden
+	case errors.Is(err, ErrBadRule), errors.Is(err, ErrNotMember):
+		code = http.StatusBadRequest
+	}
+	httpx.WriteJSONError(w, code, err.Error(), nil)
+}
+
+// authorize ตรวจสิทธิ์ตาม authz.Policies; claimed_by นับเป็นผู้เกี่ยวข้อง
+// complete/skip: คนที่รับงานหรือคนที่ถึงตา (ไม่งั้นเครดิต rotation ไปลงผิดคน) ส่วน owner/admin ทำแทนได้;
+// งานที่ไม่มีทั้งคนรับและคนถึงตา สมาชิกทุกคนทำได้
+func authorize(r *http.Request, a authz.Action, c *Chore) error {
+	sub := authz.SubjectFrom(r)
+	res := authz.Resource{Kind: authz.KindChore, HouseholdID: sub.HouseholdID}
+	if c != nil {
+		res.HouseholdID = c.HouseholdID
+		res.OwnerID = c.CreatedBy
+		if c.ClaimedBy != nil {
+			res.Participants = []string{*c.ClaimedBy}
+		}
+		if a == authz.ActionComplete || a == authz.ActionSkip {
+			if c.AssignedTo != nil {
+				res.Participants = append(res.Participants, *c.AssignedTo)
+			}
+			if len(res.Participants) == 0 {
+				res.Participants = []string{sub.UserID}
+			}
+		}
+	}
+	if !authz.Can(r.Context(), sub, a, res) {
+		return ErrForbidden
+	}
+	return nil
+}
+
 func (h Handler) Create(w http.ResponseWriter, r *http.Request) {
 	var req CreateChoreReq
 	if err := httpx.BindJSON(r, &req); err != nil {
@@ -31,54 +81,158 @@ func (h Handler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	hid, ok := auth.HouseholdIDFrom(r)
+	if !ok {
+		httpx.WriteJSONError(w, http.StatusForbidden, "household required", nil)
+		return
+	}
+	if err := authorize(r, authz.ActionCreate, nil); err != nil {
+		writeErr(w, err)
+		return
+	}
+
+	rule, err := NormalizeRecurrence(req.Recurrence)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+
 	c := &Chore{
-		Title:     req.Title,
-		Category:  req.Category,
-		Note:      req.Note,
-		CreatedBy: claims.UserID,
+		HouseholdID: hid,
+		Title:       req.Title,
+		Category:    req.Category,
+		Note:        req.Note,
+		DueAt:       req.DueAt,
+		Rotation:    req.Rotation,
+		CreatedBy:   claims.UserID,
 	}
-	if err := h.Repo.Create(r.Context(), c); err != nil {
-		httpx.WriteJSONError(w, http.StatusInternalServerError, err.Error(), nil)
+	if rule != "" {
+		c.Recurrence = &rule
+	}
+	if err := h.Repo.Create(r.Context(), c, req.Rotate); err != nil {
+		writeErr(w, err)
 		return
 	}
 	httpx.JSON(w, http.StatusCreated, c)
 }
 
-func (h Handler) Claim(w http.ResponseWriter, r *http.Request) {
+// transition โหลดงาน ตรวจสิทธิ์ แล้วเรียก fn (claim/unclaim/complete/skip)
+func (h Handler) transition(w http.ResponseWriter, r *http.Request, a authz.Action,
+	fn func(hid, id, uid string) (Chore, error)) {
 	claims := auth.ClaimsFrom(r)
 	if claims == nil {
 		httpx.WriteJSONError(w, http.StatusUnauthorized, "unauthorized", nil)
 		return
 	}
+	hid, ok := auth.HouseholdIDFrom(r)
+	if !ok {
+		httpx.WriteJSONError(w, http.StatusForbidden, "household required", nil)
+		return
+	}
 	id := chi.URLParam(r, "id")
-	c, err := h.Repo.Claim(r.Context(), id, claims.UserID)
+	cur, err := h.Repo.Get(r.Context(), hid, id)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	if err := authorize(r, a, &cur); err != nil {
+		writeErr(w, err)
+		return
+	}
+	c, err := fn(hid, id, claims.UserID)
 	if err != nil {
-		httpx.WriteJSONError(w, http.StatusBadRequest, err.Error(), nil)
+		writeErr(w, err)
 		return
 	}
 	httpx.JSON(w, http.StatusOK, c)
 }
 
+func (h Handler) Claim(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, authz.ActionClaim, func(hid, id, uid string) (Chore, error) {
+		return h.Repo.Claim(r.Context(), hid, id, uid)
+	})
+}
+
+func (h Handler) Unclaim(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, authz.ActionUnclaim, func(hid, id, _ string) (Chore, error) {
+		return h.Repo.Unclaim(r.Context(), hid, id)
+	})
+}
+
 func (h Handler) Complete(w http.ResponseWriter, r *http.Request) {
-	claims := auth.ClaimsFrom(r)
-	if claims == nil {
-		httpx.WriteJSONError(w, http.StatusUnauthorized, "unauthorized", nil)
+	h.transition(w, r, authz.ActionComplete, func(hid, id, uid string) (Chore, error) {
+		return h.Repo.Complete(r.Context(), hid, id, uid)
+	})
+}
+
+func (h Handler) Skip(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, authz.ActionSkip, func(hid, id, uid string) (Chore, error) {
+		return h.Repo.Skip(r.Context(), hid, id, uid)
+	})
+}
+
+func (h Handler) List(w http.ResponseWriter, r *http.Request) {
+	hid, ok := auth.HouseholdIDFrom(r)
+	if !ok {
+		httpx.WriteJSONError(w, http.StatusForbidden, "household required", nil)
+		return
+	}
+	cs, err := h.Repo.List(r.Context(), hid, 50)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+	httpx.JSON(w, http.StatusOK, cs)
+}
+
+func (h Handler) History(w http.ResponseWriter, r *http.Request) {
+	hid, ok := auth.HouseholdIDFrom(r)
+	if !ok {
+		httpx.WriteJSONError(w, http.StatusForbidden, "household required", nil)
 		return
 	}
 	id := chi.URLParam(r, "id")
-	c, err := h.Repo.Complete(r.Context(), id, claims.UserID)
+	if _, err := h.Repo.Get(r.Context(), hid, id); err != nil {
+		writeErr(w, err)
+		return
+	}
+	evs, err := h.Repo.History(r.Context(), hid, id, 100)
 	if err != nil {
-		httpx.WriteJSONError(w, http.StatusBadRequest, err.Error(), nil)
+		writeErr(w, err)
 		return
 	}
-	httpx.JSON(w, http.StatusOK, c)
+	httpx.JSON(w, http.StatusOK, evs)
 }
 
-func (h Handler) List(w http.ResponseWriter, r *http.Request) {
-	cs, err := h.Repo.List(r.Context(), 50)
+// Report สรุปใครทำไปกี่งานในช่วง days วันล่าสุด เทียบกับส่วนแบ่งที่ควรเป็น
+func (h Handler) Report(w http.ResponseWriter, r *http.Request) {
+	hid, ok := auth.HouseholdIDFrom(r)
+	if !ok {
+		httpx.WriteJSONError(w, http.StatusForbidden, "household required", nil)
+		return
+	}
+	if err := authorize(r, authz.ActionRead, nil); err != nil {
+		writeErr(w, err)
+		return
+	}
+	days := 30
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 366 {
+			httpx.WriteJSONError(w, http.StatusBadRequest, "days must be 1..366", nil)
+			return
+		}
+		days = n
+	}
+	since := time.Now().AddDate(0, 0, -days)
+	rows, err := h.Repo.Fairness(r.Context(), hid, since)
 	if err != nil {
-		httpx.WriteJSONError(w, http.StatusInternalServerError, err.Error(), nil)
+		writeErr(w, err)
 		return
 	}
-	httpx.JSON(w, http.StatusOK, cs)
+	httpx.JSON(w, http.StatusOK, map[string]any{
+		"since":   since,
+		"days":    days,
+		"members": rows,
+	})
 }
@@ -2,23 +2,72 @@ package chores
 
 import "time"
 
+// สถานะงานบ้าน (งานที่ทำซ้ำจะวนกลับเป็น open พร้อม due_at รอบถัดไป)
+const (
+	StatusOpen      = "open"
+	StatusClaimed   = "claimed"
+	StatusCompleted = "completed"
+	StatusSkipped   = "skipped"
+)
+
+// action ใน chore_events
+const (
+	EventCompleted = "completed"
+	EventSkipped   = "skipped"
+)
+
 type Chore struct {
 	ID          string     `json:"id"`
+	HouseholdID string     `json:"household_id"`
 	Title       string     `json:"title"`
-	Category    string     `json:"category"` // chore_category: general|kitchen|bathroom|outdoor
-	Status      string     `json:"status"`   // chore_status: open|claimed|completed
+	Category    string     `json:"category"` // general|kitchen|bathroom|outdoor
+	Status      string     `json:"status"`   // open|claimed|completed|skipped
 	ClaimedBy   *string    `json:"claimed_by,omitempty"`
 	ClaimedAt   *time.Time `json:"claimed_at,omitempty"`
-	CompletedBy *string    `json:"completed_by,omitempty"`
+	CompletedBy *string    `json:"completed_by,omitempty"` // งานทำซ้ำ: คนที่ทำรอบล่าสุด
 	CompletedAt *time.Time `json:"completed_at,omitempty"`
 	Note        *string    `json:"note,omitempty"`
-	CreatedBy   string     `json:"created_by"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+
+	Recurrence *string    `json:"recurrence,omitempty"` // RRULE เช่น FREQ=WEEKLY;BYDAY=MO,TH
+	StartsAt   *time.Time `json:"starts_at,omitempty"`  // รอบแรก (anchor ของ RRULE)
+	DueAt      *time.Time `json:"due_at,omitempty"`
+	AssignedTo *string    `json:"assigned_to,omitempty"` // ถึงตาใคร (round-robin)
+	Rotation   []string   `json:"rotation,omitempty"`
+	RotationIx int        `json:"-"`
+
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Event = ประวัติการทำ/ข้ามงานแต่ละรอบ
+type Event struct {
+	ID         string     `json:"id"`
+	ChoreID    string     `json:"chore_id"`
+	Action     string     `json:"action"`                // completed|skipped
+	UserID     *string    `json:"user_id,omitempty"`     // คนที่กด
+	AssignedTo *string    `json:"assigned_to,omitempty"` // ตอนนั้นถึงตาใคร
+	DueAt      *time.Time `json:"due_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// FairnessRow = สรุปต่อสมาชิกในช่วงเวลา
+type FairnessRow struct {
+	UserID    string  `json:"user_id"`
+	Name      string  `json:"name"`
+	Completed int     `json:"completed"`
+	Skipped   int     `json:"skipped"`    // รอบที่ถึงตาตัวเองแต่ถูกข้าม
+	Assigned  int     `json:"assigned"`   // งานที่ค้างอยู่และถึงตาตัวเอง
+	Share     float64 `json:"share"`      // สัดส่วนงานที่ทำเทียบทั้งบ้าน (0..1)
+	FairShare float64 `json:"fair_share"` // 1 / จำนวนสมาชิก
 }
 
 type CreateChoreReq struct {
-	Title    string  `json:"title" validate:"required,min=1"`
-	Category string  `json:"category" validate:"required,oneof=general kitchen bathroom outdoor"`
-	Note     *string `json:"note,omitempty"`
+	Title      string     `json:"title" validate:"required,min=1"`
+	Category   string     `json:"category" validate:"required,oneof=general kitchen bathroom outdoor"`
+	Note       *string    `json:"note,omitempty"`
+	Recurrence string     `json:"recurrence,omitempty"` // daily | weekly | monthly | RRULE
+	DueAt      *time.Time `json:"due_at,omitempty" validate:"required_with=Recurrence"`
+	Rotation   []string   `json:"rotation,omitempty" validate:"omitempty,dive,uuid"` // ลำดับคนทำ
+	Rotate     bool       `json:"rotate,omitempty"`                                  // true = วนทุกคนในบ้านตามลำดับที่เข้าร่วม
 }
@@ -0,0 +1,160 @@
+package chores
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule = RRULE ชุดย่อยที่งานบ้านใช้จริง: FREQ=DAILY|WEEKLY|MONTHLY, INTERVAL, BYDAY, BYMONTHDAY, UNTIL
+type Rule struct {
+	Freq       string // DAILY | WEEKLY | MONTHLY
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay int
+	Until      *time.Time
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// NormalizeRecurrence แปลง "daily"/"weekly"/"monthly" เป็น RRULE และตรวจว่า parse ได้
+func NormalizeRecurrence(s string) (string, error) {
+	s = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "RRULE:"))
+	switch strings.ToLower(s) {
+	case "":
+		return "", nil
+	case "daily":
+		s = "FREQ=DAILY"
+	case "weekly":
+		s = "FREQ=WEEKLY"
+	case "monthly":
+		s = "FREQ=MONTHLY"
+	}
+	s = strings.ToUpper(s)
+	if _, err := ParseRule(s); err != nil {
+		return "", err
+	}
+	return s, nil
+}
+
+func ParseRule(s string) (Rule, error) {
+	r := Rule{Interval: 1}
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(part, "=")
+		if !ok {
+			return r, fmt.Errorf("%w: %q", ErrBadRule, part)
+		}
+		switch k {
+		case "FREQ":
+			if v != "DAILY" && v != "WEEKLY" && v != "MONTHLY" {
+				return r, fmt.Errorf("%w: unsupported FREQ %q", ErrBadRule, v)
+			}
+			r.Freq = v
+		case "INTERVAL":
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				return r, fmt.Errorf("%w: bad INTERVAL %q", ErrBadRule, v)
+			}
+			r.Interval = n
+		case "BYDAY":
+			for _, d := range strings.Split(v, ",") {
+				wd, ok := weekdays[d]
+				if !ok {
+					return r, fmt.Errorf("%w: bad BYDAY %q", ErrBadRule, d)
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > 31 {
+				return r, fmt.Errorf("%w: bad BYMONTHDAY %q", ErrBadRule, v)
+			}
+			r.ByMonthDay = n
+		case "UNTIL":
+			var t time.Time
+			var err error
+			if len(v) == 8 {
+				t, err = time.Parse("20060102", v)
+				t = t.Add(24*time.Hour - time.Second) // ทั้งวันสุดท้าย
+			} else {
+				t, err = time.Parse("20060102T150405Z", v)
+			}
+			if err != nil {
+				return r, fmt.Errorf("%w: bad UNTIL %q", ErrBadRule, v)
+			}
+			r.Until = &t
+		default:
+			return r, fmt.Errorf("%w: unsupported %s", ErrBadRule, k)
+		}
+	}
+	if r.Freq == "" {
+		return r, fmt.Errorf("%w: FREQ required", ErrBadRule)
+	}
+	return r, nil
+}
+
+func dayIndex(t time.Time) int {
+	y, m, d := t.Date()
+	return int(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / 86400)
+}
+
+func (r Rule) matches(anchor, day time.Time) bool {
+	switch r.Freq {
+	case "DAILY":
+		return (dayIndex(day)-dayIndex(anchor))%r.Interval == 0
+	case "WEEKLY":
+		// นับสัปดาห์จากวันจันทร์ของสัปดาห์ที่เริ่ม
+		startOf := func(t time.Time) int { return dayIndex(t) - (int(t.Weekday())+6)%7 }
+		if ((startOf(day)-startOf(anchor))/7)%r.Interval != 0 {
+			return false
+		}
+		if len(r.ByDay) == 0 {
+			return day.Weekday() == anchor.Weekday()
+		}
+		for _, wd := range r.ByDay {
+			if day.Weekday() == wd {
+				return true
+			}
+		}
+		return false
+	case "MONTHLY":
+		months := (day.Year()-anchor.Year())*12 + int(day.Month()-anchor.Month())
+		if months%r.Interval != 0 {
+			return false
+		}
+		want := r.ByMonthDay
+		if want == 0 {
+			want = anchor.Day()
+		}
+		return day.Day() == want
+	}
+	return false
+}
+
+// Next = occurrence แรกที่อยู่หลัง after (เวลาในวันเท่ากับ anchor); ok=false เมื่อเลย UNTIL แล้ว
+func (r Rule) Next(anchor, after time.Time) (time.Time, bool) {
+	h, m, s := anchor.Clock()
+	loc := anchor.Location()
+	y, mo, d := after.In(loc).Date()
+	// ไล่ทีละวัน: รอบยาวสุดคือ MONTHLY ที่ INTERVAL สูง ๆ จึงจำกัดไว้ราว ๆ 5 ปีต่อ interval
+	for i := 0; i <= 1900*r.Interval; i++ {
+		day := time.Date(y, mo, d+i, h, m, s, 0, loc)
+		if !day.After(after) || day.Before(anchor) {
+			continue
+		}
+		if r.Until != nil && day.After(*r.Until) {
+			return time.Time{}, false
+		}
+		if r.matches(anchor, day) {
+			return day, true
+		}
+	}
+	return time.Time{}, false
+}
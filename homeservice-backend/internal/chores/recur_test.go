@@ -0,0 +1,91 @@
+package chores
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRuleNext(t *testing.T) {
+	utc := func(y int, m time.Month, d, h, min int) time.Time { return time.Date(y, m, d, h, min, 0, 0, time.UTC) }
+	bkk := time.FixedZone("ICT", 7*3600)
+	anchor := utc(2026, 1, 5, 9, 0) // จันทร์
+
+	tests := []struct {
+		name   string
+		rule   string
+		anchor time.Time
+		after  time.Time
+		want   time.Time
+		ok     bool
+	}{
+		{"daily next day", "FREQ=DAILY", anchor, anchor, utc(2026, 1, 6, 9, 0), true},
+		{"daily later same day", "FREQ=DAILY", anchor, utc(2026, 1, 7, 8, 0), utc(2026, 1, 7, 9, 0), true},
+		{"daily interval", "FREQ=DAILY;INTERVAL=3", anchor, utc(2026, 1, 6, 10, 0), utc(2026, 1, 8, 9, 0), true},
+		{"after before anchor returns anchor", "FREQ=DAILY", anchor, utc(2025, 12, 1, 0, 0), anchor, true},
+		{"weekly same weekday", "FREQ=WEEKLY", anchor, anchor, utc(2026, 1, 12, 9, 0), true},
+		{"weekly byday", "FREQ=WEEKLY;BYDAY=MO,WE,FR", anchor, anchor, utc(2026, 1, 7, 9, 0), true},
+		{"weekly byday wraps to monday", "FREQ=WEEKLY;BYDAY=MO,WE,FR", anchor, utc(2026, 1, 9, 12, 0), utc(2026, 1, 12, 9, 0), true},
+		{"biweekly skips odd week", "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH", anchor, utc(2026, 1, 9, 0, 0), utc(2026, 1, 20, 9, 0), true},
+		{"monthly anchor day", "FREQ=MONTHLY", anchor, anchor, utc(2026, 2, 5, 9, 0), true},
+		{"monthly interval", "FREQ=MONTHLY;INTERVAL=3", anchor, anchor, utc(2026, 4, 5, 9, 0), true},
+		{"monthly bymonthday", "FREQ=MONTHLY;BYMONTHDAY=20", anchor, anchor, utc(2026, 1, 20, 9, 0), true},
+		{"monthly 31 skips short months", "FREQ=MONTHLY", utc(2026, 1, 31, 9, 0), utc(2026, 1, 31, 9, 0), utc(2026, 3, 31, 9, 0), true},
+		{"until date is inclusive", "FREQ=DAILY;UNTIL=20260107", anchor, utc(2026, 1, 6, 9, 0), utc(2026, 1, 7, 9, 0), true},
+		{"past until", "FREQ=DAILY;UNTIL=20260107", anchor, utc(2026, 1, 7, 9, 0), time.Time{}, false},
+		{"until timestamp", "FREQ=WEEKLY;UNTIL=20260112T085959Z", anchor, anchor, time.Time{}, false},
+		{
+			"keeps anchor zone and clock", "FREQ=DAILY",
+			time.Date(2026, 1, 5, 7, 0, 0, 0, bkk), utc(2026, 1, 5, 1, 0), // = 08:00 ICT
+			time.Date(2026, 1, 6, 7, 0, 0, 0, bkk), true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseRule(tt.rule)
+			if err != nil {
+				t.Fatalf("ParseRule(%q) error = %v", tt.rule, err)
+			}
+			got, ok := r.Next(tt.anchor, tt.after)
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Fatalf("Next() = %v, %v; want %v, %v", got, ok, tt.want, tt.ok)
+			}
+			if ok && got.Location() != tt.anchor.Location() {
+				t.Errorf("Next() location = %v, want %v", got.Location(), tt.anchor.Location())
+			}
+		})
+	}
+}
+
+func TestNormalizeRecurrence(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"daily", "FREQ=DAILY", false},
+		{" Weekly ", "FREQ=WEEKLY", false},
+		{"RRULE:freq=monthly;bymonthday=15", "FREQ=MONTHLY;BYMONTHDAY=15", false},
+		{"FREQ=YEARLY", "", true},
+		{"FREQ=DAILY;INTERVAL=0", "", true},
+		{"FREQ=WEEKLY;BYDAY=XX", "", true},
+		{"FREQ=MONTHLY;BYMONTHDAY=32", "", true},
+		{"INTERVAL=2", "", true},
+		{"FREQ=DAILY;COUNT=3", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := NormalizeRecurrence(tt.in)
+			if tt.wantErr {
+				if !errors.Is(err, ErrBadRule) {
+					t.Fatalf("NormalizeRecurrence(%q) error = %v, want ErrBadRule", tt.in, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("NormalizeRecurrence(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+			}
+		})
+	}
+}
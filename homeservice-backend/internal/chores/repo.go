@@ -2,61 +2,251 @@ package chores
 
 import (
 	"context"
+	"errors"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Repo struct{ DB *pgxpool.Pool }
 
-func (r Repo) Create(ctx context.Context, c *Chore) error {
-	const q = `
-INSERT INTO public.chores (id, title, category, status, note, created_by)
-VALUES (gen_random_uuid(), $1, $2, 'open', $3, $4)
-RETURNING id, status, created_at, updated_at`
-	return r.DB.QueryRow(ctx, q, c.Title, c.Category, c.Note, c.CreatedBy).
-		Scan(&c.ID, &c.Status, &c.CreatedAt, &c.UpdatedAt)
+const choreCols = `id, household_id, title, category, status, claimed_by, claimed_at, completed_by, completed_at, note,
+  recurrence, starts_at, due_at, assigned_to, rotation::text[], rotation_index, created_by, created_at, updated_at`
+
+func scanChore(row pgx.Row) (Chore, error) {
+	var c Chore
+	err := row.Scan(&c.ID, &c.HouseholdID, &c.Title, &c.Category, &c.Status, &c.ClaimedBy, &c.ClaimedAt,
+		&c.CompletedBy, &c.CompletedAt, &c.Note, &c.Recurrence, &c.StartsAt, &c.DueAt, &c.AssignedTo,
+		&c.Rotation, &c.RotationIx, &c.CreatedBy, &c.CreatedAt, &c.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c, ErrNotFound
+	}
+	return c, err
+}
+
+// memberIDs สมาชิกบ้านเรียงตามวันที่เข้าร่วม (ลำดับเวรเริ่มต้น)
+func memberIDs(ctx context.Context, q interface {
+	Query(context.Context, string, ...any) (pgx.Rows, error)
+}, householdID string) ([]string, error) {
+	rows, err := q.Query(ctx, `
+SELECT user_id FROM household_members WHERE household_id=$1 ORDER BY joined_at`, householdID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
+
+// Create สร้างงาน; rotate=true ใช้สมาชิกทุกคน, ไม่งั้นตรวจว่า c.Rotation เป็นสมาชิกทั้งหมด
+func (r Repo) Create(ctx context.Context, c *Chore, rotate bool) error {
+	members, err := memberIDs(ctx, r.DB, c.HouseholdID)
+	if err != nil {
+		return err
+	}
+	if rotate {
+		c.Rotation = members
+	}
+	in := map[string]bool{}
+	for _, m := range members {
+		in[m] = true
+	}
+	for _, u := range c.Rotation {
+		if !in[u] {
+			return ErrNotMember
+		}
+	}
+	if c.Rotation == nil {
+		c.Rotation = []string{}
+	}
+	if len(c.Rotation) > 0 {
+		c.AssignedTo = &c.Rotation[0]
+	}
+	c.StartsAt = c.DueAt
+
+	got, err := scanChore(r.DB.QueryRow(ctx, `
+INSERT INTO public.chores (id, household_id, title, category, status, note, created_by,
+  recurrence, starts_at, due_at, assigned_to, rotation)
+VALUES (gen_random_uuid(), $1, $2, $3, 'open', $4, $5, $6, $7, $8, $9, $10::text[]::uuid[])
+RETURNING `+choreCols,
+		c.HouseholdID, c.Title, c.Category, c.Note, c.CreatedBy,
+		c.Recurrence, c.StartsAt, c.DueAt, c.AssignedTo, c.Rotation))
+	if err != nil {
+		return err
+	}
+	*c = got
+	return nil
+}
+
+func (r Repo) Get(ctx context.Context, householdID, id string) (Chore, error) {
+	return scanChore(r.DB.QueryRow(ctx, `SELECT `+choreCols+` FROM public.chores WHERE id=$1 AND household_id=$2`, id, householdID))
 }
 
-func (r Repo) Claim(ctx context.Context, id, userID string) (Chore, error) {
-	const q = `
+func (r Repo) Claim(ctx context.Context, householdID, id, userID string) (Chore, error) {
+	c, err := scanChore(r.DB.QueryRow(ctx, `
 UPDATE public.chores
 SET status='claimed', claimed_by=$2, claimed_at=now(), updated_at=now()
-WHERE id=$1 AND status='open'
-RETURNING id, title, category, status, claimed_by, claimed_at, created_by, created_at, updated_at`
-	var c Chore
-	err := r.DB.QueryRow(ctx, q, id, userID).
-		Scan(&c.ID, &c.Title, &c.Category, &c.Status, &c.ClaimedBy, &c.ClaimedAt, &c.CreatedBy, &c.CreatedAt, &c.UpdatedAt)
+WHERE id=$1 AND household_id=$3 AND status='open'
+RETURNING `+choreCols, id, userID, householdID))
+	if errors.Is(err, ErrNotFound) {
+		return c, r.stateErr(ctx, householdID, id)
+	}
 	return c, err
 }
 
-func (r Repo) Complete(ctx context.Context, id, userID string) (Chore, error) {
-	const q = `
+// Unclaim คืนงานที่รับไว้กลับเป็น open (สิทธิ์ตรวจที่ handler ผ่าน authz)
+func (r Repo) Unclaim(ctx context.Context, householdID, id string) (Chore, error) {
+	c, err := scanChore(r.DB.QueryRow(ctx, `
 UPDATE public.chores
-SET status='completed', completed_by=$2, completed_at=now(), updated_at=now()
-WHERE id=$1 AND status IN ('open','claimed')
-RETURNING id, title, category, status, completed_by, completed_at, created_by, created_at, updated_at`
-	var c Chore
-	err := r.DB.QueryRow(ctx, q, id, userID).
-		Scan(&c.ID, &c.Title, &c.Category, &c.Status, &c.CompletedBy, &c.CompletedAt, &c.CreatedBy, &c.CreatedAt, &c.UpdatedAt)
+SET status='open', claimed_by=NULL, claimed_at=NULL, updated_at=now()
+WHERE id=$1 AND household_id=$2 AND status='claimed'
+RETURNING `+choreCols, id, householdID))
+	if errors.Is(err, ErrNotFound) {
+		return c, r.stateErr(ctx, householdID, id)
+	}
 	return c, err
 }
 
-func (r Repo) List(ctx context.Context, limit int) ([]Chore, error) {
-	const q = `
-SELECT id, title, category, status, claimed_by, claimed_at, completed_by, completed_at, created_by, created_at, updated_at
+// stateErr แยกว่าไม่พบงาน หรือสถานะไม่อนุญาต
+func (r Repo) stateErr(ctx context.Context, householdID, id string) error {
+	if _, err := r.Get(ctx, householdID, id); err != nil {
+		return err
+	}
+	return ErrConflict
+}
+
+func (r Repo) Complete(ctx context.Context, householdID, id, userID string) (Chore, error) {
+	return r.finish(ctx, householdID, id, userID, EventCompleted, time.Now())
+}
+
+func (r Repo) Skip(ctx context.Context, householdID, id, userID string) (Chore, error) {
+	return r.finish(ctx, householdID, id, userID, EventSkipped, time.Now())
+}
+
+// finish บันทึกประวัติรอบนี้ แล้ว (งานทำซ้ำ) เลื่อนไปรอบถัดไป + ส่งเวรให้คนถัดไป
+// หรือ (งานครั้งเดียว / เลย UNTIL) ปิดงานเป็น completed/skipped
+func (r Repo) finish(ctx context.Context, householdID, id, userID, action string, now time.Time) (Chore, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return Chore{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	c, err := scanChore(tx.QueryRow(ctx, `
+SELECT `+choreCols+` FROM public.chores WHERE id=$1 AND household_id=$2 FOR UPDATE`, id, householdID))
+	if err != nil {
+		return c, err
+	}
+	if c.Status != StatusOpen && c.Status != StatusClaimed {
+		return c, ErrConflict
+	}
+
+	if _, err := tx.Exec(ctx, `
+INSERT INTO chore_events (chore_id, household_id, action, user_id, assigned_to, due_at)
+VALUES ($1,$2,$3,$4,$5,$6)`, c.ID, c.HouseholdID, action, userID, c.AssignedTo, c.DueAt); err != nil {
+		return c, err
+	}
+
+	next, hasNext, err := nextOccurrence(c, now)
+	if err != nil {
+		return c, err
+	}
+
+	var row pgx.Row
+	if !hasNext {
+		status := StatusCompleted
+		if action == EventSkipped {
+			status = StatusSkipped
+		}
+		row = tx.QueryRow(ctx, `
+UPDATE public.chores
+SET status=$3,
+    completed_by=CASE WHEN $3='completed' THEN $4::uuid ELSE completed_by END,
+    completed_at=CASE WHEN $3='completed' THEN now() ELSE completed_at END,
+    updated_at=now()
+WHERE id=$1 AND household_id=$2
+RETURNING `+choreCols, c.ID, c.HouseholdID, status, userID)
+	} else {
+		members, err := memberIDs(ctx, tx, c.HouseholdID)
+		if err != nil {
+			return c, err
+		}
+		assignee, ix := nextAssignee(c.Rotation, c.RotationIx, members)
+		row = tx.QueryRow(ctx, `
+UPDATE public.chores
+SET status='open', claimed_by=NULL, claimed_at=NULL,
+    completed_by=CASE WHEN $3='completed' THEN $4::uuid ELSE completed_by END,
+    completed_at=CASE WHEN $3='completed' THEN now() ELSE completed_at END,
+    due_at=$5, assigned_to=$6, rotation_index=$7, updated_at=now()
+WHERE id=$1 AND household_id=$2
+RETURNING `+choreCols, c.ID, c.HouseholdID, action, userID, next, assignee, ix)
+	}
+	out, err := scanChore(row)
+	if err != nil {
+		return out, err
+	}
+	return out, tx.Commit(ctx)
+}
+
+// nextOccurrence รอบถัดไปหลังจาก max(due_at, now) — ทำล่วงหน้าไม่ข้ามรอบ, ค้างนานไม่กองสะสม
+func nextOccurrence(c Chore, now time.Time) (time.Time, bool, error) {
+	if c.Recurrence == nil || *c.Recurrence == "" || c.StartsAt == nil {
+		return time.Time{}, false, nil
+	}
+	rule, err := ParseRule(*c.Recurrence)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	after := now
+	if c.DueAt != nil && c.DueAt.After(after) {
+		after = *c.DueAt
+	}
+	next, ok := rule.Next(*c.StartsAt, after)
+	return next, ok, nil
+}
+
+// nextAssignee คนถัดไปใน rotation ที่ยังเป็นสมาชิกบ้านอยู่ (ข้ามคนที่ออกไปแล้ว)
+func nextAssignee(rotation []string, ix int, members []string) (*string, int) {
+	if len(rotation) == 0 {
+		return nil, 0
+	}
+	in := map[string]bool{}
+	for _, m := range members {
+		in[m] = true
+	}
+	for i := 1; i <= len(rotation); i++ {
+		j := (ix + i) % len(rotation)
+		if in[rotation[j]] {
+			u := rotation[j]
+			return &u, j
+		}
+	}
+	return nil, ix
+}
+
+func (r Repo) List(ctx context.Context, householdID string, limit int) ([]Chore, error) {
+	rows, err := r.DB.Query(ctx, `
+SELECT `+choreCols+`
 FROM public.chores
-ORDER BY created_at DESC
-LIMIT $1`
-	rows, err := r.DB.Query(ctx, q, limit)
+WHERE household_id=$1
+ORDER BY (status IN ('completed','skipped')), due_at NULLS LAST, created_at DESC
+LIMIT $2`, householdID, limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var out []Chore
+	out := []Chore{}
 	for rows.Next() {
-		var c Chore
-		err = rows.Scan(&c.ID, &c.Title, &c.Category, &c.Status, &c.ClaimedBy, &c.ClaimedAt, &c.CompletedBy, &c.CompletedAt, &c.CreatedBy, &c.CreatedAt, &c.UpdatedAt)
+		c, err := scanChore(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -64,3 +254,68 @@ LIMIT $1`
 	}
 	return out, rows.Err()
 }
+
+// History ประวัติการทำ/ข้ามของงานหนึ่ง (ล่าสุดก่อน)
+func (r Repo) History(ctx context.Context, householdID, id string, limit int) ([]Event, error) {
+	rows, err := r.DB.Query(ctx, `
+SELECT id, chore_id, action, user_id, assigned_to, due_at, created_at
+FROM chore_events
+WHERE chore_id=$1 AND household_id=$2
+ORDER BY created_at DESC
+LIMIT $3`, id, householdID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Event{}
+	for rows.Next() {
+		var e Event
+		if err := rows.Scan(&e.ID, &e.ChoreID, &e.Action, &e.UserID, &e.AssignedTo, &e.DueAt, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// Fairness สรุปต่อสมาชิกตั้งแต่ since: ทำไปกี่ครั้ง, ข้ามตาตัวเองกี่ครั้ง, ตอนนี้ถึงตากี่งาน
+func (r Repo) Fairness(ctx context.Context, householdID string, since time.Time) ([]FairnessRow, error) {
+	rows, err := r.DB.Query(ctx, `
+SELECT m.user_id, u.name,
+  (SELECT count(*) FROM chore_events e
+    WHERE e.household_id=m.household_id AND e.action='completed' AND e.user_id=m.user_id AND e.created_at >= $2),
+  (SELECT count(*) FROM chore_events e
+    WHERE e.household_id=m.household_id AND e.action='skipped' AND e.assigned_to=m.user_id AND e.created_at >= $2),
+  (SELECT count(*) FROM public.chores c
+    WHERE c.household_id=m.household_id AND c.assigned_to=m.user_id AND c.status IN ('open','claimed'))
+FROM household_members m
+JOIN users u ON u.id = m.user_id
+WHERE m.household_id=$1
+ORDER BY m.joined_at`, householdID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []FairnessRow{}
+	total := 0
+	for rows.Next() {
+		var f FairnessRow
+		if err := rows.Scan(&f.UserID, &f.Name, &f.Completed, &f.Skipped, &f.Assigned); err != nil {
+			return nil, err
+		}
+		total += f.Completed
+		out = append(out, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range out {
+		out[i].FairShare = 1 / float64(len(out))
+		if total > 0 {
+			out[i].Share = float64(out[i].Completed) / float64(total)
+		}
+	}
+	return out, nil
+}
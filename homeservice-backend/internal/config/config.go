@@ -4,6 +4,8 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"time"
+	_ "time/tzdata" // image แบบ distroless/scratch ไม่มี zoneinfo
 )
 
 type Config struct {
@@ -12,9 +14,58 @@ type Config struct {
 	JWTSecret  string
 	CorsOrigin string
 
+	AccessTokenTTL  time.Duration // อายุ access token (สั้น; ต่ออายุด้วย refresh token)
+	RefreshTokenTTL time.Duration // อายุ refresh token / session ที่ไม่ได้ใช้งาน
+
 	StorageBackend string // "local" | "s3"
 	LocalDir       string // โฟลเดอร์เก็บไฟล์กรณี local
 	PublicBaseURL  string // URL เอาไว้โหลดไฟล์กลับไป เช่น /static/*
+
+	// S3-compatible (AWS S3, MinIO, R2 ...) ใช้เมื่อ StorageBackend = "s3"
+	S3Endpoint       string // เช่น http://minio:9000 (ว่าง = https://s3.<region>.amazonaws.com)
+	S3PublicEndpoint string // endpoint ที่ browser เข้าถึงได้ สำหรับ presigned URL (ว่าง = S3Endpoint)
+	S3Region         string
+	S3Bucket         string
+	S3AccessKey      string
+	S3SecretKey      string
+	S3PathStyle      bool          // MinIO ต้องใช้ path-style (endpoint/bucket/key)
+	S3PresignTTL     time.Duration // อายุ presigned URL
+
+	// โควตาพื้นที่ไฟล์ (ไบต์, 0 = ไม่จำกัด); ไฟล์เนื้อซ้ำกันนับครั้งเดียว
+	QuotaUserBytes      int64
+	QuotaHouseholdBytes int64
+
+	// เก็บกวาดไฟล์ที่ไม่มีใครอ้าง: อายุขั้นต่ำก่อนถือเป็นขยะ และให้งานตามรอบลบจริง (false = รายงานอย่างเดียว)
+	FilesGCGrace time.Duration
+	FilesGCApply bool
+
+	MigrateOnStart bool // รัน migration ที่ค้างอยู่ตอนบูต API
+
+	// ช่องทางแจ้งเตือน (ว่าง = ปิดช่องทางนั้น; in-app feed ใช้ได้เสมอ)
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUser     string
+	SMTPPassword string
+	SMTPFrom     string
+	VAPIDPrivate string // base64url 32 ไบต์
+	VAPIDSubject string // mailto:... ที่ push service ติดต่อได้
+
+	SchedulerTZ string // timezone ของ cron ใน scheduler เช่น Asia/Bangkok
+
+	// ปิดระบบ: หยุดรับงานใหม่ รอ DrainDelay ให้ load balancer เห็น /readyz = 503 ก่อน
+	// แล้วรอ request/งานเบื้องหลังที่ค้างอยู่ไม่เกิน ShutdownTimeout
+	DrainDelay      time.Duration
+	ShutdownTimeout time.Duration
+}
+
+// SchedulerLocation timezone ของ cron; โหลดไม่ได้ = เวลาเครื่อง
+func (c Config) SchedulerLocation() *time.Location {
+	loc, err := time.LoadLocation(c.SchedulerTZ)
+	if err != nil {
+		log.Printf("[WARN] SCHEDULER_TZ %q: %v; using local time", c.SchedulerTZ, err)
+		return time.Local
+	}
+	return loc
 }
 
 func Getenv(key, def string) string {
@@ -36,6 +87,30 @@ func Getbool(key string, def bool) bool {
 	return b
 }
 
+func Getint64(key string, def int64) int64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+func Getduration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return def
+	}
+	return d
+}
+
 func Load() Config {
 	c := Config{
 		AppPort:    Getenv("APP_PORT", "8080"),
@@ -43,9 +118,42 @@ func Load() Config {
 		JWTSecret:  Getenv("JWT_SECRET", "change-me"),
 		CorsOrigin: Getenv("CORS_ALLOW_ORIGIN", "*"),
 
+		AccessTokenTTL:  Getduration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: Getduration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+
 		StorageBackend: Getenv("STORAGE_BACKEND", "local"),
 		LocalDir:       Getenv("LOCAL_STORAGE_DIR", "./data/uploads"),
 		PublicBaseURL:  Getenv("PUBLIC_BASE_URL", "http://localhost:8080/static"),
+
+		S3Endpoint:       Getenv("S3_ENDPOINT", ""),
+		S3PublicEndpoint: Getenv("S3_PUBLIC_ENDPOINT", ""),
+		S3Region:         Getenv("S3_REGION", "us-east-1"),
+		S3Bucket:         Getenv("S3_BUCKET", ""),
+		S3AccessKey:      Getenv("S3_ACCESS_KEY", ""),
+		S3SecretKey:      Getenv("S3_SECRET_KEY", ""),
+		S3PathStyle:      Getbool("S3_PATH_STYLE", true),
+		S3PresignTTL:     Getduration("S3_PRESIGN_TTL", 15*time.Minute),
+
+		QuotaUserBytes:      Getint64("QUOTA_USER_BYTES", 2<<30),       // 2 GiB
+		QuotaHouseholdBytes: Getint64("QUOTA_HOUSEHOLD_BYTES", 10<<30), // 10 GiB
+
+		FilesGCGrace: Getduration("FILES_GC_GRACE", 24*time.Hour),
+		FilesGCApply: Getbool("FILES_GC_APPLY", false),
+
+		MigrateOnStart: Getbool("MIGRATE_ON_START", true),
+
+		SMTPHost:     Getenv("SMTP_HOST", ""),
+		SMTPPort:     Getenv("SMTP_PORT", "587"),
+		SMTPUser:     Getenv("SMTP_USER", ""),
+		SMTPPassword: Getenv("SMTP_PASSWORD", ""),
+		SMTPFrom:     Getenv("SMTP_FROM", ""),
+		VAPIDPrivate: Getenv("VAPID_PRIVATE_KEY", ""),
+		VAPIDSubject: Getenv("VAPID_SUBJECT", "mailto:admin@localhost"),
+
+		SchedulerTZ: Getenv("SCHEDULER_TZ", "Asia/Bangkok"),
+
+		DrainDelay:      Getduration("SHUTDOWN_DRAIN_DELAY", 5*time.Second),
+		ShutdownTimeout: Getduration("SHUTDOWN_TIMEOUT", 30*time.Second),
 	}
 
 	if c.JWTSecret == "change-me" {
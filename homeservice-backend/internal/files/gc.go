@@ -0,0 +1,327 @@
+package files
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/iMookatayou/homeservice-backend/internal/httputil"
+	"github.com/iMookatayou/homeservice-backend/internal/storage"
+)
+
+// maxReportItems จำนวนรายการสูงสุดที่เก็บในรายงาน (ตัวนับยังนับครบ)
+const maxReportItems = 200
+
+// GCRow แถว files ที่ไม่มีข้อมูลไหนอ้างถึง
+type GCRow struct {
+	ID        string    `json:"id"`
+	OwnerID   string    `json:"owner_id"`
+	Filename  string    `json:"filename"`
+	Key       string    `json:"storage_key"`
+	Size      int64     `json:"size"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GCObject object ใน storage ที่ไม่มีแถว files ไหนใช้ (ทั้งต้นฉบับและภาพย่อ)
+type GCObject struct {
+	Key     string    `json:"key"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+type GCReport struct {
+	ID         string    `json:"id,omitempty"`
+	DryRun     bool      `json:"dry_run"`
+	Grace      string    `json:"grace"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+
+	ExpiredUploads int        `json:"expired_uploads"` // resumable upload ที่หมดอายุ (ก้อนที่ค้างถูกลบด้วย)
+	OrphanRows     int        `json:"orphan_rows"`
+	OrphanObjects  int        `json:"orphan_objects"`
+	OrphanBytes    int64      `json:"orphan_bytes"`
+	DeletedRows    int        `json:"deleted_rows"`
+	DeletedObjects int        `json:"deleted_objects"`
+	Rows           []GCRow    `json:"rows"`
+	Objects        []GCObject `json:"objects"`
+	Truncated      bool       `json:"truncated"` // rows/objects ถูกตัดที่ maxReportItems
+	Errors         []string   `json:"errors,omitempty"`
+}
+
+func (rp *GCReport) fail(format string, args ...any) {
+	if len(rp.Errors) < maxReportItems {
+		rp.Errors = append(rp.Errors, fmt.Sprintf(format, args...))
+	}
+}
+
+// GC งานเก็บกวาดไฟล์: แถว files ที่เก่ากว่า Grace และไม่มีคำขอซื้อ/บิล/ยา/snapshot หุ้นอ้างถึง
+// กับ object ใน storage ที่ไม่มีแถวไหนใช้ (เช่น presign แล้วไม่ confirm, ลบ object ไม่สำเร็จตอนลบไฟล์)
+// ค่าเริ่มต้นเป็น dry-run: รายงานอย่างเดียว ต้องตั้ง Apply หรือสั่งผ่าน admin จึงลบจริง
+type GC struct {
+	Repo    Repo
+	Storage storage.Service
+	Grace   time.Duration // อายุขั้นต่ำก่อนถือว่าเป็นขยะ (ค่าเริ่มต้น 24 ชม.) กันไฟล์ที่เพิ่งอัปโหลดรอผูก
+	Apply   bool          // งานตามรอบลบจริง (false = dry-run)
+	Logf    func(format string, args ...any)
+}
+
+func (g *GC) logf(format string, args ...any) {
+	if g.Logf != nil {
+		g.Logf(format, args...)
+	}
+}
+
+// RunOnce สำหรับ scheduler
+func (g *GC) RunOnce(ctx context.Context) error {
+	_, err := g.Run(ctx, g.Apply)
+	return err
+}
+
+// Run สแกนหนึ่งรอบแล้วบันทึกรายงาน; apply = false ไม่ลบอะไรเลย
+func (g *GC) Run(ctx context.Context, apply bool) (*GCReport, error) {
+	grace := g.Grace
+	if grace <= 0 {
+		grace = 24 * time.Hour
+	}
+	now := time.Now()
+	cutoff := now.Add(-grace)
+	rp := &GCReport{DryRun: !apply, Grace: grace.String(), StartedAt: now, Rows: []GCRow{}, Objects: []GCObject{}}
+
+	expired, err := g.Repo.ExpiredUploads(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	rp.ExpiredUploads = len(expired)
+	for _, id := range expired {
+		if !apply {
+			continue
+		}
+		keys, err := g.Repo.DeleteUpload(ctx, id)
+		if err != nil && !errors.Is(err, ErrUploadNotFound) {
+			rp.fail("upload %s: %v", id, err)
+			continue
+		}
+		for _, k := range keys {
+			if err := g.Storage.Delete(ctx, k); err != nil {
+				rp.fail("upload part %s: %v", k, err)
+			}
+		}
+	}
+
+	orphans, err := g.Repo.Unreferenced(ctx, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	for i := range orphans {
+		f := &orphans[i]
+		rp.OrphanRows++
+		rp.OrphanBytes += f.Size
+		if len(rp.Rows) < maxReportItems {
+			rp.Rows = append(rp.Rows, GCRow{
+				ID: f.ID, OwnerID: f.OwnerID, Filename: f.Filename, Key: f.StorageKey,
+				Size: f.Size, Status: f.Status, CreatedAt: f.CreatedAt,
+			})
+		} else {
+			rp.Truncated = true
+		}
+		if !apply {
+			continue
+		}
+		err := remove(ctx, g.Repo, g.Storage, f)
+		switch {
+		case errors.Is(err, ErrNotFound): // ถูกลบไปแล้วระหว่างสแกน
+		case err != nil:
+			rp.fail("row %s: %v", f.ID, err)
+		default:
+			rp.DeletedRows++
+		}
+	}
+
+	// key ที่ยังมีแถวใช้: อ่านหลังลบแถวแล้ว object ของแถวที่เพิ่งลบจึงไม่ถูกนับซ้ำ
+	known, err := g.Repo.KnownKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = g.Storage.List(ctx, "", func(o storage.ObjectInfo) error {
+		if known[o.Key] || o.ModTime.After(cutoff) {
+			return nil
+		}
+		rp.OrphanObjects++
+		rp.OrphanBytes += o.Size
+		if len(rp.Objects) < maxReportItems {
+			rp.Objects = append(rp.Objects, GCObject{Key: o.Key, Size: o.Size, ModTime: o.ModTime})
+		} else {
+			rp.Truncated = true
+		}
+		if !apply {
+			return nil
+		}
+		// ตรวจซ้ำก่อนลบ: ระหว่างไล่ list อาจมีไฟล์ใหม่ผูกกับ key นี้แล้ว
+		used, err := g.Repo.KeyInUse(ctx, o.Key)
+		if err != nil {
+			return err
+		}
+		if used {
+			return nil
+		}
+		if err := g.Storage.Delete(ctx, o.Key); err != nil {
+			rp.fail("object %s: %v", o.Key, err)
+			return nil
+		}
+		rp.DeletedObjects++
+		return nil
+	})
+	if err != nil {
+		rp.fail("list storage: %v", err)
+	}
+
+	rp.FinishedAt = time.Now()
+	if err := g.Repo.SaveGCReport(ctx, rp); err != nil {
+		return rp, err
+	}
+	g.logf("files: gc dry_run=%t orphan_rows=%d orphan_objects=%d deleted_rows=%d deleted_objects=%d",
+		rp.DryRun, rp.OrphanRows, rp.OrphanObjects, rp.DeletedRows, rp.DeletedObjects)
+	if len(rp.Errors) > 0 {
+		return rp, fmt.Errorf("files gc: %d errors, first: %s", len(rp.Errors), rp.Errors[0])
+	}
+	return rp, nil
+}
+
+// Unreferenced แถวที่สร้างก่อน before และไม่มีคำขอซื้อ (แนบ/ความเห็น)/บิล/ยา/snapshot หุ้นอ้างถึง
+func (r Repo) Unreferenced(ctx context.Context, before time.Time) ([]File, error) {
+	rows, err := r.DB.Query(ctx, `
+	SELECT `+fileCols+` FROM files f
+	WHERE f.created_at < $1
+	  AND NOT EXISTS (SELECT 1 FROM purchase_attachments a WHERE a.file_id = f.id)
+	  AND NOT EXISTS (SELECT 1 FROM purchase_message_files mf WHERE mf.file_id = f.id)
+	  AND NOT EXISTS (SELECT 1 FROM bills b WHERE b.receipt_file_id = f.id)
+	  AND NOT EXISTS (SELECT 1 FROM medicine_items m WHERE m.photo_file_id = f.id)
+	  AND NOT EXISTS (
+		SELECT 1 FROM stock_snapshot s
+		WHERE s.files @> jsonb_build_array(jsonb_build_object('id', f.id::text)))
+	ORDER BY f.created_at`, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []File
+	for rows.Next() {
+		f, err := scanFile(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *f)
+	}
+	return out, rows.Err()
+}
+
+// KnownKeys ทุก key ที่แถว files ใช้อยู่ (ต้นฉบับ + ภาพย่อ) และก้อนของ resumable upload ที่ยังค้าง
+func (r Repo) KnownKeys(ctx context.Context) (map[string]bool, error) {
+	rows, err := r.DB.Query(ctx, `
+	SELECT storage_key FROM files WHERE storage_key IS NOT NULL
+	UNION
+	SELECT rd.value->>'key' FROM files, jsonb_each(renditions) rd WHERE rd.value ? 'key'
+	UNION
+	SELECT storage_key FROM upload_parts`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]bool{}
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			return nil, err
+		}
+		out[k] = true
+	}
+	return out, rows.Err()
+}
+
+// KeyInUse = มีแถว files ใช้ key นี้เป็นต้นฉบับหรือภาพย่อ หรือเป็นก้อนของ upload ที่ยังค้าง
+func (r Repo) KeyInUse(ctx context.Context, key string) (bool, error) {
+	var used bool
+	err := r.DB.QueryRow(ctx, `
+	SELECT EXISTS (SELECT 1 FROM files WHERE storage_key = $1)
+	    OR EXISTS (SELECT 1 FROM files, jsonb_each(renditions) rd WHERE rd.value->>'key' = $1)
+	    OR EXISTS (SELECT 1 FROM upload_parts WHERE storage_key = $1)`, key).Scan(&used)
+	return used, err
+}
+
+func (r Repo) SaveGCReport(ctx context.Context, rp *GCReport) error {
+	raw, err := json.Marshal(rp)
+	if err != nil {
+		return err
+	}
+	return r.DB.QueryRow(ctx, `
+	INSERT INTO file_gc_reports (dry_run, started_at, finished_at, report)
+	VALUES ($1, $2, $3, $4) RETURNING id::text`, rp.DryRun, rp.StartedAt, rp.FinishedAt, raw).Scan(&rp.ID)
+}
+
+// LastGCReport รายงานรอบล่าสุด (ErrNotFound = ยังไม่เคยรัน)
+func (r Repo) LastGCReport(ctx context.Context) (*GCReport, error) {
+	var id string
+	var raw []byte
+	err := r.DB.QueryRow(ctx, `
+	SELECT id::text, report FROM file_gc_reports ORDER BY started_at DESC LIMIT 1`).Scan(&id, &raw)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rp GCReport
+	if err := json.Unmarshal(raw, &rp); err != nil {
+		return nil, err
+	}
+	rp.ID = id
+	return &rp, nil
+}
+
+// GCHandler endpoint ของ admin ระบบ
+type GCHandler struct{ GC *GC }
+
+// RegisterAdminRoutes — ใช้ใต้กลุ่ม admin
+func (h GCHandler) RegisterAdminRoutes(r chi.Router) {
+	r.Get("/admin/files/gc", h.Last)
+	r.Post("/admin/files/gc/run", h.Run) // {"apply": true} = ลบจริง, ไม่ส่ง = dry-run
+}
+
+func (h GCHandler) Last(w http.ResponseWriter, r *http.Request) {
+	rp, err := h.GC.Repo.LastGCReport(r.Context())
+	if errors.Is(err, ErrNotFound) {
+		httputil.Error(w, http.StatusNotFound, "NOT_FOUND", "gc has not run yet", "")
+		return
+	}
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, "INTERNAL", err.Error(), "")
+		return
+	}
+	httputil.OK(w, rp)
+}
+
+func (h GCHandler) Run(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Apply bool `json:"apply"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			httputil.Error(w, http.StatusBadRequest, "BAD_REQUEST", err.Error(), "")
+			return
+		}
+	}
+	rp, err := h.GC.Run(r.Context(), payload.Apply)
+	if rp == nil {
+		httputil.Error(w, http.StatusInternalServerError, "INTERNAL", err.Error(), "")
+		return
+	}
+	// ลบบางรายการไม่สำเร็จ: รายงานยังใช้ได้ (ดู errors)
+	httputil.OK(w, rp)
+}
@@ -1,23 +1,38 @@
 package files
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"mime"
 	"net/http"
-	"path/filepath"
 	"strconv"
-	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/iMookatayou/homeservice-backend/internal/auth"
+	"github.com/iMookatayou/homeservice-backend/internal/authz"
 	"github.com/iMookatayou/homeservice-backend/internal/httputil"
+	"github.com/iMookatayou/homeservice-backend/internal/imaging"
 	"github.com/iMookatayou/homeservice-backend/internal/storage"
 )
 
+const maxUploadSize = 25 << 20 // 25MB
+
 type Handler struct {
 	Repo      Repo
 	Storage   storage.Service
 	JWTSecret string
+	// Kick ปลุก Processor ทันทีหลังมีไฟล์ใหม่ (nil = รอรอบปกติของ scheduler)
+	Kick  func(ctx context.Context)
+	Quota Quota
+}
+
+func (h Handler) kick(ctx context.Context) {
+	if h.Kick != nil {
+		h.Kick(ctx)
+	}
 }
 
 func (h Handler) RegisterRoutes(r chi.Router) {
@@ -26,7 +41,17 @@ func (h Handler) RegisterRoutes(r chi.Router) {
 	r.Post("/uploads/presign", h.Presign)
 	r.Post("/uploads/confirm", h.Confirm)
 
+	// resumable (tus-style) สำหรับไฟล์ใหญ่ เช่น วิดีโอจากมือถือ
+	r.Post("/uploads/resumable", h.CreateUpload)
+	r.Head("/uploads/resumable/{id}", h.UploadStatus)
+	r.Get("/uploads/resumable/{id}", h.UploadStatus)
+	r.Patch("/uploads/resumable/{id}", h.PatchUpload)
+	r.Delete("/uploads/resumable/{id}", h.AbortUpload)
+
+	r.Get("/files/usage", h.Usage)
 	r.Get("/files/{id}", h.Get)
+	r.Get("/files/{id}/content", h.Content) // stream (Range/ETag) หลังตรวจสิทธิ์; ?rendition=thumb|medium
+	r.Get("/files/{id}/url", h.SignedURL)   // ?ttl=10m&rendition= ลิงก์ชั่วคราวไม่ต้อง login
 	r.Delete("/files/{id}", h.Delete)
 }
 
@@ -51,13 +76,16 @@ func (h Handler) UploadLocal(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	//MIME resolve
-	mtype := header.Header.Get("Content-Type")
-	if mtype == "" {
-		mtype = mime.TypeByExtension(filepath.Ext(header.Filename)) 
+	// MIME จาก magic bytes (ไม่เชื่อ Content-Type ของ client); Processor ตรวจซ้ำทั้งไฟล์อีกรอบ
+	head := make([]byte, 3072)
+	n, _ := io.ReadFull(file, head)
+	mtype := imaging.Sniff(head[:n])
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		httputil.Error(w, http.StatusBadRequest, "BAD_REQUEST", err.Error(), "")
+		return
 	}
 	if !allowMIME(mtype) {
-		httputil.Error(w, http.StatusBadRequest, "BAD_REQUEST", "unsupported mimetype", "")
+		httputil.Error(w, http.StatusBadRequest, "BAD_REQUEST", "unsupported file type", "")
 		return
 	}
 
@@ -70,10 +98,13 @@ func (h Handler) UploadLocal(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 	}
-	if size <= 0 || size > (25<<20) { // 25MB
+	if size <= 0 || size > maxUploadSize {
 		httputil.Error(w, http.StatusBadRequest, "BAD_REQUEST", "invalid file size", "")
 		return
 	}
+	if !h.checkQuota(w, r, uid, size) {
+		return
+	}
 
 	// --- Save to storage (local) ---
 	put, err := h.Storage.Save(r.Context(), uid, file, header.Filename, mtype, size)
@@ -82,25 +113,28 @@ func (h Handler) UploadLocal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// --- Record (ใช้เฉพาะฟิลด์ที่มีใน type File เดิม) ---
+	// --- Record: pending จนกว่า Processor จะลบ metadata/สร้างภาพย่อเสร็จ ---
 	rec := &File{
 		OwnerID:    uid,
 		Filename:   put.Filename,
 		MIME:       mtype,
 		Size:       put.Size,
 		StorageURL: put.URL,
+		StorageKey: put.Key,
 	}
 
-	if err := h.Repo.Create(r.Context(), rec); err != nil {
-		httputil.Error(w, http.StatusInternalServerError, "INTERNAL", err.Error(), "")
+	if err := h.Repo.Create(r.Context(), rec, h.Quota); err != nil {
+		_ = h.Storage.Delete(context.WithoutCancel(r.Context()), put.Key)
+		h.createFailed(w, err)
 		return
 	}
+	h.kick(r.Context())
 	httputil.Created(w, rec)
 }
 
+// Presign ออก URL ให้ client PUT ไฟล์ตรงไปที่ storage แล้วค่อยเรียก /uploads/confirm พร้อม key
 func (h Handler) Presign(w http.ResponseWriter, r *http.Request) {
 	uid, _ := auth.UserIDFrom(r)
-	_ = uid
 
 	var payload struct {
 		Filename string `json:"filename"`
@@ -119,74 +153,274 @@ func (h Handler) Presign(w http.ResponseWriter, r *http.Request) {
 		httputil.Error(w, http.StatusBadRequest, "BAD_REQUEST", "invalid mimetype", "")
 		return
 	}
-	// local backend ไม่รองรับ presign
-	httputil.Error(w, http.StatusNotImplemented, "NOT_SUPPORTED", "presign not supported for local storage", "")
+	if payload.Size > maxUploadSize {
+		httputil.Error(w, http.StatusBadRequest, "BAD_REQUEST", "invalid file size", "")
+		return
+	}
+	if !h.checkQuota(w, r, uid, payload.Size) {
+		return
+	}
+	ps, err := h.Storage.PresignPut(r.Context(), uid, payload.Filename, payload.MIME, payload.Size)
+	if errors.Is(err, storage.ErrNotSupported) {
+		httputil.Error(w, http.StatusNotImplemented, "NOT_SUPPORTED", "presign not supported for this storage backend", "")
+		return
+	}
+	if err != nil {
+		httputil.Error(w, http.StatusBadGateway, "PRESIGN_FAILED", err.Error(), "")
+		return
+	}
+	httputil.OK(w, ps)
 }
 
+// Confirm บันทึกไฟล์หลัง client อัปโหลดผ่าน presigned URL เสร็จ:
+// key ต้องเป็นของผู้ใช้คนนี้ และต้อง HEAD เจอ object จริง (ขนาด/ชนิดเอาจาก storage ไม่เชื่อ client)
 func (h Handler) Confirm(w http.ResponseWriter, r *http.Request) {
 	uid, _ := auth.UserIDFrom(r)
 
 	var payload struct {
+		Key      string `json:"key"`
 		Filename string `json:"filename"`
 		MIME     string `json:"mimetype"`
 		Size     int64  `json:"size"`
-		URL      string `json:"url"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
 		httputil.Error(w, http.StatusBadRequest, "BAD_REQUEST", err.Error(), "")
 		return
 	}
-	if payload.URL == "" || payload.Filename == "" || payload.MIME == "" || payload.Size <= 0 {
-		httputil.Error(w, http.StatusBadRequest, "BAD_REQUEST", "missing fields", "")
+	if payload.Key == "" || payload.Filename == "" {
+		httputil.Error(w, http.StatusBadRequest, "BAD_REQUEST", "missing key/filename", "")
 		return
 	}
-	if !allowMIME(payload.MIME) {
+	if !storage.OwnedKey(uid, payload.Key) {
+		httputil.Error(w, http.StatusForbidden, "FORBIDDEN", "key was not issued to this user", "")
+		return
+	}
+
+	obj, err := h.Storage.Stat(r.Context(), payload.Key)
+	if errors.Is(err, storage.ErrNotFound) {
+		httputil.Error(w, http.StatusBadRequest, "NOT_UPLOADED", "object not found in storage; upload before confirming", "")
+		return
+	}
+	if err != nil {
+		httputil.Error(w, http.StatusBadGateway, "STORAGE_ERROR", err.Error(), "")
+		return
+	}
+	mtype := obj.MIME
+	if mtype == "" {
+		mtype = payload.MIME
+	}
+	if !allowMIME(mtype) {
 		httputil.Error(w, http.StatusBadRequest, "BAD_REQUEST", "invalid mimetype", "")
 		return
 	}
+	if obj.Size <= 0 || obj.Size > maxUploadSize || (payload.Size > 0 && payload.Size != obj.Size) {
+		httputil.Error(w, http.StatusBadRequest, "BAD_REQUEST", "uploaded size does not match", "")
+		return
+	}
+	// ตรวจซ้ำตอน confirm: presign ไปหลายไฟล์พร้อมกันอาจเกินโควตารวม
+	if !h.checkQuota(w, r, uid, obj.Size) {
+		_ = h.Storage.Delete(r.Context(), obj.Key)
+		return
+	}
 
 	rec := &File{
 		OwnerID:    uid,
 		Filename:   payload.Filename,
-		MIME:       payload.MIME,
-		Size:       payload.Size,
-		StorageURL: payload.URL,
+		MIME:       mtype,
+		Size:       obj.Size,
+		StorageURL: obj.URL,
+		StorageKey: obj.Key,
 	}
-	if err := h.Repo.Create(r.Context(), rec); err != nil {
-		httputil.Error(w, http.StatusInternalServerError, "INTERNAL", err.Error(), "")
+	if err := h.Repo.Create(r.Context(), rec, h.Quota); err != nil {
+		_ = h.Storage.Delete(context.WithoutCancel(r.Context()), obj.Key)
+		h.createFailed(w, err)
 		return
 	}
+	h.kick(r.Context())
 	httputil.Created(w, rec)
 }
 
-func (h Handler) Get(w http.ResponseWriter, r *http.Request) {
-	id := chi.URLParam(r, "id")
-	f, err := h.Repo.Get(r.Context(), id)
+// load ดึงไฟล์และตรวจสิทธิ์; false = เขียน error ตอบไปแล้ว
+func (h Handler) load(w http.ResponseWriter, r *http.Request, action authz.Action) (*File, bool) {
+	f, err := h.Repo.Get(r.Context(), chi.URLParam(r, "id"))
 	if err != nil {
 		httputil.Error(w, http.StatusNotFound, "NOT_FOUND", "file not found", "")
+		return nil, false
+	}
+	res := authz.Resource{Kind: authz.KindFile, OwnerID: f.OwnerID}
+	if action == authz.ActionRead {
+		if res.Participants, err = h.Repo.Readers(r.Context(), f.ID); err != nil {
+			httputil.Error(w, http.StatusInternalServerError, "INTERNAL", err.Error(), "")
+			return nil, false
+		}
+	}
+	if !authz.Can(r.Context(), authz.SubjectFrom(r), action, res) {
+		// อ่านไม่ได้ = ไม่บอกว่ามีไฟล์นี้อยู่
+		if action == authz.ActionRead {
+			httputil.Error(w, http.StatusNotFound, "NOT_FOUND", "file not found", "")
+		} else {
+			httputil.Error(w, http.StatusForbidden, "FORBIDDEN", "only the uploader can delete this file", "")
+		}
+		return nil, false
+	}
+	return f, true
+}
+
+func (h Handler) Get(w http.ResponseWriter, r *http.Request) {
+	f, ok := h.load(w, r, authz.ActionRead)
+	if !ok {
 		return
 	}
 	httputil.OK(w, f)
 }
 
+// objectKey เลือก key ตาม ?rendition=; ไฟล์ที่ยังประมวลผลไม่เสร็จ (อาจยังมี GPS) หรือถูกปฏิเสธไม่เสิร์ฟ
+func (h Handler) objectKey(w http.ResponseWriter, r *http.Request, f *File) (key, mtype string, ok bool) {
+	switch f.Status {
+	case StatusReady:
+	case StatusFailed:
+		httputil.Error(w, http.StatusUnprocessableEntity, "FILE_REJECTED", "file failed processing", "")
+		return "", "", false
+	default:
+		w.Header().Set("Retry-After", "2")
+		httputil.Error(w, http.StatusConflict, "PROCESSING", "file is still being processed", "")
+		return "", "", false
+	}
+	name := r.URL.Query().Get("rendition")
+	if name == "" && f.StorageKey != "" {
+		return f.StorageKey, f.MIME, true
+	}
+	rd, found := f.Renditions[name]
+	if !found {
+		httputil.Error(w, http.StatusNotFound, "NOT_FOUND", "rendition not available", "")
+		return "", "", false
+	}
+	return rd.Key, rd.MIME, true
+}
+
+func (h Handler) Content(w http.ResponseWriter, r *http.Request) {
+	f, ok := h.load(w, r, authz.ActionRead)
+	if !ok {
+		return
+	}
+	key, mtype, ok := h.objectKey(w, r, f)
+	if !ok {
+		return
+	}
+	rc, info, err := h.Storage.Open(r.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		httputil.Error(w, http.StatusNotFound, "NOT_FOUND", "file content missing", "")
+		return
+	}
+	if err != nil {
+		httputil.Error(w, http.StatusBadGateway, "STORAGE_ERROR", err.Error(), "")
+		return
+	}
+	defer rc.Close()
+	w.Header().Set("Content-Type", mtype)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": f.Filename}))
+	storage.Serve(w, r, rc, info)
+}
+
+// SignedURL ลิงก์อ่านไฟล์ชั่วคราว (ค่าเริ่มต้น 10 นาที, ไม่เกิน 24 ชม.)
+func (h Handler) SignedURL(w http.ResponseWriter, r *http.Request) {
+	f, ok := h.load(w, r, authz.ActionRead)
+	if !ok {
+		return
+	}
+	ttl := 10 * time.Minute
+	if v := r.URL.Query().Get("ttl"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 || d > 24*time.Hour {
+			httputil.Error(w, http.StatusBadRequest, "BAD_REQUEST", "ttl must be a duration up to 24h", "")
+			return
+		}
+		ttl = d
+	}
+	key, _, ok := h.objectKey(w, r, f)
+	if !ok {
+		return
+	}
+	u, err := h.Storage.SignedGetURL(r.Context(), key, ttl)
+	if err != nil {
+		httputil.Error(w, http.StatusBadGateway, "STORAGE_ERROR", err.Error(), "")
+		return
+	}
+	httputil.OK(w, map[string]any{"url": u, "expire": time.Now().Add(ttl)})
+}
+
+// Delete ลบ metadata แล้วลบ object; ลบ object ไม่สำเร็จไม่ถือว่าล้ม (เหลือเป็น orphan ให้ files.gc เก็บกวาด)
 func (h Handler) Delete(w http.ResponseWriter, r *http.Request) {
-	uid, _ := auth.UserIDFrom(r)
-	id := chi.URLParam(r, "id")
-	if err := h.Repo.Delete(r.Context(), uid, id); err != nil {
-		httputil.Error(w, http.StatusNotFound, "NOT_FOUND", "file not found or not owner", "")
+	f, ok := h.load(w, r, authz.ActionDelete)
+	if !ok {
+		return
+	}
+	if err := remove(r.Context(), h.Repo, h.Storage, f); err != nil {
+		httputil.Error(w, http.StatusNotFound, "NOT_FOUND", "file not found", "")
 		return
 	}
-	httputil.OK(w, map[string]string{"deleted": id})
+	httputil.OK(w, map[string]string{"deleted": f.ID})
 }
 
-func allowMIME(m string) bool {
-	if m == "" {
+// remove ลบแถวไฟล์และ object ของมัน; blob ที่ใช้ร่วมกันลบเมื่อไม่มีไฟล์อื่นอ้างแล้วเท่านั้น
+func remove(ctx context.Context, repo Repo, st storage.Service, f *File) error {
+	dropObjects := func(ctx context.Context) error {
+		if f.StorageKey != "" {
+			_ = st.Delete(ctx, f.StorageKey)
+		}
+		for _, rd := range f.Renditions {
+			_ = st.Delete(ctx, rd.Key)
+		}
+		return nil
+	}
+	if f.ContentSHA256 != "" {
+		return repo.DeleteShared(ctx, f.ID, f.ContentSHA256, dropObjects)
+	}
+	if err := repo.Delete(ctx, f.ID); err != nil {
+		return err
+	}
+	return dropObjects(ctx)
+}
+
+// checkQuota ตรวจก่อนรับไฟล์ (ตัดจบเร็ว); ตัวที่กันเกินจริงคือ Repo.Create/FinishUpload ที่ตรวจซ้ำใต้ lock
+// false = เกินโควตา/ตรวจไม่ได้ และเขียน error ตอบไปแล้ว
+func (h Handler) checkQuota(w http.ResponseWriter, r *http.Request, uid string, size int64) bool {
+	u, err := h.Repo.Usage(r.Context(), uid, h.Quota)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, "INTERNAL", err.Error(), "")
 		return false
 	}
-	if strings.HasPrefix(m, "image/") {
-		return true
+	if !u.Fits(size) {
+		httputil.Error(w, http.StatusRequestEntityTooLarge, "QUOTA_EXCEEDED", "storage quota exceeded", "")
+		return false
+	}
+	return true
+}
+
+// createFailed ตอบ error ของ Repo.Create: ตรวจโควตาซ้ำใน transaction เดียวกับ insert จึงอาจเกินตรงนี้ได้แม้ checkQuota ผ่าน
+func (h Handler) createFailed(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrQuotaExceeded) {
+		httputil.Error(w, http.StatusRequestEntityTooLarge, "QUOTA_EXCEEDED", "storage quota exceeded", "")
+		return
 	}
-	if m == "video/mp4" {
+	httputil.Error(w, http.StatusInternalServerError, "INTERNAL", err.Error(), "")
+}
+
+// Usage พื้นที่ที่ใช้ไปเทียบกับโควตา (ของตัวเองและทุกบ้านที่เป็นสมาชิก)
+func (h Handler) Usage(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFrom(r)
+	u, err := h.Repo.Usage(r.Context(), uid, h.Quota)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, "INTERNAL", err.Error(), "")
+		return
+	}
+	httputil.OK(w, u)
+}
+
+// allowMIME เฉพาะภาพที่ลบ metadata ได้ (heic/tiff ฯลฯ ลบ GPS ไม่ได้จึงไม่รับ) และ mp4
+func allowMIME(m string) bool {
+	switch m {
+	case "image/jpeg", "image/png", "image/gif", "image/webp", "video/mp4":
 		return true
 	}
 	return false
@@ -2,6 +2,19 @@ package files
 
 import "time"
 
+const (
+	StatusPending    = "pending"
+	StatusProcessing = "processing"
+	StatusReady      = "ready"
+	StatusFailed     = "failed"
+)
+
+// Rendition names
+const (
+	RenditionThumb  = "thumb"  // ด้านยาว 256px
+	RenditionMedium = "medium" // ด้านยาว 1280px
+)
+
 type File struct {
 	ID         string    `json:"id"`
 	OwnerID    string    `json:"owner_id"`
@@ -9,5 +22,35 @@ type File struct {
 	MIME       string    `json:"mimetype"`
 	Size       int64     `json:"size"`
 	StorageURL string    `json:"storage_url"`
+	StorageKey string    `json:"storage_key"`
 	CreatedAt  time.Time `json:"created_at"`
+
+	Status     string               `json:"status"` // pending|processing|ready|failed
+	Error      *string              `json:"error,omitempty"`
+	Width      *int                 `json:"width,omitempty"`
+	Height     *int                 `json:"height,omitempty"`
+	Renditions map[string]Rendition `json:"renditions"`
+	// ContentSHA256 = sha256 ของเนื้อไฟล์หลังประมวลผล (ไฟล์เนื้อซ้ำกันใช้ blob เดียวกัน)
+	ContentSHA256 string `json:"sha256,omitempty"`
+
+	attempts int // จำนวนครั้งที่ Processor จองไฟล์นี้ (รวมรอบปัจจุบัน)
+}
+
+// Rendition ภาพย่อที่เก็บข้างต้นฉบับ; URL ชี้ /files/{id}/content?rendition=<name>
+type Rendition struct {
+	Key    string `json:"-"`
+	URL    string `json:"url"`
+	MIME   string `json:"mimetype"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Size   int64  `json:"size"`
+}
+
+// storedRendition = รูปแบบใน jsonb (มี key แต่ไม่มี url)
+type storedRendition struct {
+	Key    string `json:"key"`
+	MIME   string `json:"mime"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Size   int64  `json:"size"`
 }
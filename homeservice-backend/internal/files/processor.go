@@ -0,0 +1,233 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"strings"
+
+	"github.com/iMookatayou/homeservice-backend/internal/imaging"
+	"github.com/iMookatayou/homeservice-backend/internal/storage"
+)
+
+// errRejected = เนื้อไฟล์ใช้ไม่ได้ (ชนิดไม่อนุญาต/เสีย) ลองใหม่ก็ไม่ผ่าน → failed ทันที
+var errRejected = errors.New("file rejected")
+
+// ขนาดด้านยาวสุดของภาพย่อแต่ละแบบ
+var renditionSizes = []struct {
+	name  string
+	limit int
+}{
+	{RenditionThumb, 256},
+	{RenditionMedium, 1280},
+}
+
+// Processor งานเบื้องหลังหลังอัปโหลด: ตรวจชนิดจาก magic bytes, ลบ EXIF/GPS (หมุนภาพตาม orientation ก่อน)
+// แล้วเก็บเนื้อไฟล์ตาม sha256 (ไฟล์เนื้อซ้ำใช้ blob/ภาพย่อร่วมกัน) พร้อม thumb/medium; ระหว่างนี้ /content ยังไม่เสิร์ฟไฟล์ (กันพิกัดหลุด)
+type Processor struct {
+	Repo        Repo
+	Storage     storage.Service
+	Batch       int // ต่อรอบ (ค่าเริ่มต้น 20)
+	MaxAttempts int // error ชั่วคราว (storage ล่ม ฯลฯ) ลองได้กี่ครั้ง (ค่าเริ่มต้น 3)
+	Logf        func(format string, args ...any)
+}
+
+func (p *Processor) logf(format string, args ...any) {
+	if p.Logf != nil {
+		p.Logf(format, args...)
+	}
+}
+
+func (p *Processor) RunOnce(ctx context.Context) error {
+	batch, maxAttempts := p.Batch, p.MaxAttempts
+	if batch <= 0 {
+		batch = 20
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = 3
+	}
+	claimed, err := p.Repo.ClaimPending(ctx, batch)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for i := range claimed {
+		f := &claimed[i]
+		err := p.process(ctx, f)
+		if err == nil {
+			continue
+		}
+		final := errors.Is(err, errRejected) || f.attempts >= maxAttempts
+		if mErr := p.Repo.MarkFailed(ctx, f.ID, err.Error(), final); mErr != nil {
+			errs = append(errs, mErr)
+		}
+		if errors.Is(err, errRejected) {
+			p.logf("files: %s rejected: %v", f.ID, err)
+			continue
+		}
+		errs = append(errs, fmt.Errorf("file %s: %w", f.ID, err))
+	}
+	return errors.Join(errs...)
+}
+
+func (p *Processor) process(ctx context.Context, f *File) error {
+	if f.StorageKey == "" {
+		return fmt.Errorf("%w: no storage key", errRejected)
+	}
+	rc, _, err := p.Storage.Open(ctx, f.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("%w: object missing", errRejected)
+	}
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	head := make([]byte, 3072)
+	n, err := io.ReadFull(rc, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return err
+	}
+	head = head[:n]
+
+	mime := imaging.Sniff(head)
+	if !allowMIME(mime) {
+		return fmt.Errorf("%w: content is %s", errRejected, mime)
+	}
+	if !strings.HasPrefix(mime, "image/") {
+		// วิดีโออาจใหญ่ระดับร้อย MB (อัปโหลดแบบ resumable): hash แบบ stream ไม่โหลดทั้งไฟล์เข้าหน่วยความจำ
+		return p.storeStream(ctx, f, mime, io.MultiReader(bytes.NewReader(head), rc))
+	}
+
+	data, err := io.ReadAll(io.LimitReader(io.MultiReader(bytes.NewReader(head), rc), maxUploadSize+1))
+	if err != nil {
+		return err
+	}
+	if len(data) > maxUploadSize {
+		return fmt.Errorf("%w: larger than %d bytes", errRejected, maxUploadSize)
+	}
+
+	clean, err := imaging.StripMetadata(mime, data)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errRejected, err)
+	}
+	img, format, decErr := imaging.Decode(clean)
+	if errors.Is(decErr, imaging.ErrCorrupt) {
+		return fmt.Errorf("%w: %v", errRejected, decErr)
+	}
+	// ลบ EXIF แล้วค่า orientation หายไปด้วย: หมุนพิกเซลจริงให้ตั้งตรงก่อน
+	if o := imaging.JPEGOrientation(data); mime == "image/jpeg" && o > 1 && decErr == nil {
+		img = imaging.Orient(img, o)
+		if clean, _, err = imaging.Encode(img, format); err != nil {
+			return err
+		}
+	}
+	// webp (ไม่มี decoder) หรือภาพใหญ่เกิน: ลบ metadata แล้วแต่ไม่มีภาพย่อ
+	if decErr != nil {
+		img = nil
+	}
+	sum := sha256.Sum256(clean)
+	put := func(ctx context.Context, key string) error {
+		return p.Storage.Put(ctx, key, bytes.NewReader(clean), int64(len(clean)), mime)
+	}
+	return p.store(ctx, f, mime, hex.EncodeToString(sum[:]), int64(len(clean)), put, img, format)
+}
+
+// storeStream เนื้อไฟล์ที่ไม่ต้องแก้ (วิดีโอ): hash ระหว่างอ่าน แล้วคัดลอก object เดิมไปที่ key ตาม sha256
+func (p *Processor) storeStream(ctx context.Context, f *File, mime string, r io.Reader) error {
+	h := sha256.New()
+	size, err := io.Copy(h, io.LimitReader(r, maxResumableSize+1))
+	if err != nil {
+		return err
+	}
+	if size > maxResumableSize {
+		return fmt.Errorf("%w: larger than %d bytes", errRejected, maxResumableSize)
+	}
+	put := func(ctx context.Context, key string) error {
+		src, _, err := p.Storage.Open(ctx, f.StorageKey)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		return p.Storage.Put(ctx, key, src, size, mime)
+	}
+	return p.store(ctx, f, mime, hex.EncodeToString(h.Sum(nil)), size, put, nil, "")
+}
+
+// store ย้ายเนื้อไฟล์ที่สะอาดแล้วไปไว้ที่ key ตาม sha256 (put เขียน object); ถ้ามีไฟล์อื่นเนื้อเดียวกันอยู่แล้ว
+// ใช้ blob/ภาพย่อร่วมกัน แล้วลบ object ชั่วคราวที่อัปโหลดมา
+func (p *Processor) store(ctx context.Context, f *File, mime, hexSum string, size int64, put func(ctx context.Context, key string) error, img image.Image, format string) error {
+	key := storage.ContentKey(hexSum)
+
+	err := p.Repo.withBlob(ctx, hexSum, func(b blobTx) error {
+		other, err := b.shared(ctx, f.ID)
+		if err != nil {
+			return err
+		}
+		if other != nil {
+			key = other.StorageKey
+			return b.markReady(ctx, f.ID, other.StorageKey, other.MIME, other.Size, other.Width, other.Height, toStored(other.Renditions))
+		}
+		if err := put(ctx, key); err != nil {
+			return err
+		}
+		if img == nil {
+			return b.markReady(ctx, f.ID, key, mime, size, nil, nil, nil)
+		}
+		rend, err := p.renditions(ctx, key, img, format)
+		if err != nil {
+			return err
+		}
+		bounds := img.Bounds()
+		w, h := bounds.Dx(), bounds.Dy()
+		return b.markReady(ctx, f.ID, key, mime, size, &w, &h, rend)
+	})
+	if err != nil {
+		return err
+	}
+	if f.StorageKey != key {
+		if err := p.Storage.Delete(ctx, f.StorageKey); err != nil {
+			p.logf("files: delete upload %s: %v", f.StorageKey, err)
+		}
+	}
+	return nil
+}
+
+func (p *Processor) renditions(ctx context.Context, key string, img image.Image, format string) (map[string]storedRendition, error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	rend := map[string]storedRendition{}
+	for _, rs := range renditionSizes {
+		if rs.name != RenditionThumb && max(w, h) <= rs.limit {
+			continue // ต้นฉบับเล็กพออยู่แล้ว
+		}
+		small := imaging.Fit(img, rs.limit)
+		out, outMIME, err := imaging.Encode(small, format)
+		if err != nil {
+			return nil, err
+		}
+		ext := ".jpg"
+		if outMIME == "image/png" {
+			ext = ".png"
+		}
+		rkey := key + "." + rs.name + ext
+		if err := p.Storage.Put(ctx, rkey, bytes.NewReader(out), int64(len(out)), outMIME); err != nil {
+			return nil, err
+		}
+		sb := small.Bounds()
+		rend[rs.name] = storedRendition{Key: rkey, MIME: outMIME, Width: sb.Dx(), Height: sb.Dy(), Size: int64(len(out))}
+	}
+	return rend, nil
+}
+
+func toStored(in map[string]Rendition) map[string]storedRendition {
+	out := make(map[string]storedRendition, len(in))
+	for name, r := range in {
+		out[name] = storedRendition{Key: r.Key, MIME: r.MIME, Width: r.Width, Height: r.Height, Size: r.Size}
+	}
+	return out
+}
@@ -0,0 +1,146 @@
+package files
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+// Quota พื้นที่สูงสุด (ไบต์) ต่อผู้ใช้และต่อบ้าน; 0 = ไม่จำกัด
+// บ้าน = ไฟล์ของสมาชิกทุกคนรวมกัน, ไฟล์เนื้อซ้ำกันในขอบเขตเดียวกันนับครั้งเดียว
+type Quota struct {
+	UserBytes      int64
+	HouseholdBytes int64
+}
+
+type ScopeUsage struct {
+	HouseholdID string `json:"household_id,omitempty"`
+	Name        string `json:"name,omitempty"`
+	UsedBytes   int64  `json:"used_bytes"`
+	LimitBytes  int64  `json:"limit_bytes"` // 0 = ไม่จำกัด
+	Blobs       int64  `json:"blobs"`       // จำนวน blob ไม่ซ้ำ
+}
+
+func (s ScopeUsage) fits(size int64) bool {
+	return s.LimitBytes <= 0 || s.UsedBytes+size <= s.LimitBytes
+}
+
+type Usage struct {
+	User       ScopeUsage   `json:"user"`
+	Households []ScopeUsage `json:"households"`
+}
+
+// Fits = อัปโหลดเพิ่ม size ไบต์แล้วยังไม่เกินทุกขอบเขต
+func (u Usage) Fits(size int64) bool {
+	if !u.User.fits(size) {
+		return false
+	}
+	for _, h := range u.Households {
+		if !h.fits(size) {
+			return false
+		}
+	}
+	return true
+}
+
+// usedBytes: ไฟล์ที่ยังไม่ประมวลผลยังไม่มี hash จึงนับแยกตาม id
+const usedBytes = `
+	SELECT COALESCE(SUM(size), 0), COUNT(*) FROM (
+		SELECT DISTINCT ON (COALESCE(content_sha256, id::text)) size
+		FROM files WHERE owner_id = ANY($1::uuid[])
+		ORDER BY COALESCE(content_sha256, id::text)
+	) t`
+
+// querier = pool หรือ tx (usage ใช้ได้ทั้งนอกและใน withQuota)
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// Usage พื้นที่ที่ใช้ของผู้ใช้และทุกบ้านที่เป็นสมาชิก
+func (r Repo) Usage(ctx context.Context, uid string, q Quota) (Usage, error) {
+	return usage(ctx, r.DB, uid, q)
+}
+
+func usage(ctx context.Context, db querier, uid string, q Quota) (Usage, error) {
+	u := Usage{User: ScopeUsage{LimitBytes: q.UserBytes}, Households: []ScopeUsage{}}
+	if err := db.QueryRow(ctx, usedBytes, []string{uid}).Scan(&u.User.UsedBytes, &u.User.Blobs); err != nil {
+		return u, err
+	}
+
+	rows, err := db.Query(ctx, `
+	SELECT h.id::text, h.name, array_agg(all_m.user_id::text)
+	FROM household_members me
+	JOIN households h ON h.id = me.household_id
+	JOIN household_members all_m ON all_m.household_id = h.id
+	WHERE me.user_id = $1
+	GROUP BY h.id, h.name
+	ORDER BY h.name`, uid)
+	if err != nil {
+		return u, err
+	}
+	type hh struct {
+		id, name string
+		members  []string
+	}
+	var hs []hh
+	for rows.Next() {
+		var h hh
+		if err := rows.Scan(&h.id, &h.name, &h.members); err != nil {
+			rows.Close()
+			return u, err
+		}
+		hs = append(hs, h)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return u, err
+	}
+
+	for _, h := range hs {
+		s := ScopeUsage{HouseholdID: h.id, Name: h.name, LimitBytes: q.HouseholdBytes}
+		if err := db.QueryRow(ctx, usedBytes, h.members).Scan(&s.UsedBytes, &s.Blobs); err != nil {
+			return u, err
+		}
+		u.Households = append(u.Households, s)
+	}
+	return u, nil
+}
+
+// withQuota ตรวจโควตาแล้วเรียก fn (เช่น insert แถว files) ใน transaction ที่ถือ advisory lock ของผู้ใช้และทุกบ้านที่เป็นสมาชิก:
+// อัปโหลดพร้อมกันหลายไฟล์ (หรือสมาชิกหลายคนในบ้านเดียวกัน) จึงผ่านการตรวจแล้วเกินโควตารวมไม่ได้
+// ล็อกเรียงลำดับเดียวกันเสมอ (ผู้ใช้ก่อน แล้วบ้านตาม id) กัน deadlock; ไม่ตั้งโควตา = ไม่ล็อก
+func (r Repo) withQuota(ctx context.Context, uid string, size int64, q Quota, fn func(tx pgx.Tx) error) error {
+	return pgx.BeginFunc(ctx, r.DB, func(tx pgx.Tx) error {
+		if q.UserBytes > 0 || q.HouseholdBytes > 0 {
+			rows, err := tx.Query(ctx, `
+			SELECT household_id::text FROM household_members WHERE user_id = $1 ORDER BY household_id`, uid)
+			if err != nil {
+				return err
+			}
+			keys, err := pgx.CollectRows(rows, pgx.RowTo[string])
+			if err != nil {
+				return err
+			}
+			for i := range keys {
+				keys[i] = "household:" + keys[i]
+			}
+			for _, k := range append([]string{"user:" + uid}, keys...) {
+				if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('files.quota:' || $1))`, k); err != nil {
+					return err
+				}
+			}
+			u, err := usage(ctx, tx, uid, q)
+			if err != nil {
+				return err
+			}
+			if !u.Fits(size) {
+				return ErrQuotaExceeded
+			}
+		}
+		return fn(tx)
+	})
+}
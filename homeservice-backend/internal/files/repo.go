@@ -2,8 +2,10 @@ package files
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -13,27 +15,184 @@ type Repo struct {
 	DB *pgxpool.Pool
 }
 
-func (r Repo) Create(ctx context.Context, f *File) error {
-	const q = `
-	INSERT INTO files (id, owner_id, filename, mimetype, size, storage_url, created_at)
-	VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, now())
-	RETURNING id, created_at`
-	return r.DB.QueryRow(ctx, q, f.OwnerID, f.Filename, f.MIME, f.Size, f.StorageURL).
-		Scan(&f.ID, &f.CreatedAt)
+const fileCols = `id, owner_id, file_name, mime, size, url, COALESCE(storage_key, ''), created_at,
+	processing_status, processing_error, width, height, renditions, COALESCE(content_sha256, '')`
+
+// contentPath = URL ของเนื้อไฟล์ (ผ่าน API ที่ตรวจสิทธิ์)
+func contentPath(id, rendition string) string {
+	u := "/api/v1/files/" + id + "/content"
+	if rendition != "" {
+		u += "?rendition=" + rendition
+	}
+	return u
 }
 
-func (r Repo) Get(ctx context.Context, id string) (*File, error) {
-	const q = `SELECT id, owner_id, filename, mimetype, size, storage_url, created_at FROM files WHERE id=$1`
+// scanFile อ่านตาม fileCols; extra = คอลัมน์เพิ่มท้าย SELECT/RETURNING
+func scanFile(row pgx.Row, extra ...any) (*File, error) {
 	var f File
-	if err := r.DB.QueryRow(ctx, q, id).Scan(&f.ID, &f.OwnerID, &f.Filename, &f.MIME, &f.Size, &f.StorageURL, &f.CreatedAt); err != nil {
-		return nil, ErrNotFound
+	var rend []byte
+	dest := append([]any{&f.ID, &f.OwnerID, &f.Filename, &f.MIME, &f.Size, &f.StorageURL, &f.StorageKey, &f.CreatedAt,
+		&f.Status, &f.Error, &f.Width, &f.Height, &rend, &f.ContentSHA256}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	stored := map[string]storedRendition{}
+	_ = json.Unmarshal(rend, &stored)
+	f.Renditions = make(map[string]Rendition, len(stored))
+	for name, sr := range stored {
+		f.Renditions[name] = Rendition{
+			Key: sr.Key, URL: contentPath(f.ID, name), MIME: sr.MIME,
+			Width: sr.Width, Height: sr.Height, Size: sr.Size,
+		}
 	}
 	return &f, nil
 }
 
-func (r Repo) Delete(ctx context.Context, ownerID, id string) error {
-	const q = `DELETE FROM files WHERE id=$1 AND owner_id=$2`
-	ct, err := r.DB.Exec(ctx, q, id, ownerID)
+// Create บันทึกไฟล์ใหม่ภายใต้โควตา q (เกิน = ErrQuotaExceeded); Status ว่าง = pending (รอ Processor)
+func (r Repo) Create(ctx context.Context, f *File, q Quota) error {
+	return r.withQuota(ctx, f.OwnerID, f.Size, q, func(tx pgx.Tx) error {
+		return insertFile(ctx, tx, f)
+	})
+}
+
+func insertFile(ctx context.Context, tx pgx.Tx, f *File) error {
+	if f.Status == "" {
+		f.Status = StatusPending
+	}
+	q := `
+	INSERT INTO files (id, owner_id, file_name, mime, size, url, storage_key, processing_status, created_at)
+	VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, $6, $7, now())
+	RETURNING ` + fileCols
+	out, err := scanFile(tx.QueryRow(ctx, q, f.OwnerID, f.Filename, f.MIME, f.Size, f.StorageURL, f.StorageKey, f.Status))
+	if err != nil {
+		return err
+	}
+	*f = *out
+	return nil
+}
+
+func (r Repo) Get(ctx context.Context, id string) (*File, error) {
+	f, err := scanFile(r.DB.QueryRow(ctx, `SELECT `+fileCols+` FROM files WHERE id=$1`, id))
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	return f, nil
+}
+
+// ClaimPending จองไฟล์ที่รอประมวลผล (รวมตัวที่ค้าง processing เกิน 10 นาที เช่น worker ตายกลางทาง)
+func (r Repo) ClaimPending(ctx context.Context, limit int) ([]File, error) {
+	rows, err := r.DB.Query(ctx, `
+	UPDATE files SET processing_status = 'processing', processing_attempts = processing_attempts + 1, updated_at = now()
+	WHERE id IN (
+		SELECT id FROM files
+		WHERE processing_status = 'pending'
+		   OR (processing_status = 'processing' AND updated_at < now() - interval '10 minutes')
+		ORDER BY created_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING `+fileCols+`, processing_attempts`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []File
+	for rows.Next() {
+		var attempts int
+		f, err := scanFile(rows, &attempts)
+		if err != nil {
+			return nil, err
+		}
+		f.attempts = attempts
+		out = append(out, *f)
+	}
+	return out, rows.Err()
+}
+
+// blobTx = transaction ที่ถือ advisory lock ของ content hash หนึ่ง:
+// การผูกไฟล์เข้ากับ blob และการลบ blob ที่ไม่มีใครอ้างแล้วจึงไม่ชนกัน
+type blobTx struct {
+	tx  pgx.Tx
+	sum string
+}
+
+func (r Repo) withBlob(ctx context.Context, sum string, fn func(b blobTx) error) error {
+	return pgx.BeginFunc(ctx, r.DB, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('files.blob:' || $1))`, sum); err != nil {
+			return err
+		}
+		return fn(blobTx{tx: tx, sum: sum})
+	})
+}
+
+// shared ไฟล์อื่นที่พร้อมแล้วและใช้ blob นี้อยู่ (nil = ยังไม่มี ต้องเขียน blob เอง)
+func (b blobTx) shared(ctx context.Context, exceptID string) (*File, error) {
+	f, err := scanFile(b.tx.QueryRow(ctx, `
+	SELECT `+fileCols+` FROM files
+	WHERE content_sha256 = $1 AND processing_status = 'ready' AND id <> $2
+	LIMIT 1`, b.sum, exceptID))
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	return f, err
+}
+
+// markReady บันทึกผลประมวลผล: key ของ blob, mime/size ที่ตรวจจากเนื้อไฟล์จริง, ขนาดภาพ, ภาพย่อ
+func (b blobTx) markReady(ctx context.Context, id, key, mime string, size int64, width, height *int, rend map[string]storedRendition) error {
+	if rend == nil {
+		rend = map[string]storedRendition{}
+	}
+	raw, err := json.Marshal(rend)
+	if err != nil {
+		return err
+	}
+	_, err = b.tx.Exec(ctx, `
+	UPDATE files SET processing_status = 'ready', processing_error = NULL, content_sha256 = $2, storage_key = $3,
+	       mime = $4, size = $5, width = $6, height = $7, renditions = $8, updated_at = now()
+	WHERE id = $1`, id, b.sum, key, mime, size, width, height, raw)
+	return err
+}
+
+// DeleteShared ลบแถวไฟล์ที่ผูกกับ blob แล้ว; ถ้าไม่เหลือแถวไหนอ้าง blob นี้ เรียก drop (ลบ object) ขณะยังถือ lock
+func (r Repo) DeleteShared(ctx context.Context, id, sum string, drop func(ctx context.Context) error) error {
+	return r.withBlob(ctx, sum, func(b blobTx) error {
+		ct, err := b.tx.Exec(ctx, `DELETE FROM files WHERE id = $1`, id)
+		if err != nil {
+			return err
+		}
+		if ct.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+		var refs int
+		if err := b.tx.QueryRow(ctx, `SELECT count(*) FROM files WHERE content_sha256 = $1`, sum).Scan(&refs); err != nil {
+			return err
+		}
+		if refs == 0 {
+			// ลบ object ไม่สำเร็จไม่ย้อนการลบแถว: เหลือเป็น orphan ให้งานเก็บกวาดจัดการ
+			_ = drop(ctx)
+		}
+		return nil
+	})
+}
+
+// MarkFailed: final = เลิกลองแล้ว (failed) ไม่งั้นกลับไป pending ให้รอบหน้าลองใหม่
+func (r Repo) MarkFailed(ctx context.Context, id, msg string, final bool) error {
+	status := StatusPending
+	if final {
+		status = StatusFailed
+	}
+	_, err := r.DB.Exec(ctx, `
+	UPDATE files SET processing_status = $2, processing_error = $3, updated_at = now() WHERE id = $1`, id, status, msg)
+	return err
+}
+
+// Delete ลบ metadata ของไฟล์ (ผู้เรียกตรวจสิทธิ์ผ่าน authz ก่อน)
+func (r Repo) Delete(ctx context.Context, id string) error {
+	const q = `DELETE FROM files WHERE id=$1`
+	ct, err := r.DB.Exec(ctx, q, id)
 	if err != nil {
 		return err
 	}
@@ -42,3 +201,32 @@ func (r Repo) Delete(ctx context.Context, ownerID, id string) error {
 	}
 	return nil
 }
+
+// Readers สมาชิกของทุกบ้านที่มีข้อมูลอ้างถึงไฟล์นี้ (นอกจากเจ้าของ) — ใช้เป็น Participants ตอนตรวจสิทธิ์อ่าน
+func (r Repo) Readers(ctx context.Context, id string) ([]string, error) {
+	const q = `
+	SELECT DISTINCT m.user_id::text
+	FROM household_members m
+	WHERE m.household_id IN (
+		SELECT p.household_id FROM purchase_attachments a JOIN purchases p ON p.id = a.purchase_id WHERE a.file_id = $1
+		UNION SELECT p.household_id FROM purchase_message_files mf
+		      JOIN purchase_messages pm ON pm.id = mf.message_id JOIN purchases p ON p.id = pm.purchase_id
+		      WHERE mf.file_id = $1
+		UNION SELECT household_id FROM bills WHERE receipt_file_id = $1
+		UNION SELECT household_id FROM medicine_items WHERE photo_file_id = $1
+	)`
+	rows, err := r.DB.Query(ctx, q, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var uid string
+		if err := rows.Scan(&uid); err != nil {
+			return nil, err
+		}
+		out = append(out, uid)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,560 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/iMookatayou/homeservice-backend/internal/auth"
+	"github.com/iMookatayou/homeservice-backend/internal/httputil"
+	"github.com/iMookatayou/homeservice-backend/internal/imaging"
+	"github.com/iMookatayou/homeservice-backend/internal/storage"
+)
+
+const (
+	maxResumableSize = 1 << 30  // 1GB ต่อไฟล์ (วิดีโอจากมือถือหลายร้อย MB)
+	maxChunkSize     = 16 << 20 // ต่อ PATCH; ก้อนเล็กลงเมื่อเน็ตช้า ไม่ให้ชน timeout 60s ของ request
+	uploadTTL        = 24 * time.Hour
+	tusVersion       = "1.0.0"
+
+	// รวมก้อนทำนอก deadline ของ request (สูงสุด finalizeTimeout); รอผลใน request แค่ finalizeWait
+	// ที่เหลือ client HEAD/GET ดู status จนเป็น done; ค้าง finalizing เกิน finalizeStale = งานตาย ให้สั่งรวมใหม่ได้
+	finalizeTimeout = 30 * time.Minute
+	finalizeWait    = 20 * time.Second
+	finalizeStale   = finalizeTimeout + 5*time.Minute
+)
+
+const (
+	UploadUploading  = "uploading"
+	UploadFinalizing = "finalizing" // ได้ครบแล้ว กำลังรวมก้อนเป็นไฟล์
+	UploadDone       = "done"
+)
+
+var (
+	ErrUploadNotFound = errors.New("upload session not found")
+	ErrOffsetMismatch = errors.New("upload offset mismatch")
+)
+
+// UploadSession การอัปโหลดแบบ resumable หนึ่งไฟล์; Offset = จำนวนไบต์ที่เก็บสำเร็จแล้ว
+type UploadSession struct {
+	ID        string    `json:"id"`
+	OwnerID   string    `json:"owner_id"`
+	Filename  string    `json:"filename"`
+	MIME      string    `json:"mimetype"`
+	Size      int64     `json:"size"`
+	Offset    int64     `json:"offset"`
+	Status    string    `json:"status"`
+	FileID    *string   `json:"file_id,omitempty"` // มีเมื่อ status = done
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type uploadPart struct {
+	Offset int64
+	Size   int64
+	Key    string
+}
+
+const uploadCols = `id, owner_id, file_name, mime, size, received, status, file_id, expires_at, created_at`
+
+func scanUpload(row pgx.Row) (*UploadSession, error) {
+	var s UploadSession
+	if err := row.Scan(&s.ID, &s.OwnerID, &s.Filename, &s.MIME, &s.Size, &s.Offset, &s.Status, &s.FileID, &s.ExpiresAt, &s.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUploadNotFound
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r Repo) CreateUpload(ctx context.Context, s *UploadSession) error {
+	out, err := scanUpload(r.DB.QueryRow(ctx, `
+	INSERT INTO upload_sessions (owner_id, file_name, mime, size, expires_at)
+	VALUES ($1, $2, $3, $4, now() + make_interval(secs => $5))
+	RETURNING `+uploadCols, s.OwnerID, s.Filename, s.MIME, s.Size, uploadTTL.Seconds()))
+	if err != nil {
+		return err
+	}
+	*s = *out
+	return nil
+}
+
+// GetUpload เฉพาะของ ownerID (ของคนอื่น = ไม่พบ)
+func (r Repo) GetUpload(ctx context.Context, id, ownerID string) (*UploadSession, error) {
+	return scanUpload(r.DB.QueryRow(ctx, `
+	SELECT `+uploadCols+` FROM upload_sessions WHERE id = $1 AND owner_id = $2 AND expires_at > now()`, id, ownerID))
+}
+
+// AppendPart บันทึกก้อนที่เขียนลง storage แล้ว: ขยับ offset เฉพาะเมื่อ offset ยังตรงกับที่ client ส่งมา
+// (สองคำขอส่งก้อนเดียวกันพร้อมกัน ผ่านได้คำขอเดียว); ได้ครบ size แล้วเปลี่ยนเป็น finalizing ในคำสั่งเดียวกัน
+func (r Repo) AppendPart(ctx context.Context, id string, p uploadPart) (*UploadSession, error) {
+	var out *UploadSession
+	err := pgx.BeginFunc(ctx, r.DB, func(tx pgx.Tx) error {
+		s, err := scanUpload(tx.QueryRow(ctx, `
+		UPDATE upload_sessions
+		SET received = received + $3,
+		    status = CASE WHEN received + $3 = size THEN 'finalizing' ELSE status END,
+		    expires_at = now() + make_interval(secs => $4), updated_at = now()
+		WHERE id = $1 AND received = $2 AND status = 'uploading' AND received + $3 <= size
+		RETURNING `+uploadCols, id, p.Offset, p.Size, uploadTTL.Seconds()))
+		if errors.Is(err, ErrUploadNotFound) {
+			return ErrOffsetMismatch
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `
+		INSERT INTO upload_parts (session_id, byte_offset, size, storage_key) VALUES ($1, $2, $3, $4)`,
+			id, p.Offset, p.Size, p.Key); err != nil {
+			return err
+		}
+		out = s
+		return nil
+	})
+	return out, err
+}
+
+// ClaimFinalize จองการรวมไฟล์ที่ได้ครบแล้วแต่รวมไม่สำเร็จรอบก่อน หรือที่ค้าง finalizing นานเกิน stale
+// (server ตายกลางทาง); false = มีคนอื่นทำอยู่/ยังไม่ครบ
+func (r Repo) ClaimFinalize(ctx context.Context, id string, stale time.Duration) (bool, error) {
+	ct, err := r.DB.Exec(ctx, `
+	UPDATE upload_sessions SET status = 'finalizing', updated_at = now()
+	WHERE id = $1 AND received = size
+	  AND (status = 'uploading' OR (status = 'finalizing' AND updated_at < now() - make_interval(secs => $2)))`,
+		id, stale.Seconds())
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() == 1, nil
+}
+
+// ReleaseFinalize รวมไม่สำเร็จ: กลับไป uploading ให้ client สั่งรวมใหม่ได้
+func (r Repo) ReleaseFinalize(ctx context.Context, id string) error {
+	_, err := r.DB.Exec(ctx, `
+	UPDATE upload_sessions SET status = 'uploading', updated_at = now() WHERE id = $1 AND status = 'finalizing'`, id)
+	return err
+}
+
+// FinishUpload สร้างแถว files (ภายใต้โควตา q) และผูกกับ session ใน transaction เดียว แล้วลบรายการก้อน (ผู้เรียกลบ object ของก้อนเอง)
+// session ต้องยัง finalizing อยู่ (ไม่ถูกยกเลิก/หมดอายุ/ถูกรวมซ้ำ) ไม่งั้น ErrUploadNotFound และไม่มีแถว files ค้าง
+func (r Repo) FinishUpload(ctx context.Context, id string, f *File, q Quota) error {
+	return r.withQuota(ctx, f.OwnerID, f.Size, q, func(tx pgx.Tx) error {
+		if err := insertFile(ctx, tx, f); err != nil {
+			return err
+		}
+		ct, err := tx.Exec(ctx, `
+		UPDATE upload_sessions SET status = 'done', file_id = $2, updated_at = now()
+		WHERE id = $1 AND status = 'finalizing'`, id, f.ID)
+		if err != nil {
+			return err
+		}
+		if ct.RowsAffected() == 0 {
+			return ErrUploadNotFound
+		}
+		_, err = tx.Exec(ctx, `DELETE FROM upload_parts WHERE session_id = $1`, id)
+		return err
+	})
+}
+
+func (r Repo) UploadParts(ctx context.Context, id string) ([]uploadPart, error) {
+	rows, err := r.DB.Query(ctx, `
+	SELECT byte_offset, size, storage_key FROM upload_parts WHERE session_id = $1 ORDER BY byte_offset`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []uploadPart
+	for rows.Next() {
+		var p uploadPart
+		if err := rows.Scan(&p.Offset, &p.Size, &p.Key); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// DeleteUpload ลบ session (ก้อนถูกลบตาม) คืน key ของก้อนให้ผู้เรียกลบ object
+func (r Repo) DeleteUpload(ctx context.Context, id string) ([]string, error) {
+	parts, err := r.UploadParts(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	ct, err := r.DB.Exec(ctx, `DELETE FROM upload_sessions WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	if ct.RowsAffected() == 0 {
+		return nil, ErrUploadNotFound
+	}
+	keys := make([]string, len(parts))
+	for i, p := range parts {
+		keys[i] = p.Key
+	}
+	return keys, nil
+}
+
+// ExpiredUploads session ที่หมดอายุแล้ว (ทั้งที่ค้างและที่เสร็จแล้ว) ให้ files.gc ลบ
+func (r Repo) ExpiredUploads(ctx context.Context, now time.Time) ([]string, error) {
+	rows, err := r.DB.Query(ctx, `SELECT id::text FROM upload_sessions WHERE expires_at < $1`, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
+
+// partsReader อ่านก้อนต่อกันตามลำดับ offset โดยเปิด object ทีละก้อน
+type partsReader struct {
+	ctx   context.Context
+	st    storage.Service
+	parts []uploadPart
+	cur   io.ReadCloser
+}
+
+func (p *partsReader) Read(b []byte) (int, error) {
+	for {
+		if p.cur == nil {
+			if len(p.parts) == 0 {
+				return 0, io.EOF
+			}
+			rc, _, err := p.st.Open(p.ctx, p.parts[0].Key)
+			if err != nil {
+				return 0, err
+			}
+			p.cur, p.parts = rc, p.parts[1:]
+		}
+		n, err := p.cur.Read(b)
+		if errors.Is(err, io.EOF) {
+			p.cur.Close()
+			p.cur = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (p *partsReader) Close() error {
+	if p.cur != nil {
+		return p.cur.Close()
+	}
+	return nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.n += int64(n)
+	return n, err
+}
+
+func partKey(sessionID string, offset int64) string {
+	var rnd [4]byte
+	_, _ = rand.Read(rnd[:])
+	return fmt.Sprintf("parts/%s/%020d-%x", sessionID, offset, rnd)
+}
+
+func setUploadHeaders(w http.ResponseWriter, s *UploadSession) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Upload-Offset", strconv.FormatInt(s.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(s.Size, 10))
+	w.Header().Set("Upload-Expires", s.ExpiresAt.UTC().Format(http.TimeFormat))
+}
+
+// CreateUpload เริ่มอัปโหลดแบบ resumable {"filename","mimetype","size"} → Location + offset 0
+func (h Handler) CreateUpload(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFrom(r)
+
+	var payload struct {
+		Filename string `json:"filename"`
+		MIME     string `json:"mimetype"`
+		Size     int64  `json:"size"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		httputil.Error(w, http.StatusBadRequest, "BAD_REQUEST", err.Error(), "")
+		return
+	}
+	if payload.Filename == "" || payload.MIME == "" || payload.Size <= 0 {
+		httputil.Error(w, http.StatusBadRequest, "BAD_REQUEST", "missing filename/mimetype/size", "")
+		return
+	}
+	if !allowMIME(payload.MIME) {
+		httputil.Error(w, http.StatusBadRequest, "BAD_REQUEST", "invalid mimetype", "")
+		return
+	}
+	if payload.Size > maxResumableSize {
+		httputil.Error(w, http.StatusRequestEntityTooLarge, "TOO_LARGE", "file exceeds resumable upload limit", "")
+		return
+	}
+	if !h.checkQuota(w, r, uid, payload.Size) {
+		return
+	}
+	s := &UploadSession{OwnerID: uid, Filename: payload.Filename, MIME: payload.MIME, Size: payload.Size}
+	if err := h.Repo.CreateUpload(r.Context(), s); err != nil {
+		httputil.Error(w, http.StatusInternalServerError, "INTERNAL", err.Error(), "")
+		return
+	}
+	setUploadHeaders(w, s)
+	w.Header().Set("Location", "/api/v1/uploads/resumable/"+s.ID)
+	httputil.Created(w, s)
+}
+
+func (h Handler) loadUpload(w http.ResponseWriter, r *http.Request) (*UploadSession, bool) {
+	uid, _ := auth.UserIDFrom(r)
+	s, err := h.Repo.GetUpload(r.Context(), chi.URLParam(r, "id"), uid)
+	if errors.Is(err, ErrUploadNotFound) {
+		httputil.Error(w, http.StatusNotFound, "NOT_FOUND", "upload session not found or expired", "")
+		return nil, false
+	}
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, "INTERNAL", err.Error(), "")
+		return nil, false
+	}
+	return s, true
+}
+
+// UploadStatus (HEAD/GET) ให้ client รู้ว่าต้องส่งต่อจาก offset ไหนหลังเน็ตหลุด
+func (h Handler) UploadStatus(w http.ResponseWriter, r *http.Request) {
+	s, ok := h.loadUpload(w, r)
+	if !ok {
+		return
+	}
+	setUploadHeaders(w, s)
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	httputil.OK(w, s)
+}
+
+// PatchUpload รับก้อนถัดไป: header Upload-Offset ต้องตรงกับ offset ปัจจุบัน, ต้องมี Content-Length
+// ก้อนสุดท้ายรวมเป็นไฟล์แล้วตอบ 201 พร้อมไฟล์ (status pending รอ files.process); ก้อนอื่นตอบ 204
+// ส่ง PATCH ว่างเมื่อ offset = size = สั่งรวมใหม่หลังรวมไม่สำเร็จ
+func (h Handler) PatchUpload(w http.ResponseWriter, r *http.Request) {
+	s, ok := h.loadUpload(w, r)
+	if !ok {
+		return
+	}
+	setUploadHeaders(w, s)
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, "BAD_REQUEST", "missing or invalid Upload-Offset header", "")
+		return
+	}
+	switch {
+	case s.Status == UploadDone:
+		httputil.Error(w, http.StatusConflict, "UPLOAD_COMPLETE", "upload already finalized", "")
+		return
+	case s.Status == UploadFinalizing && (r.ContentLength != 0 || offset != s.Size):
+		w.Header().Set("Retry-After", "2")
+		httputil.Error(w, http.StatusConflict, "FINALIZING", "upload is being finalized", "")
+		return
+	case offset != s.Offset:
+		httputil.Error(w, http.StatusConflict, "OFFSET_MISMATCH", "Upload-Offset does not match; resume from the current offset", "")
+		return
+	}
+
+	n := r.ContentLength
+	if n == 0 && s.Offset == s.Size {
+		claimed, err := h.Repo.ClaimFinalize(r.Context(), s.ID, finalizeStale)
+		if err != nil {
+			httputil.Error(w, http.StatusInternalServerError, "INTERNAL", err.Error(), "")
+			return
+		}
+		if !claimed {
+			w.Header().Set("Retry-After", "2")
+			httputil.Error(w, http.StatusConflict, "FINALIZING", "upload is being finalized", "")
+			return
+		}
+		h.finalize(w, r, s)
+		return
+	}
+	if n <= 0 {
+		httputil.Error(w, http.StatusLengthRequired, "LENGTH_REQUIRED", "chunk must have Content-Length", "")
+		return
+	}
+	if n > maxChunkSize || s.Offset+n > s.Size {
+		httputil.Error(w, http.StatusRequestEntityTooLarge, "CHUNK_TOO_LARGE", "chunk exceeds chunk limit or declared size", "")
+		return
+	}
+
+	var body io.Reader = io.LimitReader(r.Body, n)
+	if s.Offset == 0 {
+		// ก้อนแรก: ตรวจชนิดจาก magic bytes ก่อนรับทั้งไฟล์ (Processor ตรวจซ้ำทั้งไฟล์อีกรอบ)
+		head := make([]byte, min(n, 3072))
+		if _, err := io.ReadFull(body, head); err != nil {
+			httputil.Error(w, http.StatusBadRequest, "INCOMPLETE_CHUNK", err.Error(), "")
+			return
+		}
+		if !allowMIME(imaging.Sniff(head)) {
+			httputil.Error(w, http.StatusBadRequest, "BAD_REQUEST", "unsupported file type", "")
+			return
+		}
+		body = io.MultiReader(bytes.NewReader(head), body)
+	}
+
+	// เน็ตหลุดกลางก้อน: ไม่บันทึกก้อนนี้เลย client HEAD แล้วส่งใหม่จาก offset เดิม
+	key := partKey(s.ID, s.Offset)
+	cr := &countingReader{r: body}
+	if err := h.Storage.Put(r.Context(), key, cr, n, "application/octet-stream"); err != nil || cr.n != n {
+		_ = h.Storage.Delete(context.WithoutCancel(r.Context()), key)
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		httputil.Error(w, http.StatusBadRequest, "INCOMPLETE_CHUNK", err.Error(), "")
+		return
+	}
+	next, err := h.Repo.AppendPart(r.Context(), s.ID, uploadPart{Offset: s.Offset, Size: n, Key: key})
+	if err != nil {
+		_ = h.Storage.Delete(context.WithoutCancel(r.Context()), key)
+		if errors.Is(err, ErrOffsetMismatch) {
+			httputil.Error(w, http.StatusConflict, "OFFSET_MISMATCH", "another request advanced this upload; check the current offset", "")
+			return
+		}
+		httputil.Error(w, http.StatusInternalServerError, "INTERNAL", err.Error(), "")
+		return
+	}
+	setUploadHeaders(w, next)
+	if next.Status == UploadFinalizing {
+		h.finalize(w, r, next)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type finalizeResult struct {
+	file   *File
+	status int
+	code   string
+	err    error
+}
+
+// finalize รวมก้อนเป็นไฟล์ใน goroutine แยก (ผู้เรียกจอง status finalizing ไว้แล้ว): เสร็จภายใน finalizeWait ตอบ 201 พร้อมไฟล์
+// ไม่งั้นตอบ 202 พร้อม session ให้ client HEAD/GET ดูต่อ — งานรวมไม่ผูกกับ timeout 60s ของ request
+func (h Handler) finalize(w http.ResponseWriter, r *http.Request, s *UploadSession) {
+	done := make(chan finalizeResult, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), finalizeTimeout)
+		defer cancel()
+		done <- h.assemble(ctx, s)
+	}()
+
+	wait := time.NewTimer(finalizeWait)
+	defer wait.Stop()
+	select {
+	case res := <-done:
+		if res.err != nil {
+			httputil.Error(w, res.status, res.code, res.err.Error(), "")
+			return
+		}
+		httputil.Created(w, res.file)
+	case <-wait.C:
+		w.Header().Set("Retry-After", "5")
+		httputil.JSON(w, http.StatusAccepted, httputil.Envelope{Status: "success", Data: s})
+	case <-r.Context().Done():
+		// client หลุด/หมดเวลา request: งานรวมยังทำต่อจนจบ
+	}
+}
+
+// assemble อ่านก้อนต่อกันเป็น object เดียวผ่าน Storage.Save แล้วสร้างแถว files + ปิด session
+// ล้มเหลวตรงไหนก็คืน session เป็น uploading (สั่งรวมใหม่ได้) และลบ object ที่เพิ่งสร้าง
+func (h Handler) assemble(ctx context.Context, s *UploadSession) finalizeResult {
+	fail := func(status int, code string, err error) finalizeResult {
+		_ = h.Repo.ReleaseFinalize(context.WithoutCancel(ctx), s.ID)
+		return finalizeResult{status: status, code: code, err: err}
+	}
+	parts, err := h.Repo.UploadParts(ctx, s.ID)
+	if err != nil {
+		return fail(http.StatusInternalServerError, "INTERNAL", err)
+	}
+	var total int64
+	for _, p := range parts {
+		if p.Offset != total {
+			return fail(http.StatusInternalServerError, "INTERNAL", fmt.Errorf("part gap at offset %d", total))
+		}
+		total += p.Size
+	}
+	if total != s.Size {
+		return fail(http.StatusInternalServerError, "INTERNAL", fmt.Errorf("parts cover %d of %d bytes", total, s.Size))
+	}
+
+	pr := &partsReader{ctx: ctx, st: h.Storage, parts: parts}
+	put, err := h.Storage.Save(ctx, s.OwnerID, pr, s.Filename, s.MIME, s.Size)
+	pr.Close()
+	if err != nil {
+		return fail(http.StatusBadGateway, "UPLOAD_FAILED", err)
+	}
+	rec := &File{
+		OwnerID:    s.OwnerID,
+		Filename:   put.Filename,
+		MIME:       s.MIME,
+		Size:       put.Size,
+		StorageURL: put.URL,
+		StorageKey: put.Key,
+	}
+	if err := h.Repo.FinishUpload(ctx, s.ID, rec, h.Quota); err != nil {
+		_ = h.Storage.Delete(context.WithoutCancel(ctx), put.Key)
+		if errors.Is(err, ErrUploadNotFound) {
+			// ถูกยกเลิก/หมดอายุ/มีงานอื่นรวมเสร็จไปก่อนระหว่างนี้
+			return finalizeResult{status: http.StatusConflict, code: "FINALIZE_LOST", err: errors.New("upload session changed while finalizing")}
+		}
+		if errors.Is(err, ErrQuotaExceeded) {
+			// ไฟล์อื่นเข้ามาระหว่างอัปโหลด: ลบไฟล์อื่นแล้วสั่งรวมใหม่ได้
+			return fail(http.StatusRequestEntityTooLarge, "QUOTA_EXCEEDED", err)
+		}
+		return fail(http.StatusInternalServerError, "INTERNAL", err)
+	}
+	for _, p := range parts {
+		_ = h.Storage.Delete(ctx, p.Key)
+	}
+	h.kick(ctx)
+	return finalizeResult{file: rec, status: http.StatusCreated}
+}
+
+// AbortUpload ยกเลิกและลบก้อนที่ส่งมาแล้ว
+func (h Handler) AbortUpload(w http.ResponseWriter, r *http.Request) {
+	s, ok := h.loadUpload(w, r)
+	if !ok {
+		return
+	}
+	if s.Status == UploadFinalizing {
+		httputil.Error(w, http.StatusConflict, "FINALIZING", "upload is being finalized", "")
+		return
+	}
+	keys, err := h.Repo.DeleteUpload(r.Context(), s.ID)
+	if err != nil {
+		httputil.Error(w, http.StatusNotFound, "NOT_FOUND", "upload session not found or expired", "")
+		return
+	}
+	for _, k := range keys {
+		_ = h.Storage.Delete(r.Context(), k)
+	}
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.WriteHeader(http.StatusNoContent)
+}
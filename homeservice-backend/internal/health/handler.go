@@ -1,14 +1,109 @@
 package health
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// draining = กำลังปิดระบบ: /readyz ตอบ 503 ให้ load balancer เลิกส่ง traffic มา
+var draining atomic.Bool
+
+// SetDraining เรียกตอนได้ SIGTERM ก่อนเริ่ม srv.Shutdown
+func SetDraining(v bool) { draining.Store(v) }
+
+// Live = process ยังตอบได้ (ไม่แตะ dependency ใด ๆ ไม่งั้น orchestrator จะ restart ทั้งที่แค่ DB ล่ม)
 func Live(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("ok"))
 }
-func Ready(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("ready"))
+
+const (
+	StatusOK       = "ok"
+	StatusFail     = "fail"
+	StatusDraining = "draining"
+)
+
+// Check ตรวจ dependency หนึ่งตัว; Critical = ล้มแล้ว instance ไม่พร้อมรับ traffic
+// Run คืน detail เพิ่มเติม (เช่นเวลา run ล่าสุด) ได้; error = component นั้น fail
+type Check struct {
+	Name     string
+	Critical bool
+	Run      func(ctx context.Context) (detail map[string]any, err error)
+}
+
+type Component struct {
+	Status    string         `json:"status"`
+	Critical  bool           `json:"critical"`
+	LatencyMs float64        `json:"latency_ms"`
+	Error     string         `json:"error,omitempty"`
+	Detail    map[string]any `json:"detail,omitempty"`
+}
+
+type Report struct {
+	Status     string               `json:"status"`
+	Components map[string]Component `json:"components"`
+}
+
+// Readiness รันทุก Check พร้อมกันภายใน Timeout (ค่าเริ่มต้น 2s)
+type Readiness struct {
+	Checks  []Check
+	Timeout time.Duration
+}
+
+func (rd Readiness) Run(ctx context.Context) Report {
+	timeout := rd.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	rep := Report{Status: StatusOK, Components: make(map[string]Component, len(rd.Checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range rd.Checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			detail, err := c.Run(ctx)
+			comp := Component{
+				Status:    StatusOK,
+				Critical:  c.Critical,
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+				Detail:    detail,
+			}
+			if err != nil {
+				comp.Status, comp.Error = StatusFail, err.Error()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			rep.Components[c.Name] = comp
+			if err != nil && c.Critical {
+				rep.Status = StatusFail
+			}
+		}()
+	}
+	wg.Wait()
+	if draining.Load() {
+		rep.Status = StatusDraining
+	}
+	return rep
+}
+
+// Ready ตอบ 200 เมื่อ component critical ผ่านทั้งหมด, 503 เมื่อมีตัว fail หรือกำลังปิดระบบ
+func (rd Readiness) Ready(w http.ResponseWriter, r *http.Request) {
+	rep := rd.Run(r.Context())
+	code := http.StatusOK
+	if rep.Status != StatusOK {
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(rep)
 }
@@ -0,0 +1,19 @@
+package household
+
+import "errors"
+
+var (
+	ErrNotFound = errors.New("household not found")
+
+	// ผู้ใช้ไม่ได้อยู่ในบ้านนี้
+	ErrNotMember = errors.New("not a household member")
+
+	// บทบาทไม่พอ เช่น member พยายามสร้าง invite
+	ErrForbidden = errors.New("forbidden")
+
+	// โค้ดเชิญหมดอายุ / ใช้ครบ / ถูกยกเลิก / ไม่มีอยู่จริง
+	ErrInviteInvalid = errors.New("invite invalid or expired")
+
+	// ห้ามเอา owner คนสุดท้ายออกจากบ้าน
+	ErrLastOwner = errors.New("household must keep at least one owner")
+)
@@ -0,0 +1,261 @@
+package household
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/iMookatayou/homeservice-backend/internal/auth"
+	"github.com/iMookatayou/homeservice-backend/internal/authz"
+	"github.com/iMookatayou/homeservice-backend/internal/httpx"
+)
+
+type Handler struct{ Repo Repo }
+
+// RegisterRoutes — ใช้ใต้ RequireAuth (ไม่ต้องมี household ใน context เพราะเป็นที่สร้าง/เข้าร่วมบ้าน)
+func (h Handler) RegisterRoutes(r chi.Router) {
+	r.Route("/households", func(r chi.Router) {
+		r.Get("/", h.List)
+		r.Post("/", h.Create)
+		r.Post("/join", h.Join) // body: {code}
+
+		r.Route("/{id}", func(r chi.Router) {
+			r.Get("/", h.Get)
+			r.Put("/members/{userID}", h.SetMemberRole) // body: {role}
+			r.Delete("/members/{userID}", h.RemoveMember)
+
+			r.Get("/invites", h.ListInvites)
+			r.Post("/invites", h.CreateInvite)
+			r.Delete("/invites/{code}", h.RevokeInvite)
+		})
+	})
+}
+
+func writeErr(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		httpx.WriteJSONError(w, http.StatusNotFound, err.Error(), nil)
+	case errors.Is(err, ErrNotMember), errors.Is(err, ErrForbidden):
+		httpx.WriteJSONError(w, http.StatusForbidden, err.Error(), nil)
+	case errors.Is(err, ErrInviteInvalid):
+		httpx.WriteJSONError(w, http.StatusGone, err.Error(), nil)
+	case errors.Is(err, ErrLastOwner):
+		httpx.WriteJSONError(w, http.StatusConflict, err.Error(), nil)
+	default:
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "internal error", nil)
+	}
+}
+
+// authorize ประกอบ subject ของผู้เรียกในบ้าน {id} (route นี้ไม่ได้อยู่ใต้ RequireHousehold) แล้วตรวจสิทธิ์
+// id ใน URL ที่ไม่ใช่ UUID ถือว่าไม่พบ (ไม่งั้น Postgres cast error กลายเป็น 500)
+func (h Handler) authorize(r *http.Request, a authz.Action, res authz.Resource) (authz.Subject, error) {
+	sub := authz.SubjectFrom(r)
+	if _, err := uuid.Parse(res.HouseholdID); err != nil {
+		return sub, ErrNotFound
+	}
+	if res.Kind == authz.KindMember {
+		if _, err := uuid.Parse(res.OwnerID); err != nil {
+			return sub, ErrNotFound
+		}
+	}
+	role, err := h.Repo.MemberRole(r.Context(), res.HouseholdID, sub.UserID)
+	if err != nil {
+		return sub, err
+	}
+	sub.HouseholdID, sub.HouseholdRole = res.HouseholdID, role
+	if !authz.Can(r.Context(), sub, a, res) {
+		return sub, ErrForbidden
+	}
+	return sub, nil
+}
+
+func householdRes(id string) authz.Resource {
+	return authz.Resource{Kind: authz.KindHousehold, HouseholdID: id}
+}
+
+func memberRes(id, userID string) authz.Resource {
+	return authz.Resource{Kind: authz.KindMember, HouseholdID: id, OwnerID: userID}
+}
+
+func (h Handler) List(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFrom(r)
+	out, err := h.Repo.ListForUser(r.Context(), uid)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	httpx.JSON(w, http.StatusOK, out)
+}
+
+func (h Handler) Create(w http.ResponseWriter, r *http.Request) {
+	var req CreateReq
+	if err := httpx.BindJSON(r, &req); err != nil {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "validation failed", httpx.ValidationErrors(err))
+		return
+	}
+	uid, _ := auth.UserIDFrom(r)
+	hh, err := h.Repo.Create(r.Context(), uid, req.Name)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	httpx.JSON(w, http.StatusCreated, hh)
+}
+
+func (h Handler) Get(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	sub, err := h.authorize(r, authz.ActionRead, householdRes(id))
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	hh, err := h.Repo.Get(r.Context(), id)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	hh.Role = sub.HouseholdRole
+	members, err := h.Repo.Members(r.Context(), id)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	httpx.JSON(w, http.StatusOK, Detail{Household: *hh, Members: members})
+}
+
+// RemoveMember: owner/admin เอาคนอื่นออกได้, สมาชิกทุกคนออกจากบ้านเองได้
+func (h Handler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	target := chi.URLParam(r, "userID")
+	if _, err := h.authorize(r, authz.ActionDelete, memberRes(id, target)); err != nil {
+		writeErr(w, err)
+		return
+	}
+	if err := h.Repo.RemoveMember(r.Context(), id, target); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetMemberRole: owner เปลี่ยนบทบาทสมาชิก (กันไม่ให้บ้านไม่มี owner เหลือ)
+func (h Handler) SetMemberRole(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	target := chi.URLParam(r, "userID")
+	var req SetRoleReq
+	if err := httpx.BindJSON(r, &req); err != nil {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "validation failed", httpx.ValidationErrors(err))
+		return
+	}
+	if _, err := h.authorize(r, authz.ActionUpdate, memberRes(id, target)); err != nil {
+		writeErr(w, err)
+		return
+	}
+	if err := h.Repo.SetMemberRole(r.Context(), id, target, req.Role); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ---------- Invites ----------
+
+func newInviteCode() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (h Handler) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := h.authorize(r, authz.ActionManage, householdRes(id)); err != nil {
+		writeErr(w, err)
+		return
+	}
+	var req CreateInviteReq
+	if err := httpx.BindJSON(r, &req); err != nil {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "validation failed", httpx.ValidationErrors(err))
+		return
+	}
+	code, err := newInviteCode()
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	uid, _ := auth.UserIDFrom(r)
+	in := &Invite{
+		Code:        code,
+		HouseholdID: id,
+		Role:        req.Role,
+		CreatedBy:   uid,
+		MaxUses:     req.MaxUses,
+	}
+	if in.Role == "" {
+		in.Role = RoleMember
+	}
+	// ค่าเริ่มต้น: หมดอายุใน 7 วัน
+	hours := 7 * 24
+	if req.ExpiresInHours != nil {
+		hours = *req.ExpiresInHours
+	}
+	exp := time.Now().Add(time.Duration(hours) * time.Hour)
+	in.ExpiresAt = &exp
+
+	if err := h.Repo.CreateInvite(r.Context(), in); err != nil {
+		writeErr(w, err)
+		return
+	}
+	httpx.JSON(w, http.StatusCreated, map[string]any{
+		"invite": in,
+		// ลิงก์ให้ฝั่ง app เปิดหน้าเข้าร่วมบ้าน แล้ว POST /households/join {code}
+		"link": "/join/" + in.Code,
+	})
+}
+
+func (h Handler) ListInvites(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := h.authorize(r, authz.ActionManage, householdRes(id)); err != nil {
+		writeErr(w, err)
+		return
+	}
+	out, err := h.Repo.ListInvites(r.Context(), id)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	httpx.JSON(w, http.StatusOK, out)
+}
+
+func (h Handler) RevokeInvite(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := h.authorize(r, authz.ActionManage, householdRes(id)); err != nil {
+		writeErr(w, err)
+		return
+	}
+	if err := h.Repo.RevokeInvite(r.Context(), id, chi.URLParam(r, "code")); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h Handler) Join(w http.ResponseWriter, r *http.Request) {
+	var req JoinReq
+	if err := httpx.BindJSON(r, &req); err != nil {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "validation failed", httpx.ValidationErrors(err))
+		return
+	}
+	uid, _ := auth.UserIDFrom(r)
+	hh, err := h.Repo.Join(r.Context(), req.Code, uid)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	httpx.JSON(w, http.StatusOK, hh)
+}
@@ -0,0 +1,66 @@
+package household
+
+import "time"
+
+// บทบาทในบ้าน
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+type Household struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedBy *string   `json:"created_by,omitempty"`
+	Role      string    `json:"role,omitempty"` // บทบาทของผู้เรียก (ตอน list ของฉัน)
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Member struct {
+	HouseholdID string    `json:"household_id"`
+	UserID      string    `json:"user_id"`
+	Name        string    `json:"name"`
+	Email       string    `json:"email"`
+	Role        string    `json:"role"`
+	JoinedAt    time.Time `json:"joined_at"`
+}
+
+// Invite = โค้ดเชิญเข้าบ้าน (ส่งเป็นโค้ดหรือลิงก์ก็ได้)
+type Invite struct {
+	Code        string     `json:"code"`
+	HouseholdID string     `json:"household_id"`
+	Role        string     `json:"role"`
+	CreatedBy   string     `json:"created_by"`
+	MaxUses     *int       `json:"max_uses,omitempty"` // nil = ไม่จำกัด
+	Uses        int        `json:"uses"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Detail = บ้าน + สมาชิก
+type Detail struct {
+	Household
+	Members []Member `json:"members"`
+}
+
+// payloads
+type CreateReq struct {
+	Name string `json:"name" validate:"required,min=1,max=120"`
+}
+
+type CreateInviteReq struct {
+	Role           string `json:"role" validate:"omitempty,oneof=admin member"`
+	MaxUses        *int   `json:"max_uses,omitempty" validate:"omitempty,min=1"`
+	ExpiresInHours *int   `json:"expires_in_hours,omitempty" validate:"omitempty,min=1,max=720"`
+}
+
+type SetRoleReq struct {
+	Role string `json:"role" validate:"required,oneof=owner admin member"`
+}
+
+type JoinReq struct {
+	Code string `json:"code" validate:"required"`
+}
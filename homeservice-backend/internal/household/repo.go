@@ -0,0 +1,352 @@
+package household
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repo struct {
+	DB *pgxpool.Pool
+}
+
+// Create สร้างบ้านใหม่ และให้ผู้สร้างเป็น owner (ใน transaction เดียว)
+func (r Repo) Create(ctx context.Context, userID, name string) (*Household, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var h Household
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO households (name, created_by)
+		VALUES ($1, $2)
+		RETURNING id, name, created_by, created_at, updated_at
+	`, name, userID).Scan(&h.ID, &h.Name, &h.CreatedBy, &h.CreatedAt, &h.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO household_members (household_id, user_id, role)
+		VALUES ($1, $2, $3)
+	`, h.ID, userID, RoleOwner); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	h.Role = RoleOwner
+	return &h, nil
+}
+
+// ListForUser บ้านทั้งหมดที่ผู้ใช้เป็นสมาชิก (เรียงตามวันที่เข้าร่วม)
+func (r Repo) ListForUser(ctx context.Context, userID string) ([]Household, error) {
+	rows, err := r.DB.Query(ctx, `
+		SELECT h.id, h.name, h.created_by, m.role, h.created_at, h.updated_at
+		FROM households h
+		JOIN household_members m ON m.household_id = h.id
+		WHERE m.user_id = $1
+		ORDER BY m.joined_at, h.created_at
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Household{}
+	for rows.Next() {
+		var h Household
+		if err := rows.Scan(&h.ID, &h.Name, &h.CreatedBy, &h.Role, &h.CreatedAt, &h.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
+
+func (r Repo) Get(ctx context.Context, id string) (*Household, error) {
+	var h Household
+	err := r.DB.QueryRow(ctx, `
+		SELECT id, name, created_by, created_at, updated_at
+		FROM households WHERE id=$1
+	`, id).Scan(&h.ID, &h.Name, &h.CreatedBy, &h.CreatedAt, &h.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &h, nil
+}
+
+func (r Repo) Members(ctx context.Context, householdID string) ([]Member, error) {
+	rows, err := r.DB.Query(ctx, `
+		SELECT m.household_id, m.user_id, u.name, u.email, m.role, m.joined_at
+		FROM household_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.household_id = $1
+		ORDER BY m.joined_at
+	`, householdID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Member{}
+	for rows.Next() {
+		var m Member
+		if err := rows.Scan(&m.HouseholdID, &m.UserID, &m.Name, &m.Email, &m.Role, &m.JoinedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// MemberRole implements auth.Membership
+func (r Repo) MemberRole(ctx context.Context, householdID, userID string) (string, error) {
+	var role string
+	err := r.DB.QueryRow(ctx, `
+		SELECT role FROM household_members WHERE household_id=$1 AND user_id=$2
+	`, householdID, userID).Scan(&role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrNotMember
+		}
+		return "", err
+	}
+	return role, nil
+}
+
+// DefaultHousehold implements auth.Membership — บ้านแรกที่ผู้ใช้เข้าร่วม
+func (r Repo) DefaultHousehold(ctx context.Context, userID string) (string, error) {
+	var id string
+	err := r.DB.QueryRow(ctx, `
+		SELECT household_id FROM household_members
+		WHERE user_id=$1
+		ORDER BY joined_at
+		LIMIT 1
+	`, userID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrNotMember
+		}
+		return "", err
+	}
+	return id, nil
+}
+
+// lockHousehold ล็อกแถวบ้านไว้จนจบ tx ให้การนับ owner กับการลบ/ลดบทบาทเป็นลำดับเดียวกัน
+// (ไม่งั้น owner สองคนลดกันเองพร้อมกันจะเห็น owners=2 ทั้งคู่ แล้วบ้านไม่เหลือ owner)
+func lockHousehold(ctx context.Context, tx pgx.Tx, householdID string) error {
+	var one int
+	err := tx.QueryRow(ctx, `SELECT 1 FROM households WHERE id=$1 FOR UPDATE`, householdID).Scan(&one)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// RemoveMember ลบสมาชิกออก (กัน owner คนสุดท้าย)
+func (r Repo) RemoveMember(ctx context.Context, householdID, userID string) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := lockHousehold(ctx, tx, householdID); err != nil {
+		return err
+	}
+	var role string
+	if err := tx.QueryRow(ctx, `
+		SELECT role FROM household_members
+		WHERE household_id=$1 AND user_id=$2
+		FOR UPDATE
+	`, householdID, userID).Scan(&role); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotMember
+		}
+		return err
+	}
+	if role == RoleOwner {
+		var owners int
+		if err := tx.QueryRow(ctx, `
+			SELECT count(*) FROM household_members WHERE household_id=$1 AND role=$2
+		`, householdID, RoleOwner).Scan(&owners); err != nil {
+			return err
+		}
+		if owners <= 1 {
+			return ErrLastOwner
+		}
+	}
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM household_members WHERE household_id=$1 AND user_id=$2
+	`, householdID, userID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// SetMemberRole เปลี่ยนบทบาทสมาชิก (กันลด owner คนสุดท้าย)
+func (r Repo) SetMemberRole(ctx context.Context, householdID, userID, role string) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := lockHousehold(ctx, tx, householdID); err != nil {
+		return err
+	}
+	var cur string
+	if err := tx.QueryRow(ctx, `
+		SELECT role FROM household_members
+		WHERE household_id=$1 AND user_id=$2
+		FOR UPDATE
+	`, householdID, userID).Scan(&cur); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotMember
+		}
+		return err
+	}
+	if cur == RoleOwner && role != RoleOwner {
+		var owners int
+		if err := tx.QueryRow(ctx, `
+			SELECT count(*) FROM household_members WHERE household_id=$1 AND role=$2
+		`, householdID, RoleOwner).Scan(&owners); err != nil {
+			return err
+		}
+		if owners <= 1 {
+			return ErrLastOwner
+		}
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE household_members SET role=$3 WHERE household_id=$1 AND user_id=$2
+	`, householdID, userID, role); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ---------- Invites ----------
+
+const inviteCols = `code, household_id, role, created_by, max_uses, uses, expires_at, revoked_at, created_at`
+
+func scanInvite(row pgx.Row) (*Invite, error) {
+	var in Invite
+	if err := row.Scan(&in.Code, &in.HouseholdID, &in.Role, &in.CreatedBy, &in.MaxUses,
+		&in.Uses, &in.ExpiresAt, &in.RevokedAt, &in.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &in, nil
+}
+
+func (r Repo) CreateInvite(ctx context.Context, in *Invite) error {
+	got, err := scanInvite(r.DB.QueryRow(ctx, `
+		INSERT INTO household_invites (code, household_id, role, created_by, max_uses, expires_at)
+		VALUES ($1,$2,$3,$4,$5,$6)
+		RETURNING `+inviteCols,
+		in.Code, in.HouseholdID, in.Role, in.CreatedBy, in.MaxUses, in.ExpiresAt))
+	if err != nil {
+		return err
+	}
+	*in = *got
+	return nil
+}
+
+func (r Repo) ListInvites(ctx context.Context, householdID string) ([]Invite, error) {
+	rows, err := r.DB.Query(ctx, `
+		SELECT `+inviteCols+`
+		FROM household_invites
+		WHERE household_id=$1 AND revoked_at IS NULL
+		ORDER BY created_at DESC
+	`, householdID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Invite{}
+	for rows.Next() {
+		in, err := scanInvite(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *in)
+	}
+	return out, rows.Err()
+}
+
+func (r Repo) RevokeInvite(ctx context.Context, householdID, code string) error {
+	ct, err := r.DB.Exec(ctx, `
+		UPDATE household_invites SET revoked_at=now()
+		WHERE household_id=$1 AND code=$2 AND revoked_at IS NULL
+	`, householdID, code)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Join ใช้โค้ดเชิญเข้าบ้าน — ตรวจ/นับการใช้งานใน transaction เดียว กันใช้เกิน max_uses
+func (r Repo) Join(ctx context.Context, code, userID string) (*Household, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	in, err := scanInvite(tx.QueryRow(ctx, `
+		SELECT `+inviteCols+`
+		FROM household_invites
+		WHERE code=$1
+		FOR UPDATE
+	`, code))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInviteInvalid
+		}
+		return nil, err
+	}
+
+	expired := in.ExpiresAt != nil && !in.ExpiresAt.After(time.Now())
+	if expired || in.RevokedAt != nil || (in.MaxUses != nil && in.Uses >= *in.MaxUses) {
+		return nil, ErrInviteInvalid
+	}
+
+	ct, err := tx.Exec(ctx, `
+		INSERT INTO household_members (household_id, user_id, role)
+		VALUES ($1,$2,$3)
+		ON CONFLICT (household_id, user_id) DO NOTHING
+	`, in.HouseholdID, userID, in.Role)
+	if err != nil {
+		return nil, err
+	}
+	// นับเฉพาะการเข้าร่วมใหม่จริง ๆ (สมาชิกเดิมกดซ้ำไม่เสียโควตา)
+	if ct.RowsAffected() > 0 {
+		if _, err := tx.Exec(ctx, `UPDATE household_invites SET uses=uses+1 WHERE code=$1`, code); err != nil {
+			return nil, err
+		}
+	}
+
+	var h Household
+	if err := tx.QueryRow(ctx, `
+		SELECT h.id, h.name, h.created_by, m.role, h.created_at, h.updated_at
+		FROM households h
+		JOIN household_members m ON m.household_id=h.id AND m.user_id=$2
+		WHERE h.id=$1
+	`, in.HouseholdID, userID).Scan(&h.ID, &h.Name, &h.CreatedBy, &h.Role, &h.CreatedAt, &h.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
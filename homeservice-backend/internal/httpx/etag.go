@@ -0,0 +1,32 @@
+package httpx
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var ErrBadIfMatch = errors.New("invalid If-Match")
+
+// SetETag: ETag = version ของแถว (เพิ่มทุกครั้งที่แถวถูกแก้)
+func SetETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", `"`+strconv.Itoa(version)+`"`)
+}
+
+// IfMatch อ่าน version จาก header If-Match; 0 = ไม่ได้ส่งมา (หรือ "*") คือไม่ตรวจ
+func IfMatch(r *http.Request) (int, error) {
+	v := strings.TrimSpace(r.Header.Get("If-Match"))
+	if v == "" || v == "*" {
+		return 0, nil
+	}
+	v = strings.TrimPrefix(v, "W/")
+	if len(v) < 2 || v[0] != '"' || v[len(v)-1] != '"' {
+		return 0, ErrBadIfMatch
+	}
+	n, err := strconv.Atoi(v[1 : len(v)-1])
+	if err != nil || n <= 0 {
+		return 0, ErrBadIfMatch
+	}
+	return n, nil
+}
@@ -53,7 +53,8 @@ func CommonMiddlewares(allowOrigin string) []func(http.Handler) http.Handler {
 		middleware.Recoverer,
 		middleware.Timeout(60 * time.Second),
 
-		middleware.AllowContentType("application/json", "multipart/form-data"),
+		// application/offset+octet-stream = ก้อนของ resumable upload (PATCH /uploads/resumable/{id})
+		middleware.AllowContentType("application/json", "multipart/form-data", "application/offset+octet-stream"),
 
 		middleware.Compress(5),
 		middleware.Logger,
@@ -62,8 +63,8 @@ func CommonMiddlewares(allowOrigin string) []func(http.Handler) http.Handler {
 		cors.Handler(cors.Options{
 			AllowedOrigins:   []string{allowOrigin, "http://localhost:*", "http://127.0.0.1:*"},
 			AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS", "HEAD"},
-			AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-			ExposedHeaders:   []string{"Link"},
+			AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Household-ID", "If-Match", "Tus-Resumable", "Upload-Offset"},
+			ExposedHeaders:   []string{"Link", "Location", "ETag", "Tus-Resumable", "Upload-Offset", "Upload-Length", "Upload-Expires"},
 			AllowCredentials: true,
 			MaxAge:           300,
 		}),
@@ -0,0 +1,9 @@
+package imaging
+
+import "errors"
+
+var (
+	ErrUnsupported = errors.New("imaging: unsupported format")
+	ErrCorrupt     = errors.New("imaging: malformed image data")
+	ErrTooLarge    = errors.New("imaging: image dimensions too large")
+)
@@ -0,0 +1,128 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/draw"
+	_ "image/gif" // ลงทะเบียน decoder gif ให้ image.Decode
+	"image/jpeg"
+	"image/png"
+)
+
+// MaxPixels กันภาพ "decompression bomb" (ไฟล์เล็กแต่ขยายเป็นหลาย GB ตอน decode)
+const MaxPixels = 50_000_000
+
+// Decode รองรับ jpeg/png/gif (gif ใช้เฟรมแรก); ตรวจขนาดจาก header ก่อน decode จริง
+func Decode(data []byte) (image.Image, string, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrUnsupported
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return nil, "", ErrTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrCorrupt
+	}
+	return img, format, nil
+}
+
+// Encode: ต้นฉบับ png/gif (อาจโปร่งใส) → png, อื่น ๆ → jpeg; คืน mime ที่ใช้
+func Encode(img image.Image, srcFormat string) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if srcFormat == "png" || srcFormat == "gif" {
+		enc := png.Encoder{CompressionLevel: png.BestSpeed}
+		if err := enc.Encode(&buf, img); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/png", nil
+	}
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "image/jpeg", nil
+}
+
+func toRGBA(src image.Image) *image.RGBA {
+	if m, ok := src.(*image.RGBA); ok && m.Rect.Min == (image.Point{}) {
+		return m
+	}
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Rect, src, b.Min, draw.Src)
+	return dst
+}
+
+// Fit ย่อให้ด้านยาวสุดไม่เกิน limit (ไม่ขยาย) ด้วย box filter: เฉลี่ยทุก pixel ต้นทางที่ตกในช่อง
+// คุณภาพพอสำหรับ thumbnail และไม่ต้องพึ่ง x/image
+func Fit(src image.Image, limit int) image.Image {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	if sw <= limit && sh <= limit {
+		return src
+	}
+	dw, dh := limit, sh*limit/sw
+	if sh > sw {
+		dw, dh = sw*limit/sh, limit
+	}
+	dw, dh = max(dw, 1), max(dh, 1)
+
+	s := toRGBA(src)
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*sh/dh, max((y+1)*sh/dh, y*sh/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*sw/dw, max((x+1)*sw/dw, x*sw/dw+1)
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				row := s.Pix[sy*s.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r, g, bl, a = r+uint64(p[0]), g+uint64(p[1]), bl+uint64(p[2]), a+uint64(p[3])
+					n++
+				}
+			}
+			d := dst.Pix[y*dst.Stride+x*4:]
+			d[0], d[1], d[2], d[3] = uint8(r/n), uint8(g/n), uint8(bl/n), uint8(a/n)
+		}
+	}
+	return dst
+}
+
+// Orient หมุน/กลับภาพตามค่า EXIF Orientation (2-8) ให้ตั้งตรงก่อนที่ metadata จะถูกลบ
+func Orient(src image.Image, o int) image.Image {
+	if o < 2 || o > 8 {
+		return src
+	}
+	s := toRGBA(src)
+	w, h := s.Rect.Dx(), s.Rect.Dy()
+	dw, dh := w, h
+	if o >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch o {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[y*dst.Stride+x*4:y*dst.Stride+x*4+4], s.Pix[sy*s.Stride+sx*4:])
+		}
+	}
+	return dst
+}
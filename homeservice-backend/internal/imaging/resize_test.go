@@ -0,0 +1,54 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image/png"
+	"testing"
+)
+
+// pngHeader png ขนาด w x h ที่มีแค่ IHDR ถูกต้อง (ข้อมูลภาพจริงเป็นของภาพเล็ก) ใช้ทดสอบการกันจาก header
+func pngHeader(t *testing.T, w, h uint32) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage()); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	ihdr := len(pngSig)
+	binary.BigEndian.PutUint32(b[ihdr+8:], w)
+	binary.BigEndian.PutUint32(b[ihdr+12:], h)
+	binary.BigEndian.PutUint32(b[ihdr+8+13:], crc32.ChecksumIEEE(b[ihdr+4:ihdr+8+13]))
+	return b
+}
+
+func TestDecodeMaxPixels(t *testing.T) {
+	tests := []struct {
+		name string
+		w, h uint32
+		want error
+	}{
+		{"small image decodes", 8, 4, nil},
+		{"bomb over limit", 100_000, 100_000, ErrTooLarge},
+		{"one pixel over", 10_000, MaxPixels/10_000 + 1, ErrTooLarge},
+		{"wide strip over", MaxPixels + 1, 1, ErrTooLarge},
+		// เท่ากับขีดพอดีผ่านการกัน แต่ข้อมูลภาพไม่ครบจึง decode ไม่ได้
+		{"exactly at limit", 10_000, MaxPixels / 10_000, ErrCorrupt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, format, err := Decode(pngHeader(t, tt.w, tt.h))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Decode() error = %v, want %v", err, tt.want)
+			}
+			if tt.want == nil && (format != "png" || img.Bounds().Dx() != 8) {
+				t.Errorf("Decode() = %v %q", img.Bounds(), format)
+			}
+		})
+	}
+	if _, _, err := Decode([]byte("not an image")); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Decode(junk) error = %v, want ErrUnsupported", err)
+	}
+}
@@ -0,0 +1,18 @@
+// Package imaging งานภาพฝั่ง server: ดูชนิดไฟล์จาก magic bytes, ลบ metadata (EXIF/GPS) และย่อภาพ
+// ใช้แค่ stdlib decoder (jpeg/png/gif); webp ลบ metadata ได้แต่ย่อไม่ได้
+package imaging
+
+import (
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// Sniff ชนิดไฟล์จากเนื้อไฟล์จริง (ไม่สน Content-Type ที่ client ส่งมา); ตัด parameter เช่น charset ออก
+func Sniff(data []byte) string {
+	m := mimetype.Detect(data).String()
+	if i := strings.IndexByte(m, ';'); i >= 0 {
+		m = m[:i]
+	}
+	return strings.TrimSpace(m)
+}
@@ -0,0 +1,178 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// StripMetadata ลบ EXIF/XMP/IPTC/ข้อความออกโดยไม่ encode ภาพใหม่ (ไม่เสียคุณภาพ)
+// jpeg: ตัด APP1/APP13/COM (เก็บ JFIF, ICC, Adobe); png: ตัด eXIf/tEXt/zTXt/iTXt/tIME; webp: ตัด EXIF/XMP chunk
+// gif ไม่มีพิกัดคืนตามเดิม; ชนิดอื่น = ErrUnsupported
+func StripMetadata(mime string, data []byte) ([]byte, error) {
+	switch mime {
+	case "image/jpeg":
+		return stripJPEG(data)
+	case "image/png":
+		return stripPNG(data)
+	case "image/webp":
+		return stripWebP(data)
+	case "image/gif":
+		return data, nil
+	}
+	return nil, ErrUnsupported
+}
+
+// jpegSegments เรียก fn กับทุก marker segment ก่อน SOS; คืน offset ของ SOS (ข้อมูลภาพที่เหลือ)
+func jpegSegments(data []byte, fn func(marker byte, seg []byte)) (int, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 0, ErrCorrupt
+	}
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return 0, ErrCorrupt
+		}
+		marker := data[i+1]
+		if marker == 0xFF { // fill byte
+			i++
+			continue
+		}
+		if marker == 0xDA { // SOS: ที่เหลือคือ entropy-coded data
+			return i, nil
+		}
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			fn(marker, data[i:i+2])
+			i += 2
+			continue
+		}
+		n := int(binary.BigEndian.Uint16(data[i+2:]))
+		if n < 2 || i+2+n > len(data) {
+			return 0, ErrCorrupt
+		}
+		fn(marker, data[i:i+2+n])
+		i += 2 + n
+	}
+	return 0, ErrCorrupt
+}
+
+func stripJPEG(data []byte) ([]byte, error) {
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:2])
+	sos, err := jpegSegments(data, func(marker byte, seg []byte) {
+		switch marker {
+		case 0xE1, 0xED, 0xFE: // APP1 (EXIF/XMP), APP13 (IPTC), COM
+			return
+		}
+		out.Write(seg)
+	})
+	if err != nil {
+		return nil, err
+	}
+	out.Write(data[sos:])
+	return out.Bytes(), nil
+}
+
+// JPEGOrientation ค่า EXIF Orientation (1-8); ไม่มี/อ่านไม่ได้ = 1
+func JPEGOrientation(data []byte) int {
+	o := 1
+	_, _ = jpegSegments(data, func(marker byte, seg []byte) {
+		if marker != 0xE1 || len(seg) < 4+6+8 || !bytes.Equal(seg[4:10], []byte("Exif\x00\x00")) {
+			return
+		}
+		if v := exifOrientation(seg[10:]); v >= 1 && v <= 8 {
+			o = v
+		}
+	})
+	return o
+}
+
+func exifOrientation(tiff []byte) int {
+	var bo binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return 0
+	}
+	ifd := int(bo.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 0
+	}
+	n := int(bo.Uint16(tiff[ifd:]))
+	for k := 0; k < n; k++ {
+		e := ifd + 2 + k*12
+		if e+12 > len(tiff) {
+			return 0
+		}
+		if bo.Uint16(tiff[e:]) == 0x0112 { // Orientation (SHORT)
+			return int(bo.Uint16(tiff[e+8:]))
+		}
+	}
+	return 0
+}
+
+var pngSig = []byte("\x89PNG\r\n\x1a\n")
+
+func stripPNG(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSig) {
+		return nil, ErrCorrupt
+	}
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(pngSig)
+	for i := len(pngSig); i < len(data); {
+		if i+12 > len(data) {
+			return nil, ErrCorrupt
+		}
+		n := int(binary.BigEndian.Uint32(data[i:]))
+		end := i + 12 + n
+		if n < 0 || end > len(data) {
+			return nil, ErrCorrupt
+		}
+		switch string(data[i+4 : i+8]) {
+		case "eXIf", "tEXt", "zTXt", "iTXt", "tIME":
+		default:
+			out.Write(data[i:end])
+		}
+		i = end
+	}
+	return out.Bytes(), nil
+}
+
+func stripWebP(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, ErrCorrupt
+	}
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:12])
+	vp8x := -1
+	for i := 12; i < len(data); {
+		if i+8 > len(data) {
+			return nil, ErrCorrupt
+		}
+		n := int(binary.LittleEndian.Uint32(data[i+4:]))
+		end := i + 8 + n + n%2 // chunk ขนาดคี่มี padding 1 ไบต์
+		if end > len(data) {
+			if i+8+n != len(data) {
+				return nil, ErrCorrupt
+			}
+			end = len(data)
+		}
+		switch string(data[i : i+4]) {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			vp8x = out.Len()
+			out.Write(data[i:end])
+		default:
+			out.Write(data[i:end])
+		}
+		i = end
+	}
+	b := out.Bytes()
+	if vp8x >= 0 && vp8x+8 < len(b) {
+		b[vp8x+8] &^= 0x08 | 0x04 // flag EXIF, XMP
+	}
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(b)-8))
+	return b, nil
+}
@@ -0,0 +1,213 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 4))
+	for x := 0; x < 8; x++ {
+		for y := 0; y < 4; y++ {
+			img.Set(x, y, color.RGBA{uint8(x * 30), uint8(y * 60), 90, 255})
+		}
+	}
+	return img
+}
+
+// exifSegment APP1 "Exif" (TIFF little-endian) ที่มี Orientation หนึ่ง tag และข้อความ GPS ปลอมให้ตรวจว่าหายไป
+func exifSegment(orientation uint16) []byte {
+	tiff := []byte("II*\x00\x08\x00\x00\x00") // IFD0 ที่ offset 8
+	tiff = binary.LittleEndian.AppendUint16(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0x0112) // Orientation
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3)      // SHORT
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	tiff = append(tiff, "GPS 13.7563N 100.5018E"...)
+	body := append([]byte("Exif\x00\x00"), tiff...)
+	seg := []byte{0xFF, 0xE1}
+	seg = binary.BigEndian.AppendUint16(seg, uint16(len(body)+2))
+	return append(seg, body...)
+}
+
+func jpegWith(t *testing.T, segs ...[]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(), nil); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	out := append([]byte(nil), b[:2]...) // SOI
+	for _, s := range segs {
+		out = append(out, s...)
+	}
+	return append(out, b[2:]...)
+}
+
+func comSegment(text string) []byte {
+	seg := []byte{0xFF, 0xFE}
+	seg = binary.BigEndian.AppendUint16(seg, uint16(len(text)+2))
+	return append(seg, text...)
+}
+
+func pngChunk(typ string, data []byte) []byte {
+	c := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	c = append(c, typ...)
+	c = append(c, data...)
+	return binary.BigEndian.AppendUint32(c, crc32.ChecksumIEEE(c[4:]))
+}
+
+func pngWith(t *testing.T, chunks ...[]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage()); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	ihdrEnd := len(pngSig) + 12 + 13
+	out := append([]byte(nil), b[:ihdrEnd]...)
+	for _, c := range chunks {
+		out = append(out, c...)
+	}
+	return append(out, b[ihdrEnd:]...)
+}
+
+func riffChunk(typ string, data []byte) []byte {
+	c := append([]byte(typ), binary.LittleEndian.AppendUint32(nil, uint32(len(data)))...)
+	c = append(c, data...)
+	if len(data)%2 == 1 {
+		c = append(c, 0)
+	}
+	return c
+}
+
+func webpWith(chunks ...[]byte) []byte {
+	var body []byte
+	for _, c := range chunks {
+		body = append(body, c...)
+	}
+	out := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)+4))...)
+	out = append(out, "WEBP"...)
+	return append(out, body...)
+}
+
+func TestStripMetadata(t *testing.T) {
+	vp8x := make([]byte, 10)
+	vp8x[0] = 0x08 | 0x04 | 0x10 // EXIF, XMP, ALPHA
+	tests := []struct {
+		name    string
+		mime    string
+		in      []byte
+		gone    []string // ข้อความที่ต้องไม่เหลือ
+		decodes bool     // ผลลัพธ์ต้อง decode ได้ด้วย image/*
+		check   func(t *testing.T, out []byte)
+	}{
+		{
+			name:    "jpeg exif and comment",
+			mime:    "image/jpeg",
+			in:      jpegWith(t, exifSegment(6), comSegment("taken at home")),
+			gone:    []string{"Exif", "GPS", "taken at home"},
+			decodes: true,
+			check: func(t *testing.T, out []byte) {
+				if o := JPEGOrientation(out); o != 1 {
+					t.Errorf("orientation after strip = %d, want 1", o)
+				}
+			},
+		},
+		{
+			name:    "png text and exif chunks",
+			mime:    "image/png",
+			in:      pngWith(t, pngChunk("tEXt", []byte("Comment\x00secret")), pngChunk("eXIf", []byte("MM\x00*GPS")), pngChunk("tIME", make([]byte, 7))),
+			gone:    []string{"tEXt", "secret", "eXIf", "GPS", "tIME"},
+			decodes: true,
+		},
+		{
+			name: "webp exif/xmp chunks and flags",
+			mime: "image/webp",
+			in:   webpWith(riffChunk("VP8X", vp8x), riffChunk("VP8L", []byte{1, 2, 3}), riffChunk("EXIF", []byte("GPS 1")), riffChunk("XMP ", []byte("<x:xmpmeta/>"))),
+			gone: []string{"EXIF", "GPS", "XMP ", "xmpmeta"},
+			check: func(t *testing.T, out []byte) {
+				if got := binary.LittleEndian.Uint32(out[4:]); int(got) != len(out)-8 {
+					t.Errorf("RIFF size = %d, want %d", got, len(out)-8)
+				}
+				if flags := out[12+8]; flags != 0x10 {
+					t.Errorf("VP8X flags = %#x, want only alpha (0x10)", flags)
+				}
+				if !bytes.Contains(out, []byte("VP8L")) {
+					t.Error("image chunk dropped")
+				}
+			},
+		},
+		{
+			name: "gif unchanged",
+			mime: "image/gif",
+			in:   []byte("GIF89a..."),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := StripMetadata(tt.mime, tt.in)
+			if err != nil {
+				t.Fatalf("StripMetadata() error = %v", err)
+			}
+			for _, s := range tt.gone {
+				if bytes.Contains(out, []byte(s)) {
+					t.Errorf("output still contains %q", s)
+				}
+			}
+			if tt.decodes {
+				if _, _, err := image.Decode(bytes.NewReader(out)); err != nil {
+					t.Errorf("stripped image does not decode: %v", err)
+				}
+			}
+			if tt.check != nil {
+				tt.check(t, out)
+			}
+		})
+	}
+}
+
+func TestStripMetadataErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		mime string
+		in   []byte
+		want error
+	}{
+		{"unsupported mime", "image/heic", []byte("...."), ErrUnsupported},
+		{"jpeg without SOI", "image/jpeg", []byte("not a jpeg"), ErrCorrupt},
+		{"jpeg segment overruns", "image/jpeg", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x10, 0x00, 'E'}, ErrCorrupt},
+		{"png bad signature", "image/png", []byte("PNG?"), ErrCorrupt},
+		{"png truncated chunk", "image/png", append(append([]byte(nil), pngSig...), 0, 0, 0, 9, 'I', 'H'), ErrCorrupt},
+		{"webp bad header", "image/webp", []byte("RIFF\x00\x00\x00\x00WAVE"), ErrCorrupt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := StripMetadata(tt.mime, tt.in); !errors.Is(err, tt.want) {
+				t.Errorf("StripMetadata() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	for _, o := range []uint16{1, 3, 6, 8} {
+		if got := JPEGOrientation(jpegWith(t, exifSegment(o))); got != int(o) {
+			t.Errorf("JPEGOrientation(exif %d) = %d", o, got)
+		}
+	}
+	if got := JPEGOrientation(jpegWith(t, exifSegment(42))); got != 1 {
+		t.Errorf("invalid orientation = %d, want 1", got)
+	}
+	if got := JPEGOrientation([]byte("junk")); got != 1 {
+		t.Errorf("non-jpeg orientation = %d, want 1", got)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Repo interface {
@@ -22,9 +23,9 @@ type Repo interface {
 	ListMediaByWatch(ctx context.Context, watchID string, limit int, cursor *string) ([]MediaPost, *string, error)
 }
 
-type pgRepo struct{ db *pgx.Conn }
+type pgRepo struct{ db *pgxpool.Pool }
 
-func NewPGRepo(db *pgx.Conn) Repo { return &pgRepo{db: db} }
+func NewPGRepo(db *pgxpool.Pool) Repo { return &pgRepo{db: db} }
 
 // --- Channels ---
 func (r *pgRepo) UpsertChannel(ctx context.Context, ch *MediaChannel) (*MediaChannel, bool, error) {
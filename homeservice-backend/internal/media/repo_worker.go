@@ -9,6 +9,7 @@ import (
 type WorkerRepo interface {
 	ListChannelsWithActiveSubs(ctx context.Context, limit int) ([]MediaChannel, error)
 	UpsertMediaPost(ctx context.Context, p *MediaPost) (created bool, err error)
+	ListNotifySubscribers(ctx context.Context, channelID string) ([]string, error)
 }
 
 type workerRepo struct{ db *pgxpool.Pool }
@@ -66,3 +67,32 @@ RETURNING id::text, channel_id::text, source, external_id, title, url, thumbnail
 	*p = got
 	return createdNew, nil
 }
+
+// ผู้ที่ควรได้แจ้งเตือนโพสต์ใหม่ของ channel: watch ส่วนตัว = เจ้าของ, watch ของบ้าน = สมาชิกทุกคน
+func (r *workerRepo) ListNotifySubscribers(ctx context.Context, channelID string) ([]string, error) {
+	rows, err := r.db.Query(ctx, `
+SELECT DISTINCT u.user_id::text
+FROM watch_media_subscriptions s
+JOIN stock_watch w ON w.id = s.watch_id
+CROSS JOIN LATERAL (
+  SELECT w.created_by AS user_id WHERE w.scope <> 'household' OR w.household_id IS NULL
+  UNION
+  SELECT m.user_id FROM household_members m WHERE w.scope = 'household' AND m.household_id = w.household_id
+) u
+WHERE s.channel_id = $1 AND s.notify = true
+`, channelID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
@@ -6,11 +6,18 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
-// RSSWorker
+// Notifier ฝั่งระบบแจ้งเตือน (notifications.Dispatcher)
+type Notifier interface {
+	NotifyNewPost(ctx context.Context, userIDs []string, postID, title, url string) error
+}
+
+// RSSWorker ดึง feed ของช่องที่มีคนติดตาม; ถ้ามี Notifier จะแจ้งผู้ติดตาม (notify=true) เมื่อมีโพสต์ใหม่
 type RSSWorker struct {
+	Notifier        Notifier
 	Repo            WorkerRepo
 	Every           time.Duration // ex: 3 * time.Minute
 	Timeout         time.Duration // ex: 5 * time.Second
@@ -34,19 +41,20 @@ func (w *RSSWorker) Run(ctx context.Context) error {
 	ticker := time.NewTicker(w.Every)
 	defer ticker.Stop()
 
-	_ = w.tick(ctx) // run once immediately
+	_ = w.RunOnce(ctx) // run once immediately
 
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-ticker.C:
-			_ = w.tick(ctx)
+			_ = w.RunOnce(ctx)
 		}
 	}
 }
 
-func (w *RSSWorker) tick(ctx context.Context) error {
+// RunOnce ดึงทุก feed หนึ่งรอบ (ใช้กับ scheduler)
+func (w *RSSWorker) RunOnce(ctx context.Context) error {
 	chs, err := w.Repo.ListChannelsWithActiveSubs(ctx, w.MaxFeedsPerTick)
 	if err != nil {
 		return err
@@ -65,15 +73,22 @@ func (w *RSSWorker) tick(ctx context.Context) error {
 				Source:       SourceYouTube,
 				ExternalID:   e.VideoID,
 				Title:        e.Title,
-				URL:          first(e.Link.Href, e.LinkHref, fmt.Sprintf("https://www.youtube.com/watch?v=%s", e.VideoID)),
+				URL:          first(e.Link.Href, fmt.Sprintf("https://www.youtube.com/watch?v=%s", e.VideoID)),
 				ThumbnailURL: strPtr(e.Thumb.URL),
 			}
 			if t, err := time.Parse(time.RFC3339, e.Published); err == nil {
 				tt := t.UTC()
 				mp.PublishedAt = &tt
 			}
-			_, _ = w.Repo.UpsertMediaPost(ctx, &mp)
-			// ไม่ยิง notify ตามที่ตกลง — ไว้ค่อยต่อ “ตัวกลาง” ทีหลัง
+			created, err := w.Repo.UpsertMediaPost(ctx, &mp)
+			if err != nil || !created || w.Notifier == nil {
+				continue
+			}
+			users, err := w.Repo.ListNotifySubscribers(ctx, ch.ID)
+			if err != nil {
+				continue
+			}
+			_ = w.Notifier.NotifyNewPost(ctx, users, mp.ID, mp.Title, mp.URL)
 		}
 	}
 	return nil
@@ -87,7 +102,6 @@ type rssFeed struct {
 }
 type rssEntry struct {
 	Title     string       `xml:"title"`
-	LinkHref  string       `xml:"link"`
 	Link      rssLink      `xml:"link"`
 	Published string       `xml:"published"`
 	VideoID   string       `xml:"{http://www.youtube.com/xml/schemas/2015}videoId"`
@@ -95,6 +109,7 @@ type rssEntry struct {
 }
 type rssLink struct {
 	Href string `xml:"href,attr"`
+	Text string `xml:",chardata"`
 }
 type rssThumbnail struct {
 	URL string `xml:"url,attr"`
@@ -124,7 +139,7 @@ func (w *RSSWorker) fetchRSS(ctx context.Context, url string) ([]rssEntry, error
 	// fallback: บาง feed ให้ link เป็น text แทน href
 	for i := range feed.Entries {
 		if feed.Entries[i].Link.Href == "" {
-			feed.Entries[i].Link.Href = feed.Entries[i].LinkHref
+			feed.Entries[i].Link.Href = strings.TrimSpace(feed.Entries[i].Link.Text)
 		}
 	}
 	return feed.Entries, nil
@@ -0,0 +1,321 @@
+// internal/medicine/catalog.go
+package medicine
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/iMookatayou/homeservice-backend/internal/db"
+)
+
+// CatalogEntry ข้อมูลสินค้าจากแคตตาล็อก ใช้เติมค่าเริ่มต้นของ MedicineItem
+type CatalogEntry struct {
+	GTIN        string  `json:"gtin"` // GTIN-14
+	Name        string  `json:"name"`
+	GenericName *string `json:"generic_name,omitempty"`
+	Strength    *string `json:"strength,omitempty"`
+	Form        Form    `json:"form,omitempty"`
+	Unit        string  `json:"unit,omitempty"`
+	Category    *string `json:"category,omitempty"`
+	Source      string  `json:"source,omitempty"`
+}
+
+// Catalog ผู้ให้ข้อมูลสินค้าจากบาร์โค้ด (สลับเป็น API ภายนอกได้); ไม่รู้จัก = ErrNotFound
+type Catalog interface {
+	LookupGTIN(ctx context.Context, gtin string) (*CatalogEntry, error)
+}
+
+// NormalizeGTIN ตัดขีด/ช่องว่าง ตรวจ check digit (GS1 mod 10) แล้วคืนเป็น GTIN-14
+// รับ GTIN-8, UPC-A (12), EAN-13 และ GTIN-14
+func NormalizeGTIN(s string) (string, error) {
+	s = strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(s))
+	switch len(s) {
+	case 8, 12, 13, 14:
+	default:
+		return "", fmt.Errorf("%w: gtin must be 8, 12, 13 or 14 digits", ErrBadInput)
+	}
+	s = strings.Repeat("0", 14-len(s)) + s
+	sum := 0
+	for i := 0; i < 13; i++ {
+		c := s[i]
+		if c < '0' || c > '9' {
+			return "", fmt.Errorf("%w: gtin must be digits", ErrBadInput)
+		}
+		d := int(c - '0')
+		if i%2 == 0 { // ตำแหน่งคู่จากขวา (ไม่นับ check digit) คูณ 3
+			d *= 3
+		}
+		sum += d
+	}
+	if s[13] < '0' || s[13] > '9' || int(s[13]-'0') != (10-sum%10)%10 {
+		return "", fmt.Errorf("%w: gtin check digit mismatch", ErrBadInput)
+	}
+	return s, nil
+}
+
+// ParseCatalog อ่านไฟล์แคตตาล็อก format "json" (array ของ CatalogEntry) หรือ "csv"
+// (แถวแรกเป็นหัวคอลัมน์: gtin,name,generic_name,strength,form,unit,category — ต้องมี gtin กับ name)
+// gtin ถูก normalize; แถวที่ผิดถูกข้ามพร้อมเหตุผลใน skipped
+func ParseCatalog(r io.Reader, format string) (entries []CatalogEntry, skipped []string, err error) {
+	var raw []CatalogEntry
+	switch format {
+	case "json":
+		if err := json.NewDecoder(r).Decode(&raw); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrBadInput, err)
+		}
+	case "csv":
+		cr := csv.NewReader(r)
+		cr.TrimLeadingSpace = true
+		cr.FieldsPerRecord = -1
+		head, err := cr.Read()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrBadInput, err)
+		}
+		col := map[string]int{}
+		for i, h := range head {
+			col[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))] = i
+		}
+		if _, ok := col["gtin"]; !ok {
+			return nil, nil, fmt.Errorf("%w: csv needs gtin and name columns", ErrBadInput)
+		}
+		if _, ok := col["name"]; !ok {
+			return nil, nil, fmt.Errorf("%w: csv needs gtin and name columns", ErrBadInput)
+		}
+		for {
+			rec, err := cr.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("%w: %v", ErrBadInput, err)
+			}
+			get := func(k string) string {
+				if i, ok := col[k]; ok && i < len(rec) {
+					return strings.TrimSpace(rec[i])
+				}
+				return ""
+			}
+			opt := func(k string) *string {
+				if v := get(k); v != "" {
+					return &v
+				}
+				return nil
+			}
+			raw = append(raw, CatalogEntry{
+				GTIN: get("gtin"), Name: get("name"), GenericName: opt("generic_name"), Strength: opt("strength"),
+				Form: Form(get("form")), Unit: get("unit"), Category: opt("category"),
+			})
+		}
+	default:
+		return nil, nil, fmt.Errorf("%w: format must be json or csv", ErrBadInput)
+	}
+
+	for i, e := range raw {
+		g, err := NormalizeGTIN(e.GTIN)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("row %d: %v", i+1, err))
+			continue
+		}
+		if e.Name = strings.TrimSpace(e.Name); e.Name == "" {
+			skipped = append(skipped, fmt.Sprintf("row %d: name required", i+1))
+			continue
+		}
+		e.GTIN = g
+		if e.Form = Form(strings.ToLower(strings.TrimSpace(string(e.Form)))); e.Form != "" && !e.Form.valid() {
+			e.Form = FormOther // แคตตาล็อกภายนอกอาจใช้ชื่อรูปแบบอื่น
+		}
+		entries = append(entries, e)
+	}
+	return entries, skipped, nil
+}
+
+// PGCatalog แคตตาล็อกออฟไลน์ในตาราง medicine_catalog (เติมข้อมูลด้วย Import)
+type PGCatalog struct {
+	DB *db.Pool
+}
+
+func (c *PGCatalog) LookupGTIN(ctx context.Context, gtin string) (*CatalogEntry, error) {
+	var e CatalogEntry
+	var form, unit *string
+	err := c.DB.QueryRow(ctx, `
+	SELECT gtin, name, generic_name, strength, form, unit, category, source
+	FROM medicine_catalog WHERE gtin=$1`, gtin).
+		Scan(&e.GTIN, &e.Name, &e.GenericName, &e.Strength, &form, &unit, &e.Category, &e.Source)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if form != nil {
+		e.Form = Form(*form)
+	}
+	if unit != nil {
+		e.Unit = *unit
+	}
+	return &e, nil
+}
+
+// Import upsert ทั้งชุดใน transaction เดียว (gtin ซ้ำ = แทนค่าเดิม)
+func (c *PGCatalog) Import(ctx context.Context, entries []CatalogEntry, source string) (int, error) {
+	if source == "" {
+		source = "import"
+	}
+	err := pgx.BeginFunc(ctx, c.DB, func(tx pgx.Tx) error {
+		for _, e := range entries {
+			if _, err := tx.Exec(ctx, `
+			INSERT INTO medicine_catalog (gtin, name, generic_name, strength, form, unit, category, source)
+			VALUES ($1,$2,$3,$4,NULLIF($5,''),NULLIF($6,''),$7,$8)
+			ON CONFLICT (gtin) DO UPDATE SET
+			  name=EXCLUDED.name, generic_name=EXCLUDED.generic_name, strength=EXCLUDED.strength,
+			  form=EXCLUDED.form, unit=EXCLUDED.unit, category=EXCLUDED.category,
+			  source=EXCLUDED.source, updated_at=now()`,
+				e.GTIN, e.Name, e.GenericName, e.Strength, string(e.Form), e.Unit, e.Category, source); err != nil {
+				return fmt.Errorf("gtin %s: %w", e.GTIN, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+// ---------- Lookup / Quick-add ----------
+
+// LookupResult ผลสแกนบาร์โค้ด: เจอยาของบ้าน (Item) หรือข้อมูลจากแคตตาล็อกไว้เติมฟอร์ม (Catalog)
+type LookupResult struct {
+	GTIN    string        `json:"gtin"`
+	Source  string        `json:"source"` // household | catalog
+	Item    *MedicineItem `json:"item,omitempty"`
+	Catalog *CatalogEntry `json:"catalog,omitempty"`
+}
+
+// Lookup หายาของบ้านจากบาร์โค้ดก่อน ไม่เจอค่อยถามแคตตาล็อก; ไม่เจอทั้งคู่ = ErrNotFound
+func (s *Service) Lookup(ctx context.Context, householdID, gtin string) (*LookupResult, error) {
+	g, err := NormalizeGTIN(gtin)
+	if err != nil {
+		return nil, err
+	}
+	it, err := s.Repo.FindItemByGTIN(ctx, householdID, g)
+	if err == nil {
+		return &LookupResult{GTIN: g, Source: "household", Item: it}, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	if s.Catalog == nil {
+		return nil, ErrNotFound
+	}
+	e, err := s.Catalog.LookupGTIN(ctx, g)
+	if err != nil {
+		return nil, err
+	}
+	return &LookupResult{GTIN: g, Source: "catalog", Catalog: e}, nil
+}
+
+// ScanInput สแกนกล่องยาแล้วรับเข้า: Item ใช้ทับค่าจากแคตตาล็อกตอนสร้างยาใหม่ (ยาที่มีอยู่แล้วไม่แก้)
+type ScanInput struct {
+	GTIN   string        `json:"gtin"`
+	Qty    float64       `json:"qty"`
+	LotNo  *string       `json:"lot_no"`
+	Expiry *time.Time    `json:"expiry_date"`
+	Reason *string       `json:"reason"`
+	Item   *MedicineItem `json:"item"`
+}
+
+// ScanResult ยาที่รับเข้า (Created = สร้างใหม่จากการสแกนนี้) + batch และ txn in
+type ScanResult struct {
+	Created bool          `json:"created"`
+	Item    MedicineItem  `json:"item"`
+	Batch   MedicineBatch `json:"batch"`
+	Txn     MedicineTxn   `json:"txn"`
+}
+
+// QuickAdd สแกนครั้งเดียว: เจอยาของบ้าน = รับเข้า batch (lot+วันหมดอายุเดิมบวกเข้า batch เดิม)
+// ไม่เจอ = สร้างยาจากแคตตาล็อก/ค่าที่ส่งมา แล้วรับเข้า — ทั้งหมดใน transaction เดียว
+func (s *Service) QuickAdd(ctx context.Context, householdID string, in ScanInput, actor string) (*ScanResult, error) {
+	if in.Qty <= 0 {
+		return nil, ErrBadInput
+	}
+	lr, err := s.Lookup(ctx, householdID, in.GTIN)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	res := &ScanResult{}
+	var newItem *MedicineItem
+	if lr != nil && lr.Item != nil {
+		res.Item = *lr.Item
+	} else {
+		g, _ := NormalizeGTIN(in.GTIN) // ผ่าน Lookup มาแล้ว
+		it := MedicineItem{Form: FormOther}
+		if lr != nil && lr.Catalog != nil {
+			c := lr.Catalog
+			it.Name, it.GenericName, it.Strength, it.Category, it.Unit = c.Name, c.GenericName, c.Strength, c.Category, c.Unit
+			if c.Form != "" {
+				it.Form = c.Form
+			}
+		}
+		if o := in.Item; o != nil {
+			if o.Name != "" {
+				it.Name = o.Name
+			}
+			if o.Unit != "" {
+				it.Unit = o.Unit
+			}
+			if o.Form != "" {
+				it.Form = o.Form
+			}
+			if o.GenericName != nil {
+				it.GenericName = o.GenericName
+			}
+			if o.Strength != nil {
+				it.Strength = o.Strength
+			}
+			if o.Category != nil {
+				it.Category = o.Category
+			}
+			it.LocationID, it.PhotoFileID, it.Notes = o.LocationID, o.PhotoFileID, o.Notes
+		}
+		if !it.Form.valid() {
+			return nil, fmt.Errorf("%w: unknown form %q", ErrBadInput, it.Form)
+		}
+		if it.Name == "" || it.Unit == "" {
+			return nil, fmt.Errorf("%w: unknown gtin; item.name and item.unit required", ErrBadInput)
+		}
+		if err := s.checkPhoto(ctx, householdID, it.PhotoFileID); err != nil {
+			return nil, err
+		}
+		it.ID, it.HouseholdID, it.GTIN = uuid.NewString(), householdID, &g
+		newItem, res.Item, res.Created = &it, it, true
+	}
+
+	res.Batch = MedicineBatch{
+		ID: uuid.NewString(), ItemID: res.Item.ID, LotNo: in.LotNo, Expiry: in.Expiry, Qty: in.Qty, Unit: res.Item.Unit,
+	}
+	res.Txn = MedicineTxn{ItemID: res.Item.ID, BatchID: &res.Batch.ID, ActorID: actor, Type: TxnIn, QtyChange: in.Qty, Reason: in.Reason}
+	created, err := s.Repo.ReceiveScan(ctx, newItem, &res.Batch, &res.Txn)
+	if err != nil {
+		return nil, err
+	}
+	if newItem != nil {
+		res.Item, res.Created = *newItem, created
+	}
+	return res, nil
+}
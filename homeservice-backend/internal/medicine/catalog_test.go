@@ -0,0 +1,46 @@
+package medicine
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeGTIN(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"EAN-13", "4006381333931", "04006381333931", false},
+		{"UPC-A", "036000291452", "00036000291452", false},
+		{"GTIN-8", "96385074", "00000096385074", false},
+		{"GTIN-14", "10614141000415", "10614141000415", false},
+		{"check digit 0", "00012345600012", "00012345600012", false},
+		{"dashes and spaces", " 885-0999 320014 ", "08850999320014", false},
+		{"EAN-13 bad check digit", "4006381333932", "", true},
+		{"UPC-A bad check digit", "036000291453", "", true},
+		{"GTIN-14 bad check digit", "10614141000416", "", true},
+		{"transposed digits", "4006381339331", "", true},
+		{"too short", "1234567", "", true},
+		{"GTIN-10 not accepted", "0123456789", "", true},
+		{"too long", "123456789012345", "", true},
+		{"letters", "40063813339A1", "", true},
+		{"letter in check digit", "400638133393X", "", true},
+		{"empty", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeGTIN(tt.in)
+			if tt.wantErr {
+				if !errors.Is(err, ErrBadInput) {
+					t.Fatalf("NormalizeGTIN(%q) = %q, %v; want ErrBadInput", tt.in, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("NormalizeGTIN(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+			}
+		})
+	}
+}
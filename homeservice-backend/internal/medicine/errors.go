@@ -8,4 +8,19 @@ var (
 	ErrConflict  = errors.New("conflict")
 	ErrBadInput  = errors.New("bad_input")
 	ErrNoStock   = errors.New("no_stock")
+
+	// ของที่เหลือพอเฉพาะเมื่อนับ batch ที่หมดอายุแล้ว (ส่ง force=true เพื่อยอมเบิก)
+	ErrExpiredStock = errors.New("expired_stock")
+
+	// If-Match ไม่ตรงกับ version ปัจจุบัน
+	ErrPreconditionFailed = errors.New("precondition_failed")
 )
+
+// ConflictError แนบรายละเอียดยาล่าสุดไปกับ ErrConflict/ErrPreconditionFailed
+type ConflictError struct {
+	Err     error
+	Current *ItemDetail
+}
+
+func (e *ConflictError) Error() string { return e.Err.Error() }
+func (e *ConflictError) Unwrap() error { return e.Err }
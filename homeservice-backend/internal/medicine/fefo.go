@@ -0,0 +1,72 @@
+// internal/medicine/fefo.go
+package medicine
+
+import (
+	"time"
+)
+
+// Dispense = คำขอเบิกแบบไม่ชี้ batch (ระบบเลือกให้แบบ FEFO)
+type Dispense struct {
+	ItemID       string
+	Qty          float64
+	AllowExpired bool      // force: ยอมเบิกจาก batch ที่หมดอายุแล้ว
+	AsOf         time.Time // วันที่ใช้ตัดสินว่า batch หมดอายุหรือยัง
+	ActorID      string
+	Reason       *string
+	DoseID       *string // ผูก txn กับนัดกินยา (TakeDose)
+}
+
+// BatchDraw = เบิกจาก batch ไหนเท่าไร
+type BatchDraw struct {
+	BatchID string
+	Qty     float64
+	Expired bool
+}
+
+// qtyEpsilon: qty เก็บเป็น numeric(14,3) กันเศษทศนิยมของ float
+const qtyEpsilon = 1e-6
+
+// expired = วันหมดอายุผ่านไปแล้ว (หมดอายุวันนี้ยังใช้ได้)
+func expired(b MedicineBatch, asOf time.Time) bool {
+	if b.Expiry == nil {
+		return false
+	}
+	y, m, d := asOf.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	ey, em, ed := b.Expiry.Date()
+	return time.Date(ey, em, ed, 0, 0, 0, 0, time.UTC).Before(today)
+}
+
+// pickFEFO เลือก batch ตามลำดับ First-Expire-First-Out (batches ต้องเรียง expiry NULLS LAST, created_at แล้ว)
+// ข้าม batch ที่หมดอายุถ้าไม่ AllowExpired; ไม่พอ = ErrNoStock, พอเฉพาะเมื่อนับของหมดอายุ = ErrExpiredStock
+func pickFEFO(batches []MedicineBatch, d Dispense) ([]BatchDraw, error) {
+	need := d.Qty
+	var draws []BatchDraw
+	var skipped float64
+	for _, b := range batches {
+		if need <= qtyEpsilon {
+			break
+		}
+		if b.Qty <= qtyEpsilon {
+			continue
+		}
+		exp := expired(b, d.AsOf)
+		if exp && !d.AllowExpired {
+			skipped += b.Qty
+			continue
+		}
+		use := b.Qty
+		if use > need {
+			use = need
+		}
+		draws = append(draws, BatchDraw{BatchID: b.ID, Qty: use, Expired: exp})
+		need -= use
+	}
+	if need > qtyEpsilon {
+		if skipped >= need-qtyEpsilon {
+			return nil, ErrExpiredStock
+		}
+		return nil, ErrNoStock
+	}
+	return draws, nil
+}
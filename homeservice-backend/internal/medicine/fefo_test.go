@@ -0,0 +1,141 @@
+package medicine
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPickFEFO(t *testing.T) {
+	asOf := time.Date(2026, 3, 10, 15, 0, 0, 0, time.UTC)
+	day := func(y int, m time.Month, d int) *time.Time {
+		t := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+	batch := func(id string, qty float64, exp *time.Time) MedicineBatch {
+		return MedicineBatch{ID: id, Qty: qty, Expiry: exp}
+	}
+	// เรียงแบบที่ repo ส่งมา: expiry NULLS LAST, created_at
+	stock := []MedicineBatch{
+		batch("old", 5, day(2026, 3, 1)),    // หมดอายุแล้ว
+		batch("today", 2, day(2026, 3, 10)), // หมดอายุวันนี้ = ยังใช้ได้
+		batch("empty", 0, day(2026, 4, 1)),
+		batch("soon", 4, day(2026, 5, 1)),
+		batch("later", 10, day(2027, 1, 1)),
+		batch("noexp", 3, nil),
+	}
+
+	tests := []struct {
+		name    string
+		batches []MedicineBatch
+		qty     float64
+		force   bool
+		want    []BatchDraw
+		wantErr error
+	}{
+		{
+			name:    "earliest usable first, expired skipped",
+			batches: stock, qty: 1,
+			want: []BatchDraw{{BatchID: "today", Qty: 1}},
+		},
+		{
+			name:    "spans batches in order, skips empty",
+			batches: stock, qty: 7.5,
+			want: []BatchDraw{{BatchID: "today", Qty: 2}, {BatchID: "soon", Qty: 4}, {BatchID: "later", Qty: 1.5}},
+		},
+		{
+			name:    "no-expiry batch used last",
+			batches: stock, qty: 18,
+			want: []BatchDraw{{BatchID: "today", Qty: 2}, {BatchID: "soon", Qty: 4}, {BatchID: "later", Qty: 10}, {BatchID: "noexp", Qty: 2}},
+		},
+		{
+			name:    "force draws expired first and flags it",
+			batches: stock, qty: 6, force: true,
+			want: []BatchDraw{{BatchID: "old", Qty: 5, Expired: true}, {BatchID: "today", Qty: 1}},
+		},
+		{
+			name:    "not enough stock at all",
+			batches: stock, qty: 25,
+			wantErr: ErrNoStock,
+		},
+		{
+			name:    "only enough when counting expired",
+			batches: []MedicineBatch{batch("old", 5, day(2026, 3, 1)), batch("soon", 1, day(2026, 5, 1))},
+			qty:     4,
+			wantErr: ErrExpiredStock,
+		},
+		{
+			name:    "expired alone not enough either",
+			batches: []MedicineBatch{batch("old", 2, day(2026, 3, 1)), batch("soon", 1, day(2026, 5, 1))},
+			qty:     4,
+			wantErr: ErrNoStock,
+		},
+		{
+			name:    "all expired refused",
+			batches: []MedicineBatch{batch("old", 5, day(2026, 3, 9))},
+			qty:     1,
+			wantErr: ErrExpiredStock,
+		},
+		{
+			name:    "empty stock",
+			qty:     1,
+			wantErr: ErrNoStock,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pickFEFO(tt.batches, Dispense{Qty: tt.qty, AllowExpired: tt.force, AsOf: asOf})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("pickFEFO() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("pickFEFO() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pickFEFO() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 0.1 + 0.2 ของ float ไม่เท่ากับ 0.3 พอดี: เศษต่ำกว่า qtyEpsilon ต้องไม่กลายเป็น ErrNoStock
+func TestPickFEFORounding(t *testing.T) {
+	got, err := pickFEFO([]MedicineBatch{{ID: "a", Qty: 0.1}, {ID: "b", Qty: 0.2}}, Dispense{Qty: 0.3, AsOf: time.Now()})
+	if err != nil {
+		t.Fatalf("pickFEFO() error = %v", err)
+	}
+	if len(got) != 2 || math.Abs(got[0].Qty+got[1].Qty-0.3) > qtyEpsilon {
+		t.Errorf("pickFEFO() = %+v", got)
+	}
+}
+
+func TestExpired(t *testing.T) {
+	exp := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		asOf time.Time
+		want bool
+	}{
+		{"day before", time.Date(2026, 3, 9, 23, 59, 0, 0, time.UTC), false},
+		{"expiry day itself", time.Date(2026, 3, 10, 23, 0, 0, 0, time.UTC), false},
+		{"day after", time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC), true},
+		// ใช้วันที่ตามโซนของ asOf ไม่ใช่ UTC
+		{"local date still expiry day", time.Date(2026, 3, 10, 23, 30, 0, 0, time.FixedZone("ICT", 7*3600)), false},
+		{"local date already next day", time.Date(2026, 3, 11, 0, 30, 0, 0, time.FixedZone("ICT", 7*3600)), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expired(MedicineBatch{Expiry: &exp}, tt.asOf); got != tt.want {
+				t.Errorf("expired() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if expired(MedicineBatch{}, time.Now()) {
+		t.Error("batch without expiry reported expired")
+	}
+}
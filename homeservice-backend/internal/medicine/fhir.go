@@ -0,0 +1,319 @@
+// internal/medicine/fhir.go
+package medicine
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// โครงสร้าง FHIR R4 เฉพาะส่วนที่ใช้ (Bundle แบบ collection ของ Medication + MedicationStatement)
+
+type FHIRBundle struct {
+	ResourceType string      `json:"resourceType"` // "Bundle"
+	ID           string      `json:"id"`
+	Type         string      `json:"type"` // "collection"
+	Timestamp    string      `json:"timestamp"`
+	Entry        []FHIREntry `json:"entry"`
+}
+
+type FHIREntry struct {
+	FullURL  string `json:"fullUrl"` // urn:uuid:<id>
+	Resource any    `json:"resource"`
+}
+
+type fhirIdentity struct {
+	System string `json:"system,omitempty"`
+	Value  string `json:"value"`
+}
+
+type fhirCoding struct {
+	System  string `json:"system,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+type fhirCodeableConcept struct {
+	Coding []fhirCoding `json:"coding,omitempty"`
+	Text   string       `json:"text,omitempty"`
+}
+
+type fhirReference struct {
+	Reference string `json:"reference,omitempty"`
+	Display   string `json:"display,omitempty"`
+}
+
+type fhirQuantity struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit,omitempty"`
+}
+
+type fhirPeriod struct {
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+}
+
+type fhirAnnotation struct {
+	Text string `json:"text"`
+}
+
+type fhirIngredient struct {
+	ItemCodeableConcept fhirCodeableConcept `json:"itemCodeableConcept"`
+	IsActive            bool                `json:"isActive"`
+}
+
+type fhirBatch struct {
+	LotNumber      string `json:"lotNumber,omitempty"`
+	ExpirationDate string `json:"expirationDate,omitempty"`
+}
+
+type fhirExtension struct {
+	URL           string        `json:"url"`
+	ValueQuantity *fhirQuantity `json:"valueQuantity,omitempty"`
+	ValueString   string        `json:"valueString,omitempty"`
+}
+
+// FHIRMedication = R4 Medication (ยา 1 รายการ หรือ 1 batch ของยานั้น)
+type FHIRMedication struct {
+	ResourceType string               `json:"resourceType"` // "Medication"
+	ID           string               `json:"id"`
+	Identifier   []fhirIdentity       `json:"identifier,omitempty"`
+	Code         fhirCodeableConcept  `json:"code"`
+	Status       string               `json:"status"` // active | inactive
+	Form         *fhirCodeableConcept `json:"form,omitempty"`
+	Ingredient   []fhirIngredient     `json:"ingredient,omitempty"`
+	Batch        *fhirBatch           `json:"batch,omitempty"`
+	Extension    []fhirExtension      `json:"extension,omitempty"`
+}
+
+type fhirTimingRepeat struct {
+	Frequency  int      `json:"frequency"`
+	Period     float64  `json:"period"`
+	PeriodUnit string   `json:"periodUnit"`
+	DayOfWeek  []string `json:"dayOfWeek,omitempty"`
+	TimeOfDay  []string `json:"timeOfDay,omitempty"`
+}
+
+type fhirTiming struct {
+	Repeat fhirTimingRepeat `json:"repeat"`
+}
+
+type fhirDoseAndRate struct {
+	DoseQuantity fhirQuantity `json:"doseQuantity"`
+}
+
+type fhirDosage struct {
+	Text        string            `json:"text,omitempty"`
+	Timing      *fhirTiming       `json:"timing,omitempty"`
+	DoseAndRate []fhirDoseAndRate `json:"doseAndRate,omitempty"`
+}
+
+// FHIRMedicationStatement = R4 MedicationStatement (ตารางกินยา หรือสรุปการเบิกใช้จาก txn)
+type FHIRMedicationStatement struct {
+	ResourceType        string           `json:"resourceType"` // "MedicationStatement"
+	ID                  string           `json:"id"`
+	Status              string           `json:"status"` // active | completed
+	MedicationReference fhirReference    `json:"medicationReference"`
+	Subject             fhirReference    `json:"subject"`
+	EffectivePeriod     *fhirPeriod      `json:"effectivePeriod,omitempty"`
+	DateAsserted        string           `json:"dateAsserted"`
+	Note                []fhirAnnotation `json:"note,omitempty"`
+	Dosage              []fhirDosage     `json:"dosage,omitempty"`
+}
+
+const (
+	fhirGTINSystem   = "https://www.gs1.org/gtin"
+	fhirLocalSystem  = "urn:homeservice:medicine-item"
+	fhirStockExtURL  = "urn:homeservice:fhir:quantity-on-hand"
+	fhirLocationExt  = "urn:homeservice:fhir:storage-location"
+	fhirUsageWindow  = 30 * 24 * time.Hour // เบิกล่าสุดภายในช่วงนี้ = ยังใช้อยู่
+	fhirDateTimeForm = time.RFC3339
+)
+
+var fhirDays = [...]string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+func fhirRef(id string) string { return "urn:uuid:" + id }
+
+// ExportFHIR สร้าง Bundle (collection):
+//   - Medication ต่อยา 1 ตัว (code = ชื่อ + GTIN, form, ส่วนประกอบ = generic name + strength)
+//     และต่อ batch คงเหลือ (batch.lotNumber/expirationDate + ยอดคงเหลือใน extension)
+//   - MedicationStatement ต่อตารางกินยา (subject = คนกิน, dosage ตามเวลา/วัน)
+//     และต่อยาที่มีประวัติเบิกแต่ไม่มีตาราง (subject = บ้าน, ช่วงเวลาจาก txn out แรก-ล่าสุด)
+//
+// kind = "Medication" | "MedicationStatement" | "" (ทั้งคู่)
+func (s *Service) ExportFHIR(ctx context.Context, householdID, kind string) (*FHIRBundle, error) {
+	if kind != "" && kind != "Medication" && kind != "MedicationStatement" {
+		return nil, fmt.Errorf("%w: type must be Medication or MedicationStatement", ErrBadInput)
+	}
+	now := s.Now()
+	items, err := s.Repo.ListItems(ctx, householdID, ListItemFilter{})
+	if err != nil {
+		return nil, err
+	}
+	locName := map[string]string{}
+	if locs, err := s.Repo.ListLocations(ctx, householdID); err == nil {
+		for _, l := range locs {
+			locName[l.ID] = l.Name
+		}
+	} else {
+		return nil, err
+	}
+
+	b := &FHIRBundle{
+		ResourceType: "Bundle", ID: uuid.NewString(), Type: "collection",
+		Timestamp: now.Format(fhirDateTimeForm), Entry: []FHIREntry{},
+	}
+	itemByID := map[string]MedicineItem{}
+	for _, is := range items {
+		it := is.Item
+		itemByID[it.ID] = it
+		if kind == "MedicationStatement" {
+			continue
+		}
+		med := fhirMedicationOf(it)
+		if it.LocationID != nil && locName[*it.LocationID] != "" {
+			med.Extension = append(med.Extension, fhirExtension{URL: fhirLocationExt, ValueString: locName[*it.LocationID]})
+		}
+		med.Extension = append(med.Extension, fhirExtension{URL: fhirStockExtURL, ValueQuantity: &fhirQuantity{Value: is.TotalQty, Unit: it.Unit}})
+		b.Entry = append(b.Entry, FHIREntry{FullURL: fhirRef(med.ID), Resource: med})
+
+		bs, err := s.Repo.GetBatchesByItem(ctx, it.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, bt := range bs {
+			if bt.Qty <= qtyEpsilon {
+				continue
+			}
+			bm := fhirMedicationOf(it)
+			bm.ID = bt.ID
+			fb := &fhirBatch{}
+			if bt.LotNo != nil {
+				fb.LotNumber = *bt.LotNo
+			}
+			if bt.Expiry != nil {
+				fb.ExpirationDate = bt.Expiry.Format("2006-01-02")
+				if expired(bt, now) {
+					bm.Status = "inactive"
+				}
+			}
+			bm.Batch = fb
+			bm.Extension = []fhirExtension{{URL: fhirStockExtURL, ValueQuantity: &fhirQuantity{Value: bt.Qty, Unit: bt.Unit}}}
+			b.Entry = append(b.Entry, FHIREntry{FullURL: fhirRef(bm.ID), Resource: bm})
+		}
+	}
+	if kind == "Medication" {
+		return b, nil
+	}
+
+	regs, err := s.Repo.ListRegimens(ctx, householdID, "", "", false)
+	if err != nil {
+		return nil, err
+	}
+	withRegimen := map[string]bool{}
+	for _, rg := range regs {
+		it, ok := itemByID[rg.ItemID]
+		if !ok {
+			continue // ยาถูกเก็บเข้าคลังแล้ว
+		}
+		withRegimen[rg.ItemID] = true
+		b.Entry = append(b.Entry, FHIREntry{FullURL: fhirRef(rg.ID), Resource: s.fhirRegimenStatement(rg, it, now)})
+	}
+
+	usage, err := s.Repo.UsageHistory(ctx, householdID)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range usage {
+		it, ok := itemByID[u.ItemID]
+		if !ok || withRegimen[u.ItemID] {
+			continue
+		}
+		status := "completed"
+		if now.Sub(u.LastOut) <= fhirUsageWindow {
+			status = "active"
+		}
+		st := FHIRMedicationStatement{
+			ResourceType: "MedicationStatement",
+			// id คงที่ต่อยา: export ซ้ำได้ resource เดิม
+			ID:                  uuid.NewSHA1(uuid.NameSpaceURL, []byte("medication-statement:"+it.ID)).String(),
+			Status:              status,
+			MedicationReference: fhirReference{Reference: fhirRef(it.ID), Display: it.Name},
+			Subject:             fhirReference{Display: "household"},
+			EffectivePeriod:     &fhirPeriod{Start: u.FirstOut.Format(fhirDateTimeForm), End: u.LastOut.Format(fhirDateTimeForm)},
+			DateAsserted:        now.Format(fhirDateTimeForm),
+			Dosage: []fhirDosage{{
+				Text: fmt.Sprintf("used %s %s in %d dispenses", strconv.FormatFloat(u.TotalOut, 'f', -1, 64), it.Unit, u.Count),
+			}},
+		}
+		b.Entry = append(b.Entry, FHIREntry{FullURL: fhirRef(st.ID), Resource: st})
+	}
+	return b, nil
+}
+
+func fhirMedicationOf(it MedicineItem) FHIRMedication {
+	med := FHIRMedication{
+		ResourceType: "Medication", ID: it.ID, Status: "active",
+		Identifier: []fhirIdentity{{System: fhirLocalSystem, Value: it.ID}},
+		Code:       fhirCodeableConcept{Text: it.Name},
+	}
+	if it.GTIN != nil {
+		if g, err := NormalizeGTIN(*it.GTIN); err == nil {
+			med.Code.Coding = []fhirCoding{{System: fhirGTINSystem, Code: g, Display: it.Name}}
+		}
+	}
+	if it.Form != "" {
+		med.Form = &fhirCodeableConcept{Text: string(it.Form)}
+	}
+	if it.GenericName != nil {
+		text := *it.GenericName
+		if it.Strength != nil {
+			text += " " + *it.Strength
+		}
+		med.Ingredient = []fhirIngredient{{ItemCodeableConcept: fhirCodeableConcept{Text: text}, IsActive: true}}
+	}
+	return med
+}
+
+func (s *Service) fhirRegimenStatement(rg Regimen, it MedicineItem, now time.Time) FHIRMedicationStatement {
+	y, m, d := now.In(s.loc()).Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, time.UTC) // end_date อ่านจาก DB เป็นเที่ยงคืน UTC
+	status := "completed"
+	if rg.IsActive && (rg.EndDate == nil || !rg.EndDate.Before(today)) {
+		status = "active"
+	}
+	period := &fhirPeriod{Start: rg.StartDate.Format("2006-01-02")}
+	if rg.EndDate != nil {
+		period.End = rg.EndDate.Format("2006-01-02")
+	}
+	rep := fhirTimingRepeat{Frequency: len(rg.Times), Period: 1, PeriodUnit: "d"}
+	for _, t := range rg.Times {
+		rep.TimeOfDay = append(rep.TimeOfDay, t+":00")
+	}
+	for _, d := range rg.DaysOfWeek {
+		if d >= 0 && d < len(fhirDays) {
+			rep.DayOfWeek = append(rep.DayOfWeek, fhirDays[d])
+		}
+	}
+	st := FHIRMedicationStatement{
+		ResourceType:        "MedicationStatement",
+		ID:                  rg.ID,
+		Status:              status,
+		MedicationReference: fhirReference{Reference: fhirRef(it.ID), Display: it.Name},
+		Subject:             fhirReference{Display: rg.PersonName},
+		EffectivePeriod:     period,
+		DateAsserted:        now.Format(fhirDateTimeForm),
+		Dosage: []fhirDosage{{
+			Text:        fmt.Sprintf("%s %s at %v", strconv.FormatFloat(rg.Dose, 'f', -1, 64), rg.Unit, rg.Times),
+			Timing:      &fhirTiming{Repeat: rep},
+			DoseAndRate: []fhirDoseAndRate{{DoseQuantity: fhirQuantity{Value: rg.Dose, Unit: rg.Unit}}},
+		}},
+	}
+	if rg.Notes != nil && *rg.Notes != "" {
+		st.Note = []fhirAnnotation{{Text: *rg.Notes}}
+	}
+	return st
+}
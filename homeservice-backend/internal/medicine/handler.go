@@ -1,10 +1,18 @@
 package medicine
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"path"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/iMookatayou/homeservice-backend/internal/auth"
 	"github.com/iMookatayou/homeservice-backend/internal/httpx"
 )
 
@@ -33,16 +41,54 @@ func MountHTTP(r chi.Router, svc *Service) {
 		r.Get("/", h.getAlert)
 	})
 
+	r.Get("/lookup", h.lookup)        // ?gtin=
+	r.Post("/scan", h.scan)           // สแกนกล่องแล้วรับเข้าในครั้งเดียว (สร้างยาใหม่ถ้ายังไม่มี)
+	r.Get("/restock", h.restockNeeds) // ?item_id=...&item_id=... (ว่าง = ทุกตัวที่ต่ำกว่าขั้นต่ำ)
+
 	r.Get("/locations", h.listLocations)
 	r.Post("/locations", h.createLocation)
+
+	// ตารางกินยา + บันทึกการกิน
+	r.Get("/regimens", h.listRegimens) // ?item_id=&person_user_id=&active=1
+	r.Post("/regimens", h.createRegimen)
+	r.Put("/regimens/{rid}", h.updateRegimen)
+	r.Delete("/regimens/{rid}", h.stopRegimen)
+	r.Post("/regimens/{rid}/doses", h.logDose)
+	r.Get("/doses", h.listDoses) // ?from=YYYY-MM-DD&to=YYYY-MM-DD&person_user_id= (ว่าง = วันนี้)
+
+	// ส่งออก/นำเข้าตู้ยา
+	r.Get("/export/csv", h.exportCSV)
+	r.Get("/export/fhir", h.exportFHIR) // ?type=Medication|MedicationStatement (ว่าง = ทั้งคู่)
+	r.Post("/import", h.importCSV)      // multipart "file"; ?dry_run=1 = ตรวจอย่างเดียว
+
+	// รอบตรวจนับสต็อก
+	r.Get("/stocktakes", h.listStocktakes) // ?location_id=
+	r.Post("/stocktakes", h.openStocktake)
+	r.Get("/stocktakes/report", h.stocktakeReport) // ?from=&to=&location_id=&item_id=
+	r.Get("/stocktakes/{sid}", h.getStocktake)
+	r.Put("/stocktakes/{sid}/counts", h.recordCounts)
+	r.Post("/stocktakes/{sid}/commit", h.commitStocktake)
+	r.Delete("/stocktakes/{sid}", h.cancelStocktake)
 }
 
 type Handler struct {
 	svc *Service
 }
 
+// householdFrom: household ที่ผ่าน auth.RequireHousehold แล้ว
+func householdFrom(r *http.Request) string {
+	hid, _ := auth.HouseholdIDFrom(r)
+	return hid
+}
+
+// actorFrom: ผู้ทำรายการคือผู้ใช้จาก token เสมอ (ไม่เชื่อ actor_user_id จาก body)
+func actorFrom(r *http.Request) string {
+	uid, _ := auth.UserIDFrom(r)
+	return uid
+}
+
 func (h *Handler) listItems(w http.ResponseWriter, r *http.Request) {
-	householdID := r.Header.Get("X-Debug-Household")
+	householdID := householdFrom(r)
 	f := ListItemFilter{
 		Query:        r.URL.Query().Get("q"),
 		Category:     r.URL.Query().Get("category"),
@@ -62,7 +108,7 @@ func (h *Handler) listItems(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) createItem(w http.ResponseWriter, r *http.Request) {
-	householdID := r.Header.Get("X-Debug-Household")
+	householdID := householdFrom(r)
 	var it MedicineItem
 	if err := httpx.BindJSON(r, &it); err != nil {
 		httpx.JSON(w, 400, map[string]any{"error": "invalid JSON"})
@@ -79,7 +125,7 @@ func (h *Handler) createItem(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) getItem(w http.ResponseWriter, r *http.Request) {
-	householdID := r.Header.Get("X-Debug-Household")
+	householdID := householdFrom(r)
 	id := chi.URLParam(r, "id")
 
 	item, err := h.svc.GetItemFull(r.Context(), householdID, id)
@@ -91,26 +137,48 @@ func (h *Handler) getItem(w http.ResponseWriter, r *http.Request) {
 		httpx.JSON(w, code, map[string]any{"error": err.Error()})
 		return
 	}
+	httpx.SetETag(w, item.Item.Version)
 	httpx.JSON(w, 200, item)
 }
 
 func (h *Handler) updateItem(w http.ResponseWriter, r *http.Request) {
-	householdID := r.Header.Get("X-Debug-Household")
+	householdID := householdFrom(r)
 	id := chi.URLParam(r, "id")
 	var patch map[string]any
 	if err := httpx.BindJSON(r, &patch); err != nil {
 		httpx.JSON(w, 400, map[string]any{"error": "invalid JSON"})
 		return
 	}
-	if err := h.svc.UpdateItemPartial(r.Context(), householdID, id, patch); err != nil {
+	ifMatch, err := httpx.IfMatch(r)
+	if err != nil {
 		httpx.JSON(w, 400, map[string]any{"error": err.Error()})
 		return
 	}
-	httpx.JSON(w, 200, map[string]any{"updated": true})
+	version, err := h.svc.UpdateItemPartial(r.Context(), householdID, id, ifMatch, patch)
+	if err != nil {
+		// 409/412 ตอบรายละเอียดล่าสุดให้ client merge แล้วส่งใหม่
+		var ce *ConflictError
+		switch {
+		case errors.As(err, &ce):
+			code := 409
+			if errors.Is(err, ErrPreconditionFailed) {
+				code = 412
+			}
+			httpx.SetETag(w, ce.Current.Item.Version)
+			httpx.JSON(w, code, map[string]any{"error": err.Error(), "current": ce.Current})
+		case err == ErrNotFound:
+			httpx.JSON(w, 404, map[string]any{"error": err.Error()})
+		default:
+			httpx.JSON(w, 400, map[string]any{"error": err.Error()})
+		}
+		return
+	}
+	httpx.SetETag(w, version)
+	httpx.JSON(w, 200, map[string]any{"updated": true, "version": version})
 }
 
 func (h *Handler) archiveItem(w http.ResponseWriter, r *http.Request) {
-	householdID := r.Header.Get("X-Debug-Household")
+	householdID := householdFrom(r)
 	id := chi.URLParam(r, "id")
 	if err := h.svc.ArchiveItem(r.Context(), householdID, id); err != nil {
 		httpx.JSON(w, 400, map[string]any{"error": err.Error()})
@@ -122,7 +190,7 @@ func (h *Handler) archiveItem(w http.ResponseWriter, r *http.Request) {
 // ------------------- Batch -------------------
 
 func (h *Handler) addBatch(w http.ResponseWriter, r *http.Request) {
-	householdID := r.Header.Get("X-Debug-Household")
+	householdID := householdFrom(r)
 	itemID := chi.URLParam(r, "id")
 	var b MedicineBatch
 	if err := httpx.BindJSON(r, &b); err != nil {
@@ -140,6 +208,10 @@ func (h *Handler) addBatch(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) listBatches(w http.ResponseWriter, r *http.Request) {
 	itemID := chi.URLParam(r, "id")
+	if err := h.svc.ensureItem(r.Context(), householdFrom(r), itemID); err != nil {
+		httpx.JSON(w, 404, map[string]any{"error": err.Error()})
+		return
+	}
 	bs, err := h.svc.Repo.GetBatchesByItem(r.Context(), itemID)
 	if err != nil {
 		httpx.JSON(w, 500, map[string]any{"error": err.Error()})
@@ -156,13 +228,13 @@ func (h *Handler) receiveIn(w http.ResponseWriter, r *http.Request) {
 		BatchID string  `json:"batch_id"`
 		Qty     float64 `json:"qty"`
 		Reason  *string `json:"reason"`
-		Actor   string  `json:"actor_user_id"`
+		Actor   string  `json:"actor_user_id"` // ไม่ใช้แล้ว: ใช้ผู้ใช้จาก token
 	}
 	if err := httpx.BindJSON(r, &payload); err != nil {
 		httpx.JSON(w, 400, map[string]any{"error": "invalid JSON"})
 		return
 	}
-	res, err := h.svc.ReceiveIn(r.Context(), itemID, payload.BatchID, payload.Qty, payload.Reason, payload.Actor)
+	res, err := h.svc.ReceiveIn(r.Context(), householdFrom(r), itemID, payload.BatchID, payload.Qty, payload.Reason, actorFrom(r))
 	if err != nil {
 		httpx.JSON(w, 400, map[string]any{"error": err.Error()})
 		return
@@ -175,15 +247,23 @@ func (h *Handler) useOut(w http.ResponseWriter, r *http.Request) {
 	var payload struct {
 		Qty    float64 `json:"qty"`
 		Reason *string `json:"reason"`
-		Actor  string  `json:"actor_user_id"`
+		Force  bool    `json:"force"`         // ยอมเบิกจาก batch ที่หมดอายุแล้ว
+		Actor  string  `json:"actor_user_id"` // ไม่ใช้แล้ว: ใช้ผู้ใช้จาก token
 	}
 	if err := httpx.BindJSON(r, &payload); err != nil {
 		httpx.JSON(w, 400, map[string]any{"error": "invalid JSON"})
 		return
 	}
-	res, err := h.svc.UseOut(r.Context(), itemID, payload.Qty, payload.Reason, payload.Actor)
+	res, err := h.svc.UseOut(r.Context(), householdFrom(r), itemID, payload.Qty, payload.Reason, actorFrom(r), payload.Force)
 	if err != nil {
-		httpx.JSON(w, 400, map[string]any{"error": err.Error()})
+		code := 400
+		switch err {
+		case ErrNotFound:
+			code = 404
+		case ErrNoStock, ErrExpiredStock:
+			code = 409
+		}
+		httpx.JSON(w, code, map[string]any{"error": err.Error()})
 		return
 	}
 	httpx.JSON(w, 200, res)
@@ -195,13 +275,13 @@ func (h *Handler) adjust(w http.ResponseWriter, r *http.Request) {
 		BatchID string  `json:"batch_id"`
 		Delta   float64 `json:"delta"`
 		Reason  *string `json:"reason"`
-		Actor   string  `json:"actor_user_id"`
+		Actor   string  `json:"actor_user_id"` // ไม่ใช้แล้ว: ใช้ผู้ใช้จาก token
 	}
 	if err := httpx.BindJSON(r, &payload); err != nil {
 		httpx.JSON(w, 400, map[string]any{"error": "invalid JSON"})
 		return
 	}
-	res, err := h.svc.Adjust(r.Context(), itemID, payload.BatchID, payload.Delta, payload.Reason, payload.Actor)
+	res, err := h.svc.Adjust(r.Context(), householdFrom(r), itemID, payload.BatchID, payload.Delta, payload.Reason, actorFrom(r))
 	if err != nil {
 		httpx.JSON(w, 400, map[string]any{"error": err.Error()})
 		return
@@ -222,7 +302,7 @@ func (h *Handler) setAlert(w http.ResponseWriter, r *http.Request) {
 		httpx.JSON(w, 400, map[string]any{"error": "invalid JSON"})
 		return
 	}
-	if err := h.svc.SetAlert(r.Context(), itemID, payload.MinQty, payload.ExpiryDays, payload.IsEnabled); err != nil {
+	if err := h.svc.SetAlert(r.Context(), householdFrom(r), itemID, payload.MinQty, payload.ExpiryDays, payload.IsEnabled); err != nil {
 		httpx.JSON(w, 400, map[string]any{"error": err.Error()})
 		return
 	}
@@ -231,6 +311,10 @@ func (h *Handler) setAlert(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) getAlert(w http.ResponseWriter, r *http.Request) {
 	itemID := chi.URLParam(r, "id")
+	if err := h.svc.ensureItem(r.Context(), householdFrom(r), itemID); err != nil {
+		httpx.JSON(w, 404, map[string]any{"error": err.Error()})
+		return
+	}
 	al, err := h.svc.Repo.GetAlert(r.Context(), itemID)
 	if err != nil {
 		httpx.JSON(w, 400, map[string]any{"error": err.Error()})
@@ -239,10 +323,19 @@ func (h *Handler) getAlert(w http.ResponseWriter, r *http.Request) {
 	httpx.JSON(w, 200, al)
 }
 
+func (h *Handler) restockNeeds(w http.ResponseWriter, r *http.Request) {
+	needs, err := h.svc.RestockNeeds(r.Context(), householdFrom(r), r.URL.Query()["item_id"])
+	if err != nil {
+		httpx.JSON(w, 500, map[string]any{"error": err.Error()})
+		return
+	}
+	httpx.JSON(w, 200, needs)
+}
+
 // ------------------- Locations -------------------
 
 func (h *Handler) listLocations(w http.ResponseWriter, r *http.Request) {
-	householdID := r.Header.Get("X-Debug-Household")
+	householdID := householdFrom(r)
 	locs, err := h.svc.Repo.ListLocations(r.Context(), householdID)
 	if err != nil {
 		httpx.JSON(w, 400, map[string]any{"error": err.Error()})
@@ -252,7 +345,7 @@ func (h *Handler) listLocations(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) createLocation(w http.ResponseWriter, r *http.Request) {
-	householdID := r.Header.Get("X-Debug-Household")
+	householdID := householdFrom(r)
 	var loc MedicineLocation
 	if err := httpx.BindJSON(r, &loc); err != nil {
 		httpx.JSON(w, 400, map[string]any{"error": "invalid JSON"})
@@ -266,3 +359,372 @@ func (h *Handler) createLocation(w http.ResponseWriter, r *http.Request) {
 	}
 	httpx.JSON(w, 201, loc)
 }
+
+// ------------------- Regimens / Doses -------------------
+
+// regimenPayload วันที่รับเป็น YYYY-MM-DD (ตามปฏิทินของบ้าน)
+type regimenPayload struct {
+	ItemID       string   `json:"item_id"`
+	PersonUserID *string  `json:"person_user_id"`
+	PersonName   string   `json:"person_name"`
+	Dose         float64  `json:"dose"`
+	Unit         string   `json:"unit"`
+	Times        []string `json:"times"`
+	DaysOfWeek   []int    `json:"days_of_week"`
+	StartDate    string   `json:"start_date"`
+	EndDate      string   `json:"end_date"`
+	Notes        *string  `json:"notes"`
+	IsActive     *bool    `json:"is_active"`
+}
+
+func (p regimenPayload) regimen() (Regimen, error) {
+	rg := Regimen{
+		ItemID: p.ItemID, PersonUserID: p.PersonUserID, PersonName: p.PersonName,
+		Dose: p.Dose, Unit: p.Unit, Times: p.Times, DaysOfWeek: p.DaysOfWeek, Notes: p.Notes, IsActive: true,
+	}
+	if p.IsActive != nil {
+		rg.IsActive = *p.IsActive
+	}
+	if p.StartDate != "" {
+		t, err := time.Parse("2006-01-02", p.StartDate)
+		if err != nil {
+			return rg, fmt.Errorf("start_date must be YYYY-MM-DD")
+		}
+		rg.StartDate = t
+	}
+	if p.EndDate != "" {
+		t, err := time.Parse("2006-01-02", p.EndDate)
+		if err != nil {
+			return rg, fmt.Errorf("end_date must be YYYY-MM-DD")
+		}
+		rg.EndDate = &t
+	}
+	return rg, nil
+}
+
+// errCode: ErrBadInput ถูก wrap ด้วยรายละเอียด จึงใช้ errors.Is
+func errCode(err error) int {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return 404
+	case errors.Is(err, ErrBadInput):
+		return 400
+	case errors.Is(err, ErrConflict), errors.Is(err, ErrNoStock), errors.Is(err, ErrExpiredStock):
+		return 409
+	}
+	return 500
+}
+
+func (h *Handler) listRegimens(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	regs, err := h.svc.Repo.ListRegimens(r.Context(), householdFrom(r), q.Get("item_id"), q.Get("person_user_id"), q.Get("active") == "1")
+	if err != nil {
+		httpx.JSON(w, 500, map[string]any{"error": err.Error()})
+		return
+	}
+	httpx.JSON(w, 200, regs)
+}
+
+func (h *Handler) createRegimen(w http.ResponseWriter, r *http.Request) {
+	var p regimenPayload
+	if err := httpx.BindJSON(r, &p); err != nil {
+		httpx.JSON(w, 400, map[string]any{"error": "invalid JSON"})
+		return
+	}
+	rg, err := p.regimen()
+	if err != nil {
+		httpx.JSON(w, 400, map[string]any{"error": err.Error()})
+		return
+	}
+	rg.HouseholdID = householdFrom(r)
+	rg.CreatedBy = actorFrom(r)
+	if err := h.svc.CreateRegimen(r.Context(), &rg); err != nil {
+		httpx.JSON(w, errCode(err), map[string]any{"error": err.Error()})
+		return
+	}
+	httpx.JSON(w, 201, rg)
+}
+
+func (h *Handler) updateRegimen(w http.ResponseWriter, r *http.Request) {
+	var p regimenPayload
+	if err := httpx.BindJSON(r, &p); err != nil {
+		httpx.JSON(w, 400, map[string]any{"error": "invalid JSON"})
+		return
+	}
+	in, err := p.regimen()
+	if err != nil {
+		httpx.JSON(w, 400, map[string]any{"error": err.Error()})
+		return
+	}
+	rg, err := h.svc.UpdateRegimen(r.Context(), householdFrom(r), chi.URLParam(r, "rid"), in)
+	if err != nil {
+		httpx.JSON(w, errCode(err), map[string]any{"error": err.Error()})
+		return
+	}
+	httpx.JSON(w, 200, rg)
+}
+
+func (h *Handler) stopRegimen(w http.ResponseWriter, r *http.Request) {
+	if err := h.svc.StopRegimen(r.Context(), householdFrom(r), chi.URLParam(r, "rid")); err != nil {
+		httpx.JSON(w, errCode(err), map[string]any{"error": err.Error()})
+		return
+	}
+	httpx.JSON(w, 200, map[string]any{"stopped": true})
+}
+
+func (h *Handler) logDose(w http.ResponseWriter, r *http.Request) {
+	var in DoseInput
+	if err := httpx.BindJSON(r, &in); err != nil {
+		httpx.JSON(w, 400, map[string]any{"error": "invalid JSON"})
+		return
+	}
+	dl, err := h.svc.LogDose(r.Context(), householdFrom(r), chi.URLParam(r, "rid"), in, actorFrom(r))
+	if err != nil {
+		httpx.JSON(w, errCode(err), map[string]any{"error": err.Error()})
+		return
+	}
+	httpx.JSON(w, 201, dl)
+}
+
+func (h *Handler) listDoses(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	loc := h.svc.loc()
+	now := h.svc.Now().In(loc)
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	to := from.AddDate(0, 0, 1)
+	if v := q.Get("from"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, loc)
+		if err != nil {
+			httpx.JSON(w, 400, map[string]any{"error": "from must be YYYY-MM-DD"})
+			return
+		}
+		from, to = t, t.AddDate(0, 0, 1)
+	}
+	if v := q.Get("to"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, loc)
+		if err != nil {
+			httpx.JSON(w, 400, map[string]any{"error": "to must be YYYY-MM-DD"})
+			return
+		}
+		to = t.AddDate(0, 0, 1) // รวมวัน to
+	}
+	doses, err := h.svc.Schedule(r.Context(), householdFrom(r), q.Get("person_user_id"), from, to)
+	if err != nil {
+		httpx.JSON(w, errCode(err), map[string]any{"error": err.Error()})
+		return
+	}
+	httpx.JSON(w, 200, doses)
+}
+
+// ------------------- Barcode -------------------
+
+func (h *Handler) lookup(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.Lookup(r.Context(), householdFrom(r), r.URL.Query().Get("gtin"))
+	if err != nil {
+		httpx.JSON(w, errCode(err), map[string]any{"error": err.Error()})
+		return
+	}
+	httpx.JSON(w, 200, res)
+}
+
+func (h *Handler) scan(w http.ResponseWriter, r *http.Request) {
+	var in ScanInput
+	if err := httpx.BindJSON(r, &in); err != nil {
+		httpx.JSON(w, 400, map[string]any{"error": "invalid JSON"})
+		return
+	}
+	res, err := h.svc.QuickAdd(r.Context(), householdFrom(r), in, actorFrom(r))
+	if err != nil {
+		httpx.JSON(w, errCode(err), map[string]any{"error": err.Error()})
+		return
+	}
+	code := 200
+	if res.Created {
+		code = 201
+	}
+	httpx.JSON(w, code, res)
+}
+
+// CatalogHandler endpoint ของ admin ระบบสำหรับนำเข้าแคตตาล็อกบาร์โค้ด
+type CatalogHandler struct{ Catalog *PGCatalog }
+
+// RegisterAdminRoutes — ใช้ใต้กลุ่ม admin
+func (h CatalogHandler) RegisterAdminRoutes(r chi.Router) {
+	r.Post("/admin/medicine/catalog/import", h.Import) // multipart "file" (.json/.csv) หรือ ?format=
+}
+
+func (h CatalogHandler) Import(w http.ResponseWriter, r *http.Request) {
+	f, fh, err := r.FormFile("file")
+	if err != nil {
+		httpx.JSON(w, 400, map[string]any{"error": "multipart field 'file' required"})
+		return
+	}
+	defer f.Close()
+
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(path.Ext(fh.Filename)), ".")
+	}
+	entries, skipped, err := ParseCatalog(f, format)
+	if err != nil {
+		httpx.JSON(w, 400, map[string]any{"error": err.Error()})
+		return
+	}
+	n, err := h.Catalog.Import(r.Context(), entries, r.URL.Query().Get("source"))
+	if err != nil {
+		httpx.JSON(w, 500, map[string]any{"error": err.Error()})
+		return
+	}
+	httpx.JSON(w, 200, map[string]any{"imported": n, "skipped": skipped})
+}
+
+// ------------------- Stocktake -------------------
+
+func (h *Handler) listStocktakes(w http.ResponseWriter, r *http.Request) {
+	sts, err := h.svc.Repo.ListStocktakes(r.Context(), householdFrom(r), r.URL.Query().Get("location_id"))
+	if err != nil {
+		httpx.JSON(w, 500, map[string]any{"error": err.Error()})
+		return
+	}
+	httpx.JSON(w, 200, sts)
+}
+
+func (h *Handler) openStocktake(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		LocationID string  `json:"location_id"`
+		Note       *string `json:"note"`
+	}
+	if err := httpx.BindJSON(r, &payload); err != nil {
+		httpx.JSON(w, 400, map[string]any{"error": "invalid JSON"})
+		return
+	}
+	st, err := h.svc.OpenStocktake(r.Context(), householdFrom(r), payload.LocationID, payload.Note, actorFrom(r))
+	if err != nil {
+		httpx.JSON(w, errCode(err), map[string]any{"error": err.Error()})
+		return
+	}
+	httpx.JSON(w, 201, st)
+}
+
+func (h *Handler) getStocktake(w http.ResponseWriter, r *http.Request) {
+	st, err := h.svc.GetStocktake(r.Context(), householdFrom(r), chi.URLParam(r, "sid"))
+	if err != nil {
+		httpx.JSON(w, errCode(err), map[string]any{"error": err.Error()})
+		return
+	}
+	httpx.JSON(w, 200, st)
+}
+
+func (h *Handler) recordCounts(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Counts []StocktakeCount `json:"counts"`
+	}
+	if err := httpx.BindJSON(r, &payload); err != nil {
+		httpx.JSON(w, 400, map[string]any{"error": "invalid JSON"})
+		return
+	}
+	st, err := h.svc.RecordCounts(r.Context(), householdFrom(r), chi.URLParam(r, "sid"), payload.Counts, actorFrom(r))
+	if err != nil {
+		httpx.JSON(w, errCode(err), map[string]any{"error": err.Error()})
+		return
+	}
+	httpx.JSON(w, 200, st)
+}
+
+func (h *Handler) commitStocktake(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		ZeroUncounted bool `json:"zero_uncounted"` // batch ที่ไม่ได้นับ = หาไม่เจอ (ปรับเป็น 0)
+	}
+	if r.ContentLength != 0 {
+		if err := httpx.BindJSON(r, &payload); err != nil {
+			httpx.JSON(w, 400, map[string]any{"error": "invalid JSON"})
+			return
+		}
+	}
+	st, txns, err := h.svc.CommitStocktake(r.Context(), householdFrom(r), chi.URLParam(r, "sid"), actorFrom(r), payload.ZeroUncounted)
+	if err != nil {
+		httpx.JSON(w, errCode(err), map[string]any{"error": err.Error()})
+		return
+	}
+	httpx.JSON(w, 200, map[string]any{"stocktake": st, "txns": txns})
+}
+
+func (h *Handler) cancelStocktake(w http.ResponseWriter, r *http.Request) {
+	if err := h.svc.Repo.CancelStocktake(r.Context(), householdFrom(r), chi.URLParam(r, "sid")); err != nil {
+		httpx.JSON(w, errCode(err), map[string]any{"error": err.Error()})
+		return
+	}
+	httpx.JSON(w, 200, map[string]any{"cancelled": true})
+}
+
+func (h *Handler) stocktakeReport(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var from, to *time.Time
+	for _, it := range []struct {
+		key  string
+		dst  **time.Time
+		days int
+	}{{"from", &from, 0}, {"to", &to, 1}} { // to รวมทั้งวัน
+		v := q.Get(it.key)
+		if v == "" {
+			continue
+		}
+		t, err := time.ParseInLocation("2006-01-02", v, h.svc.loc())
+		if err != nil {
+			httpx.JSON(w, 400, map[string]any{"error": it.key + " must be YYYY-MM-DD"})
+			return
+		}
+		t = t.AddDate(0, 0, it.days)
+		*it.dst = &t
+	}
+	rp, err := h.svc.StocktakeReport(r.Context(), householdFrom(r), q.Get("location_id"), q.Get("item_id"), from, to)
+	if err != nil {
+		httpx.JSON(w, 500, map[string]any{"error": err.Error()})
+		return
+	}
+	httpx.JSON(w, 200, rp)
+}
+
+// ------------------- Export / Import -------------------
+
+func (h *Handler) exportCSV(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer // เขียนครบก่อนส่ง: error กลางทางยังตอบ 500 ได้
+	if err := h.svc.ExportCSV(r.Context(), householdFrom(r), &buf); err != nil {
+		httpx.JSON(w, 500, map[string]any{"error": err.Error()})
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="medicine-`+h.svc.Now().In(h.svc.loc()).Format("20060102")+`.csv"`)
+	_, _ = w.Write(buf.Bytes())
+}
+
+func (h *Handler) exportFHIR(w http.ResponseWriter, r *http.Request) {
+	b, err := h.svc.ExportFHIR(r.Context(), householdFrom(r), r.URL.Query().Get("type"))
+	if err != nil {
+		httpx.JSON(w, errCode(err), map[string]any{"error": err.Error()})
+		return
+	}
+	w.Header().Set("Content-Type", "application/fhir+json")
+	_ = json.NewEncoder(w).Encode(b)
+}
+
+func (h *Handler) importCSV(w http.ResponseWriter, r *http.Request) {
+	f, _, err := r.FormFile("file")
+	if err != nil {
+		httpx.JSON(w, 400, map[string]any{"error": "multipart field 'file' required"})
+		return
+	}
+	defer f.Close()
+	dryRun := r.URL.Query().Get("dry_run") == "1"
+	rep, err := h.svc.ImportCSV(r.Context(), householdFrom(r), f, dryRun, actorFrom(r))
+	if err != nil {
+		httpx.JSON(w, errCode(err), map[string]any{"error": err.Error()})
+		return
+	}
+	// มีแถวผิด = ไม่ได้เขียนอะไร; ส่งรายงานให้แก้ไฟล์แล้วส่งใหม่
+	if !dryRun && !rep.Applied {
+		httpx.JSON(w, 422, map[string]any{"error": "invalid rows; nothing imported", "report": rep})
+		return
+	}
+	httpx.JSON(w, 200, rep)
+}
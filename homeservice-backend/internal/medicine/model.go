@@ -17,6 +17,14 @@ const (
 	FormOther    Form = "other"
 )
 
+func (f Form) valid() bool {
+	switch f {
+	case FormTablet, FormCapsule, FormSyrup, FormOintment, FormSpray, FormDrop, FormOther:
+		return true
+	}
+	return false
+}
+
 type TxnType string
 
 const (
@@ -44,6 +52,7 @@ type MedicineItem struct {
 	IsArchived  bool      `json:"is_archived"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+	Version     int       `json:"version"` // = ETag; เพิ่มทุกครั้งที่แถวถูกแก้
 }
 
 // MedicineBatch = ล็อตของยา (วันหมดอายุ/เลขล็อต/คงเหลือ)
@@ -60,14 +69,16 @@ type MedicineBatch struct {
 
 // MedicineTxn = ธุรกรรมขยับสต็อก (บันทึก audit)
 type MedicineTxn struct {
-	ID        string    `json:"id"`
-	ItemID    string    `json:"item_id"`
-	BatchID   *string   `json:"batch_id,omitempty"`
-	ActorID   string    `json:"actor_user_id"`
-	Type      TxnType   `json:"type"`       // in|out|adjust
-	QtyChange float64   `json:"qty_change"` // ค่าบวก/ลบ (+in, -out, ±adjust)
-	Reason    *string   `json:"reason,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
+	ID          string    `json:"id"`
+	ItemID      string    `json:"item_id"`
+	BatchID     *string   `json:"batch_id,omitempty"`
+	ActorID     string    `json:"actor_user_id"`
+	Type        TxnType   `json:"type"`       // in|out|adjust
+	QtyChange   float64   `json:"qty_change"` // ค่าบวก/ลบ (+in, -out, ±adjust)
+	Reason      *string   `json:"reason,omitempty"`
+	DoseID      *string   `json:"dose_id,omitempty"`      // มาจากการกินยาตาม Regimen
+	StocktakeID *string   `json:"stocktake_id,omitempty"` // มาจากการ commit รอบตรวจนับ
+	CreatedAt   time.Time `json:"created_at"`
 }
 
 // MedicineLocation = ตำแหน่งเก็บยาในบ้าน (เช่น ตู้ยา, ห้องครัว)
@@ -90,6 +101,154 @@ type MedicineAlert struct {
 	UpdatedAt        time.Time `json:"updated_at"`
 }
 
+// Regimen = ตารางการใช้ยาของคนหนึ่งคน (เช่น ยายกินยาความดันวันละ 2 ครั้ง)
+type Regimen struct {
+	ID           string     `json:"id"`
+	HouseholdID  string     `json:"household_id"`
+	ItemID       string     `json:"item_id"`
+	PersonUserID *string    `json:"person_user_id,omitempty"` // สมาชิกบ้านที่มีบัญชี (ถ้ามี)
+	PersonName   string     `json:"person_name"`
+	Dose         float64    `json:"dose"`                   // ต่อครั้ง
+	Unit         string     `json:"unit"`                   // ต้องตรงกับ Item.Unit (ตัดสต็อกตรง ๆ)
+	Times        []string   `json:"times"`                  // "HH:MM" เวลาท้องถิ่นของบ้าน
+	DaysOfWeek   []int      `json:"days_of_week,omitempty"` // 0=อาทิตย์..6=เสาร์; ว่าง = ทุกวัน
+	StartDate    time.Time  `json:"start_date"`
+	EndDate      *time.Time `json:"end_date,omitempty"` // รวมวันนี้ด้วย
+	Notes        *string    `json:"notes,omitempty"`
+	IsActive     bool       `json:"is_active"`
+	CreatedBy    string     `json:"created_by,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+type DoseStatus string
+
+const (
+	DosePending DoseStatus = "pending" // ยังไม่ถึง/ยังไม่เลยกำหนด (คำนวณ ไม่เก็บใน DB)
+	DoseTaken   DoseStatus = "taken"
+	DoseSkipped DoseStatus = "skipped"
+	DoseMissed  DoseStatus = "missed"
+)
+
+// DoseLog = บันทึกของนัดกินยาหนึ่งครั้ง
+type DoseLog struct {
+	ID          string        `json:"id"`
+	RegimenID   string        `json:"regimen_id"`
+	ScheduledAt time.Time     `json:"scheduled_at"`
+	Status      DoseStatus    `json:"status"`
+	TakenAt     *time.Time    `json:"taken_at,omitempty"`
+	ActorID     *string       `json:"actor_user_id,omitempty"`
+	Note        *string       `json:"note,omitempty"`
+	Txns        []MedicineTxn `json:"txns,omitempty"` // txn out ที่ตัดสต็อกให้นัดนี้
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+// DueDose = นัดหนึ่งครั้งจากตาราง รวมสถานะจาก log
+type DueDose struct {
+	RegimenID   string     `json:"regimen_id"`
+	ItemID      string     `json:"item_id"`
+	PersonName  string     `json:"person_name"`
+	Dose        float64    `json:"dose"`
+	Unit        string     `json:"unit"`
+	ScheduledAt time.Time  `json:"scheduled_at"`
+	Status      DoseStatus `json:"status"`
+	Log         *DoseLog   `json:"log,omitempty"`
+}
+
+type StocktakeStatus string
+
+const (
+	StocktakeOpen      StocktakeStatus = "open"
+	StocktakeCommitted StocktakeStatus = "committed"
+	StocktakeCancelled StocktakeStatus = "cancelled"
+)
+
+// Stocktake = รอบตรวจนับสต็อกของที่เก็บหนึ่งแห่ง
+type Stocktake struct {
+	ID          string          `json:"id"`
+	HouseholdID string          `json:"household_id"`
+	LocationID  string          `json:"location_id"`
+	Status      StocktakeStatus `json:"status"`
+	Note        *string         `json:"note,omitempty"`
+	CreatedBy   *string         `json:"created_by,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	CommittedBy *string         `json:"committed_by,omitempty"`
+	CommittedAt *time.Time      `json:"committed_at,omitempty"`
+	Lines       []StocktakeLine `json:"lines,omitempty"`
+}
+
+// StocktakeLine = หนึ่ง batch ในรอบ: ยอดในระบบ เทียบกับที่นับได้
+// รอบที่ยังเปิด Expected = ยอดปัจจุบัน; commit แล้ว = ยอด ณ ตอน commit
+type StocktakeLine struct {
+	CountID  *string    `json:"count_id,omitempty"` // ว่าง = ยังไม่ได้นับ
+	BatchID  *string    `json:"batch_id,omitempty"` // ว่าง = batch ที่เพิ่งเจอ (สร้างตอน commit)
+	ItemID   string     `json:"item_id"`
+	ItemName string     `json:"item_name"`
+	Unit     string     `json:"unit"`
+	LotNo    *string    `json:"lot_no,omitempty"`
+	Expiry   *time.Time `json:"expiry_date,omitempty"`
+	Expected float64    `json:"expected_qty"`
+	Counted  *float64   `json:"counted_qty,omitempty"`
+	Variance *float64   `json:"variance,omitempty"` // counted - expected
+	IsNew    bool       `json:"is_new"`
+	Note     *string    `json:"note,omitempty"`
+}
+
+// StocktakeCount = จำนวนที่นับได้ ส่งมาทีละหลายรายการ
+// ระบุ BatchID สำหรับ batch ที่มีอยู่ หรือ ItemID (+LotNo/Expiry) สำหรับ batch ที่ระบบไม่รู้จัก
+type StocktakeCount struct {
+	BatchID *string    `json:"batch_id"`
+	ItemID  string     `json:"item_id"`
+	LotNo   *string    `json:"lot_no"`
+	Expiry  *time.Time `json:"expiry_date"`
+	Qty     float64    `json:"counted_qty"`
+	Note    *string    `json:"note"`
+}
+
+// StocktakeVariance = หนึ่งบรรทัดที่นับไม่ตรงจากรอบที่ commit แล้ว (ใช้ทำรายงาน)
+type StocktakeVariance struct {
+	StocktakeID string    `json:"stocktake_id"`
+	LocationID  string    `json:"location_id"`
+	CommittedAt time.Time `json:"committed_at"`
+	ItemID      string    `json:"item_id"`
+	ItemName    string    `json:"item_name"`
+	Unit        string    `json:"unit"`
+	BatchID     *string   `json:"batch_id,omitempty"`
+	LotNo       *string   `json:"lot_no,omitempty"`
+	Expected    float64   `json:"expected_qty"`
+	Counted     float64   `json:"counted_qty"`
+	Variance    float64   `json:"variance"`
+	IsNew       bool      `json:"is_new"`
+}
+
+// InventoryRow = หนึ่งแถวของตู้ยาแบบแบน (ใช้ export/import CSV)
+// ยาที่ไม่มี batch คงเหลือมีแถวเดียวที่ batch ว่าง; ที่เก็บที่ไม่มียามีแถวที่ item ว่าง
+type InventoryRow struct {
+	Location    *string
+	ItemID      *string
+	Name        *string
+	GenericName *string
+	Form        *string
+	Strength    *string
+	Category    *string
+	Unit        *string
+	GTIN        *string
+	Notes       *string
+	BatchID     *string
+	LotNo       *string
+	Expiry      *time.Time
+	Qty         *float64
+}
+
+// ItemUsage = สรุปการเบิกใช้ของยาหนึ่งตัวจากประวัติ txn out
+type ItemUsage struct {
+	ItemID   string
+	FirstOut time.Time
+	LastOut  time.Time
+	TotalOut float64 // ค่าบวก
+	Count    int
+}
+
 // ---------- Query Filters (List) ----------
 
 type ListItemFilter struct {
@@ -113,6 +272,14 @@ type ItemSummary struct {
 	Expiring   bool         `json:"expiring"`  // true เมื่อถึงกรอบ ExpiryWindowDays
 }
 
+// RestockNeed = ยาที่ต่ำกว่า min_qty พร้อมจำนวนที่แนะนำให้ซื้อ (เติมให้ถึง 2 เท่าของ min_qty)
+type RestockNeed struct {
+	Item      MedicineItem `json:"item"`
+	TotalQty  float64      `json:"total_qty"`
+	MinQty    float64      `json:"min_qty"`
+	Suggested float64      `json:"suggested_qty"`
+}
+
 // ItemDetail = สำหรับหน้า detail รวม batches, summary, alert
 type ItemDetail struct {
 	Item       MedicineItem    `json:"item"`
@@ -0,0 +1,269 @@
+// internal/medicine/regimen.go
+package medicine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultMissedAfter นัดที่เลยเวลาเกินเท่านี้โดยไม่มี log ถือว่าพลาด
+const DefaultMissedAfter = time.Hour
+
+// parseClock "HH:MM" -> ชั่วโมง, นาที
+func parseClock(s string) (int, int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: time %q must be HH:MM", ErrBadInput, s)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// Occurrences นัดของ regimen ใน [from, to) ตามเวลาท้องถิ่น loc เรียงตามเวลา
+// วันที่ start/end นับตามปฏิทินของ loc (end รวมวันนั้นด้วย); เวลาที่ไม่มีจริงตอนเข้า DST เลื่อนไปข้างหน้า (02:30 → 03:30)
+func (rg Regimen) Occurrences(from, to time.Time, loc *time.Location) []time.Time {
+	days := map[time.Weekday]bool{}
+	for _, d := range rg.DaysOfWeek {
+		days[time.Weekday(d)] = true
+	}
+	start := civil(rg.StartDate)
+	var end time.Time
+	if rg.EndDate != nil {
+		end = civil(*rg.EndDate).AddDate(0, 0, 1)
+	}
+
+	var out []time.Time
+	for day := civil(from.In(loc)); localAt(day, 0, 0, loc).Before(to); day = day.AddDate(0, 0, 1) {
+		if day.Before(start) || (!end.IsZero() && !day.Before(end)) {
+			continue
+		}
+		if len(days) > 0 && !days[day.Weekday()] {
+			continue
+		}
+		for _, c := range rg.Times {
+			h, m, err := parseClock(c)
+			if err != nil {
+				continue // validate ตอนบันทึกแล้ว
+			}
+			at := localAt(day, h, m, loc)
+			if !at.Before(from) && at.Before(to) {
+				out = append(out, at)
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Before(out[j]) })
+	return out
+}
+
+// civil วันที่บนปฏิทินของ t เป็น 00:00 UTC — ใช้นับวัน (เที่ยงคืนของบางเขตไม่มีจริงในวันเข้า DST)
+func civil(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// localAt เวลา h:m ของวัน day ใน loc; time.Date ปัดเวลาที่ไม่มีจริงถอยหลังได้ จึงเลื่อนไปข้างหน้าเท่าที่นาฬิกาขาดไป
+func localAt(day time.Time, h, m int, loc *time.Location) time.Time {
+	at := time.Date(day.Year(), day.Month(), day.Day(), h, m, 0, 0, loc)
+	want := time.Date(day.Year(), day.Month(), day.Day(), h, m, 0, 0, time.UTC)
+	got := time.Date(at.Year(), at.Month(), at.Day(), at.Hour(), at.Minute(), 0, 0, time.UTC)
+	return at.Add(want.Sub(got))
+}
+
+func (s *Service) loc() *time.Location {
+	if s.Location != nil {
+		return s.Location
+	}
+	return time.Local
+}
+
+func (s *Service) missedAfter() time.Duration {
+	if s.MissedAfter > 0 {
+		return s.MissedAfter
+	}
+	return DefaultMissedAfter
+}
+
+// validRegimen ตรวจและ normalize ค่าที่ผู้ใช้ส่งมา (หน่วยต้องตรงกับยา, เวลา HH:MM, วัน 0-6)
+func (s *Service) validRegimen(ctx context.Context, rg *Regimen) error {
+	it, err := s.Repo.GetItem(ctx, rg.HouseholdID, rg.ItemID)
+	if err != nil {
+		return err
+	}
+	if rg.Unit == "" {
+		rg.Unit = it.Unit
+	}
+	if rg.Dose <= 0 || rg.Unit != it.Unit || len(rg.Times) == 0 {
+		return ErrBadInput
+	}
+	seen := map[string]bool{}
+	times := make([]string, 0, len(rg.Times))
+	for _, c := range rg.Times {
+		h, m, err := parseClock(c)
+		if err != nil {
+			return err
+		}
+		if c = fmt.Sprintf("%02d:%02d", h, m); !seen[c] {
+			seen[c] = true
+			times = append(times, c)
+		}
+	}
+	sort.Strings(times)
+	rg.Times = times
+	for _, d := range rg.DaysOfWeek {
+		if d < 0 || d > 6 {
+			return fmt.Errorf("%w: days_of_week must be 0-6", ErrBadInput)
+		}
+	}
+	if rg.StartDate.IsZero() {
+		rg.StartDate = s.Now().In(s.loc())
+	}
+	if rg.EndDate != nil && rg.EndDate.Before(rg.StartDate) {
+		return fmt.Errorf("%w: end_date before start_date", ErrBadInput)
+	}
+
+	rg.PersonName = strings.TrimSpace(rg.PersonName)
+	if rg.PersonUserID != nil && *rg.PersonUserID == "" {
+		rg.PersonUserID = nil
+	}
+	if rg.PersonUserID != nil {
+		ok, err := s.Repo.IsMember(ctx, rg.HouseholdID, *rg.PersonUserID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%w: person_user_id is not a household member", ErrBadInput)
+		}
+	}
+	if rg.PersonName == "" {
+		return fmt.Errorf("%w: person_name required", ErrBadInput)
+	}
+	return nil
+}
+
+func (s *Service) CreateRegimen(ctx context.Context, rg *Regimen) error {
+	if rg == nil || rg.HouseholdID == "" {
+		return ErrBadInput
+	}
+	if err := s.validRegimen(ctx, rg); err != nil {
+		return err
+	}
+	return s.Repo.CreateRegimen(ctx, rg)
+}
+
+// UpdateRegimen แทนค่าที่แก้ได้ทั้งหมด (ยาของ regimen เปลี่ยนไม่ได้ — สร้างใหม่แทน)
+func (s *Service) UpdateRegimen(ctx context.Context, householdID, id string, in Regimen) (*Regimen, error) {
+	rg, err := s.Repo.GetRegimen(ctx, householdID, id)
+	if err != nil {
+		return nil, err
+	}
+	in.ID, in.HouseholdID, in.ItemID = rg.ID, rg.HouseholdID, rg.ItemID
+	if in.StartDate.IsZero() {
+		in.StartDate = rg.StartDate // ไม่ส่งมา = คงวันเริ่มเดิม
+	}
+	if err := s.validRegimen(ctx, &in); err != nil {
+		return nil, err
+	}
+	if err := s.Repo.UpdateRegimen(ctx, &in); err != nil {
+		return nil, err
+	}
+	return &in, nil
+}
+
+// StopRegimen ปิดตาราง (เก็บประวัติการกินไว้)
+func (s *Service) StopRegimen(ctx context.Context, householdID, id string) error {
+	rg, err := s.Repo.GetRegimen(ctx, householdID, id)
+	if err != nil {
+		return err
+	}
+	rg.IsActive = false
+	return s.Repo.UpdateRegimen(ctx, rg)
+}
+
+// Schedule นัดทั้งหมดของบ้านใน [from, to) รวมสถานะ: มี log ใช้ตาม log,
+// ไม่มี log และเลยเวลาเกิน MissedAfter = missed, ไม่งั้น pending
+func (s *Service) Schedule(ctx context.Context, householdID, personUserID string, from, to time.Time) ([]DueDose, error) {
+	if !to.After(from) || to.Sub(from) > 62*24*time.Hour {
+		return nil, fmt.Errorf("%w: range must be positive and at most 62 days", ErrBadInput)
+	}
+	regs, err := s.Repo.ListRegimens(ctx, householdID, "", personUserID, true)
+	if err != nil {
+		return nil, err
+	}
+	logs, err := s.Repo.DosesBetween(ctx, householdID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]*DoseLog, len(logs))
+	for i := range logs {
+		byKey[logs[i].RegimenID+"@"+logs[i].ScheduledAt.UTC().Format(time.RFC3339)] = &logs[i]
+	}
+
+	now := s.Now()
+	out := []DueDose{}
+	for _, rg := range regs {
+		for _, at := range rg.Occurrences(from, to, s.loc()) {
+			dd := DueDose{
+				RegimenID: rg.ID, ItemID: rg.ItemID, PersonName: rg.PersonName,
+				Dose: rg.Dose, Unit: rg.Unit, ScheduledAt: at, Status: DosePending,
+			}
+			if l := byKey[rg.ID+"@"+at.UTC().Format(time.RFC3339)]; l != nil {
+				dd.Status, dd.Log = l.Status, l
+			} else if now.Sub(at) > s.missedAfter() {
+				dd.Status = DoseMissed
+			}
+			out = append(out, dd)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].ScheduledAt.Before(out[j].ScheduledAt) })
+	return out, nil
+}
+
+// DoseInput = บันทึกผลของนัดหนึ่งครั้ง
+type DoseInput struct {
+	ScheduledAt time.Time  `json:"scheduled_at"`
+	Status      DoseStatus `json:"status"` // taken | skipped
+	Note        *string    `json:"note"`
+	Force       bool       `json:"force"` // taken: ยอมตัดจาก batch ที่หมดอายุ
+}
+
+// LogDose taken = บันทึกและตัดสต็อก (txn out ผูก dose_id) ใน transaction เดียว; skipped = บันทึกอย่างเดียว
+// ScheduledAt ต้องเป็นนัดจริงของตาราง และไม่ล่วงหน้าเกิน 12 ชั่วโมง
+func (s *Service) LogDose(ctx context.Context, householdID, regimenID string, in DoseInput, actor string) (*DoseLog, error) {
+	rg, err := s.Repo.GetRegimen(ctx, householdID, regimenID)
+	if err != nil {
+		return nil, err
+	}
+	if !rg.IsActive {
+		return nil, ErrConflict
+	}
+	now := s.Now()
+	if in.ScheduledAt.IsZero() || in.ScheduledAt.Sub(now) > 12*time.Hour {
+		return nil, ErrBadInput
+	}
+	if occ := rg.Occurrences(in.ScheduledAt, in.ScheduledAt.Add(time.Minute), s.loc()); len(occ) == 0 || !occ[0].Equal(in.ScheduledAt) {
+		return nil, fmt.Errorf("%w: scheduled_at is not an occurrence of this regimen", ErrBadInput)
+	}
+
+	dl := &DoseLog{RegimenID: rg.ID, ScheduledAt: in.ScheduledAt, Status: in.Status, Note: in.Note}
+	if actor != "" {
+		dl.ActorID = &actor
+	}
+	switch in.Status {
+	case DoseTaken:
+		dl.TakenAt = &now
+		reason := fmt.Sprintf("dose %s %s", rg.PersonName, in.ScheduledAt.In(s.loc()).Format("2006-01-02 15:04"))
+		err = s.Repo.TakeDose(ctx, dl, Dispense{
+			ItemID: rg.ItemID, Qty: rg.Dose, AllowExpired: in.Force, AsOf: now, ActorID: actor, Reason: &reason,
+		})
+	case DoseSkipped:
+		err = s.Repo.SkipDose(ctx, dl)
+	default:
+		return nil, ErrBadInput
+	}
+	if err != nil {
+		return nil, err
+	}
+	return dl, nil
+}
@@ -0,0 +1,150 @@
+package medicine
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	_ "time/tzdata" // ไม่พึ่ง zoneinfo ของเครื่องที่รันเทสต์
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("LoadLocation(%q) error = %v", name, err)
+	}
+	return loc
+}
+
+func TestRegimenOccurrences(t *testing.T) {
+	bkk := mustLoad(t, "Asia/Bangkok")
+	at := func(m time.Month, d, h, min int) time.Time { return time.Date(2026, m, d, h, min, 0, 0, bkk) }
+	// วันที่จาก Postgres (DATE) มาเป็น 00:00 UTC
+	date := func(m time.Month, d int) time.Time { return time.Date(2026, m, d, 0, 0, 0, 0, time.UTC) }
+	datep := func(m time.Month, d int) *time.Time { t := date(m, d); return &t }
+	base := Regimen{Times: []string{"20:00", "08:00"}, StartDate: date(1, 5)} // 2026-01-05 = จันทร์
+
+	tests := []struct {
+		name     string
+		edit     func(rg *Regimen)
+		from, to time.Time
+		want     []time.Time
+	}{
+		{
+			"every day sorted", nil, at(1, 5, 0, 0), at(1, 7, 0, 0),
+			[]time.Time{at(1, 5, 8, 0), at(1, 5, 20, 0), at(1, 6, 8, 0), at(1, 6, 20, 0)},
+		},
+		{
+			"start date inclusive", func(rg *Regimen) { rg.StartDate = date(1, 6) }, at(1, 5, 0, 0), at(1, 7, 0, 0),
+			[]time.Time{at(1, 6, 8, 0), at(1, 6, 20, 0)},
+		},
+		{
+			"end date inclusive", func(rg *Regimen) { rg.EndDate = datep(1, 6) }, at(1, 5, 0, 0), at(1, 9, 0, 0),
+			[]time.Time{at(1, 5, 8, 0), at(1, 5, 20, 0), at(1, 6, 8, 0), at(1, 6, 20, 0)},
+		},
+		{
+			"single day regimen", func(rg *Regimen) { rg.StartDate, rg.EndDate = date(1, 6), datep(1, 6) }, at(1, 1, 0, 0), at(2, 1, 0, 0),
+			[]time.Time{at(1, 6, 8, 0), at(1, 6, 20, 0)},
+		},
+		{
+			"days of week", func(rg *Regimen) { rg.DaysOfWeek = []int{1, 3} }, at(1, 5, 0, 0), at(1, 12, 0, 0),
+			[]time.Time{at(1, 5, 8, 0), at(1, 5, 20, 0), at(1, 7, 8, 0), at(1, 7, 20, 0)},
+		},
+		{
+			"sunday is 0", func(rg *Regimen) { rg.DaysOfWeek = []int{0} }, at(1, 5, 0, 0), at(1, 12, 0, 0),
+			[]time.Time{at(1, 11, 8, 0), at(1, 11, 20, 0)},
+		},
+		{"from inclusive", nil, at(1, 5, 8, 0), at(1, 5, 12, 0), []time.Time{at(1, 5, 8, 0)}},
+		{"to exclusive", nil, at(1, 5, 0, 0), at(1, 5, 20, 0), []time.Time{at(1, 5, 8, 0)}},
+		{"before start", nil, at(1, 1, 0, 0), at(1, 5, 0, 0), nil},
+		{
+			"window given in utc", nil,
+			time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC), time.Date(2026, 1, 6, 2, 0, 0, 0, time.UTC), // 19:00 → 09:00 ICT
+			[]time.Time{at(1, 5, 20, 0), at(1, 6, 8, 0)},
+		},
+		// LogDose ตรวจด้วยหน้าต่าง [scheduled_at, +1 นาที)
+		{"logdose exact occurrence", nil, at(1, 6, 20, 0), at(1, 6, 20, 1), []time.Time{at(1, 6, 20, 0)}},
+		{"logdose off schedule", nil, at(1, 6, 20, 1), at(1, 6, 20, 2), nil},
+		{"logdose after end", func(rg *Regimen) { rg.EndDate = datep(1, 6) }, at(1, 7, 8, 0), at(1, 7, 8, 1), nil},
+		// DoseWorker: [now-lookback, now-missedAfter) — นัดที่ยังไม่เลยเกณฑ์ไม่ถูกนับ
+		{"worker lookback", nil, at(1, 5, 20, 30), at(1, 6, 19, 30), []time.Time{at(1, 6, 8, 0)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rg := base
+			if tt.edit != nil {
+				tt.edit(&rg)
+			}
+			got := rg.Occurrences(tt.from, tt.to, bkk)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Occurrences() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegimenOccurrencesDST(t *testing.T) {
+	ny := mustLoad(t, "America/New_York")
+	at := func(m time.Month, d, h, min int) time.Time { return time.Date(2026, m, d, h, min, 0, 0, ny) }
+	rg := Regimen{Times: []string{"01:30", "02:30", "09:00"}, StartDate: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	t.Run("spring forward", func(t *testing.T) {
+		// 2026-03-08 02:00 EST → 03:00 EDT: 02:30 ไม่มีจริง เลื่อนเป็น 03:30 EDT
+		got := rg.Occurrences(at(3, 8, 0, 0), at(3, 9, 0, 0), ny)
+		want := []time.Time{
+			time.Date(2026, 3, 8, 6, 30, 0, 0, time.UTC), // 01:30 EST
+			time.Date(2026, 3, 8, 7, 30, 0, 0, time.UTC), // 03:30 EDT
+			time.Date(2026, 3, 8, 13, 0, 0, 0, time.UTC), // 09:00 EDT
+		}
+		if !reflect.DeepEqual(utc(got), want) {
+			t.Fatalf("Occurrences() = %v, want %v", utc(got), want)
+		}
+		// LogDose ส่งเวลาที่เลื่อนแล้วกลับมา ต้องยังเป็นนัดจริง
+		if occ := rg.Occurrences(got[1], got[1].Add(time.Minute), ny); len(occ) != 1 || !occ[0].Equal(got[1]) {
+			t.Fatalf("Occurrences(shifted) = %v, want [%v]", occ, got[1])
+		}
+	})
+
+	t.Run("fall back", func(t *testing.T) {
+		// 2026-11-01 01:00-02:00 ซ้ำสองรอบ: นัด 01:30 ยังมีครั้งเดียว
+		got := rg.Occurrences(at(11, 1, 0, 0), at(11, 2, 0, 0), ny)
+		if len(got) != 3 {
+			t.Fatalf("Occurrences() = %v, want 3 doses", got)
+		}
+		for i, clock := range []string{"01:30", "02:30", "09:00"} {
+			if c := got[i].In(ny).Format("15:04"); c != clock {
+				t.Errorf("dose %d at %s, want %s", i, c, clock)
+			}
+		}
+		if d := got[2].Sub(at(11, 1, 0, 0)); d != 10*time.Hour {
+			t.Errorf("09:00 is %v after midnight, want 10h (day is 25h long)", d)
+		}
+	})
+
+	t.Run("midnight missing", func(t *testing.T) {
+		// Chile เข้า DST ตอนเที่ยงคืน: 2026-09-06 00:00 → 01:00 วันยังต้องนับถูกและได้นัดวันละครั้ง
+		scl := mustLoad(t, "America/Santiago")
+		rg := Regimen{Times: []string{"00:30"}, StartDate: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)}
+		got := rg.Occurrences(time.Date(2026, 9, 5, 0, 0, 0, 0, scl), time.Date(2026, 9, 8, 0, 0, 0, 0, scl), scl)
+		want := []string{"2026-09-05 00:30", "2026-09-06 01:30", "2026-09-07 00:30"}
+		if len(got) != len(want) {
+			t.Fatalf("Occurrences() = %v, want %v", got, want)
+		}
+		for i, w := range want {
+			if g := got[i].In(scl).Format("2006-01-02 15:04"); g != w {
+				t.Errorf("dose %d = %s, want %s", i, g, w)
+			}
+		}
+	})
+}
+
+func utc(ts []time.Time) []time.Time {
+	out := make([]time.Time, len(ts))
+	for i, t := range ts {
+		out[i] = t.UTC()
+	}
+	return out
+}
@@ -3,11 +3,14 @@ package medicine
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/iMookatayou/homeservice-backend/internal/db"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 // Repo interface PostgreSQL
@@ -15,20 +18,79 @@ type Repo interface {
 	CreateItem(ctx context.Context, it *MedicineItem) error
 	GetItem(ctx context.Context, householdID, itemID string) (*MedicineItem, error)
 	ListItems(ctx context.Context, householdID string, f ListItemFilter) ([]ItemSummary, error)
+	// UpdateItem เขียนเฉพาะเมื่อ version ยังเท่ากับ it.Version (ไม่งั้น ErrConflict); สำเร็จแล้ว it.Version เป็นค่าใหม่
 	UpdateItem(ctx context.Context, it *MedicineItem) error
 	ArchiveItem(ctx context.Context, householdID, itemID string) error
+	// FileUsable ไฟล์ต้องอัปโหลดโดยสมาชิกของบ้านนี้ (ใช้ตรวจ photo_file_id)
+	FileUsable(ctx context.Context, householdID, fileID string) (bool, error)
+	// FindItemByGTIN gtin เป็น GTIN-14 (เทียบกับ gtin ของ item หลังตัดอักขระอื่นและเติม 0)
+	FindItemByGTIN(ctx context.Context, householdID, gtin string) (*MedicineItem, error)
+	// ReceiveScan สร้าง newItem (ถ้าไม่ nil) + รับเข้า batch + txn in ใน transaction เดียว
+	// batch lot/วันหมดอายุเดียวกันที่มีอยู่แล้วถูกบวกเพิ่มแทนการสร้างใหม่ (b.ID/Qty เป็นของ batch นั้น)
+	// บาร์โค้ดถูกสร้างไปก่อนแล้ว (สแกนพร้อมกัน) = ใช้ item เดิมแทน: *newItem ถูกแทนด้วยแถวนั้น, created=false
+	ReceiveScan(ctx context.Context, newItem *MedicineItem, b *MedicineBatch, t *MedicineTxn) (created bool, err error)
 
 	CreateBatch(ctx context.Context, b *MedicineBatch) error
+	// GetBatch เฉพาะ batch ของยาในบ้านนี้
+	GetBatch(ctx context.Context, householdID, batchID string) (*MedicineBatch, error)
 	GetBatchesByItem(ctx context.Context, itemID string) ([]MedicineBatch, error)
 
 	CreateTxn(ctx context.Context, t *MedicineTxn) error
 	ApplyTxnAdjustQty(ctx context.Context, t *MedicineTxn) error
+	// DispenseFEFO ล็อก batch ของ item แล้วเบิกตาม pickFEFO ใน transaction เดียว: 1 batch = 1 MedicineTxn
+	// ไม่พอ/หมดอายุ = ไม่มีอะไรถูกเขียน
+	DispenseFEFO(ctx context.Context, d Dispense) ([]MedicineTxn, error)
+
+	// Stocktake
+	// CreateStocktake ที่เก็บที่มีรอบเปิดอยู่แล้ว = ErrConflict
+	CreateStocktake(ctx context.Context, st *Stocktake) error
+	GetStocktake(ctx context.Context, householdID, id string) (*Stocktake, error)
+	ListStocktakes(ctx context.Context, householdID, locationID string) ([]Stocktake, error)
+	// StocktakeLines batch ที่นับแล้ว + (รอบที่เปิดอยู่) batch คงเหลือของที่เก็บที่ยังไม่ได้นับ
+	StocktakeLines(ctx context.Context, st *Stocktake) ([]StocktakeLine, error)
+	// UpsertCounts นับซ้ำ batch เดิม = แทนค่าเดิม
+	UpsertCounts(ctx context.Context, stocktakeID string, counts []StocktakeCount, actor string) error
+	// CommitStocktake ล็อกรอบ/batch แล้วปรับยอดตามที่นับเป็น txn adjust (stocktake_id) ใน transaction เดียว
+	// zeroUncounted = batch คงเหลือที่ไม่ได้นับถือว่าหาไม่เจอ (ปรับเป็น 0)
+	CommitStocktake(ctx context.Context, st *Stocktake, actor string, zeroUncounted bool) ([]MedicineTxn, error)
+	CancelStocktake(ctx context.Context, householdID, id string) error
+	// StocktakeVariances บรรทัดที่นับไม่ตรงของรอบที่ commit ใน [from, to) เรียงตามเวลา
+	StocktakeVariances(ctx context.Context, householdID, locationID, itemID string, from, to *time.Time) ([]StocktakeVariance, error)
+
+	// Export / Import
+	InventoryRows(ctx context.Context, householdID string) ([]InventoryRow, error)
+	// ImportInventory สร้างที่เก็บ/ยา/batch ใหม่ทั้งชุด (batch ที่มี qty ได้ txn in) ใน transaction เดียว
+	ImportInventory(ctx context.Context, locs []MedicineLocation, items []MedicineItem, batches []MedicineBatch, actor string) error
+	UsageHistory(ctx context.Context, householdID string) ([]ItemUsage, error)
 
 	CreateLocation(ctx context.Context, loc *MedicineLocation) error
 	ListLocations(ctx context.Context, householdID string) ([]MedicineLocation, error)
 
 	UpsertAlert(ctx context.Context, a *MedicineAlert) error
 	GetAlert(ctx context.Context, itemID string) (*MedicineAlert, error)
+
+	ListHouseholdIDs(ctx context.Context) ([]string, error)
+
+	// IsMember ผู้ใช้เป็นสมาชิกบ้านนี้หรือไม่ (ใช้ตรวจ person_user_id ของ regimen)
+	IsMember(ctx context.Context, householdID, userID string) (bool, error)
+
+	CreateRegimen(ctx context.Context, rg *Regimen) error
+	GetRegimen(ctx context.Context, householdID, id string) (*Regimen, error)
+	// ListRegimens activeOnly=false รวมที่ปิดไปแล้ว; itemID/personUserID ว่าง = ไม่กรอง
+	ListRegimens(ctx context.Context, householdID, itemID, personUserID string, activeOnly bool) ([]Regimen, error)
+	UpdateRegimen(ctx context.Context, rg *Regimen) error
+	// ListActiveRegimens ทุกบ้าน (งาน medicine.doses)
+	ListActiveRegimens(ctx context.Context) ([]Regimen, error)
+
+	// DosesBetween log ของนัดใน [from, to) ของทุก regimen ในบ้าน
+	DosesBetween(ctx context.Context, householdID string, from, to time.Time) ([]DoseLog, error)
+	// TakeDose บันทึก taken แล้วตัดสต็อก FEFO (ผูก dose_id) ใน transaction เดียว
+	// นัดที่บันทึก taken/skipped ไปแล้ว = ErrConflict (missed เปลี่ยนเป็น taken ได้)
+	TakeDose(ctx context.Context, dl *DoseLog, d Dispense) error
+	// SkipDose กติกาเดียวกับ TakeDose แต่ไม่ตัดสต็อก
+	SkipDose(ctx context.Context, dl *DoseLog) error
+	// MarkMissed true = เพิ่งบันทึก missed (ยังไม่เคยมี log ของนัดนี้)
+	MarkMissed(ctx context.Context, regimenID string, scheduledAt time.Time) (bool, error)
 }
 
 type pgRepo struct {
@@ -49,13 +111,22 @@ func (r *pgRepo) CreateItem(ctx context.Context, it *MedicineItem) error {
 	_, err := r.db.Exec(ctx, q,
 		it.ID, it.HouseholdID, it.Name, it.GenericName, it.Form, it.Strength,
 		it.Category, it.Unit, it.LocationID, it.GTIN, it.PhotoFileID, it.Notes)
+	if gtinTaken(err) {
+		return fmt.Errorf("%w: another item already has this gtin", ErrConflict)
+	}
 	return err
 }
 
+// gtinTaken ชน uq_medicine_items_gtin14 (บาร์โค้ดซ้ำกับ item ที่ยังใช้อยู่ในบ้านเดียวกัน)
+func gtinTaken(err error) bool {
+	var pe *pgconn.PgError
+	return errors.As(err, &pe) && pe.Code == "23505" && pe.ConstraintName == "uq_medicine_items_gtin14"
+}
+
 func (r *pgRepo) GetItem(ctx context.Context, householdID, itemID string) (*MedicineItem, error) {
 	const q = `
 	SELECT id, household_id, name, generic_name, form, strength, category, unit,
-	       location_id, gtin, photo_file_id, notes, is_archived, created_at, updated_at
+	       location_id, gtin, photo_file_id, notes, is_archived, created_at, updated_at, version
 	FROM medicine_items
 	WHERE id=$1 AND household_id=$2 AND is_archived=false`
 	row := r.db.QueryRow(ctx, q, itemID, householdID)
@@ -64,7 +135,7 @@ func (r *pgRepo) GetItem(ctx context.Context, householdID, itemID string) (*Medi
 	if err := row.Scan(
 		&it.ID, &it.HouseholdID, &it.Name, &it.GenericName, &it.Form, &it.Strength,
 		&it.Category, &it.Unit, &it.LocationID, &it.GTIN, &it.PhotoFileID,
-		&it.Notes, &it.IsArchived, &it.CreatedAt, &it.UpdatedAt,
+		&it.Notes, &it.IsArchived, &it.CreatedAt, &it.UpdatedAt, &it.Version,
 	); err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, ErrNotFound
@@ -78,7 +149,7 @@ func (r *pgRepo) ListItems(ctx context.Context, householdID string, f ListItemFi
 	q := `
 	SELECT i.id, i.household_id, i.name, i.generic_name, i.form, i.strength, i.category,
 	       i.unit, i.location_id, i.gtin, i.photo_file_id, i.notes, i.is_archived,
-	       i.created_at, i.updated_at,
+	       i.created_at, i.updated_at, i.version,
 	       COALESCE(s.total_qty,0), ne.next_expiry
 	FROM medicine_items i
 	LEFT JOIN v_medicine_item_stock s ON s.item_id=i.id
@@ -100,7 +171,7 @@ func (r *pgRepo) ListItems(ctx context.Context, householdID string, f ListItemFi
 		if err := rows.Scan(
 			&it.ID, &it.HouseholdID, &it.Name, &it.GenericName, &it.Form, &it.Strength,
 			&it.Category, &it.Unit, &it.LocationID, &it.GTIN, &it.PhotoFileID, &it.Notes,
-			&it.IsArchived, &it.CreatedAt, &it.UpdatedAt, &total, &nextExpiry,
+			&it.IsArchived, &it.CreatedAt, &it.UpdatedAt, &it.Version, &total, &nextExpiry,
 		); err != nil {
 			return nil, err
 		}
@@ -114,17 +185,18 @@ func (r *pgRepo) UpdateItem(ctx context.Context, it *MedicineItem) error {
 	UPDATE medicine_items
 	SET name=$1, generic_name=$2, form=$3, strength=$4, category=$5, location_id=$6,
 	    gtin=$7, photo_file_id=$8, notes=$9, updated_at=now()
-	WHERE id=$10 AND household_id=$11`
-	ct, err := r.db.Exec(ctx, q,
+	WHERE id=$10 AND household_id=$11 AND version=$12
+	RETURNING updated_at, version`
+	err := r.db.QueryRow(ctx, q,
 		it.Name, it.GenericName, it.Form, it.Strength, it.Category, it.LocationID,
-		it.GTIN, it.PhotoFileID, it.Notes, it.ID, it.HouseholdID)
-	if err != nil {
-		return err
+		it.GTIN, it.PhotoFileID, it.Notes, it.ID, it.HouseholdID, it.Version).Scan(&it.UpdatedAt, &it.Version)
+	if err == pgx.ErrNoRows {
+		return ErrConflict // แถวถูกแก้/เก็บเข้าคลังไปแล้วหลังจากที่อ่าน
 	}
-	if ct.RowsAffected() == 0 {
-		return ErrNotFound
+	if gtinTaken(err) {
+		return fmt.Errorf("%w: another item already has this gtin", ErrConflict)
 	}
-	return nil
+	return err
 }
 
 func (r *pgRepo) ArchiveItem(ctx context.Context, householdID, itemID string) error {
@@ -139,6 +211,99 @@ func (r *pgRepo) ArchiveItem(ctx context.Context, householdID, itemID string) er
 	return nil
 }
 
+func (r *pgRepo) FileUsable(ctx context.Context, householdID, fileID string) (bool, error) {
+	var ok bool
+	err := r.db.QueryRow(ctx, `
+	SELECT EXISTS (
+	  SELECT 1 FROM files f
+	  JOIN household_members m ON m.user_id = f.owner_id
+	  WHERE f.id::text = $1 AND m.household_id = $2
+	)`, fileID, householdID).Scan(&ok)
+	return ok, err
+}
+
+func (r *pgRepo) FindItemByGTIN(ctx context.Context, householdID, gtin string) (*MedicineItem, error) {
+	var id string
+	err := r.db.QueryRow(ctx, `
+	SELECT id FROM medicine_items
+	WHERE household_id=$1 AND is_archived=false AND gtin IS NOT NULL
+	  AND lpad(regexp_replace(gtin, '[^0-9]', '', 'g'), 14, '0') = $2
+	ORDER BY created_at LIMIT 1`, householdID, gtin).Scan(&id)
+	if err == pgx.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.GetItem(ctx, householdID, id)
+}
+
+func (r *pgRepo) ReceiveScan(ctx context.Context, newItem *MedicineItem, b *MedicineBatch, t *MedicineTxn) (bool, error) {
+	created := false
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		if it := newItem; it != nil {
+			err := tx.QueryRow(ctx, `
+			INSERT INTO medicine_items
+			(id, household_id, name, generic_name, form, strength, category, unit, location_id, gtin, photo_file_id, notes, is_archived)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,false)
+			ON CONFLICT (household_id, lpad(regexp_replace(gtin, '[^0-9]', '', 'g'), 14, '0'))
+			  WHERE gtin IS NOT NULL AND is_archived = false
+			DO NOTHING
+			RETURNING created_at, updated_at, version`,
+				it.ID, it.HouseholdID, it.Name, it.GenericName, it.Form, it.Strength,
+				it.Category, it.Unit, it.LocationID, it.GTIN, it.PhotoFileID, it.Notes,
+			).Scan(&it.CreatedAt, &it.UpdatedAt, &it.Version)
+			switch {
+			case err == nil:
+				created = true
+			case errors.Is(err, pgx.ErrNoRows):
+				// อีกคำขอสร้างบาร์โค้ดนี้ไปก่อน (commit แล้ว): รับเข้า item นั้นแทน
+				if err := tx.QueryRow(ctx, `
+				SELECT id, name, generic_name, form, strength, category, unit, location_id, gtin, photo_file_id, notes,
+				       is_archived, created_at, updated_at, version
+				FROM medicine_items
+				WHERE household_id=$1 AND gtin IS NOT NULL AND is_archived=false
+				  AND lpad(regexp_replace(gtin, '[^0-9]', '', 'g'), 14, '0') = $2`, it.HouseholdID, *it.GTIN,
+				).Scan(&it.ID, &it.Name, &it.GenericName, &it.Form, &it.Strength, &it.Category, &it.Unit, &it.LocationID,
+					&it.GTIN, &it.PhotoFileID, &it.Notes, &it.IsArchived, &it.CreatedAt, &it.UpdatedAt, &it.Version); err != nil {
+					return err
+				}
+				b.ItemID, b.Unit, t.ItemID = it.ID, it.Unit, it.ID
+			default:
+				return err
+			}
+		}
+
+		add := b.Qty
+		err := tx.QueryRow(ctx, `
+		UPDATE medicine_batches SET qty=qty+$4, updated_at=now()
+		WHERE id = (SELECT id FROM medicine_batches
+		            WHERE item_id=$1 AND lot_no IS NOT DISTINCT FROM $2 AND expiry_date IS NOT DISTINCT FROM $3
+		            ORDER BY created_at LIMIT 1 FOR UPDATE)
+		RETURNING id, qty, created_at, updated_at`, b.ItemID, b.LotNo, b.Expiry, add,
+		).Scan(&b.ID, &b.Qty, &b.CreatedAt, &b.UpdatedAt)
+		if err == pgx.ErrNoRows {
+			err = tx.QueryRow(ctx, `
+			INSERT INTO medicine_batches (id, item_id, lot_no, expiry_date, qty, unit)
+			VALUES ($1,$2,$3,$4,$5,$6)
+			RETURNING created_at, updated_at`, b.ID, b.ItemID, b.LotNo, b.Expiry, b.Qty, b.Unit,
+			).Scan(&b.CreatedAt, &b.UpdatedAt)
+		}
+		if err != nil {
+			return err
+		}
+
+		t.BatchID = &b.ID
+		return tx.QueryRow(ctx, `
+		INSERT INTO medicine_txns (id, item_id, batch_id, actor_user_id, type, qty_change, reason)
+		VALUES (gen_random_uuid(),$1,$2,NULLIF($3,'')::uuid,$4,$5,$6)
+		RETURNING id, created_at`,
+			t.ItemID, b.ID, t.ActorID, t.Type, add, t.Reason,
+		).Scan(&t.ID, &t.CreatedAt)
+	})
+	return created, err
+}
+
 // ---------- Batches ----------
 
 func (r *pgRepo) CreateBatch(ctx context.Context, b *MedicineBatch) error {
@@ -149,6 +314,22 @@ func (r *pgRepo) CreateBatch(ctx context.Context, b *MedicineBatch) error {
 	return err
 }
 
+func (r *pgRepo) GetBatch(ctx context.Context, householdID, batchID string) (*MedicineBatch, error) {
+	var b MedicineBatch
+	err := r.db.QueryRow(ctx, `
+	SELECT b.id, b.item_id, b.lot_no, b.expiry_date, b.qty, b.unit, b.created_at, b.updated_at
+	FROM medicine_batches b JOIN medicine_items i ON i.id = b.item_id
+	WHERE b.id::text=$1 AND i.household_id=$2`, batchID, householdID).
+		Scan(&b.ID, &b.ItemID, &b.LotNo, &b.Expiry, &b.Qty, &b.Unit, &b.CreatedAt, &b.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
 func (r *pgRepo) GetBatchesByItem(ctx context.Context, itemID string) ([]MedicineBatch, error) {
 	const q = `
 	SELECT id, item_id, lot_no, expiry_date, qty, unit, created_at, updated_at
@@ -225,17 +406,619 @@ func (r *pgRepo) ApplyTxnAdjustQty(ctx context.Context, t *MedicineTxn) error {
 		return ErrBadInput
 	}
 
-	if _, err := tx.Exec(ctx, `
+	// id ว่าง = ให้ DB สร้าง (service ไม่ได้ตั้ง id ของ txn)
+	if err := tx.QueryRow(ctx, `
 		INSERT INTO medicine_txns (id, item_id, batch_id, actor_user_id, type, qty_change, reason)
-		VALUES ($1,$2,$3,$4,$5,$6,$7)`,
+		VALUES (COALESCE(NULLIF($1,'')::uuid, gen_random_uuid()),$2,$3,NULLIF($4,'')::uuid,$5,$6,$7)
+		RETURNING id, created_at`,
 		t.ID, t.ItemID, t.BatchID, t.ActorID, t.Type, t.QtyChange, t.Reason,
-	); err != nil {
+	).Scan(&t.ID, &t.CreatedAt); err != nil {
 		return err
 	}
 
 	return tx.Commit(ctx)
 }
 
+func (r *pgRepo) DispenseFEFO(ctx context.Context, d Dispense) ([]MedicineTxn, error) {
+	var out []MedicineTxn
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		var err error
+		out, err = dispenseTx(ctx, tx, d)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// dispenseTx ตัว FEFO ภายใน transaction ของผู้เรียก (ใช้ร่วมกับ TakeDose)
+func dispenseTx(ctx context.Context, tx pgx.Tx, d Dispense) ([]MedicineTxn, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT id, item_id, lot_no, expiry_date, qty, unit, created_at, updated_at
+		FROM medicine_batches
+		WHERE item_id=$1 AND qty > 0
+		ORDER BY expiry_date NULLS LAST, created_at
+		FOR UPDATE`, d.ItemID)
+	if err != nil {
+		return nil, err
+	}
+	var batches []MedicineBatch
+	for rows.Next() {
+		var b MedicineBatch
+		if err := rows.Scan(&b.ID, &b.ItemID, &b.LotNo, &b.Expiry, &b.Qty, &b.Unit, &b.CreatedAt, &b.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		batches = append(batches, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	draws, err := pickFEFO(batches, d)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]MedicineTxn, 0, len(draws))
+	for _, dr := range draws {
+		if _, err := tx.Exec(ctx,
+			`UPDATE medicine_batches SET qty=GREATEST(qty-$1, 0), updated_at=now() WHERE id=$2`,
+			dr.Qty, dr.BatchID,
+		); err != nil {
+			return nil, err
+		}
+		batchID := dr.BatchID
+		t := MedicineTxn{
+			ItemID: d.ItemID, BatchID: &batchID, ActorID: d.ActorID, Type: TxnOut,
+			QtyChange: -dr.Qty, Reason: d.Reason, DoseID: d.DoseID,
+		}
+		if err := tx.QueryRow(ctx, `
+			INSERT INTO medicine_txns (item_id, batch_id, actor_user_id, type, qty_change, reason, dose_id)
+			VALUES ($1,$2,NULLIF($3,'')::uuid,$4,$5,$6,$7)
+			RETURNING id, created_at`,
+			t.ItemID, t.BatchID, t.ActorID, t.Type, t.QtyChange, t.Reason, t.DoseID,
+		).Scan(&t.ID, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+// ---------- Regimens / doses ----------
+
+func (r *pgRepo) IsMember(ctx context.Context, householdID, userID string) (bool, error) {
+	var ok bool
+	err := r.db.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM household_members WHERE household_id=$1 AND user_id::text=$2)`,
+		householdID, userID).Scan(&ok)
+	return ok, err
+}
+
+const regimenCols = `
+	id, household_id, item_id, person_user_id, person_name, dose, unit, times, days_of_week,
+	start_date, end_date, notes, is_active, COALESCE(created_by::text,''), created_at, updated_at`
+
+func scanRegimen(row pgx.Row, rg *Regimen) error {
+	return row.Scan(
+		&rg.ID, &rg.HouseholdID, &rg.ItemID, &rg.PersonUserID, &rg.PersonName, &rg.Dose, &rg.Unit,
+		&rg.Times, &rg.DaysOfWeek, &rg.StartDate, &rg.EndDate, &rg.Notes, &rg.IsActive,
+		&rg.CreatedBy, &rg.CreatedAt, &rg.UpdatedAt,
+	)
+}
+
+func (r *pgRepo) CreateRegimen(ctx context.Context, rg *Regimen) error {
+	return scanRegimen(r.db.QueryRow(ctx, `
+	INSERT INTO medicine_regimens
+	(household_id, item_id, person_user_id, person_name, dose, unit, times, days_of_week, start_date, end_date, notes, created_by)
+	VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,NULLIF($12,'')::uuid)
+	RETURNING `+regimenCols,
+		rg.HouseholdID, rg.ItemID, rg.PersonUserID, rg.PersonName, rg.Dose, rg.Unit, rg.Times, rg.DaysOfWeek,
+		rg.StartDate, rg.EndDate, rg.Notes, rg.CreatedBy), rg)
+}
+
+func (r *pgRepo) GetRegimen(ctx context.Context, householdID, id string) (*Regimen, error) {
+	var rg Regimen
+	err := scanRegimen(r.db.QueryRow(ctx, `SELECT `+regimenCols+` FROM medicine_regimens WHERE id::text=$1 AND household_id=$2`, id, householdID), &rg)
+	if err == pgx.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rg, nil
+}
+
+func (r *pgRepo) ListRegimens(ctx context.Context, householdID, itemID, personUserID string, activeOnly bool) ([]Regimen, error) {
+	return r.queryRegimens(ctx, `
+	SELECT `+regimenCols+` FROM medicine_regimens
+	WHERE household_id=$1
+	  AND ($2 = '' OR item_id::text = $2)
+	  AND ($3 = '' OR person_user_id::text = $3)
+	  AND (NOT $4 OR is_active)
+	ORDER BY person_name, created_at`, householdID, itemID, personUserID, activeOnly)
+}
+
+func (r *pgRepo) ListActiveRegimens(ctx context.Context) ([]Regimen, error) {
+	return r.queryRegimens(ctx, `SELECT `+regimenCols+` FROM medicine_regimens WHERE is_active ORDER BY household_id`)
+}
+
+func (r *pgRepo) queryRegimens(ctx context.Context, q string, args ...any) ([]Regimen, error) {
+	rows, err := r.db.Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Regimen{}
+	for rows.Next() {
+		var rg Regimen
+		if err := scanRegimen(rows, &rg); err != nil {
+			return nil, err
+		}
+		out = append(out, rg)
+	}
+	return out, rows.Err()
+}
+
+func (r *pgRepo) UpdateRegimen(ctx context.Context, rg *Regimen) error {
+	err := scanRegimen(r.db.QueryRow(ctx, `
+	UPDATE medicine_regimens
+	SET person_user_id=$3, person_name=$4, dose=$5, unit=$6, times=$7, days_of_week=$8,
+	    start_date=$9, end_date=$10, notes=$11, is_active=$12, updated_at=now()
+	WHERE id=$1 AND household_id=$2
+	RETURNING `+regimenCols,
+		rg.ID, rg.HouseholdID, rg.PersonUserID, rg.PersonName, rg.Dose, rg.Unit, rg.Times, rg.DaysOfWeek,
+		rg.StartDate, rg.EndDate, rg.Notes, rg.IsActive), rg)
+	if err == pgx.ErrNoRows {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (r *pgRepo) DosesBetween(ctx context.Context, householdID string, from, to time.Time) ([]DoseLog, error) {
+	rows, err := r.db.Query(ctx, `
+	SELECT d.id, d.regimen_id, d.scheduled_at, d.status, d.taken_at, d.actor_user_id, d.note, d.created_at
+	FROM medicine_doses d
+	JOIN medicine_regimens g ON g.id = d.regimen_id
+	WHERE g.household_id=$1 AND d.scheduled_at >= $2 AND d.scheduled_at < $3
+	ORDER BY d.scheduled_at`, householdID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []DoseLog
+	for rows.Next() {
+		var dl DoseLog
+		if err := rows.Scan(&dl.ID, &dl.RegimenID, &dl.ScheduledAt, &dl.Status, &dl.TakenAt, &dl.ActorID, &dl.Note, &dl.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, dl)
+	}
+	return out, rows.Err()
+}
+
+// upsertDose บันทึกผลของนัด; ทับได้เฉพาะ log ที่เป็น missed
+func upsertDose(ctx context.Context, tx pgx.Tx, dl *DoseLog) error {
+	err := tx.QueryRow(ctx, `
+	INSERT INTO medicine_doses (regimen_id, scheduled_at, status, taken_at, actor_user_id, note)
+	VALUES ($1,$2,$3,$4,$5,$6)
+	ON CONFLICT (regimen_id, scheduled_at) DO UPDATE
+	SET status=EXCLUDED.status, taken_at=EXCLUDED.taken_at, actor_user_id=EXCLUDED.actor_user_id,
+	    note=EXCLUDED.note, updated_at=now()
+	WHERE medicine_doses.status = 'missed'
+	RETURNING id, created_at`,
+		dl.RegimenID, dl.ScheduledAt, dl.Status, dl.TakenAt, dl.ActorID, dl.Note,
+	).Scan(&dl.ID, &dl.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *pgRepo) TakeDose(ctx context.Context, dl *DoseLog, d Dispense) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		if err := upsertDose(ctx, tx, dl); err != nil {
+			return err
+		}
+		d.DoseID = &dl.ID
+		txns, err := dispenseTx(ctx, tx, d)
+		if err != nil {
+			return err
+		}
+		dl.Txns = txns
+		return nil
+	})
+}
+
+func (r *pgRepo) SkipDose(ctx context.Context, dl *DoseLog) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		return upsertDose(ctx, tx, dl)
+	})
+}
+
+func (r *pgRepo) MarkMissed(ctx context.Context, regimenID string, scheduledAt time.Time) (bool, error) {
+	ct, err := r.db.Exec(ctx, `
+	INSERT INTO medicine_doses (regimen_id, scheduled_at, status) VALUES ($1,$2,'missed')
+	ON CONFLICT (regimen_id, scheduled_at) DO NOTHING`, regimenID, scheduledAt)
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() == 1, nil
+}
+
+// ---------- Stocktake ----------
+
+const stocktakeCols = `id, household_id, location_id, status, note, created_by, created_at, committed_by, committed_at`
+
+func scanStocktake(row pgx.Row, st *Stocktake) error {
+	return row.Scan(&st.ID, &st.HouseholdID, &st.LocationID, &st.Status, &st.Note,
+		&st.CreatedBy, &st.CreatedAt, &st.CommittedBy, &st.CommittedAt)
+}
+
+func (r *pgRepo) CreateStocktake(ctx context.Context, st *Stocktake) error {
+	err := scanStocktake(r.db.QueryRow(ctx, `
+	INSERT INTO medicine_stocktakes (household_id, location_id, note, created_by)
+	VALUES ($1,$2,$3,$4)
+	ON CONFLICT (location_id) WHERE status = 'open' DO NOTHING
+	RETURNING `+stocktakeCols, st.HouseholdID, st.LocationID, st.Note, st.CreatedBy), st)
+	if err == pgx.ErrNoRows {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *pgRepo) GetStocktake(ctx context.Context, householdID, id string) (*Stocktake, error) {
+	var st Stocktake
+	err := scanStocktake(r.db.QueryRow(ctx, `SELECT `+stocktakeCols+` FROM medicine_stocktakes WHERE id::text=$1 AND household_id=$2`, id, householdID), &st)
+	if err == pgx.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+func (r *pgRepo) ListStocktakes(ctx context.Context, householdID, locationID string) ([]Stocktake, error) {
+	rows, err := r.db.Query(ctx, `
+	SELECT `+stocktakeCols+` FROM medicine_stocktakes
+	WHERE household_id=$1 AND ($2 = '' OR location_id::text = $2)
+	ORDER BY created_at DESC`, householdID, locationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Stocktake{}
+	for rows.Next() {
+		var st Stocktake
+		if err := scanStocktake(rows, &st); err != nil {
+			return nil, err
+		}
+		out = append(out, st)
+	}
+	return out, rows.Err()
+}
+
+func (r *pgRepo) StocktakeLines(ctx context.Context, st *Stocktake) ([]StocktakeLine, error) {
+	rows, err := r.db.Query(ctx, `
+	SELECT c.id, c.batch_id, i.id, i.name, i.unit,
+	       COALESCE(b.lot_no, c.lot_no), COALESCE(b.expiry_date, c.expiry_date),
+	       COALESCE(c.expected_qty, b.qty, 0), c.counted_qty, c.is_new OR c.batch_id IS NULL, c.note
+	FROM medicine_stocktake_counts c
+	JOIN medicine_items i ON i.id = c.item_id
+	LEFT JOIN medicine_batches b ON b.id = c.batch_id
+	WHERE c.stocktake_id = $1
+	UNION ALL
+	SELECT NULL, b.id, i.id, i.name, i.unit, b.lot_no, b.expiry_date, b.qty, NULL, false, NULL
+	FROM medicine_batches b
+	JOIN medicine_items i ON i.id = b.item_id
+	WHERE $4 AND i.household_id = $2 AND i.location_id = $3 AND i.is_archived = false AND b.qty > 0
+	  AND NOT EXISTS (SELECT 1 FROM medicine_stocktake_counts c WHERE c.stocktake_id = $1 AND c.batch_id = b.id)
+	ORDER BY 4, 7 NULLS LAST, 6`,
+		st.ID, st.HouseholdID, st.LocationID, st.Status == StocktakeOpen)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []StocktakeLine{}
+	for rows.Next() {
+		var l StocktakeLine
+		if err := rows.Scan(&l.CountID, &l.BatchID, &l.ItemID, &l.ItemName, &l.Unit, &l.LotNo, &l.Expiry,
+			&l.Expected, &l.Counted, &l.IsNew, &l.Note); err != nil {
+			return nil, err
+		}
+		if l.Counted != nil {
+			v := *l.Counted - l.Expected
+			l.Variance = &v
+		}
+		out = append(out, l)
+	}
+	return out, rows.Err()
+}
+
+func (r *pgRepo) UpsertCounts(ctx context.Context, stocktakeID string, counts []StocktakeCount, actor string) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		var status StocktakeStatus
+		if err := tx.QueryRow(ctx, `SELECT status FROM medicine_stocktakes WHERE id=$1 FOR UPDATE`, stocktakeID).Scan(&status); err != nil {
+			return err
+		}
+		if status != StocktakeOpen {
+			return ErrConflict
+		}
+		// upsert ตาม unique index ของแต่ละแบบ: นับ batch เดียวกันพร้อมกันหลายคน = คนหลังแทนค่า ไม่ชน unique
+		for _, c := range counts {
+			q := `
+			INSERT INTO medicine_stocktake_counts (stocktake_id, item_id, batch_id, lot_no, expiry_date, counted_qty, note, counted_by)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,NULLIF($8,'')::uuid)
+			ON CONFLICT (stocktake_id, batch_id) WHERE batch_id IS NOT NULL
+			DO UPDATE SET counted_qty=EXCLUDED.counted_qty, note=EXCLUDED.note, counted_by=EXCLUDED.counted_by, updated_at=now()`
+			if c.BatchID == nil {
+				q = `
+				INSERT INTO medicine_stocktake_counts (stocktake_id, item_id, batch_id, lot_no, expiry_date, counted_qty, note, counted_by)
+				VALUES ($1,$2,$3,$4,$5,$6,$7,NULLIF($8,'')::uuid)
+				ON CONFLICT (stocktake_id, item_id, COALESCE(lot_no, ''), COALESCE(expiry_date, 'infinity'::date)) WHERE batch_id IS NULL
+				DO UPDATE SET counted_qty=EXCLUDED.counted_qty, note=EXCLUDED.note, counted_by=EXCLUDED.counted_by, updated_at=now()`
+			}
+			if _, err := tx.Exec(ctx, q, stocktakeID, c.ItemID, c.BatchID, c.LotNo, c.Expiry, c.Qty, c.Note, actor); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *pgRepo) CommitStocktake(ctx context.Context, st *Stocktake, actor string, zeroUncounted bool) ([]MedicineTxn, error) {
+	txns := []MedicineTxn{}
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		if err := scanStocktake(tx.QueryRow(ctx, `SELECT `+stocktakeCols+` FROM medicine_stocktakes WHERE id=$1 AND household_id=$2 FOR UPDATE`,
+			st.ID, st.HouseholdID), st); err != nil {
+			return err
+		}
+		if st.Status != StocktakeOpen {
+			return ErrConflict
+		}
+		if zeroUncounted {
+			if _, err := tx.Exec(ctx, `
+			INSERT INTO medicine_stocktake_counts (stocktake_id, item_id, batch_id, counted_qty, note, counted_by)
+			SELECT $1, b.item_id, b.id, 0, 'not found', NULLIF($4,'')::uuid
+			FROM medicine_batches b JOIN medicine_items i ON i.id = b.item_id
+			WHERE i.household_id=$2 AND i.location_id=$3 AND i.is_archived=false AND b.qty > 0
+			  AND NOT EXISTS (SELECT 1 FROM medicine_stocktake_counts c WHERE c.stocktake_id=$1 AND c.batch_id=b.id)`,
+				st.ID, st.HouseholdID, st.LocationID, actor); err != nil {
+				return err
+			}
+		}
+
+		type count struct {
+			id, itemID, unit string
+			batchID, lotNo   *string
+			expiry           *time.Time
+			qty              float64
+		}
+		rows, err := tx.Query(ctx, `
+		SELECT c.id, c.item_id, i.unit, c.batch_id, c.lot_no, c.expiry_date, c.counted_qty
+		FROM medicine_stocktake_counts c JOIN medicine_items i ON i.id = c.item_id
+		WHERE c.stocktake_id=$1 ORDER BY c.batch_id NULLS LAST, c.id`, st.ID)
+		if err != nil {
+			return err
+		}
+		var counts []count
+		for rows.Next() {
+			var c count
+			if err := rows.Scan(&c.id, &c.itemID, &c.unit, &c.batchID, &c.lotNo, &c.expiry, &c.qty); err != nil {
+				rows.Close()
+				return err
+			}
+			counts = append(counts, c)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		reason := "stocktake"
+		if st.Note != nil && *st.Note != "" {
+			reason += ": " + *st.Note
+		}
+		for _, c := range counts {
+			var batchID string
+			var expected float64
+			if c.batchID != nil {
+				batchID = *c.batchID
+				if err := tx.QueryRow(ctx, `SELECT qty FROM medicine_batches WHERE id=$1 FOR UPDATE`, batchID).Scan(&expected); err != nil {
+					return err
+				}
+				if _, err := tx.Exec(ctx, `UPDATE medicine_stocktake_counts SET expected_qty=$2 WHERE id=$1`, c.id, expected); err != nil {
+					return err
+				}
+			} else {
+				// batch ที่เพิ่งเจอ: สร้างด้วยยอด 0 แล้วให้ txn adjust เป็นคนเพิ่ม (ยอดรวม txn = ยอด batch)
+				if err := tx.QueryRow(ctx, `
+				INSERT INTO medicine_batches (id, item_id, lot_no, expiry_date, qty, unit)
+				VALUES (gen_random_uuid(),$1,$2,$3,0,$4) RETURNING id`,
+					c.itemID, c.lotNo, c.expiry, c.unit).Scan(&batchID); err != nil {
+					return err
+				}
+				if _, err := tx.Exec(ctx, `UPDATE medicine_stocktake_counts SET batch_id=$2, is_new=true, expected_qty=0 WHERE id=$1`, c.id, batchID); err != nil {
+					return err
+				}
+			}
+
+			delta := c.qty - expected
+			if math.Abs(delta) < qtyEpsilon {
+				continue
+			}
+			if _, err := tx.Exec(ctx, `UPDATE medicine_batches SET qty=$2, updated_at=now() WHERE id=$1`, batchID, c.qty); err != nil {
+				return err
+			}
+			t := MedicineTxn{ItemID: c.itemID, BatchID: &batchID, ActorID: actor, Type: TxnAdjust,
+				QtyChange: delta, Reason: &reason, StocktakeID: &st.ID}
+			if err := tx.QueryRow(ctx, `
+			INSERT INTO medicine_txns (id, item_id, batch_id, actor_user_id, type, qty_change, reason, stocktake_id)
+			VALUES (gen_random_uuid(),$1,$2,NULLIF($3,'')::uuid,$4,$5,$6,$7)
+			RETURNING id, created_at`,
+				t.ItemID, batchID, actor, t.Type, t.QtyChange, t.Reason, st.ID).Scan(&t.ID, &t.CreatedAt); err != nil {
+				return err
+			}
+			txns = append(txns, t)
+		}
+
+		return scanStocktake(tx.QueryRow(ctx, `
+		UPDATE medicine_stocktakes SET status='committed', committed_by=NULLIF($2,'')::uuid, committed_at=now()
+		WHERE id=$1 RETURNING `+stocktakeCols, st.ID, actor), st)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return txns, nil
+}
+
+func (r *pgRepo) CancelStocktake(ctx context.Context, householdID, id string) error {
+	tag, err := r.db.Exec(ctx, `
+	UPDATE medicine_stocktakes SET status='cancelled'
+	WHERE id::text=$1 AND household_id=$2 AND status='open'`, id, householdID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		if _, err := r.GetStocktake(ctx, householdID, id); err != nil {
+			return err
+		}
+		return ErrConflict // ปิดไปแล้ว
+	}
+	return nil
+}
+
+func (r *pgRepo) StocktakeVariances(ctx context.Context, householdID, locationID, itemID string, from, to *time.Time) ([]StocktakeVariance, error) {
+	rows, err := r.db.Query(ctx, `
+	SELECT s.id, s.location_id, s.committed_at, i.id, i.name, i.unit,
+	       c.batch_id, COALESCE(b.lot_no, c.lot_no), c.expected_qty, c.counted_qty, c.is_new
+	FROM medicine_stocktakes s
+	JOIN medicine_stocktake_counts c ON c.stocktake_id = s.id
+	JOIN medicine_items i ON i.id = c.item_id
+	LEFT JOIN medicine_batches b ON b.id = c.batch_id
+	WHERE s.household_id = $1 AND s.status = 'committed'
+	  AND ($2 = '' OR s.location_id::text = $2)
+	  AND ($3 = '' OR c.item_id::text = $3)
+	  AND ($4::timestamptz IS NULL OR s.committed_at >= $4)
+	  AND ($5::timestamptz IS NULL OR s.committed_at < $5)
+	  AND c.counted_qty <> c.expected_qty
+	ORDER BY s.committed_at, i.name`, householdID, locationID, itemID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []StocktakeVariance{}
+	for rows.Next() {
+		var v StocktakeVariance
+		if err := rows.Scan(&v.StocktakeID, &v.LocationID, &v.CommittedAt, &v.ItemID, &v.ItemName, &v.Unit,
+			&v.BatchID, &v.LotNo, &v.Expected, &v.Counted, &v.IsNew); err != nil {
+			return nil, err
+		}
+		v.Variance = v.Counted - v.Expected
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
+
+// ---------- Export / Import ----------
+
+func (r *pgRepo) InventoryRows(ctx context.Context, householdID string) ([]InventoryRow, error) {
+	rows, err := r.db.Query(ctx, `
+	SELECT l.name, i.id::text, i.name, i.generic_name, i.form, i.strength, i.category, i.unit, i.gtin, i.notes,
+	       b.id::text, b.lot_no, b.expiry_date, b.qty::float8
+	FROM medicine_items i
+	LEFT JOIN medicine_locations l ON l.id = i.location_id
+	LEFT JOIN medicine_batches b ON b.item_id = i.id AND b.qty > 0
+	WHERE i.household_id = $1 AND i.is_archived = false
+	UNION ALL
+	SELECT l.name, NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL
+	FROM medicine_locations l
+	WHERE l.household_id = $1
+	  AND NOT EXISTS (SELECT 1 FROM medicine_items i WHERE i.location_id = l.id AND i.is_archived = false)
+	ORDER BY 1 NULLS LAST, 3 NULLS FIRST, 13 NULLS LAST`, householdID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []InventoryRow
+	for rows.Next() {
+		var x InventoryRow
+		if err := rows.Scan(&x.Location, &x.ItemID, &x.Name, &x.GenericName, &x.Form, &x.Strength, &x.Category,
+			&x.Unit, &x.GTIN, &x.Notes, &x.BatchID, &x.LotNo, &x.Expiry, &x.Qty); err != nil {
+			return nil, err
+		}
+		out = append(out, x)
+	}
+	return out, rows.Err()
+}
+
+func (r *pgRepo) ImportInventory(ctx context.Context, locs []MedicineLocation, items []MedicineItem, batches []MedicineBatch, actor string) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		for _, l := range locs {
+			if _, err := tx.Exec(ctx, `
+			INSERT INTO medicine_locations (id, household_id, name, notes, is_active)
+			VALUES ($1,$2,$3,$4,true)`, l.ID, l.HouseholdID, l.Name, l.Notes); err != nil {
+				return fmt.Errorf("location %q: %w", l.Name, err)
+			}
+		}
+		for _, it := range items {
+			if _, err := tx.Exec(ctx, `
+			INSERT INTO medicine_items
+			(id, household_id, name, generic_name, form, strength, category, unit, location_id, gtin, photo_file_id, notes, is_archived)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,false)`,
+				it.ID, it.HouseholdID, it.Name, it.GenericName, it.Form, it.Strength,
+				it.Category, it.Unit, it.LocationID, it.GTIN, it.PhotoFileID, it.Notes); err != nil {
+				if gtinTaken(err) {
+					err = fmt.Errorf("%w: gtin already used by another item", ErrConflict)
+				}
+				return fmt.Errorf("item %q: %w", it.Name, err)
+			}
+		}
+		reason := "import"
+		for _, b := range batches {
+			if _, err := tx.Exec(ctx, `
+			INSERT INTO medicine_batches (id, item_id, lot_no, expiry_date, qty, unit)
+			VALUES ($1,$2,$3,$4,$5,$6)`, b.ID, b.ItemID, b.LotNo, b.Expiry, b.Qty, b.Unit); err != nil {
+				return fmt.Errorf("batch of item %s: %w", b.ItemID, err)
+			}
+			if b.Qty <= 0 {
+				continue
+			}
+			if _, err := tx.Exec(ctx, `
+			INSERT INTO medicine_txns (id, item_id, batch_id, actor_user_id, type, qty_change, reason)
+			VALUES (gen_random_uuid(),$1,$2,NULLIF($3,'')::uuid,$4,$5,$6)`,
+				b.ItemID, b.ID, actor, TxnIn, b.Qty, reason); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *pgRepo) UsageHistory(ctx context.Context, householdID string) ([]ItemUsage, error) {
+	rows, err := r.db.Query(ctx, `
+	SELECT t.item_id, min(t.created_at), max(t.created_at), -sum(t.qty_change)::float8, count(*)
+	FROM medicine_txns t JOIN medicine_items i ON i.id = t.item_id
+	WHERE i.household_id = $1 AND t.type = 'out'
+	GROUP BY t.item_id`, householdID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ItemUsage
+	for rows.Next() {
+		var u ItemUsage
+		if err := rows.Scan(&u.ItemID, &u.FirstOut, &u.LastOut, &u.TotalOut, &u.Count); err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
+
 // ---------- Locations ----------
 
 func (r *pgRepo) CreateLocation(ctx context.Context, loc *MedicineLocation) error {
@@ -301,3 +1084,22 @@ func (r *pgRepo) GetAlert(ctx context.Context, itemID string) (*MedicineAlert, e
 	}
 	return &a, nil
 }
+
+// ListHouseholdIDs บ้านที่มียาอยู่ในระบบ (ให้ AlertWorker วนสแกน)
+func (r *pgRepo) ListHouseholdIDs(ctx context.Context) ([]string, error) {
+	rows, err := r.db.Query(ctx, `SELECT DISTINCT household_id FROM medicine_items WHERE is_archived=false`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
@@ -3,7 +3,9 @@ package medicine
 
 import (
 	"context"
-	"sort"
+	"errors"
+	"fmt"
+	"math"
 	"time"
 )
 
@@ -11,6 +13,13 @@ import (
 type Service struct {
 	Repo Repo
 	Now  func() time.Time
+
+	// Location timezone ของเวลาในตารางยา (Regimen.Times); nil = เวลาเครื่อง
+	Location *time.Location
+	// MissedAfter นัดที่เลยเวลาเกินเท่านี้โดยไม่มี log = พลาด; 0 = DefaultMissedAfter
+	MissedAfter time.Duration
+	// Catalog แคตตาล็อกสินค้าสำหรับบาร์โค้ดที่บ้านยังไม่มี; nil = ค้นเฉพาะยาของบ้าน
+	Catalog Catalog
 }
 
 // ---------- Item ----------
@@ -19,8 +28,12 @@ func (s *Service) CreateItem(ctx context.Context, it *MedicineItem) error {
 	if it == nil || it.Name == "" || it.Unit == "" || it.HouseholdID == "" {
 		return ErrBadInput
 	}
+	if err := s.checkPhoto(ctx, it.HouseholdID, it.PhotoFileID); err != nil {
+		return err
+	}
 	// normalize เบื้องต้น
 	it.IsArchived = false
+	it.Version = 1
 	if it.CreatedAt.IsZero() {
 		it.CreatedAt = s.Now()
 	}
@@ -82,10 +95,15 @@ func (s *Service) GetItemFull(ctx context.Context, householdID, itemID string) (
 	}, nil
 }
 
-func (s *Service) UpdateItemPartial(ctx context.Context, householdID, id string, patch map[string]any) error {
+// UpdateItemPartial คืน version ใหม่; ifMatch != 0 ต้องตรงกับ version ปัจจุบัน
+// ขัดแย้งคืน *ConflictError ที่มีรายละเอียดล่าสุด
+func (s *Service) UpdateItemPartial(ctx context.Context, householdID, id string, ifMatch int, patch map[string]any) (int, error) {
 	it, err := s.Repo.GetItem(ctx, householdID, id)
 	if err != nil {
-		return err
+		return 0, err
+	}
+	if ifMatch != 0 && ifMatch != it.Version {
+		return 0, s.conflict(ctx, householdID, id, ErrPreconditionFailed)
 	}
 	if v, ok := patch["name"].(string); ok && v != "" {
 		it.Name = v
@@ -111,14 +129,82 @@ func (s *Service) UpdateItemPartial(ctx context.Context, householdID, id string,
 	if v, ok := patch["notes"].(string); ok {
 		it.Notes = &v
 	}
+	if v, ok := patch["photo_file_id"].(string); ok {
+		it.PhotoFileID = nil // "" = เอารูปออก
+		if v != "" {
+			it.PhotoFileID = &v
+		}
+		if err := s.checkPhoto(ctx, householdID, it.PhotoFileID); err != nil {
+			return 0, err
+		}
+	}
 	it.UpdatedAt = s.Now()
-	return s.Repo.UpdateItem(ctx, it)
+	if err := s.Repo.UpdateItem(ctx, it); err != nil {
+		if errors.Is(err, ErrConflict) {
+			return 0, s.conflict(ctx, householdID, id, err)
+		}
+		return 0, err
+	}
+	return it.Version, nil
+}
+
+// checkPhoto รูปยาต้องเป็นไฟล์ของสมาชิกบ้านนี้: ไฟล์ที่ถูกอ้าง สมาชิกบ้านอ่านได้ทุกคน
+func (s *Service) checkPhoto(ctx context.Context, householdID string, fileID *string) error {
+	if fileID == nil {
+		return nil
+	}
+	ok, err := s.Repo.FileUsable(ctx, householdID, *fileID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: photo_file_id is not a file of this household", ErrBadInput)
+	}
+	return nil
+}
+
+// conflict แนบรายละเอียดยาล่าสุดไปกับ err (อ่านไม่ได้ เช่นถูกเก็บเข้าคลังแล้ว = คืน error นั้น)
+func (s *Service) conflict(ctx context.Context, householdID, id string, err error) error {
+	cur, gerr := s.GetItemFull(ctx, householdID, id)
+	if gerr != nil {
+		return gerr
+	}
+	return &ConflictError{Err: err, Current: cur}
 }
 
 func (s *Service) ArchiveItem(ctx context.Context, householdID, id string) error {
 	return s.Repo.ArchiveItem(ctx, householdID, id)
 }
 
+// RestockNeeds ยาที่ LowStock (ตาม alert) พร้อมจำนวนที่ควรซื้อ; ids ว่าง = ทุกตัว ไม่งั้นเฉพาะ ids ที่ต่ำจริง
+func (s *Service) RestockNeeds(ctx context.Context, householdID string, ids []string) ([]RestockNeed, error) {
+	items, err := s.ListItems(ctx, householdID, ListItemFilter{})
+	if err != nil {
+		return nil, err
+	}
+	want := map[string]bool{}
+	for _, id := range ids {
+		want[id] = true
+	}
+	out := []RestockNeed{}
+	for _, it := range items {
+		if !it.LowStock || (len(want) > 0 && !want[it.Item.ID]) {
+			continue
+		}
+		al, err := s.Repo.GetAlert(ctx, it.Item.ID)
+		if err != nil || al == nil || al.MinQty == nil {
+			continue // LowStock ตั้งจาก alert เดียวกัน ไม่ควรเกิด
+		}
+		out = append(out, RestockNeed{
+			Item:      it.Item,
+			TotalQty:  it.TotalQty,
+			MinQty:    *al.MinQty,
+			Suggested: math.Ceil(2**al.MinQty - it.TotalQty),
+		})
+	}
+	return out, nil
+}
+
 // ---------- Batch ----------
 
 func (s *Service) AddBatch(ctx context.Context, householdID string, b *MedicineBatch) error {
@@ -145,13 +231,22 @@ func (s *Service) AddBatch(ctx context.Context, householdID string, b *MedicineB
 	return s.Repo.CreateBatch(ctx, b)
 }
 
+// ensureItem กันไม่ให้บ้านอื่นแตะ item ที่ไม่ใช่ของตัวเอง (batch/txn/alert อ้าง item_id อย่างเดียว)
+func (s *Service) ensureItem(ctx context.Context, householdID, itemID string) error {
+	_, err := s.Repo.GetItem(ctx, householdID, itemID)
+	return err
+}
+
 // ---------- Transactions ----------
 
 // ReceiveIn: รับเข้าแบบชี้ batch ตรง ๆ
-func (s *Service) ReceiveIn(ctx context.Context, itemID, batchID string, qty float64, reason *string, actor string) (map[string]any, error) {
+func (s *Service) ReceiveIn(ctx context.Context, householdID, itemID, batchID string, qty float64, reason *string, actor string) (map[string]any, error) {
 	if qty <= 0 {
 		return nil, ErrBadInput
 	}
+	if err := s.ensureItem(ctx, householdID, itemID); err != nil {
+		return nil, err
+	}
 	t := &MedicineTxn{
 		ItemID:    itemID,
 		BatchID:   &batchID,
@@ -168,10 +263,13 @@ func (s *Service) ReceiveIn(ctx context.Context, itemID, batchID string, qty flo
 }
 
 // Adjust: ปรับยอดจากการตรวจนับ (±delta) แบบชี้ batch
-func (s *Service) Adjust(ctx context.Context, itemID, batchID string, delta float64, reason *string, actor string) (map[string]any, error) {
+func (s *Service) Adjust(ctx context.Context, householdID, itemID, batchID string, delta float64, reason *string, actor string) (map[string]any, error) {
 	if delta == 0 {
 		return nil, ErrBadInput
 	}
+	if err := s.ensureItem(ctx, householdID, itemID); err != nil {
+		return nil, err
+	}
 	t := &MedicineTxn{
 		ItemID:    itemID,
 		BatchID:   &batchID,
@@ -188,80 +286,37 @@ func (s *Service) Adjust(ctx context.Context, itemID, batchID string, delta floa
 }
 
 // UseOut: เบิก/ใช้ โดยไม่ต้องชี้ batch -> FEFO (First-Expire-First-Out)
-func (s *Service) UseOut(ctx context.Context, itemID string, qty float64, reason *string, actor string) (map[string]any, error) {
+// ตัดหลาย batch ใน transaction เดียว (ไม่พอ = ไม่ตัดเลย); batch ที่หมดอายุแล้วข้ามเว้นแต่ force
+func (s *Service) UseOut(ctx context.Context, householdID, itemID string, qty float64, reason *string, actor string, force bool) (map[string]any, error) {
 	if qty <= 0 {
 		return nil, ErrBadInput
 	}
-	batches, err := s.Repo.GetBatchesByItem(ctx, itemID)
-	if err != nil {
+	if err := s.ensureItem(ctx, householdID, itemID); err != nil {
 		return nil, err
 	}
-	if len(batches) == 0 {
-		return nil, ErrNoStock
-	}
-
-	// เรียง FEFO: วันหมดอายุใกล้สุดก่อน, ถ้าไม่มีวันหมดอายุให้ไปท้าย
-	sort.SliceStable(batches, func(i, j int) bool {
-		if batches[i].Expiry == nil && batches[j].Expiry == nil {
-			return batches[i].CreatedAt.Before(batches[j].CreatedAt)
-		}
-		if batches[i].Expiry == nil {
-			return false
-		}
-		if batches[j].Expiry == nil {
-			return true
-		}
-		return batches[i].Expiry.Before(*batches[j].Expiry)
+	txns, err := s.Repo.DispenseFEFO(ctx, Dispense{
+		ItemID: itemID, Qty: qty, AllowExpired: force, AsOf: s.Now(), ActorID: actor, Reason: reason,
 	})
+	if err != nil {
+		return nil, err
+	}
 
-	need := qty
-	affected := make([]map[string]any, 0, 3)
-
-	for i := range batches {
-		if need <= 0 {
-			break
-		}
-		if batches[i].Qty <= 0 {
-			continue
-		}
-
-		use := batches[i].Qty
-		if use > need {
-			use = need
-		}
-		delta := -use // ออกเป็นค่าลบ
-
-		t := &MedicineTxn{
-			ItemID:    itemID,
-			BatchID:   &batches[i].ID,
-			ActorID:   actor,
-			Type:      TxnOut,
-			QtyChange: delta,
-			Reason:    reason,
-			CreatedAt: s.Now(),
-		}
-		if err := s.Repo.ApplyTxnAdjustQty(ctx, t); err != nil {
-			return nil, err
-		}
+	affected := make([]map[string]any, 0, len(txns))
+	for _, t := range txns {
 		affected = append(affected, map[string]any{
-			"batch_id":  batches[i].ID,
-			"qty_delta": delta,
+			"batch_id":  *t.BatchID,
+			"qty_delta": t.QtyChange,
 		})
-		need -= use
-	}
-
-	if need > 0 {
-		// ยังคงต้องการอีก แสดงว่า stock รวมไม่พอ → ย้อนกลับยากเพราะคอมมิตทีละ batch ไปแล้ว
-		// ใน production อาจหุ้มทั้งหมดด้วยกลไกระดับ service transaction แยกอีกชั้น
-		return nil, ErrNoStock
 	}
-
-	return map[string]any{"affected_batches": affected}, nil
+	return map[string]any{"affected_batches": affected, "txns": txns}, nil
 }
 
 // ---------- Alerts ----------
 
-func (s *Service) SetAlert(ctx context.Context, itemID string, minQty *float64, expiryDays *int, enabled *bool) error {
+func (s *Service) SetAlert(ctx context.Context, householdID, itemID string, minQty *float64, expiryDays *int, enabled *bool) error {
+	if err := s.ensureItem(ctx, householdID, itemID); err != nil {
+		return err
+	}
 	al := &MedicineAlert{
 		ItemID:           itemID,
 		MinQty:           minQty,
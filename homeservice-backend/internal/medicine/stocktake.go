@@ -0,0 +1,218 @@
+// internal/medicine/stocktake.go
+package medicine
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// sameBatchKey batch เดียวกัน = lot และวันหมดอายุ (ตามวันที่) ตรงกัน
+func sameBatchKey(b MedicineBatch, lot *string, expiry *time.Time) bool {
+	if (b.LotNo == nil) != (lot == nil) || (lot != nil && *b.LotNo != *lot) {
+		return false
+	}
+	if (b.Expiry == nil) != (expiry == nil) {
+		return false
+	}
+	return expiry == nil || b.Expiry.Format("2006-01-02") == expiry.Format("2006-01-02")
+}
+
+// OpenStocktake เปิดรอบตรวจนับของที่เก็บ (รอบเดียวต่อที่เก็บ: มีรอบเปิดอยู่แล้ว = ErrConflict)
+func (s *Service) OpenStocktake(ctx context.Context, householdID, locationID string, note *string, actor string) (*Stocktake, error) {
+	locs, err := s.Repo.ListLocations(ctx, householdID)
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for _, l := range locs {
+		found = found || l.ID == locationID
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+	st := &Stocktake{HouseholdID: householdID, LocationID: locationID, Note: note}
+	if actor != "" {
+		st.CreatedBy = &actor
+	}
+	if err := s.Repo.CreateStocktake(ctx, st); err != nil {
+		return nil, err
+	}
+	return s.withLines(ctx, st)
+}
+
+func (s *Service) withLines(ctx context.Context, st *Stocktake) (*Stocktake, error) {
+	lines, err := s.Repo.StocktakeLines(ctx, st)
+	if err != nil {
+		return nil, err
+	}
+	st.Lines = lines
+	return st, nil
+}
+
+// GetStocktake รายละเอียดรอบ + บรรทัดทั้งหมด (รอบที่เปิดอยู่ = preview ส่วนต่างกับยอดปัจจุบัน)
+func (s *Service) GetStocktake(ctx context.Context, householdID, id string) (*Stocktake, error) {
+	st, err := s.Repo.GetStocktake(ctx, householdID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.withLines(ctx, st)
+}
+
+// RecordCounts บันทึกจำนวนที่นับได้ (นับซ้ำ = แทนค่าเดิม)
+// batch ที่ไม่ระบุ batch_id แต่ lot/วันหมดอายุตรงกับ batch ที่มีอยู่ ถูกผูกกับ batch นั้น
+func (s *Service) RecordCounts(ctx context.Context, householdID, id string, counts []StocktakeCount, actor string) (*Stocktake, error) {
+	st, err := s.Repo.GetStocktake(ctx, householdID, id)
+	if err != nil {
+		return nil, err
+	}
+	if st.Status != StocktakeOpen {
+		return nil, ErrConflict
+	}
+	if len(counts) == 0 {
+		return nil, ErrBadInput
+	}
+
+	// นับได้เฉพาะยาที่อยู่ในที่เก็บของรอบนี้ (batch ของที่เก็บอื่นจะถูกปรับยอดผิดรอบตอน commit)
+	located := map[string]bool{} // item_id -> อยู่ที่ st.LocationID
+	checkItem := func(itemID string) error {
+		ok, seen := located[itemID]
+		if !seen {
+			it, err := s.Repo.GetItem(ctx, householdID, itemID)
+			if err != nil {
+				return err
+			}
+			ok = it.LocationID != nil && *it.LocationID == st.LocationID
+			located[itemID] = ok
+		}
+		if !ok {
+			return fmt.Errorf("%w: item is not stored at this stocktake's location", ErrBadInput)
+		}
+		return nil
+	}
+	batches := map[string][]MedicineBatch{} // item_id -> batches
+	batchesOf := func(itemID string) ([]MedicineBatch, error) {
+		if bs, ok := batches[itemID]; ok {
+			return bs, nil
+		}
+		if err := checkItem(itemID); err != nil {
+			return nil, err
+		}
+		bs, err := s.Repo.GetBatchesByItem(ctx, itemID)
+		if err != nil {
+			return nil, err
+		}
+		batches[itemID] = bs
+		return bs, nil
+	}
+
+	for i := range counts {
+		c := &counts[i]
+		if c.Qty < 0 || math.IsNaN(c.Qty) {
+			return nil, fmt.Errorf("%w: counts[%d]: counted_qty must be >= 0", ErrBadInput, i)
+		}
+		if c.BatchID != nil && *c.BatchID == "" {
+			c.BatchID = nil
+		}
+		if c.BatchID != nil {
+			b, err := s.Repo.GetBatch(ctx, householdID, *c.BatchID)
+			if err != nil {
+				return nil, fmt.Errorf("counts[%d]: %w", i, err)
+			}
+			if err := checkItem(b.ItemID); err != nil {
+				return nil, fmt.Errorf("counts[%d]: %w", i, err)
+			}
+			c.ItemID = b.ItemID
+			continue
+		}
+		if c.ItemID == "" {
+			return nil, fmt.Errorf("%w: counts[%d]: batch_id or item_id required", ErrBadInput, i)
+		}
+		bs, err := batchesOf(c.ItemID)
+		if err != nil {
+			return nil, fmt.Errorf("counts[%d]: %w", i, err)
+		}
+		for _, b := range bs {
+			if sameBatchKey(b, c.LotNo, c.Expiry) {
+				c.BatchID = &b.ID
+				break
+			}
+		}
+		if c.BatchID == nil && c.Qty <= 0 {
+			return nil, fmt.Errorf("%w: counts[%d]: new batch needs counted_qty > 0", ErrBadInput, i)
+		}
+	}
+	if err := s.Repo.UpsertCounts(ctx, st.ID, counts, actor); err != nil {
+		return nil, err
+	}
+	return s.withLines(ctx, st)
+}
+
+// CommitStocktake ปรับยอดตามที่นับเป็น txn adjust ที่ผูก stocktake_id แล้วปิดรอบ
+func (s *Service) CommitStocktake(ctx context.Context, householdID, id, actor string, zeroUncounted bool) (*Stocktake, []MedicineTxn, error) {
+	st, err := s.Repo.GetStocktake(ctx, householdID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	txns, err := s.Repo.CommitStocktake(ctx, st, actor, zeroUncounted)
+	if err != nil {
+		return nil, nil, err
+	}
+	st, err = s.withLines(ctx, st)
+	return st, txns, err
+}
+
+// StocktakeItemSummary ส่วนต่างสะสมต่อยาในช่วงรายงาน
+type StocktakeItemSummary struct {
+	ItemID      string  `json:"item_id"`
+	ItemName    string  `json:"item_name"`
+	Unit        string  `json:"unit"`
+	Stocktakes  int     `json:"stocktakes"`   // จำนวนรอบที่นับไม่ตรง
+	NetVariance float64 `json:"net_variance"` // ขาด (-) / เกิน (+) สุทธิ
+	Missing     float64 `json:"missing"`      // รวมที่หายไป (ค่าบวก)
+	Found       float64 `json:"found"`        // รวมที่เจอเกิน
+}
+
+// StocktakeReport ประวัติส่วนต่างจากการตรวจนับ
+type StocktakeReport struct {
+	From  *time.Time             `json:"from,omitempty"`
+	To    *time.Time             `json:"to,omitempty"`
+	Items []StocktakeItemSummary `json:"items"` // เรียงตามที่หายมากสุดก่อน
+	Lines []StocktakeVariance    `json:"lines"` // เรียงตามเวลา commit
+}
+
+func (s *Service) StocktakeReport(ctx context.Context, householdID, locationID, itemID string, from, to *time.Time) (*StocktakeReport, error) {
+	lines, err := s.Repo.StocktakeVariances(ctx, householdID, locationID, itemID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	byItem := map[string]*StocktakeItemSummary{}
+	seen := map[string]bool{} // item+stocktake ที่นับแล้ว
+	var order []string
+	for _, l := range lines {
+		sum := byItem[l.ItemID]
+		if sum == nil {
+			sum = &StocktakeItemSummary{ItemID: l.ItemID, ItemName: l.ItemName, Unit: l.Unit}
+			byItem[l.ItemID] = sum
+			order = append(order, l.ItemID)
+		}
+		if k := l.ItemID + "@" + l.StocktakeID; !seen[k] {
+			seen[k] = true
+			sum.Stocktakes++
+		}
+		sum.NetVariance += l.Variance
+		if l.Variance < 0 {
+			sum.Missing -= l.Variance
+		} else {
+			sum.Found += l.Variance
+		}
+	}
+	items := make([]StocktakeItemSummary, 0, len(order))
+	for _, id := range order {
+		items = append(items, *byItem[id])
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Missing > items[j].Missing })
+	return &StocktakeReport{From: from, To: to, Items: items, Lines: lines}, nil
+}
@@ -0,0 +1,336 @@
+// internal/medicine/transfer.go
+package medicine
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// inventoryCSVHeader คอลัมน์ของไฟล์ export/import (แถวละ 1 batch)
+var inventoryCSVHeader = []string{
+	"location", "item_id", "name", "generic_name", "form", "strength", "category", "unit", "gtin", "notes",
+	"batch_id", "lot_no", "expiry_date", "qty",
+}
+
+// ExportCSV เขียนตู้ยาของบ้านเป็น CSV (ยาที่ไม่มี batch คงเหลือ/ที่เก็บว่างก็มีแถว จึง import กลับได้ครบ)
+func (s *Service) ExportCSV(ctx context.Context, householdID string, w io.Writer) error {
+	rows, err := s.Repo.InventoryRows(ctx, householdID)
+	if err != nil {
+		return err
+	}
+	str := func(p *string) string {
+		if p == nil {
+			return ""
+		}
+		return *p
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(inventoryCSVHeader); err != nil {
+		return err
+	}
+	for _, x := range rows {
+		var expiry, qty string
+		if x.Expiry != nil {
+			expiry = x.Expiry.Format("2006-01-02")
+		}
+		if x.Qty != nil {
+			qty = strconv.FormatFloat(*x.Qty, 'f', -1, 64)
+		}
+		if err := cw.Write([]string{
+			str(x.Location), str(x.ItemID), str(x.Name), str(x.GenericName), str(x.Form), str(x.Strength),
+			str(x.Category), str(x.Unit), str(x.GTIN), str(x.Notes), str(x.BatchID), str(x.LotNo), expiry, qty,
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ImportRow ผลของแต่ละแถว: created | existing | merged (batch ซ้ำในไฟล์) | "" (ไม่มีส่วนนั้น)
+type ImportRow struct {
+	Row      int    `json:"row"` // เลขบรรทัดในไฟล์ (หัวคอลัมน์ = 1)
+	Location string `json:"location,omitempty"`
+	Item     string `json:"item,omitempty"`
+	Batch    string `json:"batch,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ImportReport ผลตรวจ/นำเข้า; มี error แม้แถวเดียว = ไม่เขียนอะไรเลย
+type ImportReport struct {
+	DryRun           bool        `json:"dry_run"`
+	Applied          bool        `json:"applied"`
+	Rows             []ImportRow `json:"rows"`
+	Errors           int         `json:"errors"`
+	LocationsCreated int         `json:"locations_created"`
+	ItemsCreated     int         `json:"items_created"`
+	BatchesCreated   int         `json:"batches_created"`
+}
+
+func normName(s string) string { return strings.ToLower(strings.Join(strings.Fields(s), " ")) }
+
+// itemKey ยาตัวเดียวกัน = ชื่อ + ความแรง + หน่วย (ไม่สนตัวพิมพ์/ช่องว่าง)
+func itemKey(name string, strength *string, unit string) string {
+	st := ""
+	if strength != nil {
+		st = *strength
+	}
+	return normName(name) + "|" + normName(st) + "|" + normName(unit)
+}
+
+// ImportCSV ตรวจไฟล์ทั้งไฟล์ก่อน แล้ว (ถ้าไม่ใช่ dry-run และไม่มี error) สร้างของใหม่ทั้งหมดใน transaction เดียว
+// จับคู่ของเดิม: ที่เก็บตามชื่อ, ยาตาม item_id → gtin → ชื่อ+ความแรง+หน่วย, batch ตาม lot+วันหมดอายุ
+// ของที่มีอยู่แล้วไม่ถูกแก้ (import ไฟล์ที่ export ไปซ้ำจึงไม่เพิ่มสต็อก)
+func (s *Service) ImportCSV(ctx context.Context, householdID string, r io.Reader, dryRun bool, actor string) (*ImportReport, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	cr.FieldsPerRecord = -1
+	head, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadInput, err)
+	}
+	col := map[string]int{}
+	for i, h := range head {
+		col[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))] = i
+	}
+	if _, ok := col["name"]; !ok {
+		return nil, fmt.Errorf("%w: csv needs columns %s", ErrBadInput, strings.Join(inventoryCSVHeader, ","))
+	}
+
+	// ของเดิมในบ้าน
+	locs, err := s.Repo.ListLocations(ctx, householdID)
+	if err != nil {
+		return nil, err
+	}
+	locByName := map[string]string{}
+	for _, l := range locs {
+		locByName[normName(l.Name)] = l.ID
+	}
+	items, err := s.Repo.ListItems(ctx, householdID, ListItemFilter{})
+	if err != nil {
+		return nil, err
+	}
+	itemByID, itemByGTIN, itemByKey := map[string]*MedicineItem{}, map[string]*MedicineItem{}, map[string]*MedicineItem{}
+	addItem := func(it *MedicineItem) {
+		itemByID[it.ID] = it
+		if it.GTIN != nil {
+			if g, err := NormalizeGTIN(*it.GTIN); err == nil {
+				itemByGTIN[g] = it
+			}
+		}
+		itemByKey[itemKey(it.Name, it.Strength, it.Unit)] = it
+	}
+	for i := range items {
+		addItem(&items[i].Item)
+	}
+	batchesOf := map[string][]MedicineBatch{}
+	existingBatches := func(itemID string) ([]MedicineBatch, error) {
+		if bs, ok := batchesOf[itemID]; ok {
+			return bs, nil
+		}
+		bs, err := s.Repo.GetBatchesByItem(ctx, itemID)
+		batchesOf[itemID] = bs
+		return bs, err
+	}
+
+	rep := &ImportReport{DryRun: dryRun, Rows: []ImportRow{}}
+	var newLocs []MedicineLocation
+	newLocIDs := map[string]bool{}
+	var newItems []*MedicineItem
+	newItemIDs := map[string]bool{}
+	var newBatches []*MedicineBatch
+
+	line := 1
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		res := ImportRow{Row: line}
+		if err != nil {
+			res.Error = err.Error()
+			rep.Rows = append(rep.Rows, res)
+			rep.Errors++
+			continue
+		}
+		get := func(k string) string {
+			if i, ok := col[k]; ok && i < len(rec) {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+		opt := func(k string) *string {
+			if v := get(k); v != "" {
+				return &v
+			}
+			return nil
+		}
+		fail := func(format string, a ...any) {
+			res.Error = fmt.Sprintf(format, a...)
+			rep.Rows = append(rep.Rows, res)
+			rep.Errors++
+		}
+
+		// ตรวจค่าทั้งแถวก่อนสร้างอะไร (แถวผิดไม่ทิ้งของค้างใน plan)
+		var gtin *string
+		if v := get("gtin"); v != "" {
+			g, err := NormalizeGTIN(v)
+			if err != nil {
+				fail("%v", err)
+				continue
+			}
+			gtin = &g
+		}
+		form := Form(strings.ToLower(get("form")))
+		if form == "" {
+			form = FormOther
+		}
+		if !form.valid() {
+			fail("unknown form %q", form)
+			continue
+		}
+		var qty float64
+		if v := get("qty"); v != "" {
+			q, err := strconv.ParseFloat(v, 64)
+			if err != nil || q < 0 {
+				fail("qty must be a number >= 0")
+				continue
+			}
+			qty = q
+		}
+		var expiry *time.Time
+		if v := get("expiry_date"); v != "" {
+			t, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				fail("expiry_date must be YYYY-MM-DD")
+				continue
+			}
+			expiry = &t
+		}
+		name, unit := get("name"), get("unit")
+		if name == "" && (get("item_id") != "" || unit != "" || get("qty") != "") {
+			fail("name required")
+			continue
+		}
+
+		// ที่เก็บ
+		var locID *string
+		if ln := get("location"); ln != "" {
+			id, ok := locByName[normName(ln)]
+			if !ok {
+				id = uuid.NewString()
+				locByName[normName(ln)] = id
+				newLocIDs[id] = true
+				newLocs = append(newLocs, MedicineLocation{ID: id, HouseholdID: householdID, Name: ln, IsActive: true})
+			}
+			res.Location = "existing"
+			if newLocIDs[id] { // รวมที่สร้างจากแถวก่อนหน้าในไฟล์นี้
+				res.Location = "created"
+			}
+			locID = &id
+		}
+		if name == "" {
+			if locID == nil {
+				fail("empty row")
+				continue
+			}
+			rep.Rows = append(rep.Rows, res)
+			continue
+		}
+
+		// ยา
+		var it *MedicineItem
+		if id := get("item_id"); id != "" {
+			it = itemByID[id]
+		}
+		if it == nil && gtin != nil {
+			it = itemByGTIN[*gtin]
+		}
+		if it == nil {
+			it = itemByKey[itemKey(name, opt("strength"), unit)]
+		}
+		if it != nil {
+			res.Item = "existing"
+			if newItemIDs[it.ID] {
+				res.Item = "created"
+			}
+			if unit != "" && unit != it.Unit {
+				fail("unit %q does not match item %q (%s)", unit, it.Name, it.Unit)
+				continue
+			}
+		} else {
+			if unit == "" {
+				fail("unit required for new item")
+				continue
+			}
+			it = &MedicineItem{
+				ID: uuid.NewString(), HouseholdID: householdID, Name: name, GenericName: opt("generic_name"),
+				Form: form, Strength: opt("strength"), Category: opt("category"), Unit: unit,
+				LocationID: locID, GTIN: gtin, Notes: opt("notes"),
+			}
+			addItem(it)
+			newItemIDs[it.ID] = true
+			newItems = append(newItems, it)
+			res.Item = "created"
+		}
+
+		// batch (ไม่มี qty/lot/วันหมดอายุ = แถวของยาอย่างเดียว)
+		lot := opt("lot_no")
+		if qty > 0 || lot != nil || expiry != nil {
+			var bs []MedicineBatch
+			if !newItemIDs[it.ID] {
+				if bs, err = existingBatches(it.ID); err != nil {
+					return nil, err
+				}
+			}
+			for _, b := range bs {
+				if sameBatchKey(b, lot, expiry) {
+					res.Batch = "existing"
+					break
+				}
+			}
+			if res.Batch == "" {
+				for _, b := range newBatches {
+					if b.ItemID == it.ID && sameBatchKey(*b, lot, expiry) {
+						b.Qty += qty
+						res.Batch = "merged"
+						break
+					}
+				}
+			}
+			if res.Batch == "" {
+				newBatches = append(newBatches, &MedicineBatch{
+					ID: uuid.NewString(), ItemID: it.ID, LotNo: lot, Expiry: expiry, Qty: qty, Unit: it.Unit,
+				})
+				res.Batch = "created"
+			}
+		}
+		rep.Rows = append(rep.Rows, res)
+	}
+
+	rep.LocationsCreated, rep.ItemsCreated, rep.BatchesCreated = len(newLocs), len(newItems), len(newBatches)
+	if dryRun || rep.Errors > 0 {
+		return rep, nil
+	}
+	its := make([]MedicineItem, len(newItems))
+	for i, it := range newItems {
+		its[i] = *it
+	}
+	bs := make([]MedicineBatch, len(newBatches))
+	for i, b := range newBatches {
+		bs[i] = *b
+	}
+	if err := s.Repo.ImportInventory(ctx, newLocs, its, bs, actor); err != nil {
+		return nil, err
+	}
+	rep.Applied = true
+	return rep, nil
+}
@@ -3,6 +3,7 @@ package medicine
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 )
@@ -12,16 +13,32 @@ import (
 type Notifier interface {
 	NotifyLowStock(ctx context.Context, householdID, itemID string, message string) error
 	NotifyExpiring(ctx context.Context, householdID, itemID string, message string) error
+	NotifyMissedDose(ctx context.Context, householdID, regimenID string, scheduledAt time.Time, message string) error
 }
 
 // AlertWorker ทำหน้าที่สแกนยาในบ้าน และส่งแจ้งเตือน "ใกล้หมด" / "ใกล้หมดอายุ"
-// หมายเหตุ: การกันซ้ำ/คูลดาวน์ ควรจัดการในชั้น Notifier หรือ notification service ของคุณ
+// หมายเหตุ: การกันซ้ำ/คูลดาวน์ อยู่ในชั้น Notifier (notifications.Dispatcher) จึงสแกนถี่ได้
 type AlertWorker struct {
 	Svc      *Service
 	Notifier Notifier
 	Now      func() time.Time
 }
 
+// RunAll สแกนทุกบ้านที่มียา (scheduler เรียกวันละครั้ง); บ้านที่ error ไม่หยุดบ้านอื่น
+func (w *AlertWorker) RunAll(ctx context.Context) error {
+	hids, err := w.Svc.Repo.ListHouseholdIDs(ctx)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, hid := range hids {
+		if err := w.RunOnce(ctx, hid); err != nil {
+			errs = append(errs, fmt.Errorf("household %s: %w", hid, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // RunOnce สแกนและส่งแจ้งเตือนสำหรับบ้านหนึ่งหลัง
 // - ใช้กติกา alert ต่อ item (min_qty, expiry_window_days) จาก DB (ผ่าน Service.ListItems)
 // - สาระสำคัญ: ไม่แตะ SQL ตรง ๆ เพื่อให้ logic อยู่ที่ Service ที่เดียว
@@ -66,21 +83,3 @@ func (w *AlertWorker) now() time.Time {
 	}
 	return time.Now()
 }
-
-/*
-การใช้งาน (ตัวอย่าง):
-
-// wire
-repo := medicine.NewPGRepo(appDB)
-svc  := &medicine.Service{Repo: repo, Now: time.Now}
-note := &notifications.Service{DB: appDB} // ต้องมีเมธอดตาม Notifier
-
-worker := &medicine.AlertWorker{
-    Svc: svc,
-    Notifier: note,
-    Now: time.Now,
-}
-
-// schedule อาจวิ่งทุกวัน 07:00
-_ = worker.RunOnce(ctx, "<household-id>")
-*/
@@ -0,0 +1,65 @@
+package medicine
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DoseWorker บันทึกนัดกินยาที่เลยเวลาโดยไม่มีใครกด taken/skipped เป็น missed แล้วแจ้งเตือนบ้านนั้น
+// กันซ้ำด้วย unique (regimen_id, scheduled_at): นัดหนึ่งแจ้งได้ครั้งเดียว จึงรันถี่ได้
+type DoseWorker struct {
+	Svc      *Service
+	Notifier Notifier
+	// Lookback ย้อนดูนัดที่ยังไม่มี log ไกลสุดเท่านี้ (กันแจ้งย้อนหลังยาวหลังระบบดับนาน); 0 = 24 ชม.
+	Lookback time.Duration
+	Now      func() time.Time
+}
+
+// RunOnce สแกนตารางยาที่ active ของทุกบ้าน; regimen ที่ error ไม่หยุดตัวอื่น
+func (w *DoseWorker) RunOnce(ctx context.Context) error {
+	if w.Svc == nil || w.Notifier == nil {
+		return fmt.Errorf("worker not wired: svc or notifier is nil")
+	}
+	lookback := w.Lookback
+	if lookback <= 0 {
+		lookback = 24 * time.Hour
+	}
+	t := w.now()
+	to := t.Add(-w.Svc.missedAfter())
+	regs, err := w.Svc.Repo.ListActiveRegimens(ctx)
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	for _, rg := range regs {
+		from := t.Add(-lookback)
+		if rg.CreatedAt.After(from) {
+			from = rg.CreatedAt // ไม่นับนัดก่อนสร้างตาราง
+		}
+		for _, at := range rg.Occurrences(from, to, w.Svc.loc()) {
+			inserted, err := w.Svc.Repo.MarkMissed(ctx, rg.ID, at)
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			if !inserted {
+				continue
+			}
+			msg := fmt.Sprintf("%s ยังไม่ได้กินยาตามนัด %s (%g %s)",
+				rg.PersonName, at.In(w.Svc.loc()).Format("2006-01-02 15:04"), rg.Dose, rg.Unit)
+			_ = w.Notifier.NotifyMissedDose(ctx, rg.HouseholdID, rg.ID, at, msg)
+		}
+	}
+	return firstErr
+}
+
+func (w *DoseWorker) now() time.Time {
+	if w.Now != nil {
+		return w.Now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,339 @@
+// Package migrate รัน schema migrations แบบมีเวอร์ชัน (ไฟล์ NNNN_name.sql, รองรับ annotation แบบ goose)
+package migrate
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// lockKey: advisory lock กันหลาย replica รัน migration พร้อมกัน
+const lockKey int64 = 0x68736d6967 // "hsmig"
+
+// เป้าหมายพิเศษของ To (0000_init คือ version 0 จึงใช้ 0 แทน "ไม่มีอะไรเลย" ไม่ได้)
+const (
+	Latest int64 = math.MaxInt64 // ขึ้นถึงตัวล่าสุด
+	Empty  int64 = -1            // ย้อนทุกตัว รวม 0000_init
+)
+
+var (
+	ErrNoDown        = errors.New("migrate: migration has no down section")
+	ErrUnknownTarget = errors.New("migrate: unknown target version")
+)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+type Migrator struct {
+	DB   *pgxpool.Pool
+	FS   fs.FS
+	Logf func(format string, args ...any)
+}
+
+func New(db *pgxpool.Pool, fsys fs.FS) *Migrator {
+	return &Migrator{DB: db, FS: fsys}
+}
+
+func (m *Migrator) logf(format string, args ...any) {
+	if m.Logf != nil {
+		m.Logf(format, args...)
+	}
+}
+
+// Load อ่าน *.sql จาก fsys แล้วเรียงตามเวอร์ชัน (prefix ตัวเลขของชื่อไฟล์)
+func Load(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Migration, 0, len(names))
+	seen := map[int64]string{}
+	for _, name := range names {
+		base := strings.TrimSuffix(path.Base(name), ".sql")
+		num, label, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migrate: bad file name %q (want NNNN_name.sql)", name)
+		}
+		v, err := strconv.ParseInt(num, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migrate: bad version in %q: %w", name, err)
+		}
+		if prev, dup := seen[v]; dup {
+			return nil, fmt.Errorf("migrate: duplicate version %d (%s, %s)", v, prev, name)
+		}
+		seen[v] = name
+
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		up, down := split(string(b))
+		out = append(out, Migration{Version: v, Name: label, Up: up, Down: down})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// split แยก section ตาม "-- +goose Up" / "-- +goose Down"; ไฟล์ที่ไม่มี annotation ถือเป็น up ทั้งไฟล์
+func split(body string) (up, down string) {
+	if !strings.Contains(body, "+goose Up") {
+		return body, ""
+	}
+	var ub, db strings.Builder
+	var cur *strings.Builder
+	sc := bufio.NewScanner(strings.NewReader(body))
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		t := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(t, "-- +goose Up"):
+			cur = &ub
+			continue
+		case strings.HasPrefix(t, "-- +goose Down"):
+			cur = &db
+			continue
+		case strings.HasPrefix(t, "-- +goose"):
+			continue // StatementBegin/End: exec ทั้ง section ผ่าน simple protocol อยู่แล้ว
+		}
+		if cur != nil {
+			cur.WriteString(line)
+			cur.WriteByte('\n')
+		}
+	}
+	return ub.String(), db.String()
+}
+
+// withLock ถือ advisory lock บน connection เดียวตลอดการทำงาน
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgx.Conn) error) error {
+	c, err := m.DB.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Release()
+
+	conn := c.Conn()
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return err
+	}
+	defer func() { _, _ = conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey) }()
+
+	if _, err := conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+		  version     bigint PRIMARY KEY,
+		  name        text NOT NULL,
+		  applied_at  timestamptz NOT NULL DEFAULT now()
+		)`); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func applied(ctx context.Context, conn *pgx.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[int64]time.Time{}
+	for rows.Next() {
+		var v int64
+		var at time.Time
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, err
+		}
+		out[v] = at
+	}
+	return out, rows.Err()
+}
+
+// run รัน SQL ของ migration หนึ่งตัว + บันทึก/ลบ schema_migrations ใน transaction เดียว
+func run(ctx context.Context, conn *pgx.Conn, mg Migration, up bool) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	sql := mg.Up
+	if !up {
+		sql = mg.Down
+	}
+	if strings.TrimSpace(sql) != "" {
+		if _, err := tx.Exec(ctx, sql); err != nil {
+			return fmt.Errorf("migrate: %04d_%s: %w", mg.Version, mg.Name, err)
+		}
+	}
+	if up {
+		_, err = tx.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1,$2)`, mg.Version, mg.Name)
+	} else {
+		_, err = tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version=$1`, mg.Version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Up รันทุก migration ที่ยังไม่ได้รัน
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, Latest)
+}
+
+// Down ย้อน migration ล่าสุดหนึ่งตัว
+func (m *Migrator) Down(ctx context.Context) error {
+	all, err := Load(m.FS)
+	if err != nil {
+		return err
+	}
+	return m.withLock(ctx, func(conn *pgx.Conn) error {
+		done, err := applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(all) - 1; i >= 0; i-- {
+			if _, ok := done[all[i].Version]; !ok {
+				continue
+			}
+			return m.down(ctx, conn, all[i])
+		}
+		m.logf("migrate: nothing to roll back")
+		return nil
+	})
+}
+
+func (m *Migrator) down(ctx context.Context, conn *pgx.Conn, mg Migration) error {
+	if strings.TrimSpace(mg.Down) == "" {
+		return fmt.Errorf("%w: %04d_%s", ErrNoDown, mg.Version, mg.Name)
+	}
+	m.logf("migrate: down %04d_%s", mg.Version, mg.Name)
+	return run(ctx, conn, mg, false)
+}
+
+// To ขึ้น/ลงให้ schema อยู่ที่ version ที่ระบุ (Latest = ล่าสุด, Empty = ย้อนทุกตัว; 0 = เหลือแค่ 0000_init)
+func (m *Migrator) To(ctx context.Context, version int64) error {
+	all, err := Load(m.FS)
+	if err != nil {
+		return err
+	}
+	if version != Latest && version != Empty {
+		found := false
+		for _, mg := range all {
+			if mg.Version == version {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%w: %d", ErrUnknownTarget, version)
+		}
+	}
+	return m.withLock(ctx, func(conn *pgx.Conn) error {
+		done, err := applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		// up: ทุกตัวที่ <= target และยังไม่ได้รัน
+		n := 0
+		for _, mg := range all {
+			if mg.Version > version {
+				break
+			}
+			if _, ok := done[mg.Version]; ok {
+				continue
+			}
+			m.logf("migrate: up %04d_%s", mg.Version, mg.Name)
+			if err := run(ctx, conn, mg, true); err != nil {
+				return err
+			}
+			n++
+		}
+		// down: ตัวที่รันแล้วแต่ > target (ย้อนจากใหม่ไปเก่า)
+		for i := len(all) - 1; i >= 0; i-- {
+			mg := all[i]
+			if mg.Version <= version {
+				break
+			}
+			if _, ok := done[mg.Version]; !ok {
+				continue
+			}
+			if err := m.down(ctx, conn, mg); err != nil {
+				return err
+			}
+			n++
+		}
+		if n == 0 {
+			m.logf("migrate: schema is up to date")
+		}
+		return nil
+	})
+}
+
+// Baseline บันทึกว่า migration ถึง version นี้ถูกรันแล้ว โดยไม่รัน SQL
+// ใช้กับฐานข้อมูลเดิมที่เคยรันไฟล์ด้วย psql มาก่อนมี schema_migrations
+func (m *Migrator) Baseline(ctx context.Context, version int64) error {
+	all, err := Load(m.FS)
+	if err != nil {
+		return err
+	}
+	return m.withLock(ctx, func(conn *pgx.Conn) error {
+		for _, mg := range all {
+			if mg.Version > version {
+				break
+			}
+			if _, err := conn.Exec(ctx, `
+				INSERT INTO schema_migrations (version, name) VALUES ($1,$2)
+				ON CONFLICT (version) DO NOTHING
+			`, mg.Version, mg.Name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Status คืนทุก migration พร้อมเวลาที่รัน (nil = ยังไม่ได้รัน)
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	all, err := Load(m.FS)
+	if err != nil {
+		return nil, err
+	}
+	var out []Status
+	err = m.withLock(ctx, func(conn *pgx.Conn) error {
+		done, err := applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mg := range all {
+			st := Status{Version: mg.Version, Name: mg.Name}
+			if at, ok := done[mg.Version]; ok {
+				t := at
+				st.AppliedAt = &t
+			}
+			out = append(out, st)
+		}
+		return nil
+	})
+	return out, err
+}
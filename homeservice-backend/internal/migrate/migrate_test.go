@@ -0,0 +1,157 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		up, down string
+	}{
+		{
+			name: "no annotation is all up",
+			body: "CREATE TABLE a (id int);\n",
+			up:   "CREATE TABLE a (id int);\n",
+		},
+		{
+			name: "up and down",
+			body: "-- +goose Up\nCREATE TABLE a (id int);\n-- +goose Down\nDROP TABLE a;\n",
+			up:   "CREATE TABLE a (id int);\n",
+			down: "DROP TABLE a;\n",
+		},
+		{
+			name: "up only",
+			body: "-- +goose Up\nALTER TABLE a ADD COLUMN b int;\n",
+			up:   "ALTER TABLE a ADD COLUMN b int;\n",
+		},
+		{
+			name: "lines before Up ignored",
+			body: "-- header comment\n-- +goose Up\nSELECT 1;\n",
+			up:   "SELECT 1;\n",
+		},
+		{
+			name: "StatementBegin/End dropped",
+			body: "-- +goose Up\n-- +goose StatementBegin\nCREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql;\n-- +goose StatementEnd\n-- +goose Down\nDROP FUNCTION f();\n",
+			up:   "CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql;\n",
+			down: "DROP FUNCTION f();\n",
+		},
+		{
+			name: "indented markers, comments kept",
+			body: "  -- +goose Up\n-- keep me\nSELECT 1;\n  -- +goose Down\nSELECT 2;\n",
+			up:   "-- keep me\nSELECT 1;\n",
+			down: "SELECT 2;\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			up, down := split(tt.body)
+			if up != tt.up {
+				t.Errorf("up = %q, want %q", up, tt.up)
+			}
+			if down != tt.down {
+				t.Errorf("down = %q, want %q", down, tt.down)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	file := func(s string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(s)} }
+	tests := []struct {
+		name     string
+		fs       fstest.MapFS
+		versions []int64
+		names    []string
+		wantErr  bool
+	}{
+		{
+			name: "sorted by version, version 0 included",
+			fs: fstest.MapFS{
+				"0010_ten.sql":  file("SELECT 10;"),
+				"0000_init.sql": file("SELECT 0;"),
+				"0002_two.sql":  file("-- +goose Up\nSELECT 2;\n-- +goose Down\nSELECT -2;\n"),
+				"README.md":     file("not a migration"),
+			},
+			versions: []int64{0, 2, 10},
+			names:    []string{"init", "two", "ten"},
+		},
+		{
+			name: "label keeps later underscores",
+			fs: fstest.MapFS{
+				"0007_medicine_stock_takes.sql": file("SELECT 1;"),
+			},
+			versions: []int64{7},
+			names:    []string{"medicine_stock_takes"},
+		},
+		{
+			name:    "no underscore",
+			fs:      fstest.MapFS{"0001.sql": file("SELECT 1;")},
+			wantErr: true,
+		},
+		{
+			name:    "non-numeric version",
+			fs:      fstest.MapFS{"abcd_x.sql": file("SELECT 1;")},
+			wantErr: true,
+		},
+		{
+			name: "duplicate version",
+			fs: fstest.MapFS{
+				"0001_a.sql": file("SELECT 1;"),
+				"001_b.sql":  file("SELECT 1;"),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Load(tt.fs)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Load() = %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if len(got) != len(tt.versions) {
+				t.Fatalf("Load() returned %d migrations, want %d", len(got), len(tt.versions))
+			}
+			for i, mg := range got {
+				if mg.Version != tt.versions[i] || mg.Name != tt.names[i] {
+					t.Errorf("[%d] = %d_%s, want %d_%s", i, mg.Version, mg.Name, tt.versions[i], tt.names[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadSplitsSections(t *testing.T) {
+	got, err := Load(fstest.MapFS{
+		"0001_a.sql": {Data: []byte("-- +goose Up\nCREATE TABLE a ();\n-- +goose Down\nDROP TABLE a;\n")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0].Up != "CREATE TABLE a ();\n" || got[0].Down != "DROP TABLE a;\n" {
+		t.Errorf("sections = %q / %q", got[0].Up, got[0].Down)
+	}
+}
+
+// ตรวจ target ก่อนแตะฐานข้อมูล จึงทดสอบได้โดยไม่มี DB
+func TestToUnknownTarget(t *testing.T) {
+	m := &Migrator{FS: fstest.MapFS{
+		"0000_init.sql": {Data: []byte("SELECT 0;")},
+		"0001_a.sql":    {Data: []byte("SELECT 1;")},
+	}}
+	for _, v := range []int64{-2, 2, 99} {
+		if err := m.To(context.Background(), v); !errors.Is(err, ErrUnknownTarget) {
+			t.Errorf("To(%d) error = %v, want ErrUnknownTarget", v, err)
+		}
+	}
+}
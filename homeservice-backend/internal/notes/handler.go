@@ -9,6 +9,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/iMookatayou/homeservice-backend/internal/auth"
+	"github.com/iMookatayou/homeservice-backend/internal/httpx"
 )
 
 type Handler struct {
@@ -24,6 +25,7 @@ func (h Handler) RegisterRoutes(r chi.Router) {
 		r.Route("/{id}", func(r chi.Router) {
 			r.Get("/", h.get)
 			r.Put("/", h.update)
+			r.Patch("/", h.update)
 			r.Delete("/", h.delete)
 
 			// actions
@@ -40,9 +42,9 @@ func (h Handler) RegisterRoutes(r chi.Router) {
 }
 
 func (h Handler) list(w http.ResponseWriter, r *http.Request) {
-	claims := auth.ClaimsFrom(r)
-	if claims == nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	hid, ok := auth.HouseholdIDFrom(r)
+	if !ok {
+		http.Error(w, "household required", http.StatusForbidden)
 		return
 	}
 	q := strings.TrimSpace(r.URL.Query().Get("q"))
@@ -60,7 +62,7 @@ func (h Handler) list(w http.ResponseWriter, r *http.Request) {
 		pinned = &val
 	}
 
-	items, err := h.Repo.List(r.Context(), claims.UserID, ListFilter{
+	items, err := h.Repo.List(r.Context(), hid, ListFilter{
 		Query:    q,
 		Category: cat,
 		Pinned:   pinned,
@@ -75,13 +77,13 @@ func (h Handler) list(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h Handler) get(w http.ResponseWriter, r *http.Request) {
-	claims := auth.ClaimsFrom(r)
-	if claims == nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	hid, ok := auth.HouseholdIDFrom(r)
+	if !ok {
+		http.Error(w, "household required", http.StatusForbidden)
 		return
 	}
 	id := chi.URLParam(r, "id")
-	n, err := h.Repo.Get(r.Context(), claims.UserID, id)
+	n, err := h.Repo.Get(r.Context(), hid, id)
 	if err != nil {
 		if errors.Is(err, ErrNotFound) {
 			http.Error(w, "not found", http.StatusNotFound)
@@ -90,7 +92,7 @@ func (h Handler) get(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
 	}
-	writeJSON(w, http.StatusOK, n)
+	writeNote(w, http.StatusOK, n)
 }
 
 func (h Handler) create(w http.ResponseWriter, r *http.Request) {
@@ -99,6 +101,11 @@ func (h Handler) create(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
+	hid, ok := auth.HouseholdIDFrom(r)
+	if !ok {
+		http.Error(w, "household required", http.StatusForbidden)
+		return
+	}
 	var in CreateNoteReq
 	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
 		http.Error(w, "bad request", http.StatusBadRequest)
@@ -111,18 +118,18 @@ func (h Handler) create(w http.ResponseWriter, r *http.Request) {
 	if in.Category == "" {
 		in.Category = CatGeneral
 	}
-	n, err := h.Repo.Create(r.Context(), claims.UserID, in)
+	n, err := h.Repo.Create(r.Context(), hid, claims.UserID, in)
 	if err != nil {
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
 	}
-	writeJSON(w, http.StatusCreated, n)
+	writeNote(w, http.StatusCreated, n)
 }
 
 func (h Handler) update(w http.ResponseWriter, r *http.Request) {
-	claims := auth.ClaimsFrom(r)
-	if claims == nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	hid, ok := auth.HouseholdIDFrom(r)
+	if !ok {
+		http.Error(w, "household required", http.StatusForbidden)
 		return
 	}
 	var in UpdateNoteReq
@@ -130,27 +137,42 @@ func (h Handler) update(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "bad request", http.StatusBadRequest)
 		return
 	}
+	ifMatch, err := httpx.IfMatch(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	id := chi.URLParam(r, "id")
-	n, err := h.Repo.Update(r.Context(), claims.UserID, id, in)
+	n, err := h.Repo.Update(r.Context(), hid, id, ifMatch, in)
 	if err != nil {
-		if errors.Is(err, ErrNotFound) {
+		var ce *ConflictError
+		switch {
+		case errors.Is(err, ErrNotFound):
 			http.Error(w, "not found", http.StatusNotFound)
-			return
+		case errors.As(err, &ce):
+			// 409/412 พร้อมโน้ตล่าสุดให้ client merge แล้วส่งใหม่
+			code := http.StatusConflict
+			if errors.Is(err, ErrPreconditionFailed) {
+				code = http.StatusPreconditionFailed
+			}
+			httpx.SetETag(w, ce.Current.Version)
+			writeJSON(w, code, map[string]any{"error": err.Error(), "current": ce.Current})
+		default:
+			http.Error(w, "internal error", http.StatusInternalServerError)
 		}
-		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
 	}
-	writeJSON(w, http.StatusOK, n)
+	writeNote(w, http.StatusOK, n)
 }
 
 func (h Handler) delete(w http.ResponseWriter, r *http.Request) {
-	claims := auth.ClaimsFrom(r)
-	if claims == nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	hid, ok := auth.HouseholdIDFrom(r)
+	if !ok {
+		http.Error(w, "household required", http.StatusForbidden)
 		return
 	}
 	id := chi.URLParam(r, "id")
-	if err := h.Repo.Delete(r.Context(), claims.UserID, id); err != nil {
+	if err := h.Repo.Delete(r.Context(), hid, id); err != nil {
 		if errors.Is(err, ErrNotFound) {
 			http.Error(w, "not found", http.StatusNotFound)
 			return
@@ -163,13 +185,13 @@ func (h Handler) delete(w http.ResponseWriter, r *http.Request) {
 
 func (h Handler) pin(set bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		claims := auth.ClaimsFrom(r)
-		if claims == nil {
-			http.Error(w, "unauthorized", http.StatusUnauthorized)
+		hid, ok := auth.HouseholdIDFrom(r)
+		if !ok {
+			http.Error(w, "household required", http.StatusForbidden)
 			return
 		}
 		id := chi.URLParam(r, "id")
-		n, err := h.Repo.TogglePin(r.Context(), claims.UserID, id, set)
+		n, err := h.Repo.TogglePin(r.Context(), hid, id, set)
 		if err != nil {
 			if errors.Is(err, ErrNotFound) {
 				http.Error(w, "not found", http.StatusNotFound)
@@ -178,20 +200,20 @@ func (h Handler) pin(set bool) http.HandlerFunc {
 			http.Error(w, "internal error", http.StatusInternalServerError)
 			return
 		}
-		writeJSON(w, http.StatusOK, n)
+		writeNote(w, http.StatusOK, n)
 	}
 }
 
 // ---------- เสร็จสิ้น / ยกเลิกเสร็จสิ้น ----------
 
 func (h Handler) done(w http.ResponseWriter, r *http.Request) {
-	claims := auth.ClaimsFrom(r)
-	if claims == nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	hid, ok := auth.HouseholdIDFrom(r)
+	if !ok {
+		http.Error(w, "household required", http.StatusForbidden)
 		return
 	}
 	id := chi.URLParam(r, "id")
-	n, err := h.Repo.MarkDone(r.Context(), claims.UserID, id)
+	n, err := h.Repo.MarkDone(r.Context(), hid, id)
 	if err != nil {
 		if errors.Is(err, ErrNotFound) {
 			http.Error(w, "not found", http.StatusNotFound)
@@ -200,17 +222,17 @@ func (h Handler) done(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
 	}
-	writeJSON(w, http.StatusOK, n)
+	writeNote(w, http.StatusOK, n)
 }
 
 func (h Handler) undone(w http.ResponseWriter, r *http.Request) {
-	claims := auth.ClaimsFrom(r)
-	if claims == nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	hid, ok := auth.HouseholdIDFrom(r)
+	if !ok {
+		http.Error(w, "household required", http.StatusForbidden)
 		return
 	}
 	id := chi.URLParam(r, "id")
-	n, err := h.Repo.MarkUndone(r.Context(), claims.UserID, id)
+	n, err := h.Repo.MarkUndone(r.Context(), hid, id)
 	if err != nil {
 		if errors.Is(err, ErrNotFound) {
 			http.Error(w, "not found", http.StatusNotFound)
@@ -219,7 +241,13 @@ func (h Handler) undone(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
 	}
-	writeJSON(w, http.StatusOK, n)
+	writeNote(w, http.StatusOK, n)
+}
+
+// writeNote ตอบโน้ตพร้อม ETag = version
+func writeNote(w http.ResponseWriter, code int, n *Note) {
+	httpx.SetETag(w, n.Version)
+	writeJSON(w, code, n)
 }
 
 func writeJSON(w http.ResponseWriter, code int, v any) {
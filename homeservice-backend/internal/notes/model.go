@@ -38,6 +38,7 @@ type Note struct {
 
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	Version   int       `json:"version"` // = ETag; เพิ่มทุกครั้งที่แถวถูกแก้
 }
 
 // payloads
@@ -50,6 +51,7 @@ type CreateNoteReq struct {
 	// ถ้าต้องการสร้างงานพร้อมกำหนดรายละเอียด (optional)
 	AssignedTo *string    `json:"assigned_to,omitempty"`
 	DueAt      *time.Time `json:"due_at,omitempty"`
+	RemindAt   *time.Time `json:"remind_at,omitempty"` // แจ้งเตือนผู้รับงาน (หรือผู้สร้าง) ผ่าน notifications
 	Priority   *int16     `json:"priority,omitempty"`
 	Location   *string    `json:"location,omitempty"`
 	Tags       []string   `json:"tags,omitempty"`
@@ -69,11 +71,12 @@ type UpdateNoteReq struct {
 	Pinned   *bool     `json:"pinned,omitempty"`
 
 	// งาน (แก้เฉพาะฟิลด์ที่ส่งมา)
-	AssignedTo *string    `json:"assigned_to,omitempty"`
-	DueAt      *time.Time `json:"due_at,omitempty"`
-	Priority   *int16     `json:"priority,omitempty"`
-	Done       *bool      `json:"done,omitempty"` // true=mark done, false=undone (ให้ handler ตีความ)
-	Location   *string    `json:"location,omitempty"`
-	Tags       *[]string  `json:"tags,omitempty"`
-	Link       **string   `json:"link,omitempty"` // เหมือน content: รองรับล้างเป็น NULL ได้
+	AssignedTo *string     `json:"assigned_to,omitempty"`
+	DueAt      *time.Time  `json:"due_at,omitempty"`
+	RemindAt   **time.Time `json:"remind_at,omitempty"` // &nil = ยกเลิกการเตือน
+	Priority   *int16      `json:"priority,omitempty"`
+	Done       *bool       `json:"done,omitempty"` // true=mark done, false=undone (ให้ handler ตีความ)
+	Location   *string     `json:"location,omitempty"`
+	Tags       *[]string   `json:"tags,omitempty"`
+	Link       **string    `json:"link,omitempty"` // เหมือน content: รองรับล้างเป็น NULL ได้
 }
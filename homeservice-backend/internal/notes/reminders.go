@@ -0,0 +1,69 @@
+package notes
+
+import (
+	"context"
+	"time"
+)
+
+// Notifier ฝั่งระบบแจ้งเตือน (notifications.Dispatcher) — กันซ้ำด้วย remind_at อยู่ฝั่งนั้น
+type Notifier interface {
+	NotifyNoteReminder(ctx context.Context, householdID, noteID string, userIDs []string, title string, remindAt time.Time) error
+}
+
+// Reminder = โน้ตที่ถึงเวลาเตือน
+type Reminder struct {
+	NoteID      string
+	HouseholdID string
+	Title       string
+	RemindAt    time.Time
+	UserID      string // assigned_to ถ้ามี ไม่งั้น created_by
+}
+
+// DueReminders โน้ตที่ remind_at อยู่ในช่วง (now-window, now] และยังไม่เสร็จ
+// window จำกัดไม่ให้เตือนย้อนหลังเก่า ๆ ตอนเพิ่งเปิดใช้หรือ server ปิดไปนาน
+func (r Repo) DueReminders(ctx context.Context, now time.Time, window time.Duration) ([]Reminder, error) {
+	rows, err := r.DB.Query(ctx, `
+		SELECT id, household_id, title, remind_at, COALESCE(assigned_to, created_by)
+		FROM public.notes
+		WHERE remind_at <= $1 AND remind_at > $2
+		  AND done_at IS NULL
+		  AND household_id IS NOT NULL
+		  AND COALESCE(assigned_to, created_by) IS NOT NULL
+	`, now, now.Add(-window))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Reminder
+	for rows.Next() {
+		var rm Reminder
+		if err := rows.Scan(&rm.NoteID, &rm.HouseholdID, &rm.Title, &rm.RemindAt, &rm.UserID); err != nil {
+			return nil, err
+		}
+		out = append(out, rm)
+	}
+	return out, rows.Err()
+}
+
+// ReminderWorker ส่งเตือนโน้ตที่ถึง remind_at (scheduler เรียก RunOnce ทุกนาที)
+type ReminderWorker struct {
+	Repo     Repo
+	Notifier Notifier
+	Window   time.Duration // ค่าเริ่มต้น 1 ชั่วโมง
+}
+
+func (w *ReminderWorker) RunOnce(ctx context.Context, now time.Time) error {
+	window := w.Window
+	if window <= 0 {
+		window = time.Hour
+	}
+	list, err := w.Repo.DueReminders(ctx, now, window)
+	if err != nil {
+		return err
+	}
+	for _, rm := range list {
+		_ = w.Notifier.NotifyNoteReminder(ctx, rm.HouseholdID, rm.NoteID, []string{rm.UserID}, rm.Title, rm.RemindAt)
+	}
+	return nil
+}
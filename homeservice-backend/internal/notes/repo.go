@@ -10,7 +10,22 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-var ErrNotFound = errors.New("note not found")
+var (
+	ErrNotFound = errors.New("note not found")
+	// แถวถูกแก้ระหว่างอ่าน-เขียน (version เปลี่ยน)
+	ErrConflict = errors.New("note was modified concurrently")
+	// If-Match ไม่ตรงกับ version ปัจจุบัน
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
+
+// ConflictError แนบโน้ตล่าสุดไปกับ ErrConflict/ErrPreconditionFailed
+type ConflictError struct {
+	Err     error
+	Current *Note
+}
+
+func (e *ConflictError) Error() string { return e.Err.Error() }
+func (e *ConflictError) Unwrap() error { return e.Err }
 
 type Repo struct {
 	DB *pgxpool.Pool
@@ -24,14 +39,14 @@ type ListFilter struct {
 	Offset   int
 }
 
-func (r Repo) List(ctx context.Context, userID string, f ListFilter) ([]Note, error) {
+func (r Repo) List(ctx context.Context, householdID string, f ListFilter) ([]Note, error) {
 	if f.Limit <= 0 || f.Limit > 200 {
 		f.Limit = 50
 	}
 	var args []any
 	var where []string
-	args = append(args, userID)
-	where = append(where, fmt.Sprintf("created_by = $%d", len(args)))
+	args = append(args, householdID)
+	where = append(where, fmt.Sprintf("household_id = $%d", len(args)))
 
 	if f.Query != "" {
 		args = append(args, "%"+strings.TrimSpace(f.Query)+"%")
@@ -49,7 +64,7 @@ func (r Repo) List(ctx context.Context, userID string, f ListFilter) ([]Note, er
 
 	args = append(args, f.Limit, f.Offset)
 	sql := `
-		SELECT id, title, content, category, pinned, created_by, created_at, updated_at, done_at
+		SELECT id, title, content, category, pinned, created_by, created_at, updated_at, done_at, remind_at, version
 		FROM public.notes
 		WHERE ` + strings.Join(where, " AND ") + `
 		ORDER BY pinned DESC, updated_at DESC
@@ -74,6 +89,8 @@ func (r Repo) List(ctx context.Context, userID string, f ListFilter) ([]Note, er
 			&n.CreatedAt,
 			&n.UpdatedAt,
 			&n.DoneAt, // *time.Time
+			&n.RemindAt,
+			&n.Version,
 		); err != nil {
 			return nil, err
 		}
@@ -82,17 +99,17 @@ func (r Repo) List(ctx context.Context, userID string, f ListFilter) ([]Note, er
 	return out, rows.Err()
 }
 
-func (r Repo) Get(ctx context.Context, userID, id string) (*Note, error) {
+func (r Repo) Get(ctx context.Context, householdID, id string) (*Note, error) {
 	row := r.DB.QueryRow(ctx, `
-		SELECT id, title, content, category, pinned, created_by, created_at, updated_at, done_at
+		SELECT id, title, content, category, pinned, created_by, created_at, updated_at, done_at, remind_at, version
 		FROM public.notes
-		WHERE id=$1 AND created_by=$2
-	`, id, userID)
+		WHERE id=$1 AND household_id=$2
+	`, id, householdID)
 
 	var n Note
 	if err := row.Scan(
 		&n.ID, &n.Title, &n.Content, &n.Category, &n.Pinned,
-		&n.CreatedBy, &n.CreatedAt, &n.UpdatedAt, &n.DoneAt,
+		&n.CreatedBy, &n.CreatedAt, &n.UpdatedAt, &n.DoneAt, &n.RemindAt, &n.Version,
 	); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -102,29 +119,33 @@ func (r Repo) Get(ctx context.Context, userID, id string) (*Note, error) {
 	return &n, nil
 }
 
-func (r Repo) Create(ctx context.Context, userID string, in CreateNoteReq) (*Note, error) {
+func (r Repo) Create(ctx context.Context, householdID, userID string, in CreateNoteReq) (*Note, error) {
 	row := r.DB.QueryRow(ctx, `
-		INSERT INTO public.notes (title, content, category, pinned, created_by)
-		VALUES ($1,$2,$3,$4,$5)
-		RETURNING id, title, content, category, pinned, created_by, created_at, updated_at, done_at
-	`, in.Title, in.Content, in.Category, in.Pinned, userID)
+		INSERT INTO public.notes (title, content, category, pinned, created_by, household_id, remind_at, assigned_to)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+		RETURNING id, title, content, category, pinned, created_by, created_at, updated_at, done_at, remind_at, version
+	`, in.Title, in.Content, in.Category, in.Pinned, userID, householdID, in.RemindAt, in.AssignedTo)
 
 	var n Note
 	if err := row.Scan(
 		&n.ID, &n.Title, &n.Content, &n.Category, &n.Pinned,
-		&n.CreatedBy, &n.CreatedAt, &n.UpdatedAt, &n.DoneAt,
+		&n.CreatedBy, &n.CreatedAt, &n.UpdatedAt, &n.DoneAt, &n.RemindAt, &n.Version,
 	); err != nil {
 		return nil, err
 	}
 	return &n, nil
 }
 
-func (r Repo) Update(ctx context.Context, userID, id string, in UpdateNoteReq) (*Note, error) {
+// Update merge แล้วเขียนแบบมีเงื่อนไข version; ifMatch != 0 ต้องตรงกับ version ปัจจุบัน
+func (r Repo) Update(ctx context.Context, householdID, id string, ifMatch int, in UpdateNoteReq) (*Note, error) {
 	// ดึงก่อนเพื่อ merge
-	n, err := r.Get(ctx, userID, id)
+	n, err := r.Get(ctx, householdID, id)
 	if err != nil {
 		return nil, err
 	}
+	if ifMatch != 0 && ifMatch != n.Version {
+		return nil, &ConflictError{Err: ErrPreconditionFailed, Current: n}
+	}
 	if in.Title != nil {
 		n.Title = *in.Title
 	}
@@ -137,26 +158,37 @@ func (r Repo) Update(ctx context.Context, userID, id string, in UpdateNoteReq) (
 	if in.Pinned != nil {
 		n.Pinned = *in.Pinned
 	}
+	if in.RemindAt != nil { // เหมือน content: &nil = ยกเลิกการเตือน
+		n.RemindAt = *in.RemindAt
+	}
 
 	row := r.DB.QueryRow(ctx, `
 		UPDATE public.notes
-		SET title=$1, content=$2, category=$3, pinned=$4, updated_at=now()
-		WHERE id=$5 AND created_by=$6
-		RETURNING id, title, content, category, pinned, created_by, created_at, updated_at, done_at
-	`, n.Title, n.Content, n.Category, n.Pinned, id, userID)
+		SET title=$1, content=$2, category=$3, pinned=$4, remind_at=$7, updated_at=now()
+		WHERE id=$5 AND household_id=$6 AND version=$8
+		RETURNING id, title, content, category, pinned, created_by, created_at, updated_at, done_at, remind_at, version
+	`, n.Title, n.Content, n.Category, n.Pinned, id, householdID, n.RemindAt, n.Version)
 
 	var out Note
 	if err := row.Scan(
 		&out.ID, &out.Title, &out.Content, &out.Category, &out.Pinned,
-		&out.CreatedBy, &out.CreatedAt, &out.UpdatedAt, &out.DoneAt,
+		&out.CreatedBy, &out.CreatedAt, &out.UpdatedAt, &out.DoneAt, &out.RemindAt, &out.Version,
 	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// มีคนแก้ (หรือลบ) ระหว่าง Get กับ UPDATE
+			cur, gerr := r.Get(ctx, householdID, id)
+			if gerr != nil {
+				return nil, gerr
+			}
+			return nil, &ConflictError{Err: ErrConflict, Current: cur}
+		}
 		return nil, err
 	}
 	return &out, nil
 }
 
-func (r Repo) Delete(ctx context.Context, userID, id string) error {
-	ct, err := r.DB.Exec(ctx, `DELETE FROM public.notes WHERE id=$1 AND created_by=$2`, id, userID)
+func (r Repo) Delete(ctx context.Context, householdID, id string) error {
+	ct, err := r.DB.Exec(ctx, `DELETE FROM public.notes WHERE id=$1 AND household_id=$2`, id, householdID)
 	if err != nil {
 		return err
 	}
@@ -166,18 +198,18 @@ func (r Repo) Delete(ctx context.Context, userID, id string) error {
 	return nil
 }
 
-func (r Repo) TogglePin(ctx context.Context, userID, id string, pin bool) (*Note, error) {
+func (r Repo) TogglePin(ctx context.Context, householdID, id string, pin bool) (*Note, error) {
 	row := r.DB.QueryRow(ctx, `
 		UPDATE public.notes
 		SET pinned=$1, updated_at=now()
-		WHERE id=$2 AND created_by=$3
-		RETURNING id, title, content, category, pinned, created_by, created_at, updated_at, done_at
-	`, pin, id, userID)
+		WHERE id=$2 AND household_id=$3
+		RETURNING id, title, content, category, pinned, created_by, created_at, updated_at, done_at, remind_at, version
+	`, pin, id, householdID)
 
 	var n Note
 	if err := row.Scan(
 		&n.ID, &n.Title, &n.Content, &n.Category, &n.Pinned,
-		&n.CreatedBy, &n.CreatedAt, &n.UpdatedAt, &n.DoneAt,
+		&n.CreatedBy, &n.CreatedAt, &n.UpdatedAt, &n.DoneAt, &n.RemindAt, &n.Version,
 	); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -189,18 +221,18 @@ func (r Repo) TogglePin(ctx context.Context, userID, id string, pin bool) (*Note
 
 // -------- เสร็จสิ้น / ยกเลิกเสร็จสิ้น --------
 
-func (r Repo) MarkDone(ctx context.Context, userID, id string) (*Note, error) {
+func (r Repo) MarkDone(ctx context.Context, householdID, id string) (*Note, error) {
 	row := r.DB.QueryRow(ctx, `
 		UPDATE public.notes
 		   SET done_at = now(), updated_at = now()
-		 WHERE id=$1 AND created_by=$2
-		 RETURNING id, title, content, category, pinned, created_by, created_at, updated_at, done_at
-	`, id, userID)
+		 WHERE id=$1 AND household_id=$2
+		 RETURNING id, title, content, category, pinned, created_by, created_at, updated_at, done_at, remind_at, version
+	`, id, householdID)
 
 	var n Note
 	if err := row.Scan(
 		&n.ID, &n.Title, &n.Content, &n.Category, &n.Pinned,
-		&n.CreatedBy, &n.CreatedAt, &n.UpdatedAt, &n.DoneAt,
+		&n.CreatedBy, &n.CreatedAt, &n.UpdatedAt, &n.DoneAt, &n.RemindAt, &n.Version,
 	); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -210,18 +242,18 @@ func (r Repo) MarkDone(ctx context.Context, userID, id string) (*Note, error) {
 	return &n, nil
 }
 
-func (r Repo) MarkUndone(ctx context.Context, userID, id string) (*Note, error) {
+func (r Repo) MarkUndone(ctx context.Context, householdID, id string) (*Note, error) {
 	row := r.DB.QueryRow(ctx, `
 		UPDATE public.notes
 		   SET done_at = NULL, updated_at = now()
-		 WHERE id=$1 AND created_by=$2
-		 RETURNING id, title, content, category, pinned, created_by, created_at, updated_at, done_at
-	`, id, userID)
+		 WHERE id=$1 AND household_id=$2
+		 RETURNING id, title, content, category, pinned, created_by, created_at, updated_at, done_at, remind_at, version
+	`, id, householdID)
 
 	var n Note
 	if err := row.Scan(
 		&n.ID, &n.Title, &n.Content, &n.Category, &n.Pinned,
-		&n.CreatedBy, &n.CreatedAt, &n.UpdatedAt, &n.DoneAt,
+		&n.CreatedBy, &n.CreatedAt, &n.UpdatedAt, &n.DoneAt, &n.RemindAt, &n.Version,
 	); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
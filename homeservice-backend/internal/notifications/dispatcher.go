@@ -0,0 +1,112 @@
+// Package notifications รวมการแจ้งเตือนของทุกโมดูลไว้ที่เดียว:
+// เขียนลง in-app feed ต่อผู้ใช้ (กันซ้ำด้วย dedup_key + คูลดาวน์ต่อชนิด) แล้วส่งต่อไปยังช่องทางที่ผู้ใช้ลงทะเบียน
+package notifications
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// DefaultCooldowns ระยะเวลาที่ dedup_key เดิมจะไม่ถูกส่งซ้ำให้ผู้ใช้คนเดิม
+// key ของ note.reminder/media.post/medicine.missed_dose ผูกกับครั้งนั้น ๆ อยู่แล้ว จึงตั้งยาว (= ส่งครั้งเดียว)
+var DefaultCooldowns = map[string]time.Duration{
+	KindMedicineLowStock: 24 * time.Hour,
+	KindMedicineExpiring: 24 * time.Hour,
+	KindMedicineMissed:   365 * 24 * time.Hour,
+	KindBillDue:          24 * time.Hour,
+	KindBillOverdue:      24 * time.Hour,
+	KindNoteReminder:     365 * 24 * time.Hour,
+	KindMediaPost:        365 * 24 * time.Hour,
+}
+
+type Dispatcher struct {
+	Repo      Repo
+	Senders   map[string]Sender // ChannelEmail | ChannelWebhook | ChannelWebPush
+	Cooldowns map[string]time.Duration
+	Timeout   time.Duration // ต่อการส่งหนึ่งช่องทาง
+	Logf      func(format string, args ...any)
+}
+
+func (d *Dispatcher) cooldown(kind string) time.Duration {
+	if c, ok := d.Cooldowns[kind]; ok {
+		return c
+	}
+	if c, ok := DefaultCooldowns[kind]; ok {
+		return c
+	}
+	return time.Hour
+}
+
+func (d *Dispatcher) logf(format string, args ...any) {
+	if d.Logf != nil {
+		d.Logf(format, args...)
+	}
+}
+
+// Publish ส่ง event ให้ผู้รับทุกคน; คืนจำนวนรายการที่เข้า feed จริง (ไม่นับที่ถูกกันซ้ำ)
+// การส่งออกนอกระบบล้มเหลวไม่ทำให้ Publish error — บันทึกไว้ที่ channel.last_error แทน
+func (d *Dispatcher) Publish(ctx context.Context, e Event) (int, error) {
+	users := e.UserIDs
+	if len(users) == 0 && e.HouseholdID != "" {
+		var err error
+		if users, err = d.Repo.Members(ctx, e.HouseholdID); err != nil {
+			return 0, err
+		}
+	}
+	sent := 0
+	for _, uid := range users {
+		n, ok, err := d.Repo.InsertDeduped(ctx, uid, e, d.cooldown(e.Kind))
+		if err != nil {
+			return sent, err
+		}
+		if !ok {
+			continue
+		}
+		sent++
+		d.deliver(ctx, n)
+	}
+	return sent, nil
+}
+
+// deliver ส่งไปทุกช่องทางของผู้ใช้ที่รับชนิดนี้
+func (d *Dispatcher) deliver(ctx context.Context, n Notification) {
+	chs, err := d.Repo.Channels(ctx, n.UserID)
+	if err != nil {
+		d.logf("notifications: list channels user=%s: %v", n.UserID, err)
+		return
+	}
+	for _, ch := range chs {
+		if ch.wants(n.Kind) {
+			_ = d.SendTo(ctx, ch, n)
+		}
+	}
+}
+
+// SendTo ส่งผ่านช่องทางเดียวแล้วจดผล (ใช้ทั้งตอน publish และปุ่ม "ทดสอบ")
+func (d *Dispatcher) SendTo(ctx context.Context, ch Channel, n Notification) error {
+	s, ok := d.Senders[ch.Type]
+	if !ok {
+		return ErrNotConfigured
+	}
+	timeout := d.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	sctx, cancel := context.WithTimeout(ctx, timeout)
+	err := s.Send(sctx, ch, n)
+	cancel()
+	if errors.Is(err, ErrNotConfigured) {
+		return err
+	}
+	if err != nil {
+		d.logf("notifications: send %s channel=%s: %v", ch.Type, ch.ID, err)
+		if !errors.Is(err, ErrGone) {
+			err = ErrDeliveryFailed
+		}
+	}
+	if rerr := d.Repo.RecordDelivery(ctx, ch.ID, err, errors.Is(err, ErrGone)); rerr != nil {
+		d.logf("notifications: record delivery channel=%s: %v", ch.ID, rerr)
+	}
+	return err
+}
@@ -0,0 +1,19 @@
+package notifications
+
+import "errors"
+
+var (
+	ErrNotFound = errors.New("notification not found")
+
+	// ช่องทางไม่ถูกต้อง เช่น type ไม่รองรับ หรือ target ไม่ใช่ email/URL ที่ใช้ได้
+	ErrBadChannel = errors.New("invalid channel")
+
+	// ช่องทางยังไม่ได้ตั้งค่าบน server (เช่น ไม่มี SMTP_HOST / VAPID key)
+	ErrNotConfigured = errors.New("delivery channel not configured")
+
+	// ปลายทางบอกว่า subscription ไม่มีแล้ว (web push 404/410) -> ปิดช่องทางนั้น
+	ErrGone = errors.New("delivery target gone")
+
+	// ส่งไม่สำเร็จ; รายละเอียดจริง (status/error จากปลายทาง) เก็บแค่ใน log ไม่ส่งกลับผู้ใช้
+	ErrDeliveryFailed = errors.New("delivery failed")
+)
@@ -0,0 +1,202 @@
+package notifications
+
+import (
+	"errors"
+	"net/http"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/iMookatayou/homeservice-backend/internal/auth"
+	"github.com/iMookatayou/homeservice-backend/internal/httpx"
+)
+
+type Handler struct {
+	D *Dispatcher
+	// VAPIDPublicKey ให้ browser ใช้ subscribe (ว่าง = ไม่เปิด web push)
+	VAPIDPublicKey string
+}
+
+// RegisterRoutes — ใช้ใต้ RequireAuth: feed เป็นของผู้ใช้ (ทุกบ้านที่เป็นสมาชิก)
+func (h Handler) RegisterRoutes(r chi.Router) {
+	r.Route("/notifications", func(r chi.Router) {
+		r.Get("/", h.List) // ?unread=true&page=&page_size=
+		r.Get("/unread-count", h.UnreadCount)
+		r.Post("/read-all", h.ReadAll)
+		r.Post("/{id}/read", h.Read)
+
+		r.Get("/webpush-key", h.WebPushKey)
+		r.Get("/channels", h.ListChannels)
+		r.Post("/channels", h.CreateChannel)
+		r.Patch("/channels/{id}", h.UpdateChannel)
+		r.Delete("/channels/{id}", h.DeleteChannel)
+		r.Post("/channels/{id}/test", h.TestChannel)
+	})
+}
+
+func writeErr(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		httpx.WriteJSONError(w, http.StatusNotFound, err.Error(), nil)
+	case errors.Is(err, ErrBadChannel):
+		httpx.WriteJSONError(w, http.StatusBadRequest, err.Error(), nil)
+	case errors.Is(err, ErrNotConfigured):
+		httpx.WriteJSONError(w, http.StatusNotImplemented, err.Error(), nil)
+	case errors.Is(err, ErrGone), errors.Is(err, ErrDeliveryFailed):
+		httpx.WriteJSONError(w, http.StatusBadGateway, err.Error(), nil)
+	default:
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "internal error", nil)
+	}
+}
+
+func (h Handler) List(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFrom(r)
+	p := httpx.ParsePage(r, 20)
+	limit, offset := p.LimitOffset()
+	unread := r.URL.Query().Get("unread") == "true"
+	out, err := h.D.Repo.List(r.Context(), uid, unread, limit, offset)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	httpx.JSON(w, http.StatusOK, map[string]any{"items": out, "page": p})
+}
+
+func (h Handler) UnreadCount(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFrom(r)
+	n, err := h.D.Repo.UnreadCount(r.Context(), uid)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	httpx.JSON(w, http.StatusOK, map[string]int{"unread": n})
+}
+
+func (h Handler) Read(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFrom(r)
+	if err := h.D.Repo.MarkRead(r.Context(), uid, chi.URLParam(r, "id")); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h Handler) ReadAll(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFrom(r)
+	n, err := h.D.Repo.MarkAllRead(r.Context(), uid)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	httpx.JSON(w, http.StatusOK, map[string]int64{"marked": n})
+}
+
+func (h Handler) WebPushKey(w http.ResponseWriter, r *http.Request) {
+	if h.VAPIDPublicKey == "" {
+		writeErr(w, ErrNotConfigured)
+		return
+	}
+	httpx.JSON(w, http.StatusOK, map[string]string{"public_key": h.VAPIDPublicKey})
+}
+
+func (h Handler) ListChannels(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFrom(r)
+	out, err := h.D.Repo.Channels(r.Context(), uid)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	httpx.JSON(w, http.StatusOK, out)
+}
+
+// validTarget email ต้อง parse ได้, webhook http(s), webpush ต้องเป็น https (ตาม spec ของ push service)
+// host ที่เป็น localhost/IP ภายในถูกปฏิเสธตั้งแต่ตอนสร้าง; ชื่อโดเมนไปตรวจซ้ำตอน dial (NewHTTPClient)
+func validTarget(typ, target string) bool {
+	switch typ {
+	case ChannelEmail:
+		a, err := mail.ParseAddress(target)
+		return err == nil && a.Address == target
+	case ChannelWebhook, ChannelWebPush:
+		u, err := url.Parse(target)
+		if err != nil || u.Hostname() == "" {
+			return false
+		}
+		host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+		if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+			return false
+		}
+		if ip, err := netip.ParseAddr(host); err == nil && blockedAddr(ip) {
+			return false
+		}
+		return u.Scheme == "https" || (typ == ChannelWebhook && u.Scheme == "http")
+	}
+	return false
+}
+
+func (h Handler) CreateChannel(w http.ResponseWriter, r *http.Request) {
+	var req CreateChannelReq
+	if err := httpx.BindJSON(r, &req); err != nil {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "validation failed", httpx.ValidationErrors(err))
+		return
+	}
+	req.Target = strings.TrimSpace(req.Target)
+	if !validTarget(req.Type, req.Target) {
+		writeErr(w, ErrBadChannel)
+		return
+	}
+	if _, ok := h.D.Senders[req.Type]; !ok {
+		writeErr(w, ErrNotConfigured)
+		return
+	}
+	uid, _ := auth.UserIDFrom(r)
+	ch, err := h.D.Repo.UpsertChannel(r.Context(), Channel{
+		UserID: uid, Type: req.Type, Target: req.Target, Secret: req.Secret, Kinds: req.Kinds,
+	})
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	httpx.JSON(w, http.StatusCreated, ch)
+}
+
+func (h Handler) UpdateChannel(w http.ResponseWriter, r *http.Request) {
+	var req UpdateChannelReq
+	if err := httpx.BindJSON(r, &req); err != nil {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "validation failed", httpx.ValidationErrors(err))
+		return
+	}
+	uid, _ := auth.UserIDFrom(r)
+	ch, err := h.D.Repo.UpdateChannel(r.Context(), uid, chi.URLParam(r, "id"), req)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	httpx.JSON(w, http.StatusOK, ch)
+}
+
+func (h Handler) DeleteChannel(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFrom(r)
+	if err := h.D.Repo.DeleteChannel(r.Context(), uid, chi.URLParam(r, "id")); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TestChannel ส่งข้อความทดสอบผ่านช่องทางนี้ทันที (ไม่เข้า feed)
+func (h Handler) TestChannel(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFrom(r)
+	ch, err := h.D.Repo.GetChannel(r.Context(), uid, chi.URLParam(r, "id"))
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	n := Notification{ID: "test", UserID: uid, Kind: KindTest, Title: "ทดสอบการแจ้งเตือน", Body: "ช่องทางนี้ใช้งานได้"}
+	if err := h.D.SendTo(r.Context(), ch, n); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
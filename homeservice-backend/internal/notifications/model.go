@@ -0,0 +1,95 @@
+package notifications
+
+import "time"
+
+// ชนิดแจ้งเตือน (ใช้เลือกคูลดาวน์ และให้ผู้ใช้กรองช่องทางตามชนิดได้)
+const (
+	KindMedicineLowStock = "medicine.low_stock"
+	KindMedicineExpiring = "medicine.expiring"
+	KindMedicineMissed   = "medicine.missed_dose"
+	KindBillDue          = "bill.due"
+	KindBillOverdue      = "bill.overdue"
+	KindNoteReminder     = "note.reminder"
+	KindMediaPost        = "media.post"
+	KindTest             = "test"
+)
+
+// ประเภทช่องทางส่งออก (นอกเหนือจาก in-app feed ที่มีเสมอ)
+const (
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+	ChannelWebPush = "webpush"
+)
+
+type Notification struct {
+	ID          string     `json:"id"`
+	UserID      string     `json:"user_id"`
+	HouseholdID *string    `json:"household_id,omitempty"`
+	Kind        string     `json:"kind"`
+	Title       string     `json:"title"`
+	Body        string     `json:"body"`
+	Link        *string    `json:"link,omitempty"`
+	RefID       *string    `json:"ref_id,omitempty"`
+	ReadAt      *time.Time `json:"read_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Event = สิ่งที่โมดูลอื่น publish เข้ามา; UserIDs ว่าง = สมาชิกทุกคนในบ้าน HouseholdID
+type Event struct {
+	Kind        string
+	HouseholdID string
+	UserIDs     []string
+	Title       string
+	Body        string
+	Link        string
+	RefID       string
+	DedupKey    string // ว่าง = kind:ref_id
+}
+
+func (e Event) dedupKey() string {
+	if e.DedupKey != "" {
+		return e.DedupKey
+	}
+	return e.Kind + ":" + e.RefID
+}
+
+type Channel struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	Type       string     `json:"type"`
+	Target     string     `json:"target"`
+	Secret     *string    `json:"-"`
+	Kinds      []string   `json:"kinds"`
+	Enabled    bool       `json:"enabled"`
+	LastError  *string    `json:"last_error,omitempty"`
+	LastSentAt *time.Time `json:"last_sent_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// wants ช่องทางนี้รับแจ้งเตือนชนิด kind หรือไม่ (kinds ว่าง = ทุกชนิด)
+func (c Channel) wants(kind string) bool {
+	if !c.Enabled {
+		return false
+	}
+	if len(c.Kinds) == 0 || kind == KindTest {
+		return true
+	}
+	for _, k := range c.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+type CreateChannelReq struct {
+	Type   string   `json:"type" validate:"required,oneof=email webhook webpush"`
+	Target string   `json:"target" validate:"required,max=2048"`
+	Secret *string  `json:"secret,omitempty" validate:"omitempty,min=16,max=256"`
+	Kinds  []string `json:"kinds,omitempty" validate:"omitempty,dive,required"`
+}
+
+type UpdateChannelReq struct {
+	Kinds   *[]string `json:"kinds,omitempty"`
+	Enabled *bool     `json:"enabled,omitempty"`
+}
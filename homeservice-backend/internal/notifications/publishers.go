@@ -0,0 +1,74 @@
+package notifications
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// เมธอดด้านล่างทำให้ *Dispatcher ใช้เป็น Notifier ของ medicine/bills/notes/media ได้ตรง ๆ
+// (interface อยู่ในโมดูลต้นทาง โมดูลเหล่านั้นจึงไม่ต้อง import notifications)
+
+func (d *Dispatcher) NotifyLowStock(ctx context.Context, householdID, itemID, message string) error {
+	_, err := d.Publish(ctx, Event{
+		Kind: KindMedicineLowStock, HouseholdID: householdID, RefID: itemID,
+		Title: "ยาใกล้หมด", Body: message, Link: "/medicine/items/" + itemID,
+	})
+	return err
+}
+
+func (d *Dispatcher) NotifyExpiring(ctx context.Context, householdID, itemID, message string) error {
+	_, err := d.Publish(ctx, Event{
+		Kind: KindMedicineExpiring, HouseholdID: householdID, RefID: itemID,
+		Title: "ยาใกล้หมดอายุ", Body: message, Link: "/medicine/items/" + itemID,
+	})
+	return err
+}
+
+// NotifyMissedDose dedup ผูกกับนัดนั้น ๆ (regimen + เวลา): หนึ่งนัดแจ้งครั้งเดียว
+func (d *Dispatcher) NotifyMissedDose(ctx context.Context, householdID, regimenID string, scheduledAt time.Time, message string) error {
+	_, err := d.Publish(ctx, Event{
+		Kind: KindMedicineMissed, HouseholdID: householdID, RefID: regimenID,
+		DedupKey: KindMedicineMissed + ":" + regimenID + ":" + strconv.FormatInt(scheduledAt.Unix(), 10),
+		Title:    "พลาดนัดกินยา", Body: message, Link: "/medicine/doses",
+	})
+	return err
+}
+
+func (d *Dispatcher) NotifyBillDue(ctx context.Context, householdID, billID, message string) error {
+	_, err := d.Publish(ctx, Event{
+		Kind: KindBillDue, HouseholdID: householdID, RefID: billID,
+		Title: "บิลใกล้ครบกำหนด", Body: message, Link: "/bills/" + billID,
+	})
+	return err
+}
+
+func (d *Dispatcher) NotifyBillOverdue(ctx context.Context, householdID, billID, message string) error {
+	_, err := d.Publish(ctx, Event{
+		Kind: KindBillOverdue, HouseholdID: householdID, RefID: billID,
+		Title: "บิลเลยกำหนดชำระ", Body: message, Link: "/bills/" + billID,
+	})
+	return err
+}
+
+// NotifyNoteReminder dedup ผูกกับ remind_at: เลื่อนเวลาเตือนใหม่ = เตือนอีกครั้ง
+func (d *Dispatcher) NotifyNoteReminder(ctx context.Context, householdID, noteID string, userIDs []string,
+	title string, remindAt time.Time) error {
+	_, err := d.Publish(ctx, Event{
+		Kind: KindNoteReminder, HouseholdID: householdID, UserIDs: userIDs, RefID: noteID,
+		DedupKey: KindNoteReminder + ":" + noteID + ":" + strconv.FormatInt(remindAt.Unix(), 10),
+		Title:    "เตือนความจำ", Body: title, Link: "/notes/" + noteID,
+	})
+	return err
+}
+
+func (d *Dispatcher) NotifyNewPost(ctx context.Context, userIDs []string, postID, title, url string) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	_, err := d.Publish(ctx, Event{
+		Kind: KindMediaPost, UserIDs: userIDs, RefID: postID,
+		Title: "คลิปใหม่", Body: title, Link: url,
+	})
+	return err
+}
@@ -0,0 +1,225 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repo struct {
+	DB *pgxpool.Pool
+}
+
+const notifCols = `id, user_id, household_id, kind, title, body, link, ref_id, read_at, created_at`
+
+func scanNotification(row pgx.Row) (Notification, error) {
+	var n Notification
+	err := row.Scan(&n.ID, &n.UserID, &n.HouseholdID, &n.Kind, &n.Title, &n.Body, &n.Link, &n.RefID, &n.ReadAt, &n.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return n, ErrNotFound
+	}
+	return n, err
+}
+
+func nullable(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// Members ผู้รับเริ่มต้นของ event ระดับบ้าน
+func (r Repo) Members(ctx context.Context, householdID string) ([]string, error) {
+	rows, err := r.DB.Query(ctx, `SELECT user_id FROM household_members WHERE household_id=$1`, householdID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
+
+// InsertDeduped เพิ่มเข้า feed เว้นแต่ผู้ใช้คนนี้เพิ่งได้ dedup_key เดียวกันภายใน cooldown
+// lock ต่อ (user, key) ใน transaction กันสอง worker ยิงซ้ำพร้อมกัน; ok=false = ถูกกันซ้ำ
+func (r Repo) InsertDeduped(ctx context.Context, userID string, e Event, cooldown time.Duration) (Notification, bool, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return Notification{}, false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	key := e.dedupKey()
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1 || '|' || $2))`, userID, key); err != nil {
+		return Notification{}, false, err
+	}
+	n, err := scanNotification(tx.QueryRow(ctx, `
+		INSERT INTO notifications (user_id, household_id, kind, title, body, link, ref_id, dedup_key)
+		SELECT $1, $2, $3, $4, $5, $6, $7, $8
+		WHERE NOT EXISTS (
+			SELECT 1 FROM notifications
+			WHERE user_id=$1 AND dedup_key=$8 AND created_at > now() - make_interval(secs => $9)
+		)
+		RETURNING `+notifCols,
+		userID, nullable(e.HouseholdID), e.Kind, e.Title, e.Body, nullable(e.Link), nullable(e.RefID), key,
+		cooldown.Seconds()))
+	if errors.Is(err, ErrNotFound) {
+		return n, false, nil
+	}
+	if err != nil {
+		return n, false, err
+	}
+	return n, true, tx.Commit(ctx)
+}
+
+// List feed ของผู้ใช้ (ล่าสุดก่อน)
+func (r Repo) List(ctx context.Context, userID string, unreadOnly bool, limit, offset int) ([]Notification, error) {
+	rows, err := r.DB.Query(ctx, `
+		SELECT `+notifCols+`
+		FROM notifications
+		WHERE user_id=$1 AND ($2 = false OR read_at IS NULL)
+		ORDER BY created_at DESC
+		LIMIT $3 OFFSET $4
+	`, userID, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Notification{}
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, rows.Err()
+}
+
+func (r Repo) UnreadCount(ctx context.Context, userID string) (int, error) {
+	var n int
+	err := r.DB.QueryRow(ctx, `SELECT count(*) FROM notifications WHERE user_id=$1 AND read_at IS NULL`, userID).Scan(&n)
+	return n, err
+}
+
+func (r Repo) MarkRead(ctx context.Context, userID, id string) error {
+	ct, err := r.DB.Exec(ctx, `
+		UPDATE notifications SET read_at=COALESCE(read_at, now())
+		WHERE id=$1 AND user_id=$2
+	`, id, userID)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r Repo) MarkAllRead(ctx context.Context, userID string) (int64, error) {
+	ct, err := r.DB.Exec(ctx, `UPDATE notifications SET read_at=now() WHERE user_id=$1 AND read_at IS NULL`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return ct.RowsAffected(), nil
+}
+
+// --- channels ---
+
+const channelCols = `id, user_id, type, target, secret, kinds, enabled, last_error, last_sent_at, created_at`
+
+func scanChannel(row pgx.Row) (Channel, error) {
+	var c Channel
+	err := row.Scan(&c.ID, &c.UserID, &c.Type, &c.Target, &c.Secret, &c.Kinds, &c.Enabled, &c.LastError, &c.LastSentAt, &c.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c, ErrNotFound
+	}
+	return c, err
+}
+
+func (r Repo) Channels(ctx context.Context, userID string) ([]Channel, error) {
+	rows, err := r.DB.Query(ctx, `
+		SELECT `+channelCols+` FROM notification_channels WHERE user_id=$1 ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Channel{}
+	for rows.Next() {
+		c, err := scanChannel(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+func (r Repo) GetChannel(ctx context.Context, userID, id string) (Channel, error) {
+	return scanChannel(r.DB.QueryRow(ctx, `
+		SELECT `+channelCols+` FROM notification_channels WHERE id=$1 AND user_id=$2
+	`, id, userID))
+}
+
+// UpsertChannel ลงทะเบียนซ้ำ (เช่น browser เดิม subscribe ใหม่) = เปิดใช้และอัปเดต kinds/secret
+func (r Repo) UpsertChannel(ctx context.Context, c Channel) (Channel, error) {
+	if c.Kinds == nil {
+		c.Kinds = []string{}
+	}
+	return scanChannel(r.DB.QueryRow(ctx, `
+		INSERT INTO notification_channels (user_id, type, target, secret, kinds)
+		VALUES ($1,$2,$3,$4,$5)
+		ON CONFLICT (user_id, type, target) DO UPDATE
+		SET secret=EXCLUDED.secret, kinds=EXCLUDED.kinds, enabled=true, last_error=NULL
+		RETURNING `+channelCols,
+		c.UserID, c.Type, c.Target, c.Secret, c.Kinds))
+}
+
+func (r Repo) UpdateChannel(ctx context.Context, userID, id string, in UpdateChannelReq) (Channel, error) {
+	return scanChannel(r.DB.QueryRow(ctx, `
+		UPDATE notification_channels
+		SET kinds=COALESCE($3, kinds), enabled=COALESCE($4, enabled)
+		WHERE id=$1 AND user_id=$2
+		RETURNING `+channelCols,
+		id, userID, in.Kinds, in.Enabled))
+}
+
+func (r Repo) DeleteChannel(ctx context.Context, userID, id string) error {
+	ct, err := r.DB.Exec(ctx, `DELETE FROM notification_channels WHERE id=$1 AND user_id=$2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RecordDelivery จดผลส่งล่าสุดของช่องทาง; disable=true เมื่อปลายทางไม่มีแล้ว
+func (r Repo) RecordDelivery(ctx context.Context, channelID string, sendErr error, disable bool) error {
+	var msg *string
+	if sendErr != nil {
+		s := sendErr.Error()
+		msg = &s
+	}
+	_, err := r.DB.Exec(ctx, `
+		UPDATE notification_channels
+		SET last_error=$2,
+		    last_sent_at=CASE WHEN $2::text IS NULL THEN now() ELSE last_sent_at END,
+		    enabled=enabled AND NOT $3
+		WHERE id=$1
+	`, channelID, msg, disable)
+	return err
+}
@@ -0,0 +1,234 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Sender = adapter ส่งแจ้งเตือนออกนอกระบบหนึ่งประเภท (email/webhook/webpush)
+type Sender interface {
+	Send(ctx context.Context, ch Channel, n Notification) error
+}
+
+// ---------- email (SMTP) ----------
+
+type EmailSender struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (s EmailSender) Send(ctx context.Context, ch Channel, n Notification) error {
+	if s.Host == "" || s.From == "" {
+		return ErrNotConfigured
+	}
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", ch.Target)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", n.Title))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\nContent-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.WriteString(n.Body)
+	if n.Link != nil {
+		msg.WriteString("\r\n\r\n" + *n.Link)
+	}
+	msg.WriteString("\r\n")
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+	// smtp.SendMail ไม่รับ context: ตัดที่ระดับ goroutine แทน
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(net.JoinHostPort(s.Host, s.Port), auth, s.From, []string{ch.Target}, msg.Bytes())
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ---------- outbound HTTP ----------
+
+// NewHTTPClient client สำหรับส่ง webhook/web push ไปยัง URL ที่ผู้ใช้กำหนด
+// กัน SSRF: ตรวจ IP หลัง resolve แล้วทุกครั้งที่ dial (กัน DNS ชี้เข้าภายใน) และไม่ตาม redirect
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	d := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip, err := netip.ParseAddr(host)
+			if err != nil || blockedAddr(ip) {
+				return errBlockedAddr
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:                 nil, // proxy จะ dial แทนเรา ทำให้ตรวจ IP ปลายทางไม่ได้
+			DialContext:           d.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          50,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+var errBlockedAddr = errors.New("destination address not allowed")
+
+// blockedAddr loopback/private/link-local (รวม 169.254.169.254 metadata)/unspecified/multicast
+func blockedAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return !ip.IsValid() || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified()
+}
+
+// ---------- generic webhook ----------
+
+// WebhookSender POST JSON ไปยัง URL ของผู้ใช้; มี secret = แนบ X-Signature: sha256=<hmac hex>
+type WebhookSender struct {
+	Client *http.Client
+}
+
+func (s WebhookSender) Send(ctx context.Context, ch Channel, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ch.Target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "HomeService-Webhook/1.0")
+	req.Header.Set("X-Notification-Kind", n.Kind)
+	if ch.Secret != nil && *ch.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(*ch.Secret))
+		mac.Write(body)
+		req.Header.Set("X-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	return do(s.Client, req)
+}
+
+// ---------- Web Push (VAPID, ไม่มี payload) ----------
+
+// WebPushSender ส่ง push แบบไม่มี payload (RFC 8030 + VAPID RFC 8292)
+// service worker ฝั่ง client รับแล้วค่อยดึง GET /notifications เอง จึงไม่ต้องเข้ารหัสเนื้อหา
+type WebPushSender struct {
+	Client    *http.Client
+	Subject   string // mailto:ops@example.com
+	PublicKey string // base64url (uncompressed P-256) ให้ client ใช้ subscribe
+	key       *ecdsa.PrivateKey
+}
+
+// NewWebPushSender รับ private key แบบ base64url 32 ไบต์ (รูปแบบเดียวกับ web-push generate-vapid-keys)
+func NewWebPushSender(client *http.Client, subject, privateKey string) (*WebPushSender, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(privateKey, "="))
+	if err != nil {
+		return nil, fmt.Errorf("vapid private key: %w", err)
+	}
+	ek, err := ecdh.P256().NewPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("vapid private key: %w", err)
+	}
+	pub := ek.PublicKey().Bytes() // 0x04 || X || Y
+	key := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(pub[1:33]),
+			Y:     new(big.Int).SetBytes(pub[33:]),
+		},
+		D: new(big.Int).SetBytes(raw),
+	}
+	return &WebPushSender{
+		Client:    client,
+		Subject:   subject,
+		PublicKey: base64.RawURLEncoding.EncodeToString(pub),
+		key:       key,
+	}, nil
+}
+
+func (s *WebPushSender) Send(ctx context.Context, ch Channel, n Notification) error {
+	if s == nil || s.key == nil {
+		return ErrNotConfigured
+	}
+	u, err := url.Parse(ch.Target)
+	if err != nil {
+		return err
+	}
+	tok, err := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": s.Subject,
+	}).SignedString(s.key)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ch.Target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "vapid t="+tok+", k="+s.PublicKey)
+	req.Header.Set("TTL", "86400")
+	req.Header.Set("Urgency", "normal")
+	return do(s.Client, req)
+}
+
+// do ส่ง request; 404/410 = ปลายทางไม่มีแล้ว (ErrGone), 3xx/non-2xx อื่น = error (ไม่ตาม redirect)
+func do(c *http.Client, req *http.Request) error {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrGone
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return fmt.Errorf("%s responded %d", req.URL.Host, resp.StatusCode)
+	}
+	return nil
+}
@@ -14,4 +14,19 @@ var (
 
 	// ใช้เมื่อ payload ไม่ถูกต้อง เช่น input ไม่ครบหรือ type ไม่ถูก
 	ErrBadRequest = errors.New("bad request")
+
+	// ราคาประเมินทำให้เกินงบหมวดที่ตั้งเป็น block
+	ErrBudgetExceeded = errors.New("category budget exceeded")
+
+	// If-Match ไม่ตรงกับ version ปัจจุบัน (client แก้จากข้อมูลเก่า)
+	ErrPreconditionFailed = errors.New("precondition failed")
 )
+
+// ConflictError แนบสถานะล่าสุดไปกับ ErrConflict/ErrPreconditionFailed ให้ client merge แล้วลองใหม่ได้
+type ConflictError struct {
+	Err     error
+	Current *Purchase
+}
+
+func (e *ConflictError) Error() string { return e.Err.Error() }
+func (e *ConflictError) Unwrap() error { return e.Err }
@@ -1,12 +1,17 @@
 package purchases
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/iMookatayou/homeservice-backend/internal/auth"
+	"github.com/iMookatayou/homeservice-backend/internal/authz"
+	"github.com/iMookatayou/homeservice-backend/internal/httpx"
 )
 
 type Handler struct {
@@ -19,6 +24,22 @@ func (h Handler) RegisterRoutes(r chi.Router) {
 		r.Get("/", h.List)
 		r.Post("/", h.Create)
 
+		// เกณฑ์อนุมัติ, งบรายเดือนต่อหมวด, รายงานยอดจ่าย
+		r.Get("/settings", h.Settings)
+		r.Put("/settings", h.PutSettings)
+		r.Get("/budgets", h.Budgets) // ?month=YYYY-MM
+		r.Put("/budgets/{category}", h.PutBudget)
+		r.Delete("/budgets/{category}", h.DeleteBudget)
+		r.Get("/reports/spending", h.Spending) // ?from=YYYY-MM&to=YYYY-MM
+
+		// รายการซื้อจากแหล่งอื่น: ยาที่ต่ำกว่าขั้นต่ำ, template ที่ใช้ซ้ำ
+		r.Post("/restock", h.Restock)
+		r.Get("/templates", h.Templates)
+		r.Post("/templates", h.CreateTemplate)
+		r.Put("/templates/{templateID}", h.UpdateTemplate)
+		r.Delete("/templates/{templateID}", h.DeleteTemplate)
+		r.Post("/templates/{templateID}/use", h.UseTemplate)
+
 		r.Get("/{id}", h.Detail)
 		r.Patch("/{id}", h.UpdateByRequester)
 		r.Delete("/{id}", h.Delete)
@@ -27,6 +48,12 @@ func (h Handler) RegisterRoutes(r chi.Router) {
 		r.Post("/{id}/progress", h.Progress)
 		r.Post("/{id}/done", h.Done)
 		r.Post("/{id}/cancel", h.Cancel)
+		r.Post("/{id}/approve", h.Approve)
+		r.Post("/{id}/reject", h.Reject)
+		r.Post("/{id}/items", h.AddItems) // รวมรายการเข้าคำขอที่ยัง planned (ชื่อซ้ำบวก qty)
+
+		r.Get("/{id}/comments", h.Comments)
+		r.Post("/{id}/comments", h.AddComment)
 
 		r.Post("/{id}/attachments", h.AddAttachment)
 		r.Delete("/{id}/attachments/{fileID}", h.RemoveAttachment)
@@ -52,18 +79,37 @@ func writeErr(w http.ResponseWriter, err error) {
 		code = http.StatusConflict
 	case errors.Is(err, ErrNotFound):
 		code = http.StatusNotFound
+	case errors.Is(err, ErrBudgetExceeded):
+		code = http.StatusUnprocessableEntity
+	case errors.Is(err, ErrPreconditionFailed):
+		code = http.StatusPreconditionFailed
 	}
 
+	// 409/412 ตอบสถานะล่าสุด (พร้อม ETag) ให้ client merge แล้วส่งใหม่
+	var ce *ConflictError
+	if errors.As(err, &ce) && ce.Current != nil {
+		httpx.SetETag(w, ce.Current.Version)
+		writeJSON(w, code, map[string]any{"error": err.Error(), "current": ce.Current})
+		return
+	}
 	http.Error(w, err.Error(), code)
 }
 
+// writePurchase ตอบ purchase พร้อม ETag = version
+func writePurchase(w http.ResponseWriter, code int, p *Purchase) {
+	httpx.SetETag(w, p.Version)
+	writeJSON(w, code, p)
+}
+
 func userIDFromCtx(r *http.Request) string {
-	if v := r.Context().Value("uid"); v != nil {
-		if s, ok := v.(string); ok {
-			return s
-		}
-	}
-	return ""
+	uid, _ := auth.UserIDFrom(r)
+	return uid
+}
+
+// householdIDFromCtx มาจาก auth.RequireHousehold
+func householdIDFromCtx(r *http.Request) string {
+	hid, _ := auth.HouseholdIDFrom(r)
+	return hid
 }
 
 func parseLimitOffset(r *http.Request) (limit, offset int) {
@@ -104,13 +150,14 @@ func (h Handler) List(w http.ResponseWriter, r *http.Request) {
 	limit, offset := parseLimitOffset(r)
 
 	f := ListFilter{
-		Query:    q,
-		Status:   st,
-		Category: category,
-		Mine:     mine,
-		UserID:   userIDFromCtx(r),
-		Limit:    limit,
-		Offset:   offset,
+		HouseholdID: householdIDFromCtx(r),
+		Query:       q,
+		Status:      st,
+		Category:    category,
+		Mine:        mine,
+		UserID:      userIDFromCtx(r),
+		Limit:       limit,
+		Offset:      offset,
 	}
 	list, err := h.Svc.List(r.Context(), f)
 	if err != nil {
@@ -132,13 +179,12 @@ func (h Handler) Create(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid json: "+err.Error(), http.StatusBadRequest)
 		return
 	}
-	uid := userIDFromCtx(r)
-	p, err := h.Svc.Create(r.Context(), uid, in)
+	p, err := h.Svc.Create(r.Context(), authz.SubjectFrom(r), in)
 	if err != nil {
 		writeErr(w, err)
 		return
 	}
-	writeJSON(w, http.StatusCreated, p)
+	writePurchase(w, http.StatusCreated, p)
 }
 
 func (h Handler) Detail(w http.ResponseWriter, r *http.Request) {
@@ -147,12 +193,12 @@ func (h Handler) Detail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	id := chi.URLParam(r, "id")
-	p, err := h.Svc.Get(r.Context(), id)
+	p, err := h.Svc.Get(r.Context(), authz.SubjectFrom(r), id)
 	if err != nil {
 		writeErr(w, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, p)
+	writePurchase(w, http.StatusOK, p)
 }
 
 func (h Handler) Delete(w http.ResponseWriter, r *http.Request) {
@@ -161,8 +207,7 @@ func (h Handler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	id := chi.URLParam(r, "id")
-	uid := userIDFromCtx(r)
-	if err := h.Svc.Delete(r.Context(), uid, id); err != nil {
+	if err := h.Svc.Delete(r.Context(), authz.SubjectFrom(r), id); err != nil {
 		writeErr(w, err)
 		return
 	}
@@ -182,13 +227,17 @@ func (h Handler) UpdateByRequester(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid json", http.StatusBadRequest)
 		return
 	}
-	uid := userIDFromCtx(r)
-	p, err := h.Svc.UpdateByRequester(r.Context(), uid, id, in)
+	ifMatch, err := httpx.IfMatch(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p, err := h.Svc.UpdateByRequester(r.Context(), authz.SubjectFrom(r), id, ifMatch, in)
 	if err != nil {
 		writeErr(w, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, p)
+	writePurchase(w, http.StatusOK, p)
 }
 
 func (h Handler) Claim(w http.ResponseWriter, r *http.Request) {
@@ -197,13 +246,12 @@ func (h Handler) Claim(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	id := chi.URLParam(r, "id")
-	uid := userIDFromCtx(r)
-	p, err := h.Svc.Claim(r.Context(), uid, id)
+	p, err := h.Svc.Claim(r.Context(), authz.SubjectFrom(r), id)
 	if err != nil {
 		writeErr(w, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, p)
+	writePurchase(w, http.StatusOK, p)
 }
 
 func (h Handler) Progress(w http.ResponseWriter, r *http.Request) {
@@ -212,7 +260,6 @@ func (h Handler) Progress(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	id := chi.URLParam(r, "id")
-	uid := userIDFromCtx(r)
 	var in ProgressPayload
 	dec := json.NewDecoder(r.Body)
 	dec.DisallowUnknownFields()
@@ -220,12 +267,12 @@ func (h Handler) Progress(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid json", http.StatusBadRequest)
 		return
 	}
-	p, err := h.Svc.Progress(r.Context(), uid, id, in)
+	p, err := h.Svc.Progress(r.Context(), authz.SubjectFrom(r), id, in)
 	if err != nil {
 		writeErr(w, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, p)
+	writePurchase(w, http.StatusOK, p)
 }
 
 func (h Handler) Done(w http.ResponseWriter, r *http.Request) {
@@ -234,15 +281,14 @@ func (h Handler) Done(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	id := chi.URLParam(r, "id")
-	uid := userIDFromCtx(r)
 
 	// ถ้าโปรเจกต์คุณยังไม่มี StatusDone ให้ใช้ StatusDelivered ไปก่อน
-	p, err := h.Svc.Progress(r.Context(), uid, id, ProgressPayload{NextStatus: StatusDelivered})
+	p, err := h.Svc.Progress(r.Context(), authz.SubjectFrom(r), id, ProgressPayload{NextStatus: StatusDelivered})
 	if err != nil {
 		writeErr(w, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, p)
+	writePurchase(w, http.StatusOK, p)
 }
 
 func (h Handler) Cancel(w http.ResponseWriter, r *http.Request) {
@@ -251,13 +297,12 @@ func (h Handler) Cancel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	id := chi.URLParam(r, "id")
-	uid := userIDFromCtx(r)
-	p, err := h.Svc.Cancel(r.Context(), uid, id)
+	p, err := h.Svc.Cancel(r.Context(), authz.SubjectFrom(r), id)
 	if err != nil {
 		writeErr(w, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, p)
+	writePurchase(w, http.StatusOK, p)
 }
 
 type addAttachmentPayload struct {
@@ -270,7 +315,6 @@ func (h Handler) AddAttachment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	id := chi.URLParam(r, "id")
-	uid := userIDFromCtx(r)
 	var in addAttachmentPayload
 	dec := json.NewDecoder(r.Body)
 	dec.DisallowUnknownFields()
@@ -278,7 +322,7 @@ func (h Handler) AddAttachment(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "file_id required", http.StatusBadRequest)
 		return
 	}
-	if err := h.Svc.AddAttachment(r.Context(), uid, id, in.FileID); err != nil {
+	if err := h.Svc.AddAttachment(r.Context(), authz.SubjectFrom(r), id, in.FileID); err != nil {
 		writeErr(w, err)
 		return
 	}
@@ -292,14 +336,294 @@ func (h Handler) RemoveAttachment(w http.ResponseWriter, r *http.Request) {
 	}
 	id := chi.URLParam(r, "id")
 	fileID := chi.URLParam(r, "fileID")
-	uid := userIDFromCtx(r)
 	if fileID == "" {
 		http.Error(w, "fileID required", http.StatusBadRequest)
 		return
 	}
-	if err := h.Svc.RemoveAttachment(r.Context(), uid, id, fileID); err != nil {
+	if err := h.Svc.RemoveAttachment(r.Context(), authz.SubjectFrom(r), id, fileID); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h Handler) Approve(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, h.Svc.Approve)
+}
+
+func (h Handler) Reject(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, h.Svc.Reject)
+}
+
+func (h Handler) decide(w http.ResponseWriter, r *http.Request, fn func(context.Context, authz.Subject, string, DecisionPayload) (*Purchase, error)) {
+	if h.Svc == nil {
+		http.Error(w, "service not initialized", http.StatusInternalServerError)
+		return
+	}
+	id := chi.URLParam(r, "id")
+	var in DecisionPayload
+	if r.ContentLength != 0 {
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&in); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+	}
+	p, err := fn(r.Context(), authz.SubjectFrom(r), id, in)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writePurchase(w, http.StatusOK, p)
+}
+
+func (h Handler) Settings(w http.ResponseWriter, r *http.Request) {
+	st, err := h.Svc.Settings(r.Context(), authz.SubjectFrom(r))
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+func (h Handler) PutSettings(w http.ResponseWriter, r *http.Request) {
+	var in Settings
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&in); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	st, err := h.Svc.PutSettings(r.Context(), authz.SubjectFrom(r), in)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+// parseMonth อ่าน YYYY-MM; ว่าง = def
+func parseMonth(v string, def time.Time) (time.Time, error) {
+	if v == "" {
+		return def, nil
+	}
+	return time.ParseInLocation("2006-01", v, def.Location())
+}
+
+func (h Handler) Budgets(w http.ResponseWriter, r *http.Request) {
+	month, err := parseMonth(r.URL.Query().Get("month"), h.Svc.Now())
+	if err != nil {
+		http.Error(w, "month must be YYYY-MM", http.StatusBadRequest)
+		return
+	}
+	list, err := h.Svc.Budgets(r.Context(), authz.SubjectFrom(r), month)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h Handler) PutBudget(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		MonthlyLimit float64    `json:"monthly_limit"`
+		Mode         BudgetMode `json:"mode"`
+	}
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&in); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	b, err := h.Svc.PutBudget(r.Context(), authz.SubjectFrom(r), Budget{
+		Category: chi.URLParam(r, "category"), MonthlyLimit: in.MonthlyLimit, Mode: in.Mode,
+	})
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, b)
+}
+
+func (h Handler) DeleteBudget(w http.ResponseWriter, r *http.Request) {
+	if err := h.Svc.DeleteBudget(r.Context(), authz.SubjectFrom(r), chi.URLParam(r, "category")); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Spending ค่าเริ่มต้น 6 เดือนล่าสุด (รวมเดือนนี้)
+func (h Handler) Spending(w http.ResponseWriter, r *http.Request) {
+	now := h.Svc.Now()
+	from, err := parseMonth(r.URL.Query().Get("from"), time.Date(now.Year(), now.Month()-5, 1, 0, 0, 0, 0, now.Location()))
+	if err != nil {
+		http.Error(w, "from must be YYYY-MM", http.StatusBadRequest)
+		return
+	}
+	to, err := parseMonth(r.URL.Query().Get("to"), now)
+	if err != nil {
+		http.Error(w, "to must be YYYY-MM", http.StatusBadRequest)
+		return
+	}
+	rows, err := h.Svc.Spending(r.Context(), authz.SubjectFrom(r), from, to)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rows)
+}
+
+func (h Handler) Comments(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Svc.Comments(r.Context(), authz.SubjectFrom(r), chi.URLParam(r, "id"))
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h Handler) AddComment(w http.ResponseWriter, r *http.Request) {
+	var in CommentPayload
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&in); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	c, err := h.Svc.AddComment(r.Context(), authz.SubjectFrom(r), chi.URLParam(r, "id"), in)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, c)
+}
+
+// writePlanned สร้างใหม่ = 201, รวมเข้าคำขอเดิม = 200
+func writePlanned(w http.ResponseWriter, p *Purchase, created bool) {
+	code := http.StatusOK
+	if created {
+		code = http.StatusCreated
+	}
+	writePurchase(w, code, p)
+}
+
+func (h Handler) AddItems(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Items []Item `json:"items"`
+	}
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&in); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	p, created, err := h.Svc.Plan(r.Context(), authz.SubjectFrom(r), PlanPayload{Into: chi.URLParam(r, "id"), Items: in.Items})
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writePlanned(w, p, created)
+}
+
+func (h Handler) Restock(w http.ResponseWriter, r *http.Request) {
+	var in RestockPayload
+	if r.ContentLength != 0 {
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&in); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+	}
+	p, created, err := h.Svc.Restock(r.Context(), authz.SubjectFrom(r), in)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writePlanned(w, p, created)
+}
+
+func (h Handler) Templates(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Svc.Templates(r.Context(), authz.SubjectFrom(r))
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+type templatePayload struct {
+	Name     string `json:"name"`
+	Title    string `json:"title"`
+	Category string `json:"category"`
+	Store    string `json:"store"`
+	Items    Items  `json:"items"`
+}
+
+func decodeTemplate(r *http.Request) (Template, error) {
+	var in templatePayload
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&in); err != nil {
+		return Template{}, err
+	}
+	return Template{Name: in.Name, Title: in.Title, Category: in.Category, Store: in.Store, Items: in.Items}, nil
+}
+
+func (h Handler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	in, err := decodeTemplate(r)
+	if err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	t, err := h.Svc.CreateTemplate(r.Context(), authz.SubjectFrom(r), in)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, t)
+}
+
+func (h Handler) UpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	in, err := decodeTemplate(r)
+	if err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	t, err := h.Svc.UpdateTemplate(r.Context(), authz.SubjectFrom(r), chi.URLParam(r, "templateID"), in)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (h Handler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	if err := h.Svc.DeleteTemplate(r.Context(), authz.SubjectFrom(r), chi.URLParam(r, "templateID")); err != nil {
 		writeErr(w, err)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func (h Handler) UseTemplate(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Into string `json:"into"`
+	}
+	if r.ContentLength != 0 {
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&in); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+	}
+	p, created, err := h.Svc.UseTemplate(r.Context(), authz.SubjectFrom(r), chi.URLParam(r, "templateID"), in.Into)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writePlanned(w, p, created)
+}
@@ -0,0 +1,44 @@
+package purchases
+
+import "reflect"
+
+// changeEvents เทียบก่อน/หลังแล้วสร้าง event ที่จะบันทึกคู่กับ Repo.Update:
+// claim/เปลี่ยนสถานะเป็น event ของตัวเอง, ฟิลด์อื่นที่เปลี่ยนรวมเป็น EventUpdated เดียวพร้อม diff
+func changeEvents(actorID string, before, after *Purchase) []Event {
+	var out []Event
+	if before.Status != after.Status {
+		data := map[string]any{"from": before.Status, "to": after.Status}
+		kind := EventStatusChanged
+		if before.BuyerID == "" && after.BuyerID != "" {
+			kind = EventClaimed
+			data["buyer_id"] = after.BuyerID
+		}
+		if after.DecisionNote != before.DecisionNote && after.DecisionNote != "" {
+			data["note"] = after.DecisionNote
+		}
+		if after.AmountPaid != before.AmountPaid {
+			data["amount_paid"] = after.AmountPaid
+		}
+		out = append(out, Event{ActorID: actorID, Kind: kind, Data: data})
+	}
+
+	changes := map[string]Change{}
+	diff := func(field string, from, to any) {
+		if !reflect.DeepEqual(from, to) {
+			changes[field] = Change{From: from, To: to}
+		}
+	}
+	diff("title", before.Title, after.Title)
+	diff("note", before.Note, after.Note)
+	diff("items", before.Items, after.Items)
+	diff("amount_estimated", before.AmountEstimated, after.AmountEstimated)
+	diff("category", before.Category, after.Category)
+	diff("store", before.Store, after.Store)
+	if before.Status == after.Status {
+		diff("amount_paid", before.AmountPaid, after.AmountPaid)
+	}
+	if len(changes) > 0 {
+		out = append(out, Event{ActorID: actorID, Kind: EventUpdated, Data: map[string]any{"changes": changes}})
+	}
+	return out
+}
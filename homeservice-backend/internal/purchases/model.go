@@ -9,6 +9,8 @@ import (
 type Status string
 
 const (
+	StatusRequested Status = "requested" // รออนุมัติ (ราคาประเมินเกินเกณฑ์ของบ้าน)
+	StatusRejected  Status = "rejected"
 	StatusPlanned   Status = "planned"
 	StatusOrdered   Status = "ordered"
 	StatusBought    Status = "bought"
@@ -43,6 +45,7 @@ func (it *Items) Scan(src any) error {
 // Main model (response shape)
 type Purchase struct {
 	ID              string    `json:"id" db:"id"`
+	HouseholdID     string    `json:"household_id" db:"household_id"`
 	Title           string    `json:"title" db:"title"`
 	Note            string    `json:"note,omitempty" db:"note"`
 	Items           Items     `json:"items,omitempty" db:"items"`
@@ -57,4 +60,115 @@ type Purchase struct {
 	EditableUntil   time.Time `json:"editable_until" db:"editable_until"`
 	CreatedAt       time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+	Version         int       `json:"version" db:"version"` // = ETag; เพิ่มทุกครั้งที่แถวถูกแก้
+
+	ApprovedBy   string     `json:"approved_by,omitempty" db:"approved_by"` // owner/admin ที่อนุมัติ/ปฏิเสธ
+	DecidedAt    *time.Time `json:"decided_at,omitempty" db:"decided_at"`
+	DecisionNote string     `json:"decision_note,omitempty" db:"decision_note"`
+	BoughtAt     *time.Time `json:"bought_at,omitempty" db:"bought_at"`
+
+	// Budget สถานะงบของหมวดในเดือนนี้ (เฉพาะตอบ create/update ที่หมวดมีงบ)
+	Budget *BudgetCheck `json:"budget,omitempty" db:"-"`
+	// guard งบแบบ block ที่ repo ต้องล็อกแล้วตรวจซ้ำใน transaction ที่เขียนคำขอ (ตั้งโดย checkBudget)
+	guard *budgetGuard
+
+	// เฉพาะ GET /purchases/{id}
+	Events   []Event   `json:"events,omitempty" db:"-"`
+	Comments []Comment `json:"comments,omitempty" db:"-"`
+}
+
+// ชนิดของ Event
+const (
+	EventCreated           = "created"
+	EventClaimed           = "claimed"
+	EventStatusChanged     = "status_changed"
+	EventUpdated           = "updated" // Data.changes = {field: {from, to}}
+	EventAttachmentAdded   = "attachment_added"
+	EventAttachmentRemoved = "attachment_removed"
+)
+
+// Event บันทึกประวัติแบบเพิ่มอย่างเดียว (แก้/ลบไม่ได้)
+type Event struct {
+	ID         int64          `json:"id"`
+	PurchaseID string         `json:"purchase_id"`
+	ActorID    string         `json:"actor_id,omitempty"`
+	Kind       string         `json:"kind"`
+	Data       map[string]any `json:"data,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// Change ค่าก่อน/หลังของฟิลด์หนึ่งใน EventUpdated
+type Change struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// Comment ความเห็นใต้คำขอซื้อ แนบไฟล์ได้ (id จาก /uploads)
+type Comment struct {
+	ID         string    `json:"id"`
+	PurchaseID string    `json:"purchase_id"`
+	UserID     string    `json:"user_id"`
+	Body       string    `json:"body"`
+	FileIDs    []string  `json:"file_ids"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Template รายการซื้อที่ใช้ซ้ำ (เช่น ของใช้ประจำสัปดาห์); ชื่อไม่ซ้ำในบ้าน
+type Template struct {
+	ID          string    `json:"id"`
+	HouseholdID string    `json:"household_id"`
+	Name        string    `json:"name"`
+	Title       string    `json:"title"` // title ของคำขอที่สร้างจาก template
+	Category    string    `json:"category,omitempty"`
+	Store       string    `json:"store,omitempty"`
+	Items       Items     `json:"items"`
+	CreatedBy   string    `json:"created_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Settings ตั้งค่าคำขอซื้อของบ้าน; ApprovalThreshold nil = ไม่ต้องอนุมัติ
+type Settings struct {
+	HouseholdID       string   `json:"household_id"`
+	ApprovalThreshold *float64 `json:"approval_threshold"`
+}
+
+type BudgetMode string
+
+const (
+	BudgetWarn  BudgetMode = "warn"
+	BudgetBlock BudgetMode = "block"
+)
+
+// Budget งบรายเดือนต่อหมวด; Spent = ยอดผูกพันของเดือนที่ขอ (จ่ายแล้วใช้ amount_paid ไม่งั้น amount_estimated)
+type Budget struct {
+	Category     string     `json:"category"`
+	MonthlyLimit float64    `json:"monthly_limit"`
+	Mode         BudgetMode `json:"mode"`
+	Spent        float64    `json:"spent"`
+	Remaining    float64    `json:"remaining"`
+}
+
+// BudgetCheck ผลตรวจงบตอนสร้าง/แก้คำขอ (Spent รวมคำขอนี้แล้ว)
+type BudgetCheck struct {
+	Category string     `json:"category"`
+	Month    string     `json:"month"` // YYYY-MM
+	Limit    float64    `json:"limit"`
+	Spent    float64    `json:"spent"`
+	Mode     BudgetMode `json:"mode"`
+	Exceeded bool       `json:"exceeded"`
+}
+
+// budgetGuard งบหมวดหนึ่งของเดือน [from, to)
+type budgetGuard struct {
+	category string
+	from, to time.Time
+}
+
+// SpendingRow ยอดจ่ายจริง (amount_paid) ต่อเดือนต่อหมวด นับตามเดือนที่ซื้อ
+type SpendingRow struct {
+	Month      string  `json:"month"` // YYYY-MM
+	Category   string  `json:"category"`
+	Count      int     `json:"count"`
+	AmountPaid float64 `json:"amount_paid"`
 }
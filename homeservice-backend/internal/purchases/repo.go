@@ -3,31 +3,65 @@ package purchases
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type ListFilter struct {
-	Query    string
-	Status   *Status
-	Category string
-	Mine     string // "requester" | "buyer" | ""
-	UserID   string // จาก auth
-	Limit    int
-	Offset   int
+	HouseholdID string // บังคับ: จำกัดผลลัพธ์เฉพาะบ้าน
+	Query       string
+	Status      *Status
+	Category    string
+	Mine        string // "requester" | "buyer" | ""
+	UserID      string // จาก auth
+	Limit       int
+	Offset      int
 }
 
 type Repo interface {
 	List(ctx context.Context, f ListFilter) ([]Purchase, error)
-	Get(ctx context.Context, id string) (*Purchase, error)
+	Get(ctx context.Context, householdID, id string) (*Purchase, error)
 	Create(ctx context.Context, p *Purchase) error
-	Update(ctx context.Context, p *Purchase) error
-	Delete(ctx context.Context, id string) error
+	// Update เขียนทุกฟิลด์พร้อมบันทึก events ใน transaction เดียวกัน
+	// เฉพาะเมื่อ version ยังเท่ากับ p.Version (ไม่งั้น ErrConflict); สำเร็จแล้ว p.Version เป็นค่าใหม่
+	Update(ctx context.Context, p *Purchase, events ...Event) error
+	// Claim ตั้ง buyer แบบมีเงื่อนไขในคำสั่งเดียว: ยัง planned และยังไม่มี buyer ไม่งั้น ErrConflict
+	Claim(ctx context.Context, householdID, id, buyerID string) (*Purchase, error)
+	Delete(ctx context.Context, householdID, id string) error
 
-	LinkAttachment(ctx context.Context, purchaseID, fileID string) error
-	UnlinkAttachment(ctx context.Context, purchaseID, fileID string) error
+	// attachment ที่เพิ่ม/เอาออกจริงถูกบันทึกเป็น event ของ actorID
+	LinkAttachment(ctx context.Context, purchaseID, fileID, actorID string) error
+	UnlinkAttachment(ctx context.Context, purchaseID, fileID, actorID string) error
+
+	Events(ctx context.Context, purchaseID string) ([]Event, error)
+	AddComment(ctx context.Context, c *Comment) error
+	Comments(ctx context.Context, purchaseID string) ([]Comment, error)
+	// FileUsable ไฟล์ต้องอัปโหลดโดยสมาชิกของบ้านนี้
+	FileUsable(ctx context.Context, householdID, fileID string) (bool, error)
+
+	ListTemplates(ctx context.Context, householdID string) ([]Template, error)
+	GetTemplate(ctx context.Context, householdID, id string) (*Template, error)
+	// CreateTemplate ชื่อซ้ำในบ้าน = ErrConflict
+	CreateTemplate(ctx context.Context, t *Template) error
+	UpdateTemplate(ctx context.Context, t *Template) error
+	DeleteTemplate(ctx context.Context, householdID, id string) error
+
+	GetSettings(ctx context.Context, householdID string) (*Settings, error)
+	PutSettings(ctx context.Context, st *Settings) error
+	// ListBudgets งบทุกหมวดพร้อมยอดผูกพันในช่วง [from, to)
+	ListBudgets(ctx context.Context, householdID string, from, to time.Time) ([]Budget, error)
+	// GetBudget nil = หมวดนี้ไม่มีงบ; Spent ไม่รวมคำขอ excludeID (คำขอที่กำลังแก้)
+	GetBudget(ctx context.Context, householdID, category string, from, to time.Time, excludeID string) (*Budget, error)
+	PutBudget(ctx context.Context, householdID string, b *Budget) error
+	DeleteBudget(ctx context.Context, householdID, category string) error
+	// Spending แบ่งเดือนตามโซนเวลาของ from (ช่วง [from, to) คำนวณในโซนเดียวกัน)
+	Spending(ctx context.Context, householdID string, from, to time.Time) ([]SpendingRow, error)
 }
 
 type repo struct {
@@ -48,18 +82,31 @@ func jsonBytes(v any) []byte {
 }
 
 const selectCols = `
-  id, title, note, items, amount_estimated, amount_paid,
-  currency, category, store, status, requester_id, buyer_id,
-  editable_until, created_at, updated_at
+  id, household_id, title, note, items, amount_estimated, amount_paid,
+  currency, category, store, status, requester_id, COALESCE(buyer_id::text, '') AS buyer_id,
+  editable_until, created_at, updated_at,
+  COALESCE(approved_by::text, ''), decided_at, decision_note, bought_at, version
 `
 
+// scanDest ปลายทาง Scan ตามลำดับ selectCols
+func scanDest(p *Purchase) []any {
+	return []any{
+		&p.ID, &p.HouseholdID, &p.Title, &p.Note, &p.Items, &p.AmountEstimated, &p.AmountPaid,
+		&p.Currency, &p.Category, &p.Store, &p.Status, &p.RequesterID, &p.BuyerID,
+		&p.EditableUntil, &p.CreatedAt, &p.UpdatedAt,
+		&p.ApprovedBy, &p.DecidedAt, &p.DecisionNote, &p.BoughtAt, &p.Version,
+	}
+}
+
 // List with filters / search / pagination
 func (r *repo) List(ctx context.Context, f ListFilter) ([]Purchase, error) {
 	var sb strings.Builder
 	var args []any
 	arg := 1
 
-	sb.WriteString(`SELECT ` + selectCols + ` FROM purchases WHERE 1=1`)
+	sb.WriteString(fmt.Sprintf(`SELECT `+selectCols+` FROM purchases WHERE household_id = $%d`, arg))
+	args = append(args, f.HouseholdID)
+	arg++
 
 	if f.Query != "" {
 		sb.WriteString(fmt.Sprintf(` AND (title ILIKE $%d OR note ILIKE $%d OR items::text ILIKE $%d)`, arg, arg, arg))
@@ -111,11 +158,7 @@ func (r *repo) List(ctx context.Context, f ListFilter) ([]Purchase, error) {
 	out := make([]Purchase, 0, limit)
 	for rows.Next() {
 		var p Purchase
-		if err := rows.Scan(
-			&p.ID, &p.Title, &p.Note, &p.Items, &p.AmountEstimated, &p.AmountPaid,
-			&p.Currency, &p.Category, &p.Store, &p.Status, &p.RequesterID, &p.BuyerID,
-			&p.EditableUntil, &p.CreatedAt, &p.UpdatedAt,
-		); err != nil {
+		if err := rows.Scan(scanDest(&p)...); err != nil {
 			return nil, err
 		}
 		out = append(out, p)
@@ -123,14 +166,13 @@ func (r *repo) List(ctx context.Context, f ListFilter) ([]Purchase, error) {
 	return out, rows.Err()
 }
 
-func (r *repo) Get(ctx context.Context, id string) (*Purchase, error) {
-	row := r.DB.QueryRow(ctx, `SELECT `+selectCols+` FROM purchases WHERE id=$1`, id)
+func (r *repo) Get(ctx context.Context, householdID, id string) (*Purchase, error) {
+	row := r.DB.QueryRow(ctx, `SELECT `+selectCols+` FROM purchases WHERE id=$1 AND household_id=$2`, id, householdID)
 	var p Purchase
-	if err := row.Scan(
-		&p.ID, &p.Title, &p.Note, &p.Items, &p.AmountEstimated, &p.AmountPaid,
-		&p.Currency, &p.Category, &p.Store, &p.Status, &p.RequesterID, &p.BuyerID,
-		&p.EditableUntil, &p.CreatedAt, &p.UpdatedAt,
-	); err != nil {
+	if err := row.Scan(scanDest(&p)...); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
 		return nil, err
 	}
 	return &p, nil
@@ -146,56 +188,473 @@ func (r *repo) Create(ctx context.Context, p *Purchase) error {
 		p.Status = StatusPlanned
 	}
 
-	row := r.DB.QueryRow(ctx, `
-		INSERT INTO purchases (
-		  title, note, items, amount_estimated, amount_paid, currency,
-		  category, store, status, requester_id, buyer_id
-		) VALUES (
-		  $1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11
+	return pgx.BeginFunc(ctx, r.DB, func(tx pgx.Tx) error {
+		if err := lockBudget(ctx, tx, p); err != nil {
+			return err
+		}
+		row := tx.QueryRow(ctx, `
+			INSERT INTO purchases (
+			  title, note, items, amount_estimated, amount_paid, currency,
+			  category, store, status, requester_id, buyer_id, household_id
+			) VALUES (
+			  $1,$2,$3,$4,$5,$6,$7,$8,$9,$10,NULLIF($11,'')::uuid,$12
+			)
+			RETURNING `+selectCols,
+			p.Title, p.Note, jsonBytes(p.Items), p.AmountEstimated, p.AmountPaid, p.Currency,
+			p.Category, p.Store, p.Status, p.RequesterID, p.BuyerID, p.HouseholdID,
 		)
-		RETURNING `+selectCols,
-		p.Title, p.Note, jsonBytes(p.Items), p.AmountEstimated, p.AmountPaid, p.Currency,
-		p.Category, p.Store, p.Status, p.RequesterID, p.BuyerID,
-	)
-	return row.Scan(
-		&p.ID, &p.Title, &p.Note, &p.Items, &p.AmountEstimated, &p.AmountPaid,
-		&p.Currency, &p.Category, &p.Store, &p.Status, &p.RequesterID, &p.BuyerID,
-		&p.EditableUntil, &p.CreatedAt, &p.UpdatedAt,
-	)
+		if err := row.Scan(scanDest(p)...); err != nil {
+			return err
+		}
+		if err := recheckBudget(ctx, tx, p); err != nil {
+			return err
+		}
+		return insertEvents(ctx, tx, p.ID, Event{
+			ActorID: p.RequesterID, Kind: EventCreated, Data: map[string]any{"status": p.Status},
+		})
+	})
+}
+
+func insertEvents(ctx context.Context, tx pgx.Tx, purchaseID string, events ...Event) error {
+	for _, e := range events {
+		if e.Data == nil {
+			e.Data = map[string]any{}
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO purchase_events (purchase_id, actor_id, kind, data) VALUES ($1, NULLIF($2,'')::uuid, $3, $4)
+		`, purchaseID, e.ActorID, e.Kind, jsonBytes(e.Data)); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Update updates all mutable columns (service จะเป็นผู้คุมกติกา)
-func (r *repo) Update(ctx context.Context, p *Purchase) error {
-	_, err := r.DB.Exec(ctx, `
+func (r *repo) Update(ctx context.Context, p *Purchase, events ...Event) error {
+	return pgx.BeginFunc(ctx, r.DB, func(tx pgx.Tx) error {
+		if err := r.update(ctx, tx, p); err != nil {
+			return err
+		}
+		return insertEvents(ctx, tx, p.ID, events...)
+	})
+}
+
+func (r *repo) update(ctx context.Context, tx pgx.Tx, p *Purchase) error {
+	if err := lockBudget(ctx, tx, p); err != nil {
+		return err
+	}
+	// version เพิ่มโดย trigger; ไม่มีแถว = มีคนแก้ก่อน (หรือถูกลบไปแล้ว)
+	err := tx.QueryRow(ctx, `
 		UPDATE purchases
 		SET
 		  title=$2, note=$3, items=$4, amount_estimated=$5, amount_paid=$6,
-		  currency=$7, category=$8, store=$9, status=$10, requester_id=$11, buyer_id=$12,
+		  currency=$7, category=$8, store=$9, status=$10, requester_id=$11, buyer_id=NULLIF($12,'')::uuid,
+		  approved_by=NULLIF($14,'')::uuid, decided_at=$15, decision_note=$16, bought_at=$17,
 		  updated_at=now()
-		WHERE id=$1
+		WHERE id=$1 AND household_id=$13 AND version=$18
+		RETURNING updated_at, version
 	`, p.ID, p.Title, p.Note, jsonBytes(p.Items), p.AmountEstimated, p.AmountPaid,
-		p.Currency, p.Category, p.Store, p.Status, p.RequesterID, p.BuyerID)
+		p.Currency, p.Category, p.Store, p.Status, p.RequesterID, p.BuyerID, p.HouseholdID,
+		p.ApprovedBy, p.DecidedAt, p.DecisionNote, p.BoughtAt, p.Version).Scan(&p.UpdatedAt, &p.Version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	return recheckBudget(ctx, tx, p)
+}
+
+// lockBudget ล็อกงบ (บ้าน+หมวด+เดือน) ไว้จนจบ transaction: คำขอที่ตรวจงบแบบ block เดียวกันเขียนได้ทีละรายการ
+func lockBudget(ctx context.Context, tx pgx.Tx, p *Purchase) error {
+	if p.guard == nil {
+		return nil
+	}
+	_, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1, 0))`,
+		"purchase_budget:"+p.HouseholdID+":"+p.guard.category+":"+p.guard.from.Format("2006-01"))
 	return err
 }
 
-func (r *repo) Delete(ctx context.Context, id string) error {
-	_, err := r.DB.Exec(ctx, `DELETE FROM purchases WHERE id=$1`, id)
+// recheckBudget หลังเขียนแถวแล้ว (ยอดรวมคำขอนี้ด้วย) ใต้ lock เดียวกัน: เกินงบ block = ErrBudgetExceeded และย้อนทั้ง transaction
+func recheckBudget(ctx context.Context, tx pgx.Tx, p *Purchase) error {
+	g := p.guard
+	if g == nil {
+		return nil
+	}
+	var b Budget
+	err := tx.QueryRow(ctx, `
+		SELECT b.monthly_limit::float8, b.mode, `+committedExpr+`
+		FROM purchase_budgets b`+committedJoin+`
+		WHERE b.household_id = $1 AND b.category = $4
+		GROUP BY b.monthly_limit, b.mode
+	`, p.HouseholdID, g.from, g.to, g.category).Scan(&b.MonthlyLimit, &b.Mode, &b.Spent)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil // งบถูกลบไประหว่างนี้
+	}
+	if err != nil {
+		return err
+	}
+	if b.Mode == BudgetBlock && b.Spent > b.MonthlyLimit {
+		return ErrBudgetExceeded
+	}
+	if p.Budget != nil {
+		p.Budget.Limit, p.Budget.Spent, p.Budget.Mode = b.MonthlyLimit, b.Spent, b.Mode
+		p.Budget.Exceeded = b.Spent > b.MonthlyLimit
+	}
+	return nil
+}
+
+func (r *repo) Claim(ctx context.Context, householdID, id, buyerID string) (*Purchase, error) {
+	var p Purchase
+	err := pgx.BeginFunc(ctx, r.DB, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `
+			UPDATE purchases SET buyer_id=$3, status=$4, updated_at=now()
+			WHERE id=$1 AND household_id=$2 AND buyer_id IS NULL AND status=$5
+			RETURNING `+selectCols,
+			id, householdID, buyerID, StatusOrdered, StatusPlanned,
+		).Scan(scanDest(&p)...)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrConflict
+		}
+		if err != nil {
+			return err
+		}
+		return insertEvents(ctx, tx, p.ID, Event{
+			ActorID: buyerID, Kind: EventClaimed,
+			Data: map[string]any{"from": StatusPlanned, "to": StatusOrdered, "buyer_id": buyerID},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *repo) Delete(ctx context.Context, householdID, id string) error {
+	_, err := r.DB.Exec(ctx, `DELETE FROM purchases WHERE id=$1 AND household_id=$2`, id, householdID)
 	return err
 }
 
 // attachments
-func (r *repo) LinkAttachment(ctx context.Context, purchaseID, fileID string) error {
+func (r *repo) LinkAttachment(ctx context.Context, purchaseID, fileID, actorID string) error {
+	return pgx.BeginFunc(ctx, r.DB, func(tx pgx.Tx) error {
+		ct, err := tx.Exec(ctx, `
+			INSERT INTO purchase_attachments (purchase_id, file_id)
+			VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`, purchaseID, fileID)
+		if err != nil || ct.RowsAffected() == 0 {
+			return err
+		}
+		return insertEvents(ctx, tx, purchaseID, Event{
+			ActorID: actorID, Kind: EventAttachmentAdded, Data: map[string]any{"file_id": fileID},
+		})
+	})
+}
+
+func (r *repo) UnlinkAttachment(ctx context.Context, purchaseID, fileID, actorID string) error {
+	return pgx.BeginFunc(ctx, r.DB, func(tx pgx.Tx) error {
+		ct, err := tx.Exec(ctx, `
+			DELETE FROM purchase_attachments WHERE purchase_id=$1 AND file_id=$2
+		`, purchaseID, fileID)
+		if err != nil || ct.RowsAffected() == 0 {
+			return err
+		}
+		return insertEvents(ctx, tx, purchaseID, Event{
+			ActorID: actorID, Kind: EventAttachmentRemoved, Data: map[string]any{"file_id": fileID},
+		})
+	})
+}
+
+// history / comments
+
+func (r *repo) Events(ctx context.Context, purchaseID string) ([]Event, error) {
+	rows, err := r.DB.Query(ctx, `
+		SELECT id, purchase_id, COALESCE(actor_id::text, ''), kind, data, created_at
+		FROM purchase_events WHERE purchase_id=$1 ORDER BY id
+	`, purchaseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Event{}
+	for rows.Next() {
+		var e Event
+		var raw []byte
+		if err := rows.Scan(&e.ID, &e.PurchaseID, &e.ActorID, &e.Kind, &raw, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		_ = json.Unmarshal(raw, &e.Data)
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+func (r *repo) AddComment(ctx context.Context, c *Comment) error {
+	return pgx.BeginFunc(ctx, r.DB, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, `
+			INSERT INTO purchase_messages (purchase_id, user_id, body) VALUES ($1, $2, $3)
+			RETURNING id, created_at
+		`, c.PurchaseID, c.UserID, c.Body).Scan(&c.ID, &c.CreatedAt); err != nil {
+			return err
+		}
+		for _, fid := range c.FileIDs {
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO purchase_message_files (message_id, file_id) VALUES ($1, $2) ON CONFLICT DO NOTHING
+			`, c.ID, fid); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *repo) Comments(ctx context.Context, purchaseID string) ([]Comment, error) {
+	rows, err := r.DB.Query(ctx, `
+		SELECT m.id, m.purchase_id, m.user_id, m.body, m.created_at,
+		       COALESCE(array_agg(f.file_id::text ORDER BY f.file_id) FILTER (WHERE f.file_id IS NOT NULL), '{}')
+		FROM purchase_messages m
+		LEFT JOIN purchase_message_files f ON f.message_id = m.id
+		WHERE m.purchase_id=$1
+		GROUP BY m.id
+		ORDER BY m.created_at, m.id
+	`, purchaseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Comment{}
+	for rows.Next() {
+		var c Comment
+		if err := rows.Scan(&c.ID, &c.PurchaseID, &c.UserID, &c.Body, &c.CreatedAt, &c.FileIDs); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+func (r *repo) FileUsable(ctx context.Context, householdID, fileID string) (bool, error) {
+	var ok bool
+	err := r.DB.QueryRow(ctx, `
+		SELECT EXISTS (
+		  SELECT 1 FROM files f
+		  JOIN household_members m ON m.user_id = f.owner_id
+		  WHERE f.id::text = $1 AND m.household_id = $2
+		)`, fileID, householdID).Scan(&ok)
+	return ok, err
+}
+
+// templates
+
+const templateCols = `id, household_id, name, title, category, store, items, COALESCE(created_by::text, ''), created_at, updated_at`
+
+func scanTemplate(row pgx.Row, t *Template) error {
+	return row.Scan(&t.ID, &t.HouseholdID, &t.Name, &t.Title, &t.Category, &t.Store, &t.Items, &t.CreatedBy, &t.CreatedAt, &t.UpdatedAt)
+}
+
+func (r *repo) ListTemplates(ctx context.Context, householdID string) ([]Template, error) {
+	rows, err := r.DB.Query(ctx, `SELECT `+templateCols+` FROM purchase_templates WHERE household_id=$1 ORDER BY name`, householdID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Template{}
+	for rows.Next() {
+		var t Template
+		if err := scanTemplate(rows, &t); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+func (r *repo) GetTemplate(ctx context.Context, householdID, id string) (*Template, error) {
+	var t Template
+	err := scanTemplate(r.DB.QueryRow(ctx, `SELECT `+templateCols+` FROM purchase_templates WHERE id::text=$1 AND household_id=$2`, id, householdID), &t)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *repo) CreateTemplate(ctx context.Context, t *Template) error {
+	err := scanTemplate(r.DB.QueryRow(ctx, `
+		INSERT INTO purchase_templates (household_id, name, title, category, store, items, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7,'')::uuid)
+		ON CONFLICT (household_id, name) DO NOTHING
+		RETURNING `+templateCols,
+		t.HouseholdID, t.Name, t.Title, t.Category, t.Store, jsonBytes(t.Items), t.CreatedBy), t)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *repo) UpdateTemplate(ctx context.Context, t *Template) error {
+	err := scanTemplate(r.DB.QueryRow(ctx, `
+		UPDATE purchase_templates SET name=$3, title=$4, category=$5, store=$6, items=$7, updated_at=now()
+		WHERE id::text=$1 AND household_id=$2
+		  AND NOT EXISTS (SELECT 1 FROM purchase_templates o WHERE o.household_id=$2 AND o.name=$3 AND o.id::text<>$1)
+		RETURNING `+templateCols,
+		t.ID, t.HouseholdID, t.Name, t.Title, t.Category, t.Store, jsonBytes(t.Items)), t)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrConflict // ชื่อชนกับ template อื่น (Service ตรวจว่ามีอยู่จริงก่อนแล้ว)
+	}
+	return err
+}
+
+func (r *repo) DeleteTemplate(ctx context.Context, householdID, id string) error {
+	ct, err := r.DB.Exec(ctx, `DELETE FROM purchase_templates WHERE id::text=$1 AND household_id=$2`, id, householdID)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// settings / budgets
+
+func (r *repo) GetSettings(ctx context.Context, householdID string) (*Settings, error) {
+	st := &Settings{HouseholdID: householdID}
+	err := r.DB.QueryRow(ctx, `
+		SELECT approval_threshold::float8 FROM purchase_settings WHERE household_id=$1
+	`, householdID).Scan(&st.ApprovalThreshold)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	return st, nil
+}
+
+func (r *repo) PutSettings(ctx context.Context, st *Settings) error {
 	_, err := r.DB.Exec(ctx, `
-		INSERT INTO purchase_attachments (purchase_id, file_id)
-		VALUES ($1, $2)
-		ON CONFLICT DO NOTHING
-	`, purchaseID, fileID)
+		INSERT INTO purchase_settings (household_id, approval_threshold) VALUES ($1, $2)
+		ON CONFLICT (household_id) DO UPDATE SET approval_threshold = EXCLUDED.approval_threshold, updated_at = now()
+	`, st.HouseholdID, st.ApprovalThreshold)
 	return err
 }
 
-func (r *repo) UnlinkAttachment(ctx context.Context, purchaseID, fileID string) error {
+// committedExpr ยอดผูกพันของคำขอหนึ่งรายการ: จ่ายแล้วใช้ยอดจริง ไม่งั้นใช้ราคาประเมิน
+// นับตามเดือนที่ซื้อ (ยังไม่ซื้อ = เดือนที่ขอ); คำขอที่ยกเลิก/ถูกปฏิเสธไม่นับ
+const committedExpr = `
+  COALESCE(SUM(CASE WHEN p.amount_paid > 0 THEN p.amount_paid ELSE p.amount_estimated END), 0)::float8`
+
+const committedJoin = `
+  LEFT JOIN purchases p ON p.household_id = b.household_id AND p.category = b.category
+   AND p.status NOT IN ('cancelled','rejected')
+   AND COALESCE(p.bought_at, p.created_at) >= $2 AND COALESCE(p.bought_at, p.created_at) < $3`
+
+func (r *repo) ListBudgets(ctx context.Context, householdID string, from, to time.Time) ([]Budget, error) {
+	rows, err := r.DB.Query(ctx, `
+		SELECT b.category, b.monthly_limit::float8, b.mode, `+committedExpr+`
+		FROM purchase_budgets b`+committedJoin+`
+		WHERE b.household_id = $1
+		GROUP BY b.category, b.monthly_limit, b.mode
+		ORDER BY b.category
+	`, householdID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Budget{}
+	for rows.Next() {
+		var b Budget
+		if err := rows.Scan(&b.Category, &b.MonthlyLimit, &b.Mode, &b.Spent); err != nil {
+			return nil, err
+		}
+		b.Remaining = b.MonthlyLimit - b.Spent
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
+
+func (r *repo) GetBudget(ctx context.Context, householdID, category string, from, to time.Time, excludeID string) (*Budget, error) {
+	var b Budget
+	err := r.DB.QueryRow(ctx, `
+		SELECT b.category, b.monthly_limit::float8, b.mode, `+committedExpr+`
+		FROM purchase_budgets b`+committedJoin+` AND p.id::text <> $5
+		WHERE b.household_id = $1 AND b.category = $4
+		GROUP BY b.category, b.monthly_limit, b.mode
+	`, householdID, from, to, category, excludeID).Scan(&b.Category, &b.MonthlyLimit, &b.Mode, &b.Spent)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	b.Remaining = b.MonthlyLimit - b.Spent
+	return &b, nil
+}
+
+func (r *repo) PutBudget(ctx context.Context, householdID string, b *Budget) error {
 	_, err := r.DB.Exec(ctx, `
-		DELETE FROM purchase_attachments WHERE purchase_id=$1 AND file_id=$2
-	`, purchaseID, fileID)
+		INSERT INTO purchase_budgets (household_id, category, monthly_limit, mode) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (household_id, category) DO UPDATE
+		SET monthly_limit = EXCLUDED.monthly_limit, mode = EXCLUDED.mode, updated_at = now()
+	`, householdID, b.Category, b.MonthlyLimit, b.Mode)
 	return err
 }
+
+func (r *repo) DeleteBudget(ctx context.Context, householdID, category string) error {
+	ct, err := r.DB.Exec(ctx, `DELETE FROM purchase_budgets WHERE household_id=$1 AND category=$2`, householdID, category)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Spending ยอด amount_paid ของคำขอที่ซื้อแล้ว (bought/delivered) ต่อเดือนที่ซื้อ ต่อหมวด ในช่วง [from, to)
+// แบ่งเดือนฝั่ง Go ด้วยโซนของ from (date_trunc ใน DB ใช้ TimeZone ของ session ซึ่งอาจไม่ตรงกับช่วงที่ส่งมา)
+func (r *repo) Spending(ctx context.Context, householdID string, from, to time.Time) ([]SpendingRow, error) {
+	rows, err := r.DB.Query(ctx, `
+		SELECT bought_at, category, amount_paid::float8
+		FROM purchases
+		WHERE household_id = $1 AND status IN ('bought','delivered')
+		  AND bought_at >= $2 AND bought_at < $3
+	`, householdID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	type key struct{ month, category string }
+	sums := map[key]*SpendingRow{}
+	for rows.Next() {
+		var at time.Time
+		var category string
+		var paid float64
+		if err := rows.Scan(&at, &category, &paid); err != nil {
+			return nil, err
+		}
+		k := key{at.In(from.Location()).Format("2006-01"), category}
+		row := sums[k]
+		if row == nil {
+			row = &SpendingRow{Month: k.month, Category: k.category}
+			sums[k] = row
+		}
+		row.Count++
+		row.AmountPaid += paid
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	out := make([]SpendingRow, 0, len(sums))
+	for _, row := range sums {
+		out = append(out, *row)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Month != out[j].Month {
+			return out[i].Month < out[j].Month
+		}
+		return out[i].Category < out[j].Category
+	})
+	return out, nil
+}
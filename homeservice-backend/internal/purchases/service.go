@@ -2,12 +2,19 @@ package purchases
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"time"
+
+	"github.com/iMookatayou/homeservice-backend/internal/authz"
 )
 
 type Service struct {
 	Repo Repo
 	Now  func() time.Time
+
+	// Stock แหล่งของที่ต้องซื้อเติม (ยาที่ต่ำกว่าขั้นต่ำ); nil = ปิด restock
+	Stock RestockSource
 }
 
 func NewService(r Repo) *Service { return &Service{Repo: r, Now: time.Now} }
@@ -36,9 +43,11 @@ type ProgressPayload struct {
 	AmountPaid *float64 `json:"amount_paid"`
 }
 
-// กติกาเปลี่ยนสถานะ
+// กติกาเปลี่ยนสถานะ (requested → planned/rejected ผ่าน Approve/Reject เท่านั้น)
 func (s *Service) CanTransition(from, to Status) bool {
 	switch from {
+	case StatusRequested:
+		return to == StatusPlanned || to == StatusRejected || to == StatusCancelled
 	case StatusPlanned:
 		return to == StatusOrdered || to == StatusCancelled
 	case StatusOrdered:
@@ -53,6 +62,27 @@ func (s *Service) CanTransition(from, to Status) bool {
 	}
 }
 
+// resource แปลง purchase เป็น authz.Resource (owner = requester, participant = buyer)
+func resource(p *Purchase) authz.Resource {
+	res := authz.Resource{Kind: authz.KindPurchase, HouseholdID: p.HouseholdID, OwnerID: p.RequesterID}
+	if p.BuyerID != "" {
+		res.Participants = []string{p.BuyerID}
+	}
+	return res
+}
+
+// load ดึง purchase ในบ้านของ subject แล้วตรวจสิทธิ์ action
+func (s *Service) load(ctx context.Context, sub authz.Subject, id string, a authz.Action) (*Purchase, error) {
+	p, err := s.Repo.Get(ctx, sub.HouseholdID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !authz.Can(ctx, sub, a, resource(p)) {
+		return nil, ErrForbidden
+	}
+	return p, nil
+}
+
 /*************** methods ที่ handler เรียก ***************/
 
 // List passthrough
@@ -61,8 +91,12 @@ func (s *Service) List(ctx context.Context, f ListFilter) ([]Purchase, error) {
 }
 
 // Create (requester เป็นคนสร้าง)
-func (s *Service) Create(ctx context.Context, uid string, in CreatePayload) (*Purchase, error) {
+func (s *Service) Create(ctx context.Context, sub authz.Subject, in CreatePayload) (*Purchase, error) {
+	if !authz.Can(ctx, sub, authz.ActionCreate, authz.Resource{Kind: authz.KindPurchase, HouseholdID: sub.HouseholdID}) {
+		return nil, ErrForbidden
+	}
 	p := &Purchase{
+		HouseholdID:     sub.HouseholdID,
 		Title:           in.Title,
 		Note:            in.Note,
 		Items:           in.Items,
@@ -71,93 +105,155 @@ func (s *Service) Create(ctx context.Context, uid string, in CreatePayload) (*Pu
 		Category:        in.Category,
 		Store:           in.Store,
 		Status:          StatusPlanned,
-		RequesterID:     uid,
+		RequesterID:     sub.UserID,
 	}
 	if p.Currency == "" {
 		p.Currency = "THB"
 	}
+	needs, err := s.needsApproval(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	if needs {
+		p.Status = StatusRequested
+	}
+	if err := s.checkBudget(ctx, p); err != nil {
+		return nil, err
+	}
 	if err := s.Repo.Create(ctx, p); err != nil {
 		return nil, err
 	}
 	return p, nil
 }
 
-// Get
-func (s *Service) Get(ctx context.Context, id string) (*Purchase, error) {
-	return s.Repo.Get(ctx, id)
+// Get พร้อมประวัติและความเห็นทั้งหมด
+func (s *Service) Get(ctx context.Context, sub authz.Subject, id string) (*Purchase, error) {
+	p, err := s.load(ctx, sub, id, authz.ActionRead)
+	if err != nil {
+		return nil, err
+	}
+	if p.Events, err = s.Repo.Events(ctx, p.ID); err != nil {
+		return nil, err
+	}
+	if p.Comments, err = s.Repo.Comments(ctx, p.ID); err != nil {
+		return nil, err
+	}
+	return p, nil
 }
 
-// Delete: requester เท่านั้น และต้องยังแก้ไขได้อยู่ + ยัง planned
-func (s *Service) Delete(ctx context.Context, uid, id string) error {
-	p, err := s.Repo.Get(ctx, id)
+type CommentPayload struct {
+	Body    string   `json:"body"`
+	FileIDs []string `json:"file_ids"`
+}
+
+// AddComment: สมาชิกบ้านทุกคน; ไฟล์แนบต้องอัปโหลดโดยสมาชิกบ้านนี้
+func (s *Service) AddComment(ctx context.Context, sub authz.Subject, id string, in CommentPayload) (*Comment, error) {
+	p, err := s.load(ctx, sub, id, authz.ActionComment)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if p.RequesterID != uid {
-		return ErrForbidden
+	in.Body = strings.TrimSpace(in.Body)
+	if in.Body == "" && len(in.FileIDs) == 0 {
+		return nil, ErrBadRequest
+	}
+	for _, fid := range in.FileIDs {
+		ok, err := s.Repo.FileUsable(ctx, p.HouseholdID, fid)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrBadRequest
+		}
 	}
-	if s.Now().After(p.EditableUntil) || p.Status != StatusPlanned {
-		return ErrConflict
+	c := &Comment{PurchaseID: p.ID, UserID: sub.UserID, Body: in.Body, FileIDs: in.FileIDs}
+	if c.FileIDs == nil {
+		c.FileIDs = []string{}
+	}
+	if err := s.Repo.AddComment(ctx, c); err != nil {
+		return nil, err
 	}
-	return s.Repo.Delete(ctx, id)
+	return c, nil
 }
 
-// Cancel: requester หรือ buyer ยกเลิกได้ ถ้า transition อนุญาต
-func (s *Service) Cancel(ctx context.Context, uid, id string) (*Purchase, error) {
-	p, err := s.Repo.Get(ctx, id)
+// Comments ความเห็นเรียงตามเวลา
+func (s *Service) Comments(ctx context.Context, sub authz.Subject, id string) ([]Comment, error) {
+	p, err := s.load(ctx, sub, id, authz.ActionRead)
 	if err != nil {
 		return nil, err
 	}
-	if !(p.RequesterID == uid || p.BuyerID == uid) {
-		return nil, ErrForbidden
+	return s.Repo.Comments(ctx, p.ID)
+}
+
+// Delete: requester (หรือ owner/admin ของบ้าน) และต้องยังแก้ไขได้อยู่ + ยัง planned/requested
+func (s *Service) Delete(ctx context.Context, sub authz.Subject, id string) error {
+	p, err := s.load(ctx, sub, id, authz.ActionDelete)
+	if err != nil {
+		return err
+	}
+	if s.Now().After(p.EditableUntil) || (p.Status != StatusPlanned && p.Status != StatusRequested) {
+		return &ConflictError{Err: ErrConflict, Current: p}
+	}
+	return s.Repo.Delete(ctx, sub.HouseholdID, id)
+}
+
+// Cancel: requester, buyer หรือ owner/admin ของบ้าน ยกเลิกได้ ถ้า transition อนุญาต
+func (s *Service) Cancel(ctx context.Context, sub authz.Subject, id string) (*Purchase, error) {
+	p, err := s.load(ctx, sub, id, authz.ActionCancel)
+	if err != nil {
+		return nil, err
 	}
 	if !s.CanTransition(p.Status, StatusCancelled) {
-		return nil, ErrConflict
+		return nil, &ConflictError{Err: ErrConflict, Current: p}
 	}
+	before := *p
 	p.Status = StatusCancelled
-	if err := s.Repo.Update(ctx, p); err != nil {
-		return nil, err
+	if err := s.Repo.Update(ctx, p, changeEvents(sub.UserID, &before, p)...); err != nil {
+		return nil, s.conflict(ctx, sub, id, err)
 	}
 	return p, nil
 }
 
-// AddAttachment: requester หรือ buyer เท่านั้น
-func (s *Service) AddAttachment(ctx context.Context, uid, id, fileID string) error {
-	p, err := s.Repo.Get(ctx, id)
+// AddAttachment: requester หรือ buyer เท่านั้น; ไฟล์ต้องอัปโหลดโดยสมาชิกบ้านนี้
+// (ไฟล์ที่ถูกแนบ สมาชิกบ้านอ่านได้ — ห้ามแนบไฟล์ของคนนอก)
+func (s *Service) AddAttachment(ctx context.Context, sub authz.Subject, id, fileID string) error {
+	p, err := s.load(ctx, sub, id, authz.ActionAttach)
 	if err != nil {
 		return err
 	}
-	if !(p.RequesterID == uid || p.BuyerID == uid) {
-		return ErrForbidden
+	ok, err := s.Repo.FileUsable(ctx, p.HouseholdID, fileID)
+	if err != nil {
+		return err
 	}
-	return s.Repo.LinkAttachment(ctx, id, fileID)
+	if !ok {
+		return ErrBadRequest
+	}
+	return s.Repo.LinkAttachment(ctx, id, fileID, sub.UserID)
 }
 
 // RemoveAttachment: requester หรือ buyer เท่านั้น
-func (s *Service) RemoveAttachment(ctx context.Context, uid, id, fileID string) error {
-	p, err := s.Repo.Get(ctx, id)
-	if err != nil {
+func (s *Service) RemoveAttachment(ctx context.Context, sub authz.Subject, id, fileID string) error {
+	if _, err := s.load(ctx, sub, id, authz.ActionAttach); err != nil {
 		return err
 	}
-	if !(p.RequesterID == uid || p.BuyerID == uid) {
-		return ErrForbidden
-	}
-	return s.Repo.UnlinkAttachment(ctx, id, fileID)
+	return s.Repo.UnlinkAttachment(ctx, id, fileID, sub.UserID)
 }
 
-// UpdateByRequester: requester แก้ไขได้ภายใน 10 นาทีแรก
-func (s *Service) UpdateByRequester(ctx context.Context, uid, id string, patch UpdateRequesterPayload) (*Purchase, error) {
-	p, err := s.Repo.Get(ctx, id)
+// UpdateByRequester: requester แก้ไขได้ภายใน 10 นาทีแรก; ifMatch != 0 ต้องตรงกับ version ปัจจุบัน
+func (s *Service) UpdateByRequester(ctx context.Context, sub authz.Subject, id string, ifMatch int, patch UpdateRequesterPayload) (*Purchase, error) {
+	p, err := s.load(ctx, sub, id, authz.ActionUpdate)
 	if err != nil {
 		return nil, err
 	}
-	if p.RequesterID != uid {
-		return nil, ErrForbidden
+	if ifMatch != 0 && ifMatch != p.Version {
+		return nil, &ConflictError{Err: ErrPreconditionFailed, Current: p}
 	}
 	if s.Now().After(p.EditableUntil) {
-		return nil, ErrConflict // หมด 10 นาที
+		return nil, &ConflictError{Err: ErrConflict, Current: p} // หมด 10 นาที
 	}
 
+	before := *p
+	recheck := patch.AmountEstimated != nil || patch.Category != nil
+
 	// apply allowed fields
 	if patch.Title != nil {
 		p.Title = *patch.Title
@@ -178,52 +274,259 @@ func (s *Service) UpdateByRequester(ctx context.Context, uid, id string, patch U
 		p.Store = *patch.Store
 	}
 
-	if err := s.Repo.Update(ctx, p); err != nil {
-		return nil, err
+	// ราคา/หมวดเปลี่ยน: ตรวจเกณฑ์อนุมัติและงบใหม่
+	if recheck {
+		if err := s.reapprove(ctx, p); err != nil {
+			return nil, err
+		}
+		if err := s.checkBudget(ctx, p); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.Repo.Update(ctx, p, changeEvents(sub.UserID, &before, p)...); err != nil {
+		return nil, s.conflict(ctx, sub, id, err)
 	}
 	return p, nil
 }
 
 // Claim: ใครก็ claim ได้ถ้ายังไม่มี buyer และยัง planned
-func (s *Service) Claim(ctx context.Context, uid, id string) (*Purchase, error) {
-	p, err := s.Repo.Get(ctx, id)
+// เงื่อนไขถูกตรวจซ้ำใน UPDATE เดียวกัน (Repo.Claim) สองคน claim พร้อมกันสำเร็จได้คนเดียว
+func (s *Service) Claim(ctx context.Context, sub authz.Subject, id string) (*Purchase, error) {
+	p, err := s.load(ctx, sub, id, authz.ActionClaim)
 	if err != nil {
 		return nil, err
 	}
 	if p.BuyerID != "" || p.Status != StatusPlanned {
-		return nil, ErrConflict
+		return nil, &ConflictError{Err: ErrConflict, Current: p}
 	}
-	p.BuyerID = uid
-	p.Status = StatusOrdered
-	if err := s.Repo.Update(ctx, p); err != nil {
-		return nil, err
+	p, err = s.Repo.Claim(ctx, sub.HouseholdID, id, sub.UserID)
+	if err != nil {
+		return nil, s.conflict(ctx, sub, id, err)
 	}
 	return p, nil
 }
 
 // Progress: buyer เท่านั้น และต้องเปลี่ยนตามลำดับ
-func (s *Service) Progress(ctx context.Context, uid, id string, in ProgressPayload) (*Purchase, error) {
-	p, err := s.Repo.Get(ctx, id)
+func (s *Service) Progress(ctx context.Context, sub authz.Subject, id string, in ProgressPayload) (*Purchase, error) {
+	p, err := s.load(ctx, sub, id, authz.ActionProgress)
 	if err != nil {
 		return nil, err
 	}
-	if p.BuyerID != uid {
-		return nil, ErrForbidden
-	}
 	if !s.CanTransition(p.Status, in.NextStatus) {
-		return nil, ErrConflict
+		return nil, &ConflictError{Err: ErrConflict, Current: p}
 	}
 	// ถ้าไป "bought" แล้วต้องมี amount_paid
 	if in.NextStatus == StatusBought && in.AmountPaid == nil {
 		return nil, ErrBadRequest
 	}
 
+	before := *p
 	if in.AmountPaid != nil {
 		p.AmountPaid = *in.AmountPaid
 	}
+	if in.NextStatus == StatusBought {
+		now := s.Now()
+		p.BoughtAt = &now
+	}
 	p.Status = in.NextStatus
-	if err := s.Repo.Update(ctx, p); err != nil {
+	if err := s.Repo.Update(ctx, p, changeEvents(sub.UserID, &before, p)...); err != nil {
+		return nil, s.conflict(ctx, sub, id, err)
+	}
+	return p, nil
+}
+
+/*************** อนุมัติ / งบ ***************/
+
+type DecisionPayload struct {
+	Note string `json:"note"`
+}
+
+// Approve: owner/admin อนุมัติคำขอที่รออนุมัติ → planned (claim ได้)
+func (s *Service) Approve(ctx context.Context, sub authz.Subject, id string, in DecisionPayload) (*Purchase, error) {
+	return s.decide(ctx, sub, id, StatusPlanned, in.Note)
+}
+
+// Reject: owner/admin ปฏิเสธ → rejected (จบ)
+func (s *Service) Reject(ctx context.Context, sub authz.Subject, id string, in DecisionPayload) (*Purchase, error) {
+	return s.decide(ctx, sub, id, StatusRejected, in.Note)
+}
+
+func (s *Service) decide(ctx context.Context, sub authz.Subject, id string, to Status, note string) (*Purchase, error) {
+	p, err := s.load(ctx, sub, id, authz.ActionApprove)
+	if err != nil {
 		return nil, err
 	}
+	if p.Status != StatusRequested || !s.CanTransition(p.Status, to) {
+		return nil, &ConflictError{Err: ErrConflict, Current: p}
+	}
+	before := *p
+	now := s.Now()
+	p.Status, p.ApprovedBy, p.DecidedAt, p.DecisionNote = to, sub.UserID, &now, note
+	if err := s.Repo.Update(ctx, p, changeEvents(sub.UserID, &before, p)...); err != nil {
+		return nil, s.conflict(ctx, sub, id, err)
+	}
 	return p, nil
 }
+
+// conflict แนบสถานะล่าสุดไปกับ ErrConflict (อ่านใหม่ เพราะแถวอาจเพิ่งถูกคนอื่นแก้); error อื่นคืนตามเดิม
+func (s *Service) conflict(ctx context.Context, sub authz.Subject, id string, err error) error {
+	if !errors.Is(err, ErrConflict) {
+		return err
+	}
+	cur, gerr := s.Repo.Get(ctx, sub.HouseholdID, id)
+	if gerr != nil {
+		return err
+	}
+	return &ConflictError{Err: err, Current: cur}
+}
+
+// needsApproval = บ้านตั้งเกณฑ์ไว้และราคาประเมินเกินเกณฑ์
+func (s *Service) needsApproval(ctx context.Context, p *Purchase) (bool, error) {
+	st, err := s.Repo.GetSettings(ctx, p.HouseholdID)
+	if err != nil {
+		return false, err
+	}
+	return st.ApprovalThreshold != nil && p.AmountEstimated > *st.ApprovalThreshold, nil
+}
+
+// reapprove ตรวจเกณฑ์อนุมัติใหม่หลังราคา/หมวดเปลี่ยน (อนุมัติแล้วแต่ขึ้นราคาจนเกินเกณฑ์ = ต้องขออนุมัติใหม่)
+func (s *Service) reapprove(ctx context.Context, p *Purchase) error {
+	if p.Status != StatusPlanned && p.Status != StatusRequested {
+		return nil
+	}
+	needs, err := s.needsApproval(ctx, p)
+	if err != nil {
+		return err
+	}
+	switch {
+	case needs && (p.Status == StatusPlanned || p.ApprovedBy != ""):
+		p.Status, p.ApprovedBy, p.DecidedAt = StatusRequested, "", nil
+	case !needs && p.Status == StatusRequested:
+		p.Status = StatusPlanned
+	}
+	return nil
+}
+
+// monthRange [ต้นเดือน, ต้นเดือนถัดไป) ของเวลา t
+func monthRange(t time.Time) (time.Time, time.Time) {
+	from := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	return from, from.AddDate(0, 1, 0)
+}
+
+// checkBudget เติม p.Budget ถ้าหมวดมีงบ; เกินงบแบบ block = ErrBudgetExceeded
+// เดือนที่นับ = เดือนที่ซื้อ (ยังไม่ซื้อ = เดือนที่ขอ)
+// งบแบบ block ถูกตรวจซ้ำใต้ lock ตอน Repo.Create/Update (สองคำขอพร้อมกันจะไม่ผ่านจนเกินงบทั้งคู่)
+func (s *Service) checkBudget(ctx context.Context, p *Purchase) error {
+	p.Budget, p.guard = nil, nil
+	if p.Category == "" || p.Status == StatusCancelled || p.Status == StatusRejected {
+		return nil
+	}
+	at := s.Now()
+	if p.BoughtAt != nil {
+		at = *p.BoughtAt
+	} else if !p.CreatedAt.IsZero() {
+		at = p.CreatedAt
+	}
+	from, to := monthRange(at.In(s.Now().Location()))
+	b, err := s.Repo.GetBudget(ctx, p.HouseholdID, p.Category, from, to, p.ID)
+	if err != nil || b == nil {
+		return err
+	}
+	amount := p.AmountEstimated
+	if p.AmountPaid > 0 {
+		amount = p.AmountPaid
+	}
+	spent := b.Spent + amount
+	p.Budget = &BudgetCheck{
+		Category: b.Category, Month: from.Format("2006-01"), Limit: b.MonthlyLimit,
+		Spent: spent, Mode: b.Mode, Exceeded: spent > b.MonthlyLimit,
+	}
+	if b.Mode == BudgetBlock {
+		if p.Budget.Exceeded {
+			return ErrBudgetExceeded
+		}
+		p.guard = &budgetGuard{category: b.Category, from: from, to: to}
+	}
+	return nil
+}
+
+func (s *Service) can(ctx context.Context, sub authz.Subject, a authz.Action) error {
+	if !authz.Can(ctx, sub, a, authz.Resource{Kind: authz.KindPurchase, HouseholdID: sub.HouseholdID}) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func (s *Service) Settings(ctx context.Context, sub authz.Subject) (*Settings, error) {
+	if err := s.can(ctx, sub, authz.ActionRead); err != nil {
+		return nil, err
+	}
+	return s.Repo.GetSettings(ctx, sub.HouseholdID)
+}
+
+// PutSettings: owner/admin; approval_threshold null = ปิดขั้นอนุมัติ (คำขอที่รออยู่ยังต้องตัดสิน)
+func (s *Service) PutSettings(ctx context.Context, sub authz.Subject, in Settings) (*Settings, error) {
+	if err := s.can(ctx, sub, authz.ActionManage); err != nil {
+		return nil, err
+	}
+	if in.ApprovalThreshold != nil && *in.ApprovalThreshold < 0 {
+		return nil, ErrBadRequest
+	}
+	in.HouseholdID = sub.HouseholdID
+	if err := s.Repo.PutSettings(ctx, &in); err != nil {
+		return nil, err
+	}
+	return &in, nil
+}
+
+// Budgets งบทุกหมวดพร้อมยอดผูกพันของเดือนที่มี month อยู่
+func (s *Service) Budgets(ctx context.Context, sub authz.Subject, month time.Time) ([]Budget, error) {
+	if err := s.can(ctx, sub, authz.ActionRead); err != nil {
+		return nil, err
+	}
+	from, to := monthRange(month)
+	return s.Repo.ListBudgets(ctx, sub.HouseholdID, from, to)
+}
+
+func (s *Service) PutBudget(ctx context.Context, sub authz.Subject, b Budget) (*Budget, error) {
+	if err := s.can(ctx, sub, authz.ActionManage); err != nil {
+		return nil, err
+	}
+	if b.Mode == "" {
+		b.Mode = BudgetWarn
+	}
+	if b.Category == "" || b.MonthlyLimit < 0 || (b.Mode != BudgetWarn && b.Mode != BudgetBlock) {
+		return nil, ErrBadRequest
+	}
+	if err := s.Repo.PutBudget(ctx, sub.HouseholdID, &b); err != nil {
+		return nil, err
+	}
+	from, to := monthRange(s.Now())
+	out, err := s.Repo.GetBudget(ctx, sub.HouseholdID, b.Category, from, to, "")
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (s *Service) DeleteBudget(ctx context.Context, sub authz.Subject, category string) error {
+	if err := s.can(ctx, sub, authz.ActionManage); err != nil {
+		return err
+	}
+	return s.Repo.DeleteBudget(ctx, sub.HouseholdID, category)
+}
+
+// Spending ยอดจ่ายจริงต่อเดือนต่อหมวด ตั้งแต่เดือน from ถึงเดือน to (รวมทั้งสองเดือน)
+// ขอบเดือนและการแบ่งเดือนใช้โซนเวลาของ from (เดียวกับ s.Now ที่ handler ใช้)
+func (s *Service) Spending(ctx context.Context, sub authz.Subject, from, to time.Time) ([]SpendingRow, error) {
+	if err := s.can(ctx, sub, authz.ActionRead); err != nil {
+		return nil, err
+	}
+	start, _ := monthRange(from)
+	_, end := monthRange(to)
+	if !end.After(start) {
+		return nil, ErrBadRequest
+	}
+	return s.Repo.Spending(ctx, sub.HouseholdID, start, end)
+}
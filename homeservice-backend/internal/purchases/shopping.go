@@ -0,0 +1,246 @@
+package purchases
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/iMookatayou/homeservice-backend/internal/authz"
+)
+
+// RestockSource ของที่ต้องซื้อเติมจากโมดูลอื่น; ids ว่าง = ทุกรายการที่ต่ำกว่าขั้นต่ำ
+type RestockSource interface {
+	RestockItems(ctx context.Context, householdID string, ids []string) ([]Item, error)
+}
+
+// PlanPayload รายการจากแหล่งหนึ่ง (restock, template, เพิ่มเอง)
+// Into ว่าง = สร้างคำขอใหม่, ไม่ว่าง = รวมเข้าคำขอนั้น
+type PlanPayload struct {
+	Into     string `json:"into"`
+	Title    string `json:"title"`
+	Category string `json:"category"`
+	Store    string `json:"store"`
+	Items    []Item `json:"items"`
+
+	topUp bool // รวมเข้าคำขอเดิมแบบเติมให้ถึงจำนวน ไม่ใช่บวกเพิ่ม (restock)
+}
+
+type RestockPayload struct {
+	ItemIDs []string `json:"item_ids"` // ว่าง = ยาทุกตัวที่ต่ำกว่าขั้นต่ำ
+	Into    string   `json:"into"`
+}
+
+// MergeItems รวมรายการชื่อซ้ำ (ไม่สนตัวพิมพ์/ช่องว่าง) ที่หน่วยเดียวกันเป็นบรรทัดเดียว:
+// บวก qty, ยี่ห้อ/ราคาต่อหน่วยของบรรทัดแรกคงไว้ (ว่างค่อยเติม), note ต่อกัน
+func MergeItems(dst Items, add ...Item) Items {
+	out := make(Items, 0, len(dst)+len(add))
+	idx := map[string]int{}
+	for _, it := range append(append(Items{}, dst...), add...) {
+		it.Name = strings.TrimSpace(it.Name)
+		if it.Name == "" {
+			continue
+		}
+		k := itemKey(it)
+		i, ok := idx[k]
+		if !ok {
+			idx[k] = len(out)
+			out = append(out, it)
+			continue
+		}
+		m := &out[i]
+		m.Qty += it.Qty
+		if m.Brand == "" {
+			m.Brand = it.Brand
+		}
+		if m.UnitPrice == 0 {
+			m.UnitPrice = it.UnitPrice
+		}
+		if it.Note != "" && !strings.Contains(m.Note, it.Note) {
+			if m.Note != "" {
+				m.Note += "; "
+			}
+			m.Note += it.Note
+		}
+	}
+	return out
+}
+
+// itemKey ชื่อ (ไม่สนตัวพิมพ์/ช่องว่าง) + หน่วย ใช้จับบรรทัดเดียวกัน
+func itemKey(it Item) string {
+	return strings.ToLower(strings.Join(strings.Fields(it.Name), " ")) + "|" + strings.ToLower(strings.TrimSpace(it.Unit))
+}
+
+// Shortfall ส่วนที่ยังขาดของ want เมื่อเทียบกับที่มีใน have แล้ว (บรรทัดที่มีครบแล้วตัดทิ้ง)
+// ใช้กับ restock ซ้ำเข้าคำขอเดิม: เติมให้ถึงจำนวนที่ขาด ไม่บวกซ้ำทุกครั้งที่เรียก
+func Shortfall(have Items, want ...Item) Items {
+	got := map[string]float64{}
+	for _, it := range have {
+		got[itemKey(it)] += it.Qty
+	}
+	var out Items
+	for _, it := range MergeItems(nil, want...) {
+		if it.Qty -= got[itemKey(it)]; it.Qty > 0 {
+			out = append(out, it)
+		}
+	}
+	return out
+}
+
+// lineTotal ราคาประเมินจากบรรทัดที่มีราคาต่อหน่วย
+func lineTotal(items []Item) float64 {
+	var sum float64
+	for _, it := range items {
+		sum += it.Qty * it.UnitPrice
+	}
+	return sum
+}
+
+// Plan สร้างคำขอใหม่จากรายการ หรือรวมเข้าคำขอที่ยัง planned/requested, ยังไม่มีคน claim และยังไม่หมดเวลาแก้ไข
+// สร้างใหม่ใช้สิทธิ์สร้างคำขอ, รวมเข้าคำขอเดิมต้องมีสิทธิ์แก้คำขอนั้น; ราคาประเมินเพิ่มตามราคาต่อหน่วยของบรรทัดที่เติม
+// คืน created=true เมื่อสร้างคำขอใหม่
+func (s *Service) Plan(ctx context.Context, sub authz.Subject, in PlanPayload) (p *Purchase, created bool, err error) {
+	items := MergeItems(nil, in.Items...)
+	if len(items) == 0 {
+		return nil, false, ErrBadRequest
+	}
+	if in.Into == "" {
+		if strings.TrimSpace(in.Title) == "" {
+			return nil, false, ErrBadRequest
+		}
+		p, err = s.Create(ctx, sub, CreatePayload{
+			Title: in.Title, Items: items, AmountEstimated: lineTotal(items),
+			Category: in.Category, Store: in.Store,
+		})
+		return p, err == nil, err
+	}
+
+	p, err = s.load(ctx, sub, in.Into, authz.ActionUpdate)
+	if err != nil {
+		return nil, false, err
+	}
+	if p.BuyerID != "" || s.Now().After(p.EditableUntil) || (p.Status != StatusPlanned && p.Status != StatusRequested) {
+		return nil, false, &ConflictError{Err: ErrConflict, Current: p}
+	}
+	if in.topUp {
+		if items = Shortfall(p.Items, items...); len(items) == 0 {
+			return p, false, nil // มีครบแล้ว
+		}
+	}
+	before := *p
+	p.Items = MergeItems(p.Items, items...)
+	if add := lineTotal(items); add > 0 {
+		p.AmountEstimated += add
+		if err := s.reapprove(ctx, p); err != nil {
+			return nil, false, err
+		}
+		if err := s.checkBudget(ctx, p); err != nil {
+			return nil, false, err
+		}
+	}
+	if err := s.Repo.Update(ctx, p, changeEvents(sub.UserID, &before, p)...); err != nil {
+		return nil, false, s.conflict(ctx, sub, in.Into, err)
+	}
+	return p, false, nil
+}
+
+// Restock แปลงยาที่ต่ำกว่าขั้นต่ำเป็นรายการซื้อ (คำขอใหม่หมวด medicine หรือรวมเข้า into)
+// รวมเข้า into เติมแค่ส่วนที่คำขอนั้นยังขาด เรียกซ้ำจึงไม่บวกจำนวนเพิ่ม
+func (s *Service) Restock(ctx context.Context, sub authz.Subject, in RestockPayload) (*Purchase, bool, error) {
+	if s.Stock == nil {
+		return nil, false, fmt.Errorf("%w: restock source not configured", ErrBadRequest)
+	}
+	if err := s.can(ctx, sub, authz.ActionCreate); err != nil {
+		return nil, false, err
+	}
+	items, err := s.Stock.RestockItems(ctx, sub.HouseholdID, in.ItemIDs)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(items) == 0 {
+		return nil, false, fmt.Errorf("%w: nothing below minimum stock", ErrBadRequest)
+	}
+	return s.Plan(ctx, sub, PlanPayload{Into: in.Into, Title: "เติมยา", Category: "medicine", Items: items, topUp: true})
+}
+
+/*************** templates ***************/
+
+func templateResource(t *Template) authz.Resource {
+	return authz.Resource{Kind: authz.KindTemplate, HouseholdID: t.HouseholdID, OwnerID: t.CreatedBy}
+}
+
+func (s *Service) Templates(ctx context.Context, sub authz.Subject) ([]Template, error) {
+	if !authz.Can(ctx, sub, authz.ActionRead, authz.Resource{Kind: authz.KindTemplate, HouseholdID: sub.HouseholdID}) {
+		return nil, ErrForbidden
+	}
+	return s.Repo.ListTemplates(ctx, sub.HouseholdID)
+}
+
+func validTemplate(t *Template) error {
+	t.Name, t.Title = strings.TrimSpace(t.Name), strings.TrimSpace(t.Title)
+	t.Items = MergeItems(nil, t.Items...)
+	if t.Name == "" || len(t.Items) == 0 {
+		return ErrBadRequest
+	}
+	if t.Title == "" {
+		t.Title = t.Name
+	}
+	return nil
+}
+
+// CreateTemplate สมาชิกทุกคน; ชื่อซ้ำในบ้าน = 409
+func (s *Service) CreateTemplate(ctx context.Context, sub authz.Subject, in Template) (*Template, error) {
+	if !authz.Can(ctx, sub, authz.ActionCreate, authz.Resource{Kind: authz.KindTemplate, HouseholdID: sub.HouseholdID}) {
+		return nil, ErrForbidden
+	}
+	if err := validTemplate(&in); err != nil {
+		return nil, err
+	}
+	in.HouseholdID, in.CreatedBy = sub.HouseholdID, sub.UserID
+	if err := s.Repo.CreateTemplate(ctx, &in); err != nil {
+		return nil, err
+	}
+	return &in, nil
+}
+
+// UpdateTemplate ผู้สร้างหรือ owner/admin
+func (s *Service) UpdateTemplate(ctx context.Context, sub authz.Subject, id string, in Template) (*Template, error) {
+	t, err := s.Repo.GetTemplate(ctx, sub.HouseholdID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !authz.Can(ctx, sub, authz.ActionUpdate, templateResource(t)) {
+		return nil, ErrForbidden
+	}
+	if err := validTemplate(&in); err != nil {
+		return nil, err
+	}
+	t.Name, t.Title, t.Category, t.Store, t.Items = in.Name, in.Title, in.Category, in.Store, in.Items
+	if err := s.Repo.UpdateTemplate(ctx, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// DeleteTemplate ผู้สร้างหรือ owner/admin
+func (s *Service) DeleteTemplate(ctx context.Context, sub authz.Subject, id string) error {
+	t, err := s.Repo.GetTemplate(ctx, sub.HouseholdID, id)
+	if err != nil {
+		return err
+	}
+	if !authz.Can(ctx, sub, authz.ActionDelete, templateResource(t)) {
+		return ErrForbidden
+	}
+	return s.Repo.DeleteTemplate(ctx, sub.HouseholdID, id)
+}
+
+// UseTemplate สร้างคำขอจาก template หรือรวมรายการเข้าคำขอ into
+func (s *Service) UseTemplate(ctx context.Context, sub authz.Subject, id, into string) (*Purchase, bool, error) {
+	t, err := s.Repo.GetTemplate(ctx, sub.HouseholdID, id)
+	if err != nil {
+		return nil, false, err
+	}
+	if !authz.Can(ctx, sub, authz.ActionRead, templateResource(t)) {
+		return nil, false, ErrForbidden
+	}
+	return s.Plan(ctx, sub, PlanPayload{Into: into, Title: t.Title, Category: t.Category, Store: t.Store, Items: t.Items})
+}
@@ -0,0 +1,171 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule = เวลารันถัดไปหลังจาก t
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+// every = "@every 5m" (ช่วงคงที่ นับจากรอบก่อน)
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time { return t.Add(time.Duration(e)) }
+
+// cronSpec = cron 5 ช่อง: นาที ชั่วโมง วันที่ เดือน วันในสัปดาห์ (0=อาทิตย์; 7 ก็เป็นอาทิตย์)
+type cronSpec struct {
+	min, hour, dom, month, dow uint64 // bitset
+	domStar, dowStar           bool
+	loc                        *time.Location
+}
+
+var descriptors = map[string]string{
+	"@yearly":  "0 0 1 1 *",
+	"@monthly": "0 0 1 * *",
+	"@weekly":  "0 0 * * 0",
+	"@daily":   "0 0 * * *",
+	"@hourly":  "0 * * * *",
+}
+
+// Parse รองรับ cron 5 ช่อง (*, a-b, a,b, */n, a-b/n), @daily/@hourly/... และ "@every <duration>"
+func Parse(spec string, loc *time.Location) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if loc == nil {
+		loc = time.Local
+	}
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil || d < time.Second {
+			return nil, fmt.Errorf("scheduler: bad @every %q", rest)
+		}
+		return every(d), nil
+	}
+	if d, ok := descriptors[spec]; ok {
+		spec = d
+	}
+	f := strings.Fields(spec)
+	if len(f) != 5 {
+		return nil, fmt.Errorf("scheduler: cron %q must have 5 fields", spec)
+	}
+	c := &cronSpec{loc: loc, domStar: f[2] == "*", dowStar: f[4] == "*"}
+	var err error
+	for _, p := range []struct {
+		dst      *uint64
+		s        string
+		min, max int
+	}{
+		{&c.min, f[0], 0, 59},
+		{&c.hour, f[1], 0, 23},
+		{&c.dom, f[2], 1, 31},
+		{&c.month, f[3], 1, 12},
+		{&c.dow, f[4], 0, 7},
+	} {
+		if *p.dst, err = parseField(p.s, p.min, p.max); err != nil {
+			return nil, fmt.Errorf("scheduler: cron %q: %w", spec, err)
+		}
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	return c, nil
+}
+
+func parseField(s string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(s, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("bad step %q", part)
+			}
+			step = n
+		}
+		lo, hi := min, max
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = strconv.Atoi(a); err != nil {
+				return 0, fmt.Errorf("bad value %q", part)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(b); err != nil {
+					return 0, fmt.Errorf("bad range %q", part)
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func has(bits uint64, v int) bool { return bits&(1<<uint(v)) != 0 }
+
+// dayMatches ตามกติกา cron: ถ้ากำหนดทั้ง dom และ dow ให้ตรงอย่างใดอย่างหนึ่งก็พอ
+func (c *cronSpec) dayMatches(t time.Time) bool {
+	dom, dow := has(c.dom, t.Day()), has(c.dow, int(t.Weekday()))
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next ไล่ทีละเดือน/วัน/ชั่วโมง/นาที; ค้นไม่เกิน 5 ปี (เช่น 30 ก.พ. จะไม่มีวันเจอ)
+func (c *cronSpec) Next(t time.Time) time.Time {
+	t = t.In(c.loc).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if !has(c.month, int(t.Month())) {
+			t = advance(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, c.loc))
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = advance(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.loc))
+			continue
+		}
+		if !has(c.hour, t.Hour()) {
+			t = advance(t, t.Add(time.Duration(60-t.Minute())*time.Minute))
+			continue
+		}
+		if !has(c.min, t.Minute()) {
+			t = advance(t, t.Add(time.Minute))
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// advance เดินจาก t ไป next โดยเวลาบนนาฬิกาต้องเดินหน้าเสมอ:
+// เข้า DST — time.Date ของเวลาที่ไม่มีจริง (เช่น 02:00) ปัดถอยหลังได้ ถ้าไม่กันจะวนไม่จบ จึงเดินไปหนึ่งชั่วโมงแทน
+// ออกจาก DST — ชั่วโมงที่ซ้ำข้ามไป งานเวลาเดียวกันจึงไม่รันสองรอบ
+func advance(t, next time.Time) time.Time {
+	if !next.After(t) {
+		next = t.Add(time.Hour)
+	}
+	w := wall(t)
+	for !wall(next).After(w) {
+		next = next.Add(w.Sub(wall(next)) + time.Minute)
+	}
+	return next
+}
+
+// wall = เวลาบนนาฬิกาของ t (ไม่สน offset) ใช้เทียบว่านาฬิกาเดินหน้าหรือย้อน
+func wall(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, t.Hour(), t.Minute(), 0, 0, time.UTC)
+}
@@ -0,0 +1,138 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata" // ไม่พึ่ง zoneinfo ของเครื่องที่รันเทสต์
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("LoadLocation(%q) error = %v", name, err)
+	}
+	return loc
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 0 *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1-x * * * *",
+		"1,,2 * * * *",
+		"@every",
+		"@every 5",
+		"@every 500ms",
+		"@sometimes",
+	} {
+		if _, err := Parse(spec, time.UTC); err == nil {
+			t.Errorf("Parse(%q) error = nil, want error", spec)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	bkk := mustLoad(t, "Asia/Bangkok")
+	at := func(y int, m time.Month, d, h, min int) time.Time { return time.Date(y, m, d, h, min, 0, 0, bkk) }
+
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"step minutes", "*/15 * * * *", at(2026, 1, 5, 10, 7), at(2026, 1, 5, 10, 15)},
+		{"step rolls to next hour", "*/15 * * * *", at(2026, 1, 5, 10, 45), at(2026, 1, 5, 11, 0)},
+		{"strictly after from", "*/15 * * * *", at(2026, 1, 5, 10, 15), at(2026, 1, 5, 10, 30)},
+		{"seconds truncated", "*/15 * * * *", at(2026, 1, 5, 10, 14).Add(59 * time.Second), at(2026, 1, 5, 10, 15)},
+		{"range with step", "5-20/5 * * * *", at(2026, 1, 5, 10, 21), at(2026, 1, 5, 11, 5)},
+		{"value with step runs to max", "50/5 * * * *", at(2026, 1, 5, 10, 51), at(2026, 1, 5, 10, 55)},
+		{"step hours", "0 */6 * * *", at(2026, 1, 5, 13, 0), at(2026, 1, 5, 18, 0)},
+		{"list", "0 8,20 * * *", at(2026, 1, 5, 9, 0), at(2026, 1, 5, 20, 0)},
+		{"weekdays skip weekend", "0 9 * * 1-5", at(2026, 1, 9, 10, 0), at(2026, 1, 12, 9, 0)},
+		{"dow 7 is sunday", "0 8 * * 7", at(2026, 1, 5, 0, 0), at(2026, 1, 11, 8, 0)},
+		{"dom or dow when both set", "0 8 13 * 5", at(2026, 2, 1, 0, 0), at(2026, 2, 6, 8, 0)},
+		{"day 31 skips short months", "0 0 31 * *", at(2026, 1, 31, 0, 0), at(2026, 3, 31, 0, 0)},
+		{"month end rolls year", "@monthly", at(2026, 12, 15, 0, 0), at(2027, 1, 1, 0, 0)},
+		{"leap day", "0 0 29 2 *", at(2026, 3, 1, 0, 0), at(2028, 2, 29, 0, 0)},
+		{"never matches", "0 0 30 2 *", at(2026, 1, 1, 0, 0), time.Time{}},
+		{"converts from other zone", "0 9 * * *", time.Date(2026, 1, 5, 1, 59, 0, 0, time.UTC), at(2026, 1, 5, 9, 0)},
+		{"every is relative", "@every 90m", at(2026, 1, 5, 10, 7), at(2026, 1, 5, 11, 37)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.spec, bkk)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.spec, err)
+			}
+			if got := s.Next(tt.from); !got.Equal(tt.want) {
+				t.Fatalf("Next(%v) = %v, want %v", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNextDST เขตเวลาที่มี DST (SCHEDULER_TZ ตั้งเป็นอะไรก็ได้): 2026-03-08 02:00 ไม่มีจริง, 2026-11-01 01:00-02:00 ซ้ำสองรอบ
+func TestNextDST(t *testing.T) {
+	ny := mustLoad(t, "America/New_York")
+	utc := func(m time.Month, d, h, min int) time.Time { return time.Date(2026, m, d, h, min, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want []time.Time // รอบถัด ๆ ไปต่อกัน
+	}{
+		{
+			"daily across spring forward", "0 9 * * *", utc(3, 7, 17, 0), // 12:00 EST
+			[]time.Time{utc(3, 8, 13, 0), utc(3, 9, 13, 0)}, // 09:00 EDT
+		},
+		{
+			"missing local time is skipped", "30 2 * * *", utc(3, 7, 17, 0),
+			[]time.Time{utc(3, 9, 6, 30)}, // 02:30 EDT วันถัดไป
+		},
+		{
+			"hourly across spring forward", "0 * * * *", utc(3, 8, 6, 30), // 01:30 EST
+			[]time.Time{utc(3, 8, 7, 0), utc(3, 8, 8, 0)}, // 03:00, 04:00 EDT
+		},
+		{
+			"repeated hour runs once", "30 1 * * *", utc(10, 31, 16, 0), // 12:00 EDT
+			[]time.Time{utc(11, 1, 5, 30), utc(11, 2, 6, 30)}, // 01:30 EDT แล้ว 01:30 EST ของวันถัดไป
+		},
+		{
+			"hourly skips repeated hour", "0 * * * *", utc(11, 1, 4, 30), // 00:30 EDT
+			[]time.Time{utc(11, 1, 5, 0), utc(11, 1, 7, 0)}, // 01:00 EDT แล้ว 02:00 EST
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.spec, ny)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.spec, err)
+			}
+			from := tt.from
+			for i, want := range tt.want {
+				got := s.Next(from)
+				if !got.Equal(want) {
+					t.Fatalf("run %d: Next(%v) = %v, want %v", i+1, from.In(ny), got, want.In(ny))
+				}
+				if got.Location() != ny {
+					t.Errorf("run %d: location = %v, want %v", i+1, got.Location(), ny)
+				}
+				from = got
+			}
+		})
+	}
+}
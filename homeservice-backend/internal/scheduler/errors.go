@@ -0,0 +1,10 @@
+package scheduler
+
+import "errors"
+
+var (
+	ErrUnknownJob = errors.New("unknown job")
+
+	// ชื่องานซ้ำตอน Register
+	ErrDuplicateJob = errors.New("duplicate job")
+)
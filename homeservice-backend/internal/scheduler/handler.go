@@ -0,0 +1,38 @@
+package scheduler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/iMookatayou/homeservice-backend/internal/httpx"
+)
+
+type Handler struct{ S *Scheduler }
+
+// RegisterAdminRoutes — ใช้ใต้กลุ่ม admin
+func (h Handler) RegisterAdminRoutes(r chi.Router) {
+	r.Get("/admin/jobs", h.List)
+	r.Post("/admin/jobs/{name}/run", h.Trigger) // สั่งรันทันที (replica ที่ได้ lock เป็นคนรัน)
+}
+
+func (h Handler) List(w http.ResponseWriter, r *http.Request) {
+	out, err := h.S.List(r.Context())
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "internal error", nil)
+		return
+	}
+	httpx.JSON(w, http.StatusOK, out)
+}
+
+func (h Handler) Trigger(w http.ResponseWriter, r *http.Request) {
+	err := h.S.Trigger(r.Context(), chi.URLParam(r, "name"))
+	switch {
+	case errors.Is(err, ErrUnknownJob):
+		httpx.WriteJSONError(w, http.StatusNotFound, err.Error(), nil)
+	case err != nil:
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "internal error", nil)
+	default:
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
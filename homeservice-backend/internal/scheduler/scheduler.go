@@ -0,0 +1,293 @@
+// Package scheduler รันงานเบื้องหลังตาม cron ต่องาน ร่วมกันหลาย replica:
+// สถานะ (next/last run, error) อยู่ในตาราง scheduler_jobs และแต่ละรอบมีแค่ replica เดียวที่ได้ advisory lock ของงานนั้น
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// lockClass: key ชุดแรกของ pg_try_advisory_lock(int, int) แยกจาก lock ของ migration
+const lockClass int32 = 0x68736a62 // "hsjb"
+
+// Job งานหนึ่งงาน; Timeout = 0 คือไม่จำกัด (ยังหยุดตาม ctx ของ scheduler)
+type Job struct {
+	Name     string
+	Spec     string
+	Timeout  time.Duration
+	Run      func(ctx context.Context) error
+	schedule Schedule
+}
+
+// State = แถวใน scheduler_jobs (ใช้ตอบ admin endpoint)
+type State struct {
+	Name           string     `json:"name"`
+	Spec           string     `json:"spec"`
+	NextRunAt      time.Time  `json:"next_run_at"`
+	LastStartedAt  *time.Time `json:"last_started_at,omitempty"`
+	LastFinishedAt *time.Time `json:"last_finished_at,omitempty"`
+	LastSuccessAt  *time.Time `json:"last_success_at,omitempty"`
+	LastDurationMs *int64     `json:"last_duration_ms,omitempty"`
+	LastError      *string    `json:"last_error,omitempty"`
+	LastRunner     *string    `json:"last_runner,omitempty"`
+	Running        bool       `json:"running"`
+	RunCount       int64      `json:"run_count"`
+	FailCount      int64      `json:"fail_count"`
+}
+
+type Scheduler struct {
+	DB       *pgxpool.Pool
+	Location *time.Location // เวลาของ cron (ค่าเริ่มต้น time.Local)
+	Poll     time.Duration  // ถี่แค่ไหนที่ถามว่ามีงานถึงเวลาไหม (ค่าเริ่มต้น 1s)
+	Logf     func(format string, args ...any)
+
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	order   []string
+	running map[string]bool
+	wg      sync.WaitGroup
+	wake    chan struct{}
+	runner  string
+}
+
+func New(db *pgxpool.Pool) *Scheduler {
+	host, _ := os.Hostname()
+	return &Scheduler{
+		DB:      db,
+		Poll:    time.Second,
+		jobs:    map[string]*Job{},
+		running: map[string]bool{},
+		wake:    make(chan struct{}, 1),
+		runner:  fmt.Sprintf("%s:%d", host, os.Getpid()),
+	}
+}
+
+func (s *Scheduler) logf(format string, args ...any) {
+	if s.Logf != nil {
+		s.Logf(format, args...)
+	}
+}
+
+// Register เพิ่มงาน (เรียกก่อน Run)
+func (s *Scheduler) Register(name, spec string, timeout time.Duration, fn func(ctx context.Context) error) error {
+	sch, err := Parse(spec, s.Location)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[name]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateJob, name)
+	}
+	s.jobs[name] = &Job{Name: name, Spec: spec, Timeout: timeout, Run: fn, schedule: sch}
+	s.order = append(s.order, name)
+	return nil
+}
+
+// sync ลงทะเบียนงานใน DB; spec เปลี่ยน = คำนวณ next_run_at ใหม่, ไม่เปลี่ยน = คงกำหนดเดิม
+func (s *Scheduler) sync(ctx context.Context) error {
+	now := time.Now()
+	for _, name := range s.order {
+		j := s.jobs[name]
+		if _, err := s.DB.Exec(ctx, `
+			INSERT INTO scheduler_jobs (name, spec, next_run_at)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (name) DO UPDATE
+			SET spec = EXCLUDED.spec,
+			    next_run_at = CASE WHEN scheduler_jobs.spec <> EXCLUDED.spec
+			                       THEN EXCLUDED.next_run_at ELSE scheduler_jobs.next_run_at END
+		`, j.Name, j.Spec, j.schedule.Next(now)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run วนถามงานที่ถึงเวลาจนกว่า ctx จะถูกยกเลิก แล้วรอให้งานที่กำลังรันจบก่อนคืนค่า
+func (s *Scheduler) Run(ctx context.Context) error {
+	if err := s.sync(ctx); err != nil {
+		return err
+	}
+	t := time.NewTicker(s.Poll)
+	defer t.Stop()
+	defer s.wg.Wait()
+
+	for {
+		s.dispatchDue(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		case <-s.wake:
+		}
+	}
+}
+
+func (s *Scheduler) dispatchDue(ctx context.Context) {
+	rows, err := s.DB.Query(ctx, `
+		SELECT name FROM scheduler_jobs WHERE next_run_at <= now() AND name = ANY($1)
+	`, s.order)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logf("scheduler: poll: %v", err)
+		}
+		return
+	}
+	var due []string
+	for rows.Next() {
+		var name string
+		if rows.Scan(&name) == nil {
+			due = append(due, name)
+		}
+	}
+	rows.Close()
+
+	for _, name := range due {
+		s.mu.Lock()
+		j, busy := s.jobs[name], s.running[name]
+		if !busy {
+			s.running[name] = true
+		}
+		s.mu.Unlock()
+		if busy || j == nil {
+			continue
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer func() {
+				s.mu.Lock()
+				delete(s.running, j.Name)
+				s.mu.Unlock()
+			}()
+			s.runLocked(ctx, j)
+		}()
+	}
+}
+
+// runLocked ถือ advisory lock ของงานบน connection เดียวตลอดรอบ; replica ที่ไม่ได้ lock ข้ามไป
+// หลังได้ lock ตรวจ next_run_at ซ้ำ เผื่อ replica อื่นเพิ่งรันเสร็จไประหว่างที่เรา poll
+func (s *Scheduler) runLocked(ctx context.Context, j *Job) {
+	conn, err := s.DB.Acquire(ctx)
+	if err != nil {
+		return
+	}
+	defer conn.Release()
+
+	var got bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1, hashtext($2))`, lockClass, j.Name).Scan(&got); err != nil || !got {
+		return
+	}
+	defer func() {
+		_, _ = conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1, hashtext($2))`, lockClass, j.Name)
+	}()
+
+	var due bool
+	if err := conn.QueryRow(ctx, `
+		UPDATE scheduler_jobs SET running = true, last_started_at = now(), last_runner = $2
+		WHERE name = $1 AND next_run_at <= now()
+		RETURNING true
+	`, j.Name, s.runner).Scan(&due); err != nil || !due {
+		return
+	}
+
+	start := time.Now()
+	runErr := s.invoke(ctx, j)
+	finished := time.Now()
+
+	var msg *string
+	if runErr != nil {
+		m := runErr.Error()
+		msg = &m
+		s.logf("scheduler: job %s failed after %s: %v", j.Name, finished.Sub(start), runErr)
+	}
+	// ใช้ context ใหม่: ถึง ctx ถูกยกเลิกตอนปิดระบบก็ยังบันทึกผลรอบนี้ได้
+	wctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := conn.Exec(wctx, `
+		UPDATE scheduler_jobs
+		SET running = false, last_finished_at = $2, last_duration_ms = $3, last_error = $4,
+		    next_run_at = $5, run_count = run_count + 1,
+		    last_success_at = CASE WHEN $4::text IS NULL THEN $2 ELSE last_success_at END,
+		    fail_count = fail_count + CASE WHEN $4::text IS NULL THEN 0 ELSE 1 END
+		WHERE name = $1
+	`, j.Name, finished, finished.Sub(start).Milliseconds(), msg, j.schedule.Next(finished)); err != nil {
+		s.logf("scheduler: record job %s: %v", j.Name, err)
+	}
+}
+
+// invoke เรียกงานพร้อม timeout และกัน panic ไม่ให้ล้ม scheduler ทั้งตัว
+func (s *Scheduler) invoke(ctx context.Context, j *Job) (err error) {
+	if j.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, j.Timeout)
+		defer cancel()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return j.Run(ctx)
+}
+
+// Trigger ตั้ง next_run_at = now ให้ replica ใดก็ได้ที่ว่างรับไปรันในรอบ poll ถัดไป
+func (s *Scheduler) Trigger(ctx context.Context, name string) error {
+	s.mu.Lock()
+	_, ok := s.jobs[name]
+	s.mu.Unlock()
+	if !ok {
+		return ErrUnknownJob
+	}
+	if _, err := s.DB.Exec(ctx, `UPDATE scheduler_jobs SET next_run_at = now() WHERE name = $1`, name); err != nil {
+		return err
+	}
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// LastSuccess เวลาที่งานรันสำเร็จล่าสุด (จาก replica ใดก็ได้); nil = ยังไม่เคยสำเร็จ
+func (s *Scheduler) LastSuccess(ctx context.Context, name string) (*time.Time, error) {
+	var t *time.Time
+	err := s.DB.QueryRow(ctx, `SELECT last_success_at FROM scheduler_jobs WHERE name = $1`, name).Scan(&t)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUnknownJob
+	}
+	return t, err
+}
+
+// List สถานะงานทั้งหมดที่ลงทะเบียนไว้ใน replica นี้
+func (s *Scheduler) List(ctx context.Context) ([]State, error) {
+	rows, err := s.DB.Query(ctx, `
+		SELECT name, spec, next_run_at, last_started_at, last_finished_at, last_success_at, last_duration_ms,
+		       last_error, last_runner, running, run_count, fail_count
+		FROM scheduler_jobs
+		WHERE name = ANY($1)
+		ORDER BY name
+	`, s.order)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []State{}
+	for rows.Next() {
+		var st State
+		if err := rows.Scan(&st.Name, &st.Spec, &st.NextRunAt, &st.LastStartedAt, &st.LastFinishedAt, &st.LastSuccessAt,
+			&st.LastDurationMs, &st.LastError, &st.LastRunner, &st.Running, &st.RunCount, &st.FailCount); err != nil {
+			return nil, err
+		}
+		out = append(out, st)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,13 @@
+package session
+
+import "errors"
+
+var (
+	ErrNotFound = errors.New("session not found")
+
+	// refresh token ไม่มีอยู่จริง / หมดอายุ / session ถูกยกเลิกแล้ว
+	ErrInvalidToken = errors.New("invalid or expired refresh token")
+
+	// refresh token ที่ใช้ไปแล้วถูกส่งมาซ้ำ -> ถือว่าหลุด ยกเลิกทั้ง family
+	ErrTokenReused = errors.New("refresh token reused; session revoked")
+)
@@ -0,0 +1,109 @@
+package session
+
+import (
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/iMookatayou/homeservice-backend/internal/auth"
+	"github.com/iMookatayou/homeservice-backend/internal/httpx"
+)
+
+type Handler struct{ Svc Service }
+
+// RegisterPublic — /auth/refresh, /auth/logout (ไม่ต้องมี access token; ใช้ refresh token แทน)
+func (h Handler) RegisterPublic(r chi.Router) {
+	r.Post("/auth/refresh", h.Refresh)
+	r.Post("/auth/logout", h.Logout)
+}
+
+// RegisterRoutes — ใช้ใต้ RequireAuth: จัดการอุปกรณ์ที่ล็อกอินอยู่
+func (h Handler) RegisterRoutes(r chi.Router) {
+	r.Route("/me/sessions", func(r chi.Router) {
+		r.Get("/", h.List)
+		r.Delete("/", h.RevokeOthers) // ออกจากระบบทุกเครื่องยกเว้นเครื่องนี้
+		r.Delete("/{id}", h.Revoke)
+	})
+}
+
+// ClientFrom อ่านข้อมูลอุปกรณ์จาก request (device มาจาก body ตอน login ถ้ามี)
+func ClientFrom(r *http.Request, device string) Client {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	return Client{Device: device, UserAgent: r.UserAgent(), IP: ip}
+}
+
+func writeErr(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidToken), errors.Is(err, ErrTokenReused):
+		httpx.WriteJSONError(w, http.StatusUnauthorized, err.Error(), nil)
+	case errors.Is(err, ErrNotFound):
+		httpx.WriteJSONError(w, http.StatusNotFound, err.Error(), nil)
+	default:
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "internal error", nil)
+	}
+}
+
+func (h Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req RefreshReq
+	if err := httpx.BindJSON(r, &req); err != nil {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "validation failed", httpx.ValidationErrors(err))
+		return
+	}
+	tok, err := h.Svc.Refresh(r.Context(), req.RefreshToken, ClientFrom(r, ""))
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	httpx.JSON(w, http.StatusOK, map[string]any{"tokens": tok})
+}
+
+func (h Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req RefreshReq
+	if err := httpx.BindJSON(r, &req); err != nil {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "validation failed", httpx.ValidationErrors(err))
+		return
+	}
+	// logout ซ้ำ/ด้วย token ที่หมดอายุแล้ว ถือว่าสำเร็จ (idempotent)
+	if err := h.Svc.Logout(r.Context(), req.RefreshToken); err != nil && !errors.Is(err, ErrInvalidToken) {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h Handler) List(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFrom(r)
+	out, err := h.Svc.Repo.ListActive(r.Context(), uid)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	cur := auth.SessionIDFrom(r)
+	for i := range out {
+		out[i].Current = out[i].ID == cur
+	}
+	httpx.JSON(w, http.StatusOK, out)
+}
+
+func (h Handler) Revoke(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFrom(r)
+	if err := h.Svc.Repo.Revoke(r.Context(), uid, chi.URLParam(r, "id"), "revoked_by_user"); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h Handler) RevokeOthers(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFrom(r)
+	n, err := h.Svc.Repo.RevokeOthers(r.Context(), uid, auth.SessionIDFrom(r))
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	httpx.JSON(w, http.StatusOK, map[string]any{"revoked": n})
+}
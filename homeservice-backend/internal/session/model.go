@@ -0,0 +1,46 @@
+package session
+
+import "time"
+
+// Session = อุปกรณ์ที่ล็อกอินอยู่ (refresh token ทุกตัวที่หมุนต่อกันมาอยู่ใน session เดียว)
+type Session struct {
+	ID           string     `json:"id"`
+	UserID       string     `json:"user_id"`
+	Device       string     `json:"device"`
+	UserAgent    *string    `json:"user_agent,omitempty"`
+	IP           *string    `json:"ip,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	LastUsedAt   time.Time  `json:"last_used_at"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	RevokeReason *string    `json:"revoke_reason,omitempty"`
+	Current      bool       `json:"current"` // session ของ access token ที่เรียกอยู่
+}
+
+// Subject = ข้อมูลผู้ใช้ที่ต้องใช้เซ็น access token
+type Subject struct {
+	UserID string
+	Email  string
+	Role   string
+}
+
+// Tokens = response ตอน login/register/refresh
+type Tokens struct {
+	AccessToken      string `json:"access_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	RefreshToken     string `json:"refresh_token"`
+	RefreshExpiresIn int64  `json:"refresh_expires_in"`
+	SessionID        string `json:"session_id"`
+}
+
+// Client = ข้อมูลอุปกรณ์จาก request
+type Client struct {
+	Device    string
+	UserAgent string
+	IP        string
+}
+
+// payloads
+type RefreshReq struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
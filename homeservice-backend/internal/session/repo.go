@@ -0,0 +1,218 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repo struct {
+	DB *pgxpool.Pool
+}
+
+func nullable(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// Create สร้าง session ใหม่ + refresh token ตัวแรกของ family
+func (r Repo) Create(ctx context.Context, userID string, c Client, tokenHash string, expiresAt time.Time) (string, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var sid string
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO auth_sessions (user_id, device, user_agent, ip, expires_at)
+		VALUES ($1,$2,$3,$4,$5)
+		RETURNING id
+	`, userID, c.Device, nullable(c.UserAgent), nullable(c.IP), expiresAt).Scan(&sid); err != nil {
+		return "", err
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO refresh_tokens (session_id, token_hash, expires_at)
+		VALUES ($1,$2,$3)
+	`, sid, tokenHash, expiresAt); err != nil {
+		return "", err
+	}
+	return sid, tx.Commit(ctx)
+}
+
+// Rotate ใช้ refresh token (hash) หนึ่งครั้งแล้วออกตัวใหม่ใน family เดิม
+// ถ้า token เคยถูกใช้ไปแล้ว = reuse -> ยกเลิกทั้ง session และคืน ErrTokenReused
+func (r Repo) Rotate(ctx context.Context, oldHash, newHash string, expiresAt time.Time, c Client) (Subject, string, error) {
+	var sub Subject
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return sub, "", err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var (
+		tokenID, sid      string
+		usedAt, revokedAt *time.Time
+		tokenExp, sessExp time.Time
+	)
+	err = tx.QueryRow(ctx, `
+		SELECT rt.id, rt.session_id, rt.used_at, rt.expires_at,
+		       s.revoked_at, s.expires_at,
+		       u.id, u.email, u.role
+		FROM refresh_tokens rt
+		JOIN auth_sessions s ON s.id = rt.session_id
+		JOIN users u ON u.id = s.user_id
+		WHERE rt.token_hash = $1
+		FOR UPDATE OF rt, s
+	`, oldHash).Scan(&tokenID, &sid, &usedAt, &tokenExp, &revokedAt, &sessExp,
+		&sub.UserID, &sub.Email, &sub.Role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sub, "", ErrInvalidToken
+		}
+		return sub, "", err
+	}
+
+	now := time.Now()
+	if usedAt != nil {
+		if revokedAt == nil {
+			if _, err := tx.Exec(ctx, `
+				UPDATE auth_sessions SET revoked_at=now(), revoke_reason='refresh_token_reuse'
+				WHERE id=$1
+			`, sid); err != nil {
+				return sub, "", err
+			}
+			if err := tx.Commit(ctx); err != nil {
+				return sub, "", err
+			}
+		}
+		return sub, sid, ErrTokenReused
+	}
+	if revokedAt != nil || !tokenExp.After(now) || !sessExp.After(now) {
+		return sub, "", ErrInvalidToken
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE refresh_tokens SET used_at=now() WHERE id=$1`, tokenID); err != nil {
+		return sub, "", err
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO refresh_tokens (session_id, token_hash, parent_id, expires_at)
+		VALUES ($1,$2,$3,$4)
+	`, sid, newHash, tokenID, expiresAt); err != nil {
+		return sub, "", err
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE auth_sessions
+		SET last_used_at=now(), expires_at=$2,
+		    user_agent=COALESCE($3, user_agent), ip=COALESCE($4, ip)
+		WHERE id=$1
+	`, sid, expiresAt, nullable(c.UserAgent), nullable(c.IP)); err != nil {
+		return sub, "", err
+	}
+	return sub, sid, tx.Commit(ctx)
+}
+
+// RevokeByToken ยกเลิก session ที่ refresh token นี้สังกัด (logout)
+func (r Repo) RevokeByToken(ctx context.Context, tokenHash string) error {
+	ct, err := r.DB.Exec(ctx, `
+		UPDATE auth_sessions s SET revoked_at=now(), revoke_reason='logout'
+		FROM refresh_tokens rt
+		WHERE rt.token_hash=$1 AND rt.session_id=s.id AND s.revoked_at IS NULL
+	`, tokenHash)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// Revoke ยกเลิก session ของผู้ใช้เอง (เช่น มือถือหาย); id ที่ไม่ใช่ UUID = ไม่พบ (ไม่ส่งให้ Postgres cast แล้ว error)
+func (r Repo) Revoke(ctx context.Context, userID, sessionID, reason string) error {
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return ErrNotFound
+	}
+	ct, err := r.DB.Exec(ctx, `
+		UPDATE auth_sessions SET revoked_at=now(), revoke_reason=$3
+		WHERE id=$1 AND user_id=$2 AND revoked_at IS NULL
+	`, sessionID, userID, reason)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RevokeOthers ยกเลิกทุก session ของผู้ใช้ยกเว้น keepID
+func (r Repo) RevokeOthers(ctx context.Context, userID, keepID string) (int64, error) {
+	ct, err := r.DB.Exec(ctx, `
+		UPDATE auth_sessions SET revoked_at=now(), revoke_reason='revoked_by_user'
+		WHERE user_id=$1 AND id::text <> $2 AND revoked_at IS NULL
+	`, userID, keepID)
+	if err != nil {
+		return 0, err
+	}
+	return ct.RowsAffected(), nil
+}
+
+// RevokeAll ยกเลิกทุก session ของผู้ใช้ (เช่น admin เปลี่ยน role: access token เดิมใช้ต่อไม่ได้ ต้อง login ใหม่รับ role ใหม่)
+func (r Repo) RevokeAll(ctx context.Context, userID, reason string) (int64, error) {
+	ct, err := r.DB.Exec(ctx, `
+		UPDATE auth_sessions SET revoked_at=now(), revoke_reason=$2
+		WHERE user_id=$1 AND revoked_at IS NULL
+	`, userID, reason)
+	if err != nil {
+		return 0, err
+	}
+	return ct.RowsAffected(), nil
+}
+
+// ListActive session ที่ยังใช้งานได้ของผู้ใช้ (ล่าสุดก่อน)
+func (r Repo) ListActive(ctx context.Context, userID string) ([]Session, error) {
+	rows, err := r.DB.Query(ctx, `
+		SELECT id, user_id, device, user_agent, ip, created_at, last_used_at, expires_at, revoked_at, revoke_reason
+		FROM auth_sessions
+		WHERE user_id=$1 AND revoked_at IS NULL AND expires_at > now()
+		ORDER BY last_used_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Session{}
+	for rows.Next() {
+		var s Session
+		if err := rows.Scan(&s.ID, &s.UserID, &s.Device, &s.UserAgent, &s.IP, &s.CreatedAt,
+			&s.LastUsedAt, &s.ExpiresAt, &s.RevokedAt, &s.RevokeReason); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// SessionActive implements auth.SessionStore
+func (r Repo) SessionActive(ctx context.Context, sessionID string) (bool, error) {
+	var ok bool
+	err := r.DB.QueryRow(ctx, `
+		SELECT revoked_at IS NULL AND expires_at > now()
+		FROM auth_sessions WHERE id=$1
+	`, sessionID).Scan(&ok)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return ok, nil
+}
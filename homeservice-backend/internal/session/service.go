@@ -0,0 +1,88 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/iMookatayou/homeservice-backend/internal/auth"
+)
+
+// Service ออก access token (สั้น) + refresh token (ยาว, หมุนทุกครั้งที่ใช้)
+type Service struct {
+	Repo       Repo
+	JWTSecret  string
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+func newRefreshToken() (raw, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	raw = base64.RawURLEncoding.EncodeToString(b)
+	return raw, hashToken(raw), nil
+}
+
+// hashToken — เก็บแค่ sha256 ใน DB (token สุ่ม 256 บิต ไม่ต้องใช้ bcrypt)
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s Service) tokens(sub Subject, sid, refresh string) (Tokens, error) {
+	access, err := auth.SignJWTWithSession(s.JWTSecret, sub.UserID, sub.Email, sub.Role, sid, s.AccessTTL)
+	if err != nil {
+		return Tokens{}, err
+	}
+	return Tokens{
+		AccessToken:      access,
+		ExpiresIn:        int64(s.AccessTTL.Seconds()),
+		RefreshToken:     refresh,
+		RefreshExpiresIn: int64(s.RefreshTTL.Seconds()),
+		SessionID:        sid,
+	}, nil
+}
+
+// Issue เริ่ม session ใหม่ (login/register)
+func (s Service) Issue(ctx context.Context, sub Subject, c Client) (Tokens, error) {
+	raw, hash, err := newRefreshToken()
+	if err != nil {
+		return Tokens{}, err
+	}
+	if c.Device == "" {
+		c.Device = c.UserAgent
+	}
+	sid, err := s.Repo.Create(ctx, sub.UserID, c, hash, time.Now().Add(s.RefreshTTL))
+	if err != nil {
+		return Tokens{}, err
+	}
+	return s.tokens(sub, sid, raw)
+}
+
+// Refresh แลก refresh token เป็นคู่ใหม่; token เดิมใช้ซ้ำไม่ได้อีก
+func (s Service) Refresh(ctx context.Context, refresh string, c Client) (Tokens, error) {
+	raw, hash, err := newRefreshToken()
+	if err != nil {
+		return Tokens{}, err
+	}
+	sub, sid, err := s.Repo.Rotate(ctx, hashToken(refresh), hash, time.Now().Add(s.RefreshTTL), c)
+	if err != nil {
+		if errors.Is(err, ErrTokenReused) {
+			log.Printf("[AUTH] 🚨 refresh token reuse user=%s sid=%s ip=%s — session revoked", sub.UserID, sid, c.IP)
+		}
+		return Tokens{}, err
+	}
+	return s.tokens(sub, sid, raw)
+}
+
+// Logout ยกเลิก session ของ refresh token นี้
+func (s Service) Logout(ctx context.Context, refresh string) error {
+	return s.Repo.RevokeByToken(ctx, hashToken(refresh))
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/iMookatayou/homeservice-backend/internal/auth"
 )
 
 type Handler struct {
@@ -35,8 +36,8 @@ func (h *Handler) createWatch(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), 400)
 		return
 	}
-	userID := r.Header.Get("X-Debug-User")       // TODO: ดึงจาก auth/context ของคุณ
-	householdID := r.Header.Get("X-Debug-House") // "
+	userID, _ := auth.UserIDFrom(r)
+	householdID, _ := auth.HouseholdIDFrom(r)
 	res, err := h.SVC.AddWatch(r.Context(), userID, householdID, p)
 	if err != nil {
 		http.Error(w, err.Error(), 400)
@@ -21,20 +21,26 @@ func (w *QuotesWorker) Run(ctx context.Context) error {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-t.C:
-			pairs, err := w.Repo.ListDistinctWatchSymbols(ctx)
-			if err != nil || len(pairs) == 0 {
-				continue
-			}
-			batch, err := w.Prov.GetQuotes(ctx, pairs)
-			if err != nil {
-				continue
-			}
-			for _, it := range batch.Items {
-				_ = w.Repo.UpsertQuote(ctx, &StockQuote{
-					Symbol: it.Symbol, Exchange: it.Exchange,
-					TS: it.TS, Price: it.Price, Change: it.Change, ChangePct: it.ChangePct,
-				})
-			}
+			_ = w.RunOnce(ctx)
 		}
 	}
 }
+
+// RunOnce ดึงราคาหนึ่งรอบ (ใช้กับ scheduler)
+func (w *QuotesWorker) RunOnce(ctx context.Context) error {
+	pairs, err := w.Repo.ListDistinctWatchSymbols(ctx)
+	if err != nil || len(pairs) == 0 {
+		return err
+	}
+	batch, err := w.Prov.GetQuotes(ctx, pairs)
+	if err != nil {
+		return err
+	}
+	for _, it := range batch.Items {
+		_ = w.Repo.UpsertQuote(ctx, &StockQuote{
+			Symbol: it.Symbol, Exchange: it.Exchange,
+			TS: it.TS, Price: it.Price, Change: it.Change, ChangePct: it.ChangePct,
+		})
+	}
+	return nil
+}
@@ -1,13 +1,17 @@
 package storage
 
-import "github.com/iMookatayou/homeservice-backend/internal/config"
+import (
+	"fmt"
 
-func New(cfg config.Config) Service {
+	"github.com/iMookatayou/homeservice-backend/internal/config"
+)
+
+func New(cfg config.Config) (Service, error) {
 	switch cfg.StorageBackend {
-	case "local":
-		return NewLocal(cfg)
-		// case "s3": // ไว้ค่อยเพิ่ม หากต้องการ Presign S3
-		//  if s3, err := NewS3(cfg); err == nil { return s3 }
+	case "", "local":
+		return NewLocal(cfg), nil
+	case "s3":
+		return NewS3(cfg)
 	}
-	return NewLocal(cfg) // fallback local
+	return nil, fmt.Errorf("storage: unknown STORAGE_BACKEND %q", cfg.StorageBackend)
 }
@@ -2,25 +2,39 @@ package storage
 
 import (
 	"context"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/iMookatayou/homeservice-backend/internal/config"
 )
 
+// Local เก็บไฟล์ในโฟลเดอร์; อ่านผ่าน /files/{id}/content (login) หรือ URL ที่ sign แล้วจาก SignedGetURL
 type Local struct {
-	dir  string
-	base string
+	dir    string
+	base   string
+	secret []byte // HMAC ของ signed URL (แยกจาก JWT secret ด้วยการ hash พร้อม label)
 }
 
 func NewLocal(cfg config.Config) *Local {
 	_ = os.MkdirAll(cfg.LocalDir, 0o755)
-	return &Local{dir: cfg.LocalDir, base: strings.TrimRight(cfg.PublicBaseURL, "/")}
+	sum := sha256.Sum256([]byte("storage-signed-url:" + cfg.JWTSecret))
+	return &Local{dir: cfg.LocalDir, base: strings.TrimRight(cfg.PublicBaseURL, "/"), secret: sum[:]}
 }
 
 func randName(n int) (string, error) {
@@ -37,6 +51,15 @@ func sanitize(name string) string {
 	return name
 }
 
+// cleanKey ทำให้ key อยู่ใต้โฟลเดอร์ storage เสมอ (กัน ../) แต่ยังมีโฟลเดอร์ย่อยได้ เช่น blobs/ab/<sha256>
+func cleanKey(key string) string {
+	return strings.TrimPrefix(path.Clean("/"+key), "/")
+}
+
+func (l *Local) path(key string) string {
+	return filepath.Join(l.dir, filepath.FromSlash(cleanKey(key)))
+}
+
 func (l *Local) Save(ctx context.Context, ownerID string, r io.Reader, filename, mime string, size int64) (PutResult, error) {
 	fn := sanitize(filename)
 	randPart, err := randName(8)
@@ -56,6 +79,7 @@ func (l *Local) Save(ctx context.Context, ownerID string, r io.Reader, filename,
 		return PutResult{}, err
 	}
 	return PutResult{
+		Key:      outName,
 		URL:      fmt.Sprintf("%s/%s", l.base, outName),
 		Filename: outName,
 		Size:     size,
@@ -63,6 +87,161 @@ func (l *Local) Save(ctx context.Context, ownerID string, r io.Reader, filename,
 	}, nil
 }
 
+// Put เขียนไฟล์ชั่วคราวแล้ว rename เพื่อไม่ให้ผู้อ่านเห็นไฟล์ครึ่ง ๆ ตอนเขียนทับ
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, size int64, mime string) error {
+	dst := l.path(key)
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(l.dir, ".put-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), dst)
+}
+
 func (l *Local) PresignPut(ctx context.Context, ownerID, filename, mime string, size int64) (Presign, error) {
 	return Presign{}, ErrNotSupported
 }
+
+func (l *Local) info(name string, fi os.FileInfo) ObjectInfo {
+	return ObjectInfo{
+		Key:     name,
+		Size:    fi.Size(),
+		MIME:    mime.TypeByExtension(filepath.Ext(name)),
+		URL:     fmt.Sprintf("%s/%s", l.base, name),
+		ETag:    fmt.Sprintf(`"%x-%x"`, fi.ModTime().UnixNano(), fi.Size()),
+		ModTime: fi.ModTime(),
+	}
+}
+
+func (l *Local) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	name := cleanKey(key)
+	fi, err := os.Stat(l.path(name))
+	if errors.Is(err, os.ErrNotExist) || (err == nil && fi.IsDir()) {
+		return ObjectInfo{}, ErrNotFound
+	}
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	return l.info(name, fi), nil
+}
+
+func (l *Local) Open(ctx context.Context, key string) (io.ReadSeekCloser, ObjectInfo, error) {
+	name := cleanKey(key)
+	f, err := os.Open(l.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ObjectInfo{}, ErrNotFound
+	}
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	fi, err := f.Stat()
+	if err != nil || fi.IsDir() {
+		f.Close()
+		if err == nil {
+			err = ErrNotFound
+		}
+		return nil, ObjectInfo{}, err
+	}
+	return f, l.info(name, fi), nil
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	err := os.Remove(l.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (l *Local) sign(name string, exp int64) string {
+	h := hmac.New(sha256.New, l.secret)
+	fmt.Fprintf(h, "%s\n%d", name, exp)
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// SignedGetURL = <PUBLIC_BASE_URL>/<key>?exp=<unix>&sig=<hmac> ตรวจโดย SignedHandler
+func (l *Local) SignedGetURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	name := cleanKey(key)
+	exp := time.Now().Add(ttl).Unix()
+	q := url.Values{"exp": {strconv.FormatInt(exp, 10)}, "sig": {l.sign(name, exp)}}
+	return fmt.Sprintf("%s/%s?%s", l.base, (&url.URL{Path: name}).EscapedPath(), q.Encode()), nil
+}
+
+// SignedHandler เสิร์ฟไฟล์เฉพาะ URL ที่ sign ยังไม่หมดอายุ (mount ด้วย http.StripPrefix ให้ path เหลือแค่ key)
+func (l *Local) SignedHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := cleanKey(r.URL.Path)
+		exp, err := strconv.ParseInt(r.URL.Query().Get("exp"), 10, 64)
+		if err != nil || time.Now().Unix() > exp ||
+			!hmac.Equal([]byte(r.URL.Query().Get("sig")), []byte(l.sign(name, exp))) {
+			http.Error(w, "invalid or expired link", http.StatusForbidden)
+			return
+		}
+		f, info, err := l.Open(r.Context(), name)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer f.Close()
+		Serve(w, r, f, info)
+	})
+}
+
+func (l *Local) List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error {
+	return filepath.WalkDir(l.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() && p != l.dir {
+				return filepath.SkipDir
+			}
+			if !d.IsDir() {
+				return nil
+			}
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(l.dir, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return fn(l.info(key, fi))
+	})
+}
+
+func (l *Local) Check(ctx context.Context) error {
+	f, err := os.CreateTemp(l.dir, ".readyz-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	defer os.Remove(name)
+	if _, err := f.Write([]byte("ok")); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,332 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iMookatayou/homeservice-backend/internal/config"
+)
+
+// S3 = backend แบบ S3-compatible (AWS S3, MinIO, R2); client อัปโหลดตรงผ่าน presigned PUT
+type S3 struct {
+	bucket    string
+	endpoint  *url.URL // ที่ API ใช้คุยกับ S3
+	public    *url.URL // ที่ใส่ใน presigned URL ให้ browser
+	pathStyle bool
+	ttl       time.Duration
+	sig       signer
+	client    *http.Client
+}
+
+func NewS3(cfg config.Config) (*S3, error) {
+	if cfg.S3Bucket == "" || cfg.S3AccessKey == "" || cfg.S3SecretKey == "" {
+		return nil, errors.New("storage: S3_BUCKET, S3_ACCESS_KEY and S3_SECRET_KEY are required")
+	}
+	raw := cfg.S3Endpoint
+	if raw == "" {
+		raw = "https://s3." + cfg.S3Region + ".amazonaws.com"
+	}
+	ep, err := url.Parse(strings.TrimRight(raw, "/"))
+	if err != nil || ep.Host == "" {
+		return nil, fmt.Errorf("storage: bad S3_ENDPOINT %q", raw)
+	}
+	pub := ep
+	if cfg.S3PublicEndpoint != "" {
+		if pub, err = url.Parse(strings.TrimRight(cfg.S3PublicEndpoint, "/")); err != nil || pub.Host == "" {
+			return nil, fmt.Errorf("storage: bad S3_PUBLIC_ENDPOINT %q", cfg.S3PublicEndpoint)
+		}
+	}
+	ttl := cfg.S3PresignTTL
+	if ttl <= 0 || ttl > 7*24*time.Hour { // SigV4 presign ได้ไม่เกิน 7 วัน
+		ttl = 15 * time.Minute
+	}
+	return &S3{
+		bucket:    cfg.S3Bucket,
+		endpoint:  ep,
+		public:    pub,
+		pathStyle: cfg.S3PathStyle,
+		ttl:       ttl,
+		sig:       signer{accessKey: cfg.S3AccessKey, secretKey: cfg.S3SecretKey, region: cfg.S3Region},
+		client:    &http.Client{Timeout: 5 * time.Minute},
+	}, nil
+}
+
+// objectKey: uploads/<owner>/<random>_<ชื่อไฟล์> — prefix ของเจ้าของใช้ตรวจตอน confirm
+func objectKey(ownerID, filename string) (string, error) {
+	r, err := randName(8)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("uploads/%s/%s_%s", ownerID, r, sanitize(filename)), nil
+}
+
+// OwnedKey = key นี้ออกให้ ownerID ผ่าน PresignPut หรือไม่
+func OwnedKey(ownerID, key string) bool {
+	return ownerID != "" && strings.HasPrefix(key, "uploads/"+ownerID+"/") && !strings.Contains(key, "..")
+}
+
+func (s *S3) objectURL(base *url.URL, key string) *url.URL {
+	u := *base
+	path := "/" + strings.TrimLeft(key, "/")
+	if s.pathStyle {
+		path = "/" + s.bucket + path
+	} else {
+		u.Host = s.bucket + "." + u.Host
+	}
+	u.Path = strings.TrimRight(base.Path, "/") + path
+	u.RawPath = uriEncode(u.Path, true)
+	return &u
+}
+
+// do ส่ง request ที่ sign แล้ว; status ไม่ใช่ 2xx = error (404 = ErrNotFound)
+// header ใน hdr: Content-Type ถูก sign ด้วย, ตัวอื่น (เช่น Range) ไม่ต้อง sign
+func (s *S3) do(ctx context.Context, method, key string, body io.Reader, size int64, hdr map[string]string, payloadHash string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(s.endpoint, key).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	for k, v := range hdr {
+		req.Header.Set(k, v)
+	}
+	s.sig.sign(req, payloadHash, time.Now())
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode/100 == 2 {
+		return res, nil
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+	return nil, fmt.Errorf("storage: s3 %s %s: %s: %s", method, key, res.Status, bytes.TrimSpace(msg))
+}
+
+func (s *S3) Save(ctx context.Context, ownerID string, r io.Reader, filename, mime string, size int64) (PutResult, error) {
+	key, err := objectKey(ownerID, filename)
+	if err != nil {
+		return PutResult{}, err
+	}
+	res, err := s.do(ctx, http.MethodPut, key, r, size, map[string]string{"Content-Type": mime}, unsignedPayload)
+	if err != nil {
+		return PutResult{}, err
+	}
+	res.Body.Close()
+	return PutResult{
+		Key:      key,
+		URL:      s.objectURL(s.public, key).String(),
+		Filename: sanitize(filename),
+		Size:     size,
+		MIME:     mime,
+	}, nil
+}
+
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, mime string) error {
+	res, err := s.do(ctx, http.MethodPut, key, r, size, map[string]string{"Content-Type": mime}, unsignedPayload)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+// PresignPut: content-type ถูก sign ไว้ client จึงอัปโหลดได้เฉพาะชนิดที่ขอ
+func (s *S3) PresignPut(ctx context.Context, ownerID, filename, mime string, size int64) (Presign, error) {
+	key, err := objectKey(ownerID, filename)
+	if err != nil {
+		return Presign{}, err
+	}
+	now := time.Now()
+	headers := map[string]string{"Content-Type": mime}
+	return Presign{
+		Method:  http.MethodPut,
+		URL:     s.sig.presign(http.MethodPut, s.objectURL(s.public, key), map[string]string{"content-type": mime}, s.ttl, now),
+		Key:     key,
+		Headers: headers,
+		Expire:  now.Add(s.ttl),
+	}, nil
+}
+
+func (s *S3) SignedGetURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	if ttl <= 0 || ttl > 7*24*time.Hour {
+		ttl = s.ttl
+	}
+	return s.sig.presign(http.MethodGet, s.objectURL(s.public, key), nil, ttl, time.Now()), nil
+}
+
+func (s *S3) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	res, err := s.do(ctx, http.MethodHead, key, nil, 0, nil, sha256Hex(nil))
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	res.Body.Close()
+	size, _ := strconv.ParseInt(res.Header.Get("Content-Length"), 10, 64)
+	mod, _ := http.ParseTime(res.Header.Get("Last-Modified"))
+	return ObjectInfo{
+		Key:     key,
+		Size:    size,
+		MIME:    res.Header.Get("Content-Type"),
+		URL:     s.objectURL(s.public, key).String(),
+		ETag:    res.Header.Get("ETag"),
+		ModTime: mod,
+	}, nil
+}
+
+func (s *S3) Open(ctx context.Context, key string) (io.ReadSeekCloser, ObjectInfo, error) {
+	info, err := s.Stat(ctx, key)
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	return &s3Reader{s: s, ctx: ctx, key: key, size: info.Size}, info, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	res, err := s.do(ctx, http.MethodDelete, key, nil, 0, nil, sha256Hex(nil))
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+// s3Reader อ่าน object แบบ lazy: GET ด้วย Range ตั้งแต่ตำแหน่งปัจจุบันเมื่อ Read ครั้งแรกหลัง Seek
+// http.ServeContent จึงดึงเฉพาะช่วงที่ client ขอ ไม่ต้องโหลดทั้งไฟล์
+type s3Reader struct {
+	s    *S3
+	ctx  context.Context
+	key  string
+	size int64
+	off  int64
+	body io.ReadCloser
+}
+
+func (r *s3Reader) Read(p []byte) (int, error) {
+	if r.off >= r.size {
+		return 0, io.EOF
+	}
+	if r.body == nil {
+		res, err := r.s.do(r.ctx, http.MethodGet, r.key, nil, 0,
+			map[string]string{"Range": fmt.Sprintf("bytes=%d-", r.off)}, sha256Hex(nil))
+		if err != nil {
+			return 0, err
+		}
+		r.body = res.Body
+	}
+	n, err := r.body.Read(p)
+	r.off += int64(n)
+	return n, err
+}
+
+func (r *s3Reader) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = r.off + offset
+	case io.SeekEnd:
+		abs = r.size + offset
+	default:
+		return 0, errors.New("storage: bad whence")
+	}
+	if abs < 0 {
+		return 0, errors.New("storage: negative position")
+	}
+	if abs != r.off && r.body != nil {
+		r.body.Close()
+		r.body = nil
+	}
+	r.off = abs
+	return abs, nil
+}
+
+func (r *s3Reader) Close() error {
+	if r.body != nil {
+		return r.body.Close()
+	}
+	return nil
+}
+
+type listBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+		ETag         string    `xml:"ETag"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List ใช้ ListObjectsV2 ทีละหน้า (สูงสุด 1000 key ต่อหน้า)
+func (s *S3) List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error {
+	token := ""
+	for {
+		u := s.objectURL(s.endpoint, "")
+		q := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		u.RawQuery = canonicalQuery(q)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return err
+		}
+		s.sig.sign(req, sha256Hex(nil), time.Now())
+		res, err := s.client.Do(req)
+		if err != nil {
+			return err
+		}
+		var out listBucketResult
+		if res.StatusCode/100 != 2 {
+			msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+			res.Body.Close()
+			return fmt.Errorf("storage: s3 list %q: %s: %s", prefix, res.Status, bytes.TrimSpace(msg))
+		}
+		err = xml.NewDecoder(res.Body).Decode(&out)
+		res.Body.Close()
+		if err != nil {
+			return err
+		}
+		for _, c := range out.Contents {
+			if strings.HasPrefix(c.Key, ".") {
+				continue
+			}
+			if err := fn(ObjectInfo{
+				Key: c.Key, Size: c.Size, ETag: c.ETag, ModTime: c.LastModified,
+				URL: s.objectURL(s.public, c.Key).String(),
+			}); err != nil {
+				return err
+			}
+		}
+		if !out.IsTruncated || out.NextContinuationToken == "" {
+			return nil
+		}
+		token = out.NextContinuationToken
+	}
+}
+
+func (s *S3) Check(ctx context.Context) error {
+	body := []byte("ok")
+	res, err := s.do(ctx, http.MethodPut, ".readyz", bytes.NewReader(body), int64(len(body)),
+		map[string]string{"Content-Type": "text/plain"}, sha256Hex(body))
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
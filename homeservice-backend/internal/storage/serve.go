@@ -0,0 +1,21 @@
+package storage
+
+import (
+	"io"
+	"net/http"
+)
+
+// Serve ส่ง object ให้ client พร้อม ETag/Last-Modified; http.ServeContent จัดการ Range, If-Range, If-None-Match ให้
+func Serve(w http.ResponseWriter, r *http.Request, rs io.ReadSeeker, info ObjectInfo) {
+	if info.ETag != "" {
+		w.Header().Set("ETag", info.ETag)
+	}
+	if info.MIME != "" && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", info.MIME)
+	}
+	if w.Header().Get("Cache-Control") == "" {
+		w.Header().Set("Cache-Control", "private, no-cache")
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, "", info.ModTime, rs)
+}
@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AWS Signature V4 แบบเท่าที่ S3 ใช้ (ไม่ดึง SDK ทั้งก้อนมาเพื่อ PUT/GET/HEAD/DELETE)
+const (
+	sigAlgorithm    = "AWS4-HMAC-SHA256"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	amzDateFormat   = "20060102T150405Z"
+)
+
+type signer struct {
+	accessKey, secretKey, region string
+}
+
+func (s signer) scope(t time.Time) string {
+	return t.Format("20060102") + "/" + s.region + "/s3/aws4_request"
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func (s signer) signature(t time.Time, canonicalRequest string) string {
+	toSign := strings.Join([]string{
+		sigAlgorithm,
+		t.Format(amzDateFormat),
+		s.scope(t),
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+	k := hmacSHA256([]byte("AWS4"+s.secretKey), t.Format("20060102"))
+	k = hmacSHA256(k, s.region)
+	k = hmacSHA256(k, "s3")
+	k = hmacSHA256(k, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(k, toSign))
+}
+
+// uriEncode ตาม RFC 3986 (url.QueryEscape ใช้ + แทนช่องว่าง ซึ่ง SigV4 ไม่รับ); keepSlash สำหรับ path
+func uriEncode(s string, keepSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && keepSlash:
+			b.WriteByte(c)
+		default:
+			b.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{c})))
+		}
+	}
+	return b.String()
+}
+
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		vs := append([]string(nil), q[k]...)
+		sort.Strings(vs)
+		for _, v := range vs {
+			parts = append(parts, uriEncode(k, false)+"="+uriEncode(v, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// canonicalHeaders: ชื่อตัวเล็ก เรียงตามชื่อ; คืน (บล็อก header, รายชื่อที่ sign)
+func canonicalHeaders(h map[string]string) (string, string) {
+	names := make([]string, 0, len(h))
+	lower := make(map[string]string, len(h))
+	for k, v := range h {
+		lk := strings.ToLower(k)
+		names = append(names, lk)
+		lower[lk] = strings.Join(strings.Fields(v), " ")
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, n := range names {
+		b.WriteString(n + ":" + lower[n] + "\n")
+	}
+	return b.String(), strings.Join(names, ";")
+}
+
+func canonicalRequest(method string, u *url.URL, query, headers, signed, payloadHash string) string {
+	return strings.Join([]string{method, uriEncode(u.Path, true), query, headers, signed, payloadHash}, "\n")
+}
+
+// presign เติม query X-Amz-* ลงใน u; header ใน extra (เช่น content-type) ถูก sign ด้วย client ต้องส่งค่าเดียวกัน
+func (s signer) presign(method string, u *url.URL, extra map[string]string, ttl time.Duration, now time.Time) string {
+	now = now.UTC()
+	headers := map[string]string{"host": u.Host}
+	for k, v := range extra {
+		headers[k] = v
+	}
+	hdrs, signed := canonicalHeaders(headers)
+
+	q := u.Query()
+	q.Set("X-Amz-Algorithm", sigAlgorithm)
+	q.Set("X-Amz-Credential", s.accessKey+"/"+s.scope(now))
+	q.Set("X-Amz-Date", now.Format(amzDateFormat))
+	q.Set("X-Amz-Expires", strconv.Itoa(int(ttl/time.Second)))
+	q.Set("X-Amz-SignedHeaders", signed)
+	cq := canonicalQuery(q)
+
+	sig := s.signature(now, canonicalRequest(method, u, cq, hdrs, signed, unsignedPayload))
+	out := *u
+	out.RawQuery = cq + "&X-Amz-Signature=" + sig
+	return out.String()
+}
+
+// sign ใส่ Authorization header ให้ request ที่ server ส่งเอง; payloadHash = sha256 ของ body หรือ UNSIGNED-PAYLOAD
+func (s signer) sign(req *http.Request, payloadHash string, now time.Time) {
+	now = now.UTC()
+	req.Header.Set("X-Amz-Date", now.Format(amzDateFormat))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	headers := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-date":           now.Format(amzDateFormat),
+		"x-amz-content-sha256": payloadHash,
+	}
+	if ct := req.Header.Get("Content-Type"); ct != "" {
+		headers["content-type"] = ct
+	}
+	hdrs, signed := canonicalHeaders(headers)
+	cr := canonicalRequest(req.Method, req.URL, canonicalQuery(req.URL.Query()), hdrs, signed, payloadHash)
+	req.Header.Set("Authorization", sigAlgorithm+
+		" Credential="+s.accessKey+"/"+s.scope(now)+
+		", SignedHeaders="+signed+
+		", Signature="+s.signature(now, cr))
+}
@@ -7,22 +7,59 @@ import (
 	"time"
 )
 
-var ErrNotSupported = errors.New("storage: operation not supported")
+var (
+	ErrNotSupported = errors.New("storage: operation not supported")
+	ErrNotFound     = errors.New("storage: object not found")
+)
 
 type PutResult struct {
+	Key      string
 	URL      string
 	Filename string
 	Size     int64
 	MIME     string
 }
 
+// Presign = URL ที่ client ใช้อัปโหลดตรงกับ backend (ต้องส่ง Headers ตามนี้ทุกตัว)
 type Presign struct {
+	Method  string            `json:"method"`
 	URL     string            `json:"url"`
+	Key     string            `json:"key"`
 	Headers map[string]string `json:"headers"`
 	Expire  time.Time         `json:"expire"`
 }
 
+// ObjectInfo ข้อมูล object ที่อยู่ใน backend จริง (จาก Stat/Open)
+type ObjectInfo struct {
+	Key     string
+	Size    int64
+	MIME    string
+	URL     string
+	ETag    string // มีเครื่องหมายคำพูดแล้ว ใส่ header ได้เลย
+	ModTime time.Time
+}
+
 type Service interface {
 	Save(ctx context.Context, ownerID string, r io.Reader, filename, mime string, size int64) (PutResult, error)
+	// Put เขียนทับ/สร้าง object ที่ key กำหนดเอง (เช่น rendition ข้างต้นฉบับ)
+	Put(ctx context.Context, key string, r io.Reader, size int64, mime string) error
 	PresignPut(ctx context.Context, ownerID, filename, mime string, size int64) (Presign, error)
+	// Open อ่าน object แบบ seek ได้ (ใช้กับ http.ServeContent เพื่อรองรับ Range); ผู้เรียกต้อง Close
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, ObjectInfo, error)
+	// Stat ตรวจว่า object มีอยู่จริง (ErrNotFound ถ้าไม่มี)
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+	// Delete ลบ object; ไม่มีอยู่แล้วถือว่าสำเร็จ
+	Delete(ctx context.Context, key string) error
+	// SignedGetURL URL อ่านไฟล์ได้โดยไม่ต้อง login จนหมดอายุ ttl (ส่งให้ <img>/แอปอื่น)
+	SignedGetURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+	// List ไล่ทุก object ที่ key ขึ้นต้นด้วย prefix (ข้ามไฟล์ภายในของ backend เช่น .readyz)
+	List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error
+	// Check เขียน/ลบไฟล์ทดสอบเล็ก ๆ เพื่อยืนยันว่า backend เขียนได้ (ใช้ใน /readyz)
+	Check(ctx context.Context) error
+}
+
+// ContentKey key ของ blob ตามเนื้อไฟล์ (sha256 hex): ไฟล์เนื้อเหมือนกันใช้ object เดียวกัน
+// แบ่งโฟลเดอร์ด้วย 2 ตัวแรกไม่ให้โฟลเดอร์เดียวมีไฟล์มากเกินไป
+func ContentKey(sum string) string {
+	return "blobs/" + sum[:2] + "/" + sum
 }
@@ -0,0 +1,69 @@
+package user
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/iMookatayou/homeservice-backend/internal/auth"
+	"github.com/iMookatayou/homeservice-backend/internal/authz"
+	"github.com/iMookatayou/homeservice-backend/internal/httpx"
+)
+
+type setRoleReq struct {
+	Role string `json:"role" validate:"required,oneof=user admin"`
+}
+
+// RegisterAdminRoutes — ใช้ใต้กลุ่ม admin (authz.Require(ActionManage, KindUser))
+// เปลี่ยนบทบาทแล้วยกเลิกทุก session ของผู้ใช้นั้น: role อยู่ใน JWT จึงต้องบังคับ login ใหม่ ไม่งั้น admin ที่ถูกลดสิทธิ์ยังใช้ token เดิมได้จนหมดอายุ
+func (h Handler) RegisterAdminRoutes(r chi.Router) {
+	r.Get("/admin/users", h.ListUsers)
+	r.Put("/admin/users/{id}/role", h.SetRole)
+}
+
+func (h Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	limit, offset := httpx.ParsePage(r, 50).LimitOffset()
+	out, err := h.Repo.List(r.Context(), limit, offset)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "internal error", nil)
+		return
+	}
+	httpx.JSON(w, http.StatusOK, out)
+}
+
+func (h Handler) SetRole(w http.ResponseWriter, r *http.Request) {
+	var req setRoleReq
+	if err := httpx.BindJSON(r, &req); err != nil {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "validation failed", httpx.ValidationErrors(err))
+		return
+	}
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		httpx.WriteJSONError(w, http.StatusNotFound, ErrNotFound.Error(), nil)
+		return
+	}
+	// กัน admin ลดสิทธิ์ตัวเองจนไม่มีใครเข้ากลุ่ม admin ได้
+	if uid, _ := auth.UserIDFrom(r); uid == id && req.Role != authz.SystemAdmin {
+		httpx.WriteJSONError(w, http.StatusConflict, "cannot demote yourself", nil)
+		return
+	}
+	changed, err := h.Repo.SetRole(r.Context(), id, req.Role)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			httpx.WriteJSONError(w, http.StatusNotFound, err.Error(), nil)
+			return
+		}
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "internal error", nil)
+		return
+	}
+	if changed {
+		if _, err := h.Sessions.Repo.RevokeAll(r.Context(), id, "role_changed"); err != nil {
+			log.Printf("[ADMIN] ⚠️ revoke sessions after role change user=%s: %v", id, err)
+			httpx.WriteJSONError(w, http.StatusInternalServerError, "internal error", nil)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -5,28 +5,26 @@ import (
 	"errors"
 	"net/http"
 	"strings"
-	"time"
 
 	"github.com/iMookatayou/homeservice-backend/internal/auth"
+	"github.com/iMookatayou/homeservice-backend/internal/session"
 )
 
 type Handler struct {
-	Repo      Repo
-	JWTSecret string
+	Repo     Repo
+	Sessions session.Service
 }
 
 type registerReq struct {
 	Name     string `json:"name"`
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	Device   string `json:"device,omitempty"` // ชื่ออุปกรณ์ เช่น "iPhone ของแม่" (แสดงใน /me/sessions)
 }
 type loginReq struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
-}
-type tokenRes struct {
-	AccessToken string `json:"access_token"`
-	ExpiresIn   int64  `json:"expires_in"`
+	Device   string `json:"device,omitempty"`
 }
 
 func writeJSON(w http.ResponseWriter, code int, v any) {
@@ -52,10 +50,14 @@ func (h Handler) Register(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "email exists?", http.StatusConflict)
 		return
 	}
-	tok, _ := auth.SignJWT(h.JWTSecret, u.ID, u.Email, 24*time.Hour)
+	tok, err := h.Sessions.Issue(r.Context(), subject(u), session.ClientFrom(r, req.Device))
+	if err != nil {
+		http.Error(w, "could not start session", http.StatusInternalServerError)
+		return
+	}
 	writeJSON(w, http.StatusCreated, map[string]any{
 		"user":   map[string]any{"id": u.ID, "name": u.Name, "email": u.Email},
-		"tokens": tokenRes{AccessToken: tok, ExpiresIn: 24 * 3600},
+		"tokens": tok,
 	})
 }
 
@@ -75,10 +77,14 @@ func (h Handler) Login(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid credentials", http.StatusUnauthorized)
 		return
 	}
-	tok, _ := auth.SignJWT(h.JWTSecret, u.ID, u.Email, 24*time.Hour)
+	tok, err := h.Sessions.Issue(r.Context(), subject(u), session.ClientFrom(r, req.Device))
+	if err != nil {
+		http.Error(w, "could not start session", http.StatusInternalServerError)
+		return
+	}
 	writeJSON(w, http.StatusOK, map[string]any{
 		"user":   map[string]any{"id": u.ID, "name": u.Name, "email": u.Email},
-		"tokens": tokenRes{AccessToken: tok, ExpiresIn: 24 * 3600},
+		"tokens": tok,
 	})
 }
 
@@ -99,4 +105,8 @@ func (h Handler) Me(w http.ResponseWriter, r *http.Request) {
 }
 
 // helpers
+func subject(u *User) session.Subject {
+	return session.Subject{UserID: u.ID, Email: u.Email, Role: u.Role}
+}
+
 var ErrBad = errors.New("bad request")
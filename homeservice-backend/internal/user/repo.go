@@ -2,7 +2,9 @@ package user
 
 import (
 	"context"
+	"errors"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -12,22 +14,64 @@ func (r Repo) Create(ctx context.Context, u *User) error {
 	return r.DB.QueryRow(ctx, `
 		insert into users (name, email, password_hash)
 		values ($1,$2,$3)
-		returning id, created_at, updated_at
-	`, u.Name, u.Email, u.PasswordHash).Scan(&u.ID, &u.CreatedAt, &u.UpdatedAt)
+		returning id, role, created_at, updated_at
+	`, u.Name, u.Email, u.PasswordHash).Scan(&u.ID, &u.Role, &u.CreatedAt, &u.UpdatedAt)
 }
 func (r Repo) ByEmail(ctx context.Context, email string) (*User, error) {
-	row := r.DB.QueryRow(ctx, `select id,name,email,password_hash,created_at,updated_at from users where email=$1`, email)
+	row := r.DB.QueryRow(ctx, `select id,name,email,password_hash,role,created_at,updated_at from users where email=$1`, email)
 	u := new(User)
-	if err := row.Scan(&u.ID, &u.Name, &u.Email, &u.PasswordHash, &u.CreatedAt, &u.UpdatedAt); err != nil {
+	if err := row.Scan(&u.ID, &u.Name, &u.Email, &u.PasswordHash, &u.Role, &u.CreatedAt, &u.UpdatedAt); err != nil {
 		return nil, err
 	}
 	return u, nil
 }
 func (r Repo) ByID(ctx context.Context, id string) (*User, error) {
-	row := r.DB.QueryRow(ctx, `select id,name,email,password_hash,created_at,updated_at from users where id=$1`, id)
+	row := r.DB.QueryRow(ctx, `select id,name,email,password_hash,role,created_at,updated_at from users where id=$1`, id)
 	u := new(User)
-	if err := row.Scan(&u.ID, &u.Name, &u.Email, &u.PasswordHash, &u.CreatedAt, &u.UpdatedAt); err != nil {
+	if err := row.Scan(&u.ID, &u.Name, &u.Email, &u.PasswordHash, &u.Role, &u.CreatedAt, &u.UpdatedAt); err != nil {
 		return nil, err
 	}
 	return u, nil
 }
+
+var ErrNotFound = errors.New("user not found")
+
+// List ผู้ใช้ทั้งหมด (สำหรับ admin)
+func (r Repo) List(ctx context.Context, limit, offset int) ([]User, error) {
+	rows, err := r.DB.Query(ctx, `
+		select id,name,email,role,created_at,updated_at from users
+		order by created_at
+		limit $1 offset $2
+	`, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []User{}
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.Role, &u.CreatedAt, &u.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
+
+// SetRole เปลี่ยนบทบาทระดับระบบ (user|admin); changed=false เมื่อบทบาทเดิมตรงกับที่ขออยู่แล้ว
+func (r Repo) SetRole(ctx context.Context, id, role string) (changed bool, err error) {
+	var prev string
+	err = r.DB.QueryRow(ctx, `
+		update users u set role=$2
+		from (select id, role from users where id=$1 for update) o
+		where u.id=o.id
+		returning o.role
+	`, id, role).Scan(&prev)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, ErrNotFound
+	}
+	if err != nil {
+		return false, err
+	}
+	return prev != role, nil
+}
@@ -0,0 +1,108 @@
+-- +goose Up
+-- ตารางหลักที่ migration อื่นอ้างถึง (users, purchases, bills, chores) + ฟังก์ชันกลาง
+-- ใช้ IF NOT EXISTS ทั้งหมด เพื่อให้รันซ้ำบนฐานข้อมูลเดิมที่สร้างด้วยมือได้
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+CREATE OR REPLACE FUNCTION set_updated_at()
+RETURNS trigger AS $$
+BEGIN
+  NEW.updated_at := now();
+  RETURN NEW;
+END $$ LANGUAGE plpgsql;
+
+-- users
+CREATE TABLE IF NOT EXISTS users (
+  id             uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  name           text NOT NULL,
+  email          text NOT NULL UNIQUE,
+  password_hash  text NOT NULL,
+  created_at     timestamptz NOT NULL DEFAULT now(),
+  updated_at     timestamptz NOT NULL DEFAULT now()
+);
+
+DROP TRIGGER IF EXISTS users_set_updated_at ON users;
+CREATE TRIGGER users_set_updated_at
+  BEFORE UPDATE ON users
+  FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- purchases (รายการซื้อของเข้าบ้าน; items เก็บเป็น jsonb)
+CREATE TABLE IF NOT EXISTS purchases (
+  id                uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  title             text NOT NULL,
+  note              text NOT NULL DEFAULT '',
+  items             jsonb NOT NULL DEFAULT '[]',
+  amount_estimated  numeric(14,2) NOT NULL DEFAULT 0,
+  amount_paid       numeric(14,2) NOT NULL DEFAULT 0,
+  currency          text NOT NULL DEFAULT 'THB',
+  category          text NOT NULL DEFAULT '',
+  store             text NOT NULL DEFAULT '',
+  status            text NOT NULL DEFAULT 'planned'
+                    CONSTRAINT purchases_status_check
+                    CHECK (status IN ('planned','ordered','bought','delivered','cancelled')),
+  requester_id      uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  buyer_id          uuid REFERENCES users(id) ON DELETE SET NULL,
+  editable_until    timestamptz NOT NULL DEFAULT now() + interval '10 minutes',
+  created_at        timestamptz NOT NULL DEFAULT now(),
+  updated_at        timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_purchases_status    ON purchases(status);
+CREATE INDEX IF NOT EXISTS idx_purchases_requester ON purchases(requester_id);
+
+DROP TRIGGER IF EXISTS purchases_set_updated_at ON purchases;
+CREATE TRIGGER purchases_set_updated_at
+  BEFORE UPDATE ON purchases
+  FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- bills (ค่าน้ำ/ค่าไฟ/อินเทอร์เน็ต ฯลฯ)
+CREATE TABLE IF NOT EXISTS bills (
+  id                    uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  type                  text NOT NULL,
+  title                 text NOT NULL,
+  amount                numeric(14,2) NOT NULL CHECK (amount >= 0),
+  billing_period_start  date,
+  billing_period_end    date,
+  due_date              date NOT NULL,
+  status                text NOT NULL DEFAULT 'unpaid',
+  paid_at               timestamptz,
+  note                  text,
+  created_by            uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at            timestamptz NOT NULL DEFAULT now(),
+  updated_at            timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_bills_due_date ON bills(due_date DESC);
+
+DROP TRIGGER IF EXISTS bills_set_updated_at ON bills;
+CREATE TRIGGER bills_set_updated_at
+  BEFORE UPDATE ON bills
+  FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- chores (งานบ้าน: open -> claimed -> completed)
+CREATE TABLE IF NOT EXISTS chores (
+  id            uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  title         text NOT NULL,
+  category      text NOT NULL DEFAULT 'general'
+                CHECK (category IN ('general','kitchen','bathroom','outdoor')),
+  status        text NOT NULL DEFAULT 'open'
+                CONSTRAINT chores_status_check
+                CHECK (status IN ('open','claimed','completed')),
+  claimed_by    uuid REFERENCES users(id) ON DELETE SET NULL,
+  claimed_at    timestamptz,
+  completed_by  uuid REFERENCES users(id) ON DELETE SET NULL,
+  completed_at  timestamptz,
+  note          text,
+  created_by    uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at    timestamptz NOT NULL DEFAULT now(),
+  updated_at    timestamptz NOT NULL DEFAULT now()
+);
+
+DROP TRIGGER IF EXISTS chores_set_updated_at ON chores;
+CREATE TRIGGER chores_set_updated_at
+  BEFORE UPDATE ON chores
+  FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- +goose Down
+DROP TABLE IF EXISTS chores;
+DROP TABLE IF EXISTS bills;
+DROP TABLE IF EXISTS purchases;
+DROP TABLE IF EXISTS users;
+DROP FUNCTION IF EXISTS set_updated_at();
@@ -1,4 +1,4 @@
--- 0006_notes.sql
+-- 0005_notes.sql
 CREATE TABLE IF NOT EXISTS notes (
   id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
   title       TEXT NOT NULL,
@@ -24,12 +24,3 @@ DROP TRIGGER IF EXISTS trg_notes_updated_at ON notes;
 CREATE TRIGGER trg_notes_updated_at
 BEFORE UPDATE ON notes
 FOR EACH ROW EXECUTE FUNCTION set_updated_at();
-
--- (optional) seed demo
-INSERT INTO notes (title, body, tags, link)
-VALUES
-('Uploads module is LIVE ✅',
- 'อัปโหลดผ่าน POST /api/v1/uploads (JWT + form-data:file)\nbytes/size และ mime sync ผ่าน trigger files_before_write',
- ARRAY['uploads','trigger','jwt'],
- 'http://localhost:8080/static/')
-ON CONFLICT DO NOTHING;
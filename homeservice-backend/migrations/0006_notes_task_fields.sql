@@ -1,4 +1,4 @@
--- 0007_notes_task_fields.sql
+-- 0006_notes_task_fields.sql
 -- เพิ่มฟิลด์สำหรับงาน + ดัชนี
 
 ALTER TABLE notes
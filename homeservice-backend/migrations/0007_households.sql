@@ -0,0 +1,89 @@
+-- +goose Up
+-- households: แยกข้อมูลแต่ละครอบครัวใน deployment เดียวกัน
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+CREATE TABLE IF NOT EXISTS households (
+  id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  name        text NOT NULL,
+  created_by  uuid REFERENCES users(id) ON DELETE SET NULL,
+  created_at  timestamptz NOT NULL DEFAULT now(),
+  updated_at  timestamptz NOT NULL DEFAULT now()
+);
+
+DROP TRIGGER IF EXISTS households_set_updated_at ON households;
+CREATE TRIGGER households_set_updated_at
+  BEFORE UPDATE ON households
+  FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+CREATE TABLE IF NOT EXISTS household_members (
+  household_id  uuid NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+  user_id       uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  role          text NOT NULL DEFAULT 'member' CHECK (role IN ('owner','admin','member')),
+  joined_at     timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (household_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_household_members_user ON household_members(user_id, joined_at);
+
+CREATE TABLE IF NOT EXISTS household_invites (
+  code          text PRIMARY KEY,
+  household_id  uuid NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+  role          text NOT NULL DEFAULT 'member' CHECK (role IN ('admin','member')),
+  created_by    uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  max_uses      int CHECK (max_uses IS NULL OR max_uses > 0),
+  uses          int NOT NULL DEFAULT 0,
+  expires_at    timestamptz,
+  revoked_at    timestamptz,
+  created_at    timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_household_invites_household ON household_invites(household_id, created_at DESC);
+
+-- scope ข้อมูลเดิมด้วย household_id
+-- files/media ไม่มี household_id โดยตั้งใจ: files เป็นของผู้อัปโหลด (owner_id) สมาชิกบ้านเห็นผ่านรายการในบ้านที่แนบไฟล์นั้น
+-- media channel/post เป็นข้อมูลสาธารณะใช้ร่วมทุกบ้าน ส่วนการติดตามผูกกับผู้ใช้
+ALTER TABLE bills     ADD COLUMN IF NOT EXISTS household_id uuid REFERENCES households(id) ON DELETE CASCADE;
+ALTER TABLE notes     ADD COLUMN IF NOT EXISTS household_id uuid REFERENCES households(id) ON DELETE CASCADE;
+ALTER TABLE purchases ADD COLUMN IF NOT EXISTS household_id uuid REFERENCES households(id) ON DELETE CASCADE;
+ALTER TABLE chores    ADD COLUMN IF NOT EXISTS household_id uuid REFERENCES households(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_bills_household     ON bills(household_id, due_date DESC);
+CREATE INDEX IF NOT EXISTS idx_notes_household     ON notes(household_id, updated_at DESC);
+CREATE INDEX IF NOT EXISTS idx_purchases_household ON purchases(household_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_chores_household    ON chores(household_id, created_at DESC);
+
+-- backfill: ผู้ใช้เดิมที่ยังไม่มีบ้าน -> สร้างบ้านส่วนตัว (owner) แล้วย้ายข้อมูลที่ตัวเองสร้างเข้าไป
+INSERT INTO households (name, created_by)
+SELECT u.name || '''s home', u.id
+FROM users u
+WHERE NOT EXISTS (SELECT 1 FROM household_members m WHERE m.user_id = u.id);
+
+INSERT INTO household_members (household_id, user_id, role)
+SELECT h.id, h.created_by, 'owner'
+FROM households h
+WHERE h.created_by IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM household_members m WHERE m.user_id = h.created_by)
+ON CONFLICT DO NOTHING;
+
+UPDATE bills b SET household_id = m.household_id
+FROM household_members m
+WHERE b.household_id IS NULL AND m.user_id = b.created_by AND m.role = 'owner';
+
+UPDATE notes n SET household_id = m.household_id
+FROM household_members m
+WHERE n.household_id IS NULL AND m.user_id = n.created_by AND m.role = 'owner';
+
+UPDATE purchases p SET household_id = m.household_id
+FROM household_members m
+WHERE p.household_id IS NULL AND m.user_id = p.requester_id AND m.role = 'owner';
+
+UPDATE chores c SET household_id = m.household_id
+FROM household_members m
+WHERE c.household_id IS NULL AND m.user_id = c.created_by AND m.role = 'owner';
+
+-- +goose Down
+ALTER TABLE chores    DROP COLUMN IF EXISTS household_id;
+ALTER TABLE purchases DROP COLUMN IF EXISTS household_id;
+ALTER TABLE notes     DROP COLUMN IF EXISTS household_id;
+ALTER TABLE bills     DROP COLUMN IF EXISTS household_id;
+DROP TABLE IF EXISTS household_invites;
+DROP TABLE IF EXISTS household_members;
+DROP TABLE IF EXISTS households;
@@ -0,0 +1,86 @@
+-- +goose Up
+-- medicine: คลังยาประจำบ้าน (item -> batch/lot -> txn)
+CREATE TABLE IF NOT EXISTS medicine_locations (
+  id            uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  household_id  uuid NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+  name          text NOT NULL,
+  notes         text,
+  is_active     boolean NOT NULL DEFAULT true,
+  created_at    timestamptz NOT NULL DEFAULT now(),
+  updated_at    timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_medicine_locations_household ON medicine_locations(household_id, name);
+
+CREATE TABLE IF NOT EXISTS medicine_items (
+  id             uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  household_id   uuid NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+  name           text NOT NULL,
+  generic_name   text,
+  form           text NOT NULL DEFAULT 'other'
+                 CHECK (form IN ('tablet','capsule','syrup','ointment','spray','drop','other')),
+  strength       text,
+  category       text,
+  unit           text NOT NULL,
+  location_id    uuid REFERENCES medicine_locations(id) ON DELETE SET NULL,
+  gtin           text,
+  photo_file_id  uuid REFERENCES files(id) ON DELETE SET NULL,
+  notes          text,
+  is_archived    boolean NOT NULL DEFAULT false,
+  created_at     timestamptz NOT NULL DEFAULT now(),
+  updated_at     timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_medicine_items_household ON medicine_items(household_id, name);
+CREATE INDEX IF NOT EXISTS idx_medicine_items_gtin      ON medicine_items(household_id, gtin) WHERE gtin IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS medicine_batches (
+  id           uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  item_id      uuid NOT NULL REFERENCES medicine_items(id) ON DELETE CASCADE,
+  lot_no       text,
+  expiry_date  date,
+  qty          numeric(14,3) NOT NULL DEFAULT 0 CHECK (qty >= 0),
+  unit         text NOT NULL,
+  created_at   timestamptz NOT NULL DEFAULT now(),
+  updated_at   timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_medicine_batches_item ON medicine_batches(item_id, expiry_date);
+
+CREATE TABLE IF NOT EXISTS medicine_txns (
+  id             uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  item_id        uuid NOT NULL REFERENCES medicine_items(id) ON DELETE CASCADE,
+  batch_id       uuid REFERENCES medicine_batches(id) ON DELETE SET NULL,
+  actor_user_id  uuid REFERENCES users(id) ON DELETE SET NULL,
+  type           text NOT NULL CHECK (type IN ('in','out','adjust')),
+  qty_change     numeric(14,3) NOT NULL,
+  reason         text,
+  created_at     timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_medicine_txns_item ON medicine_txns(item_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS medicine_alerts (
+  item_id             uuid PRIMARY KEY REFERENCES medicine_items(id) ON DELETE CASCADE,
+  min_qty             numeric(14,3),
+  expiry_window_days  int,
+  is_enabled          boolean NOT NULL DEFAULT true,
+  updated_at          timestamptz NOT NULL DEFAULT now()
+);
+
+-- read models ที่ ListItems ใช้
+CREATE OR REPLACE VIEW v_medicine_item_stock AS
+SELECT item_id, SUM(qty) AS total_qty
+FROM medicine_batches
+GROUP BY item_id;
+
+CREATE OR REPLACE VIEW v_medicine_item_next_expiry AS
+SELECT item_id, MIN(expiry_date) AS next_expiry
+FROM medicine_batches
+WHERE qty > 0 AND expiry_date IS NOT NULL
+GROUP BY item_id;
+
+-- +goose Down
+DROP VIEW IF EXISTS v_medicine_item_next_expiry;
+DROP VIEW IF EXISTS v_medicine_item_stock;
+DROP TABLE IF EXISTS medicine_alerts;
+DROP TABLE IF EXISTS medicine_txns;
+DROP TABLE IF EXISTS medicine_batches;
+DROP TABLE IF EXISTS medicine_items;
+DROP TABLE IF EXISTS medicine_locations;
@@ -0,0 +1,45 @@
+-- +goose Up
+-- stocks: watchlist + snapshot บันทึกเหตุผล + quote cache
+CREATE TABLE IF NOT EXISTS stock_watch (
+  id            uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  symbol        text NOT NULL,
+  exchange      text NOT NULL,
+  display_name  text,
+  note          text,
+  tags          text[] DEFAULT '{}',
+  scope         text NOT NULL DEFAULT 'private' CHECK (scope IN ('household','private')),
+  household_id  uuid REFERENCES households(id) ON DELETE CASCADE,
+  created_by    uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at    timestamptz NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_stock_watch_scope
+  ON stock_watch(symbol, exchange, scope, COALESCE(household_id, created_by));
+CREATE INDEX IF NOT EXISTS idx_stock_watch_household ON stock_watch(household_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_stock_watch_creator   ON stock_watch(created_by, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS stock_snapshot (
+  id              uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  stock_watch_id  uuid NOT NULL REFERENCES stock_watch(id) ON DELETE CASCADE,
+  title           text,
+  reason          text,
+  price_target    numeric(18,4),
+  files           jsonb,
+  captured_at     timestamptz NOT NULL DEFAULT now(),
+  created_at      timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_stock_snapshot_watch ON stock_snapshot(stock_watch_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS stock_quote (
+  symbol      text NOT NULL,
+  exchange    text NOT NULL,
+  ts          timestamptz NOT NULL,
+  price       numeric(18,4) NOT NULL,
+  change      numeric(18,4),
+  change_pct  numeric(9,4),
+  PRIMARY KEY (symbol, exchange, ts)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS stock_quote;
+DROP TABLE IF EXISTS stock_snapshot;
+DROP TABLE IF EXISTS stock_watch;
@@ -0,0 +1,42 @@
+-- +goose Up
+-- media: ช่อง (YouTube/RSS) ที่ผูกกับหุ้นใน watchlist + โพสต์ที่ worker ดึงมา
+CREATE TABLE IF NOT EXISTS media_channels (
+  id            uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  source        text NOT NULL,
+  channel_id    text NOT NULL,
+  display_name  text,
+  url           text,
+  created_by    uuid REFERENCES users(id) ON DELETE SET NULL,
+  created_at    timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (source, channel_id)
+);
+
+CREATE TABLE IF NOT EXISTS watch_media_subscriptions (
+  id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  watch_id    uuid NOT NULL REFERENCES stock_watch(id) ON DELETE CASCADE,
+  channel_id  uuid NOT NULL REFERENCES media_channels(id) ON DELETE CASCADE,
+  notify      boolean NOT NULL DEFAULT true,
+  created_at  timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (watch_id, channel_id)
+);
+CREATE INDEX IF NOT EXISTS idx_watch_media_subs_channel ON watch_media_subscriptions(channel_id);
+
+CREATE TABLE IF NOT EXISTS media_posts (
+  id             uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  channel_id     uuid NOT NULL REFERENCES media_channels(id) ON DELETE CASCADE,
+  source         text NOT NULL,
+  external_id    text NOT NULL,
+  title          text NOT NULL,
+  url            text NOT NULL,
+  thumbnail_url  text,
+  published_at   timestamptz,
+  raw            jsonb,
+  created_at     timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (source, external_id)
+);
+CREATE INDEX IF NOT EXISTS idx_media_posts_channel ON media_posts(channel_id, published_at DESC NULLS LAST, id DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS media_posts;
+DROP TABLE IF EXISTS watch_media_subscriptions;
+DROP TABLE IF EXISTS media_channels;
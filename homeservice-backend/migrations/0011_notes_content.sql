@@ -0,0 +1,25 @@
+-- +goose Up
+-- notes: โค้ดใช้ content (nullable) และ category ไม่เป็น NULL — ปรับ schema จาก 0005/0006 ให้ตรง
+DO $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM information_schema.columns
+             WHERE table_schema='public' AND table_name='notes' AND column_name='body')
+     AND NOT EXISTS (SELECT 1 FROM information_schema.columns
+             WHERE table_schema='public' AND table_name='notes' AND column_name='content') THEN
+    ALTER TABLE public.notes RENAME COLUMN body TO content;
+  END IF;
+END $$;
+
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS content text;
+ALTER TABLE notes ALTER COLUMN content DROP NOT NULL;
+
+UPDATE notes SET category = 'general' WHERE category IS NULL;
+ALTER TABLE notes ALTER COLUMN category SET DEFAULT 'general';
+ALTER TABLE notes ALTER COLUMN category SET NOT NULL;
+
+-- +goose Down
+ALTER TABLE notes ALTER COLUMN category DROP NOT NULL;
+ALTER TABLE notes ALTER COLUMN category DROP DEFAULT;
+UPDATE notes SET content = '' WHERE content IS NULL;
+ALTER TABLE notes RENAME COLUMN content TO body;
+ALTER TABLE notes ALTER COLUMN body SET NOT NULL;
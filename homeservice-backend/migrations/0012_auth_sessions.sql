@@ -0,0 +1,31 @@
+-- +goose Up
+-- auth_sessions: 1 แถว = 1 อุปกรณ์ที่ล็อกอิน (= token family ของ refresh token)
+CREATE TABLE IF NOT EXISTS auth_sessions (
+  id             uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id        uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  device         text NOT NULL DEFAULT '',
+  user_agent     text,
+  ip             text,
+  created_at     timestamptz NOT NULL DEFAULT now(),
+  last_used_at   timestamptz NOT NULL DEFAULT now(),
+  expires_at     timestamptz NOT NULL,
+  revoked_at     timestamptz,
+  revoke_reason  text
+);
+CREATE INDEX IF NOT EXISTS idx_auth_sessions_user ON auth_sessions(user_id, last_used_at DESC);
+
+-- refresh_tokens: เก็บเฉพาะ sha256 ของ token; ใช้ได้ครั้งเดียว (used_at) แล้วหมุนเป็นตัวใหม่
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+  id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  session_id  uuid NOT NULL REFERENCES auth_sessions(id) ON DELETE CASCADE,
+  token_hash  text NOT NULL UNIQUE,
+  parent_id   uuid REFERENCES refresh_tokens(id) ON DELETE SET NULL,
+  expires_at  timestamptz NOT NULL,
+  used_at     timestamptz,
+  created_at  timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session ON refresh_tokens(session_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS auth_sessions;
@@ -0,0 +1,65 @@
+-- +goose Up
+-- chores: งานทำซ้ำ (RRULE), การวนเวรอัตโนมัติ และประวัติการทำแต่ละรอบ
+
+-- ฐานข้อมูลเก่าบางชุดใช้ enum chore_status/chore_category -> แปลงเป็น text + CHECK
+DO $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM information_schema.columns
+             WHERE table_schema='public' AND table_name='chores'
+               AND column_name='status' AND data_type='USER-DEFINED') THEN
+    ALTER TABLE public.chores ALTER COLUMN status DROP DEFAULT;
+    ALTER TABLE public.chores ALTER COLUMN status TYPE text USING status::text;
+    ALTER TABLE public.chores ALTER COLUMN status SET DEFAULT 'open';
+  END IF;
+  IF EXISTS (SELECT 1 FROM information_schema.columns
+             WHERE table_schema='public' AND table_name='chores'
+               AND column_name='category' AND data_type='USER-DEFINED') THEN
+    ALTER TABLE public.chores ALTER COLUMN category DROP DEFAULT;
+    ALTER TABLE public.chores ALTER COLUMN category TYPE text USING category::text;
+    ALTER TABLE public.chores ALTER COLUMN category SET DEFAULT 'general';
+  END IF;
+END $$;
+
+ALTER TABLE chores DROP CONSTRAINT IF EXISTS chores_status_check;
+ALTER TABLE chores ADD CONSTRAINT chores_status_check
+  CHECK (status IN ('open','claimed','completed','skipped'));
+
+ALTER TABLE chores
+  ADD COLUMN IF NOT EXISTS recurrence      text,
+  ADD COLUMN IF NOT EXISTS starts_at       timestamptz,
+  ADD COLUMN IF NOT EXISTS due_at          timestamptz,
+  ADD COLUMN IF NOT EXISTS assigned_to     uuid REFERENCES users(id) ON DELETE SET NULL,
+  ADD COLUMN IF NOT EXISTS rotation        uuid[] NOT NULL DEFAULT '{}',
+  ADD COLUMN IF NOT EXISTS rotation_index  int NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_chores_household_due ON chores(household_id, status, due_at);
+CREATE INDEX IF NOT EXISTS idx_chores_assigned      ON chores(assigned_to) WHERE assigned_to IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS chore_events (
+  id            uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  chore_id      uuid NOT NULL REFERENCES chores(id) ON DELETE CASCADE,
+  household_id  uuid NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+  action        text NOT NULL CHECK (action IN ('completed','skipped')),
+  user_id       uuid REFERENCES users(id) ON DELETE SET NULL,
+  assigned_to   uuid REFERENCES users(id) ON DELETE SET NULL,
+  due_at        timestamptz,
+  created_at    timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_chore_events_chore     ON chore_events(chore_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_chore_events_household ON chore_events(household_id, created_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS chore_events;
+DROP INDEX IF EXISTS idx_chores_assigned;
+DROP INDEX IF EXISTS idx_chores_household_due;
+ALTER TABLE chores
+  DROP COLUMN IF EXISTS rotation_index,
+  DROP COLUMN IF EXISTS rotation,
+  DROP COLUMN IF EXISTS assigned_to,
+  DROP COLUMN IF EXISTS due_at,
+  DROP COLUMN IF EXISTS starts_at,
+  DROP COLUMN IF EXISTS recurrence;
+UPDATE chores SET status='open' WHERE status='skipped';
+ALTER TABLE chores DROP CONSTRAINT IF EXISTS chores_status_check;
+ALTER TABLE chores ADD CONSTRAINT chores_status_check
+  CHECK (status IN ('open','claimed','completed'));
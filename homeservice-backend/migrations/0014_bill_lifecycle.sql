@@ -0,0 +1,53 @@
+-- +goose Up
+-- bills: การจ่ายเงิน (ยอด/ช่องทาง/ใบเสร็จ) + แม่แบบบิลประจำเดือน
+-- overdue ไม่เก็บในตาราง: คำนวณจาก due_date ตอน query (status เก็บแค่ unpaid|paid)
+
+UPDATE bills SET status = 'paid'   WHERE status <> 'paid' AND paid_at IS NOT NULL;
+UPDATE bills SET status = 'unpaid' WHERE status NOT IN ('paid','unpaid');
+
+ALTER TABLE bills DROP CONSTRAINT IF EXISTS bills_status_check;
+ALTER TABLE bills ADD CONSTRAINT bills_status_check CHECK (status IN ('unpaid','paid'));
+
+CREATE TABLE IF NOT EXISTS bill_templates (
+  id               uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  household_id     uuid NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+  type             text NOT NULL,
+  title            text NOT NULL,
+  amount           numeric(14,2) NOT NULL CHECK (amount >= 0), -- ยอดประมาณการ แก้ในบิลจริงได้
+  day_of_month     int  NOT NULL CHECK (day_of_month BETWEEN 1 AND 31),
+  interval_months  int  NOT NULL DEFAULT 1 CHECK (interval_months >= 1),
+  lead_days        int  NOT NULL DEFAULT 7 CHECK (lead_days >= 0), -- สร้างบิลล่วงหน้ากี่วันก่อนครบกำหนด
+  next_due         date NOT NULL,
+  active           boolean NOT NULL DEFAULT true,
+  note             text,
+  created_by       uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at       timestamptz NOT NULL DEFAULT now(),
+  updated_at       timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_bill_templates_due ON bill_templates(next_due) WHERE active;
+
+DROP TRIGGER IF EXISTS bill_templates_set_updated_at ON bill_templates;
+CREATE TRIGGER bill_templates_set_updated_at
+  BEFORE UPDATE ON bill_templates
+  FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+ALTER TABLE bills
+  ADD COLUMN IF NOT EXISTS paid_amount      numeric(14,2) CHECK (paid_amount >= 0),
+  ADD COLUMN IF NOT EXISTS payment_method   text,
+  ADD COLUMN IF NOT EXISTS paid_by          uuid REFERENCES users(id) ON DELETE SET NULL,
+  ADD COLUMN IF NOT EXISTS receipt_file_id  uuid REFERENCES files(id) ON DELETE SET NULL,
+  ADD COLUMN IF NOT EXISTS template_id      uuid REFERENCES bill_templates(id) ON DELETE SET NULL;
+
+-- แม่แบบหนึ่งสร้างบิลได้ครั้งเดียวต่อวันครบกำหนด (กัน worker รันซ้ำ)
+CREATE UNIQUE INDEX IF NOT EXISTS uq_bills_template_due ON bills(template_id, due_date) WHERE template_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS uq_bills_template_due;
+ALTER TABLE bills
+  DROP COLUMN IF EXISTS template_id,
+  DROP COLUMN IF EXISTS receipt_file_id,
+  DROP COLUMN IF EXISTS paid_by,
+  DROP COLUMN IF EXISTS payment_method,
+  DROP COLUMN IF EXISTS paid_amount;
+DROP TABLE IF EXISTS bill_templates;
+ALTER TABLE bills DROP CONSTRAINT IF EXISTS bills_status_check;
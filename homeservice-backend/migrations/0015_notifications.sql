@@ -0,0 +1,37 @@
+-- +goose Up
+-- notifications: in-app feed ต่อผู้ใช้ + ช่องทางส่งออก (email/webhook/webpush)
+
+CREATE TABLE IF NOT EXISTS notifications (
+  id            uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id       uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  household_id  uuid REFERENCES households(id) ON DELETE CASCADE,
+  kind          text NOT NULL,            -- medicine.low_stock | bill.due | note.reminder | media.post ...
+  title         text NOT NULL,
+  body          text NOT NULL DEFAULT '',
+  link          text,
+  ref_id        text,                     -- id ของสิ่งที่อ้างถึง (item/bill/note/post)
+  dedup_key     text NOT NULL,            -- kind + สิ่งที่อ้างถึง: ใช้กันซ้ำ/คูลดาวน์
+  read_at       timestamptz,
+  created_at    timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_notifications_user   ON notifications(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_unread ON notifications(user_id) WHERE read_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_notifications_dedup  ON notifications(user_id, dedup_key, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS notification_channels (
+  id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id     uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  type        text NOT NULL CHECK (type IN ('email','webhook','webpush')),
+  target      text NOT NULL,               -- email address | webhook URL | push endpoint
+  secret      text,                        -- webhook: HMAC key สำหรับ X-Signature
+  kinds       text[] NOT NULL DEFAULT '{}', -- ว่าง = ทุกชนิด
+  enabled     boolean NOT NULL DEFAULT true,
+  last_error  text,
+  last_sent_at timestamptz,
+  created_at  timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (user_id, type, target)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS notification_channels;
+DROP TABLE IF EXISTS notifications;
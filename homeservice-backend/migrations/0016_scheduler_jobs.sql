@@ -0,0 +1,24 @@
+-- +goose Up
+-- scheduler: สถานะงานเบื้องหลัง (ใช้ร่วมกันทุก replica; คนรันจริงตัดสินด้วย advisory lock ต่องาน)
+CREATE TABLE IF NOT EXISTS scheduler_jobs (
+  name              text PRIMARY KEY,
+  spec              text NOT NULL,             -- cron 5 ช่อง | @daily | @every 5m
+  next_run_at       timestamptz NOT NULL,
+  last_started_at   timestamptz,
+  last_finished_at  timestamptz,
+  last_duration_ms  bigint,
+  last_error        text,                      -- NULL = รอบล่าสุดสำเร็จ
+  last_runner       text,                      -- hostname:pid ของ replica ที่รัน
+  running           boolean NOT NULL DEFAULT false,
+  run_count         bigint NOT NULL DEFAULT 0,
+  fail_count        bigint NOT NULL DEFAULT 0,
+  updated_at        timestamptz NOT NULL DEFAULT now()
+);
+
+DROP TRIGGER IF EXISTS scheduler_jobs_set_updated_at ON scheduler_jobs;
+CREATE TRIGGER scheduler_jobs_set_updated_at
+  BEFORE UPDATE ON scheduler_jobs
+  FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- +goose Down
+DROP TABLE IF EXISTS scheduler_jobs;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- เวลาที่งานรันสำเร็จล่าสุด (last_finished_at นับรวมรอบที่ error) ใช้ใน /readyz
+ALTER TABLE scheduler_jobs ADD COLUMN IF NOT EXISTS last_success_at timestamptz;
+UPDATE scheduler_jobs SET last_success_at = last_finished_at
+WHERE last_success_at IS NULL AND last_error IS NULL AND last_finished_at IS NOT NULL;
+
+-- +goose Down
+ALTER TABLE scheduler_jobs DROP COLUMN IF EXISTS last_success_at;
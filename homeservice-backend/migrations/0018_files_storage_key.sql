@@ -0,0 +1,11 @@
+-- +goose Up
+-- storage_key = key ของ object ใน backend (local: ชื่อไฟล์ในโฟลเดอร์, s3: key ใน bucket)
+-- url เดิมยังเก็บไว้เพื่อแสดงผล แต่การอ่าน/ลบ/ตรวจ object ใช้ key
+ALTER TABLE files ADD COLUMN IF NOT EXISTS storage_key text;
+-- ไฟล์เก่าที่อัปโหลดผ่าน local: ชื่อไฟล์ที่บันทึก = ชื่อไฟล์บนดิสก์
+UPDATE files SET storage_key = file_name WHERE storage_key IS NULL;
+CREATE INDEX IF NOT EXISTS idx_files_storage_key ON files(storage_key);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_files_storage_key;
+ALTER TABLE files DROP COLUMN IF EXISTS storage_key;
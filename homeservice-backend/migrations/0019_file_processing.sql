@@ -0,0 +1,31 @@
+-- +goose Up
+-- ประมวลผลไฟล์เบื้องหลัง: ตรวจชนิดจากเนื้อไฟล์, ลบ EXIF/GPS, สร้างภาพย่อ
+-- pending → processing → ready | failed; ไฟล์เดิมถือว่า ready
+ALTER TABLE files
+  ADD COLUMN IF NOT EXISTS processing_status   text NOT NULL DEFAULT 'ready',
+  ADD COLUMN IF NOT EXISTS processing_error    text,
+  ADD COLUMN IF NOT EXISTS processing_attempts int  NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS width               int,
+  ADD COLUMN IF NOT EXISTS height              int,
+  ADD COLUMN IF NOT EXISTS renditions          jsonb NOT NULL DEFAULT '{}'::jsonb; -- {"thumb": {"key","mime","width","height","size"}}
+
+DO $$ BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'files_processing_status_check') THEN
+    ALTER TABLE files ADD CONSTRAINT files_processing_status_check
+      CHECK (processing_status IN ('pending','processing','ready','failed'));
+  END IF;
+END $$;
+
+CREATE INDEX IF NOT EXISTS idx_files_processing
+  ON files(created_at) WHERE processing_status IN ('pending','processing');
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_files_processing;
+ALTER TABLE files
+  DROP CONSTRAINT IF EXISTS files_processing_status_check,
+  DROP COLUMN IF EXISTS renditions,
+  DROP COLUMN IF EXISTS height,
+  DROP COLUMN IF EXISTS width,
+  DROP COLUMN IF EXISTS processing_attempts,
+  DROP COLUMN IF EXISTS processing_error,
+  DROP COLUMN IF EXISTS processing_status;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- content addressing: ไฟล์ที่เนื้อเหมือนกัน (หลังลบ metadata) ใช้ blob เดียวกัน
+-- จำนวนการอ้างอิง = จำนวนแถว files ที่มี content_sha256 เดียวกัน; แถวสุดท้ายถูกลบ → ลบ blob
+ALTER TABLE files ADD COLUMN IF NOT EXISTS content_sha256 text;
+CREATE INDEX IF NOT EXISTS idx_files_content_sha256 ON files(content_sha256) WHERE content_sha256 IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_files_content_sha256;
+ALTER TABLE files DROP COLUMN IF EXISTS content_sha256;
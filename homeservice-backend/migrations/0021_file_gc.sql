@@ -0,0 +1,14 @@
+-- +goose Up
+-- รายงานของงานเก็บกวาดไฟล์ (files.gc): แถว files ที่ไม่มีใครอ้าง + object ใน storage ที่ไม่มีแถว
+-- เก็บทุกรอบเพื่อให้ admin ดูรอบล่าสุดได้จาก replica ไหนก็ได้
+CREATE TABLE IF NOT EXISTS file_gc_reports (
+  id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  dry_run     boolean NOT NULL,
+  started_at  timestamptz NOT NULL,
+  finished_at timestamptz NOT NULL DEFAULT now(),
+  report      jsonb NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_file_gc_reports_started ON file_gc_reports(started_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS file_gc_reports;
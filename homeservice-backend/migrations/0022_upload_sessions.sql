@@ -0,0 +1,30 @@
+-- +goose Up
+-- อัปโหลดแบบ resumable (tus-style): ไฟล์ใหญ่ส่งเป็นก้อน ๆ ตาม offset, หลุดกลางทางแล้วต่อจากเดิมได้
+-- แต่ละก้อนเป็น object แยก (upload_parts) รวมเป็นไฟล์เดียวตอนได้ครบ size
+CREATE TABLE IF NOT EXISTS upload_sessions (
+  id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  owner_id    uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  file_name   text NOT NULL,
+  mime        text NOT NULL,
+  size        bigint NOT NULL CHECK (size > 0),
+  received    bigint NOT NULL DEFAULT 0,
+  status      text NOT NULL DEFAULT 'uploading' CHECK (status IN ('uploading','finalizing','done')),
+  file_id     uuid REFERENCES files(id) ON DELETE SET NULL,
+  expires_at  timestamptz NOT NULL,
+  created_at  timestamptz NOT NULL DEFAULT now(),
+  updated_at  timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_owner   ON upload_sessions(owner_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_expires ON upload_sessions(expires_at);
+
+CREATE TABLE IF NOT EXISTS upload_parts (
+  session_id  uuid NOT NULL REFERENCES upload_sessions(id) ON DELETE CASCADE,
+  byte_offset bigint NOT NULL,
+  size        bigint NOT NULL,
+  storage_key text NOT NULL,
+  PRIMARY KEY (session_id, byte_offset)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS upload_parts;
+DROP TABLE IF EXISTS upload_sessions;
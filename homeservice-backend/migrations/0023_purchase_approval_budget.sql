@@ -0,0 +1,46 @@
+-- +goose Up
+-- คำขอซื้อที่ประเมินราคาเกินเกณฑ์ของบ้านต้องให้ owner/admin อนุมัติก่อน: requested → planned | rejected
+-- bought_at = เวลาที่ซื้อจริง (ใช้สรุปยอดจ่ายรายเดือน)
+ALTER TABLE purchases DROP CONSTRAINT IF EXISTS purchases_status_check;
+ALTER TABLE purchases ADD CONSTRAINT purchases_status_check
+  CHECK (status IN ('requested','rejected','planned','ordered','bought','delivered','cancelled'));
+
+ALTER TABLE purchases
+  ADD COLUMN IF NOT EXISTS approved_by   uuid REFERENCES users(id) ON DELETE SET NULL,
+  ADD COLUMN IF NOT EXISTS decided_at    timestamptz,
+  ADD COLUMN IF NOT EXISTS decision_note text NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS bought_at     timestamptz;
+UPDATE purchases SET bought_at = updated_at WHERE bought_at IS NULL AND status IN ('bought','delivered');
+CREATE INDEX IF NOT EXISTS idx_purchases_spending ON purchases(household_id, category, bought_at) WHERE bought_at IS NOT NULL;
+
+-- ตั้งค่าต่อบ้าน: approval_threshold NULL = ไม่ต้องอนุมัติ
+CREATE TABLE IF NOT EXISTS purchase_settings (
+  household_id        uuid PRIMARY KEY REFERENCES households(id) ON DELETE CASCADE,
+  approval_threshold  numeric(14,2) CHECK (approval_threshold >= 0),
+  updated_at          timestamptz NOT NULL DEFAULT now()
+);
+
+-- งบรายเดือนต่อหมวด: warn = สร้างได้แต่แจ้งเตือน, block = ไม่ให้สร้าง/แก้จนเกินงบ
+CREATE TABLE IF NOT EXISTS purchase_budgets (
+  household_id   uuid NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+  category       text NOT NULL,
+  monthly_limit  numeric(14,2) NOT NULL CHECK (monthly_limit >= 0),
+  mode           text NOT NULL DEFAULT 'warn' CHECK (mode IN ('warn','block')),
+  updated_at     timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (household_id, category)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS purchase_budgets;
+DROP TABLE IF EXISTS purchase_settings;
+DROP INDEX IF EXISTS idx_purchases_spending;
+UPDATE purchases SET status = 'planned' WHERE status = 'requested';
+UPDATE purchases SET status = 'cancelled' WHERE status = 'rejected';
+ALTER TABLE purchases
+  DROP COLUMN IF EXISTS bought_at,
+  DROP COLUMN IF EXISTS decision_note,
+  DROP COLUMN IF EXISTS decided_at,
+  DROP COLUMN IF EXISTS approved_by;
+ALTER TABLE purchases DROP CONSTRAINT IF EXISTS purchases_status_check;
+ALTER TABLE purchases ADD CONSTRAINT purchases_status_check
+  CHECK (status IN ('planned','ordered','bought','delivered','cancelled'));
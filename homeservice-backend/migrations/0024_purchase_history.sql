@@ -0,0 +1,42 @@
+-- +goose Up
+-- ประวัติคำขอซื้อแบบ append-only: ใครเปลี่ยนสถานะ/แก้ฟิลด์ (พร้อม diff)/แนบหรือเอาไฟล์ออก เมื่อไหร่
+CREATE TABLE IF NOT EXISTS purchase_events (
+  id           bigserial PRIMARY KEY,
+  purchase_id  uuid NOT NULL REFERENCES purchases(id) ON DELETE CASCADE,
+  actor_id     uuid REFERENCES users(id) ON DELETE SET NULL,
+  kind         text NOT NULL, -- created|claimed|status_changed|updated|attachment_added|attachment_removed
+  data         jsonb NOT NULL DEFAULT '{}'::jsonb,
+  created_at   timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_purchase_events_purchase ON purchase_events(purchase_id, id);
+
+CREATE OR REPLACE FUNCTION purchase_events_append_only() RETURNS TRIGGER AS $$
+BEGIN
+  RAISE EXCEPTION 'purchase_events is append-only';
+END; $$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS purchase_events_no_update ON purchase_events;
+CREATE TRIGGER purchase_events_no_update
+  BEFORE UPDATE ON purchase_events
+  FOR EACH ROW EXECUTE FUNCTION purchase_events_append_only();
+
+-- ความเห็นใต้คำขอซื้อ: ใช้ purchase_messages เดิม (เดิมผูกกับ purchase_requests ที่เลิกใช้แล้ว)
+ALTER TABLE purchase_messages ALTER COLUMN request_id DROP NOT NULL;
+ALTER TABLE purchase_messages ADD COLUMN IF NOT EXISTS purchase_id uuid REFERENCES purchases(id) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS idx_purchase_messages_purchase ON purchase_messages(purchase_id, created_at);
+
+CREATE TABLE IF NOT EXISTS purchase_message_files (
+  message_id  uuid NOT NULL REFERENCES purchase_messages(id) ON DELETE CASCADE,
+  file_id     uuid NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+  PRIMARY KEY (message_id, file_id)
+);
+CREATE INDEX IF NOT EXISTS idx_purchase_message_files_file ON purchase_message_files(file_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS purchase_message_files;
+DELETE FROM purchase_messages WHERE request_id IS NULL;
+DROP INDEX IF EXISTS idx_purchase_messages_purchase;
+ALTER TABLE purchase_messages DROP COLUMN IF EXISTS purchase_id;
+ALTER TABLE purchase_messages ALTER COLUMN request_id SET NOT NULL;
+DROP TABLE IF EXISTS purchase_events;
+DROP FUNCTION IF EXISTS purchase_events_append_only();
@@ -0,0 +1,33 @@
+-- +goose Up
+-- version ต่อแถวสำหรับ optimistic concurrency (ETag / If-Match)
+-- trigger เพิ่ม version ทุก UPDATE ไม่ว่ามาจากเส้นทางไหน; ฝั่งแอปเขียนแบบมีเงื่อนไข WHERE version = ที่อ่านมา
+CREATE OR REPLACE FUNCTION trg_bump_version() RETURNS TRIGGER AS $$
+BEGIN
+  NEW.version := OLD.version + 1;
+  RETURN NEW;
+END; $$ LANGUAGE plpgsql;
+
+ALTER TABLE purchases      ADD COLUMN IF NOT EXISTS version integer NOT NULL DEFAULT 1;
+ALTER TABLE notes          ADD COLUMN IF NOT EXISTS version integer NOT NULL DEFAULT 1;
+ALTER TABLE medicine_items ADD COLUMN IF NOT EXISTS version integer NOT NULL DEFAULT 1;
+
+DROP TRIGGER IF EXISTS purchases_bump_version ON purchases;
+CREATE TRIGGER purchases_bump_version BEFORE UPDATE ON purchases
+FOR EACH ROW EXECUTE FUNCTION trg_bump_version();
+
+DROP TRIGGER IF EXISTS notes_bump_version ON notes;
+CREATE TRIGGER notes_bump_version BEFORE UPDATE ON notes
+FOR EACH ROW EXECUTE FUNCTION trg_bump_version();
+
+DROP TRIGGER IF EXISTS medicine_items_bump_version ON medicine_items;
+CREATE TRIGGER medicine_items_bump_version BEFORE UPDATE ON medicine_items
+FOR EACH ROW EXECUTE FUNCTION trg_bump_version();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS medicine_items_bump_version ON medicine_items;
+DROP TRIGGER IF EXISTS notes_bump_version ON notes;
+DROP TRIGGER IF EXISTS purchases_bump_version ON purchases;
+ALTER TABLE medicine_items DROP COLUMN IF EXISTS version;
+ALTER TABLE notes          DROP COLUMN IF EXISTS version;
+ALTER TABLE purchases      DROP COLUMN IF EXISTS version;
+DROP FUNCTION IF EXISTS trg_bump_version();
@@ -0,0 +1,18 @@
+-- +goose Up
+-- รายการซื้อที่ใช้ซ้ำ (เช่น ของใช้ประจำสัปดาห์) — ใช้แล้วได้คำขอใหม่หรือรวมเข้าคำขอที่ยัง planned
+CREATE TABLE IF NOT EXISTS purchase_templates (
+  id            uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  household_id  uuid NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+  name          text NOT NULL,
+  title         text NOT NULL,
+  category      text NOT NULL DEFAULT '',
+  store         text NOT NULL DEFAULT '',
+  items         jsonb NOT NULL DEFAULT '[]'::jsonb,
+  created_by    uuid REFERENCES users(id) ON DELETE SET NULL,
+  created_at    timestamptz NOT NULL DEFAULT now(),
+  updated_at    timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (household_id, name)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS purchase_templates;
@@ -0,0 +1,46 @@
+-- +goose Up
+-- ตารางการใช้ยาต่อคน: ยาอะไร ครั้งละเท่าไร เวลาไหน วันไหน ช่วงวันที่เท่าไร
+-- คนกินยาอาจไม่มีบัญชี (เช่น ยาย) จึงมี person_name เสมอ และ person_user_id เมื่อเป็นสมาชิกที่มีบัญชี
+CREATE TABLE IF NOT EXISTS medicine_regimens (
+  id              uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  household_id    uuid NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+  item_id         uuid NOT NULL REFERENCES medicine_items(id) ON DELETE CASCADE,
+  person_user_id  uuid REFERENCES users(id) ON DELETE SET NULL,
+  person_name     text NOT NULL,
+  dose            numeric(14,3) NOT NULL CHECK (dose > 0),
+  unit            text NOT NULL,
+  times           text[] NOT NULL,                     -- "HH:MM" เวลาท้องถิ่น (SCHEDULER_TZ)
+  days_of_week    integer[] NOT NULL DEFAULT '{}',     -- 0=อาทิตย์..6=เสาร์; ว่าง = ทุกวัน
+  start_date      date NOT NULL,
+  end_date        date,
+  notes           text,
+  is_active       boolean NOT NULL DEFAULT true,
+  created_by      uuid REFERENCES users(id) ON DELETE SET NULL,
+  created_at      timestamptz NOT NULL DEFAULT now(),
+  updated_at      timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_medicine_regimens_household ON medicine_regimens(household_id) WHERE is_active;
+
+-- บันทึกการกินยาต่อนัด: taken/skipped จากผู้ใช้, missed จากงาน medicine.doses
+CREATE TABLE IF NOT EXISTS medicine_doses (
+  id             uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  regimen_id     uuid NOT NULL REFERENCES medicine_regimens(id) ON DELETE CASCADE,
+  scheduled_at   timestamptz NOT NULL,
+  status         text NOT NULL CHECK (status IN ('taken','skipped','missed')),
+  taken_at       timestamptz,
+  actor_user_id  uuid REFERENCES users(id) ON DELETE SET NULL,
+  note           text,
+  created_at     timestamptz NOT NULL DEFAULT now(),
+  updated_at     timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (regimen_id, scheduled_at)
+);
+
+-- txn ที่เกิดจากการกินยา (1 นัดอาจตัดหลาย batch)
+ALTER TABLE medicine_txns ADD COLUMN IF NOT EXISTS dose_id uuid REFERENCES medicine_doses(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_medicine_txns_dose ON medicine_txns(dose_id) WHERE dose_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_medicine_txns_dose;
+ALTER TABLE medicine_txns DROP COLUMN IF EXISTS dose_id;
+DROP TABLE IF EXISTS medicine_doses;
+DROP TABLE IF EXISTS medicine_regimens;
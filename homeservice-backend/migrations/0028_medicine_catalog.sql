@@ -0,0 +1,23 @@
+-- +goose Up
+-- แคตตาล็อกสินค้ายาแบบออฟไลน์ (นำเข้าจากไฟล์ JSON/CSV) ใช้เติมชื่อ/ความแรง/รูปแบบ/หน่วยตอนสแกนบาร์โค้ด
+-- gtin เก็บแบบ GTIN-14 (เติม 0 ข้างหน้า) ให้ EAN-8/UPC-A/EAN-13 ของสินค้าเดียวกันตรงกัน
+CREATE TABLE IF NOT EXISTS medicine_catalog (
+  gtin          text PRIMARY KEY CHECK (gtin ~ '^[0-9]{14}$'),
+  name          text NOT NULL,
+  generic_name  text,
+  strength      text,
+  form          text,
+  unit          text,
+  category      text,
+  source        text NOT NULL DEFAULT 'import',
+  updated_at    timestamptz NOT NULL DEFAULT now()
+);
+
+-- ค้นยาของบ้านด้วยบาร์โค้ดแบบเดียวกัน (item เก่าอาจเก็บ gtin มีขีด/ช่องว่าง หรือไม่เติม 0)
+CREATE INDEX IF NOT EXISTS idx_medicine_items_gtin14
+  ON medicine_items(household_id, lpad(regexp_replace(gtin, '[^0-9]', '', 'g'), 14, '0'))
+  WHERE gtin IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_medicine_items_gtin14;
+DROP TABLE IF EXISTS medicine_catalog;
@@ -0,0 +1,48 @@
+-- +goose Up
+-- รอบตรวจนับสต็อกยาต่อที่เก็บ: เปิดรอบ -> บันทึกจำนวนที่นับได้ต่อ batch -> ดูส่วนต่าง -> commit เป็น txn adjust
+CREATE TABLE IF NOT EXISTS medicine_stocktakes (
+  id            uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  household_id  uuid NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+  location_id   uuid NOT NULL REFERENCES medicine_locations(id) ON DELETE CASCADE,
+  status        text NOT NULL DEFAULT 'open' CHECK (status IN ('open','committed','cancelled')),
+  note          text,
+  created_by    uuid REFERENCES users(id) ON DELETE SET NULL,
+  created_at    timestamptz NOT NULL DEFAULT now(),
+  committed_by  uuid REFERENCES users(id) ON DELETE SET NULL,
+  committed_at  timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_medicine_stocktakes_household ON medicine_stocktakes(household_id, created_at DESC);
+-- ที่เก็บหนึ่งมีรอบที่เปิดอยู่ได้รอบเดียว
+CREATE UNIQUE INDEX IF NOT EXISTS uq_medicine_stocktakes_open ON medicine_stocktakes(location_id) WHERE status = 'open';
+
+-- จำนวนที่นับได้: batch_id ว่าง = เจอ batch ที่ระบบไม่รู้จัก (สร้างตอน commit แล้วเติม batch_id, is_new = true)
+-- expected_qty = ยอดในระบบตอน commit (ระหว่างเปิดรอบใช้ยอดปัจจุบัน)
+CREATE TABLE IF NOT EXISTS medicine_stocktake_counts (
+  id            uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  stocktake_id  uuid NOT NULL REFERENCES medicine_stocktakes(id) ON DELETE CASCADE,
+  item_id       uuid NOT NULL REFERENCES medicine_items(id) ON DELETE CASCADE,
+  batch_id      uuid REFERENCES medicine_batches(id) ON DELETE SET NULL,
+  lot_no        text,
+  expiry_date   date,
+  is_new        boolean NOT NULL DEFAULT false,
+  counted_qty   numeric(14,3) NOT NULL CHECK (counted_qty >= 0),
+  expected_qty  numeric(14,3),
+  note          text,
+  counted_by    uuid REFERENCES users(id) ON DELETE SET NULL,
+  updated_at    timestamptz NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_medicine_stocktake_counts_batch
+  ON medicine_stocktake_counts(stocktake_id, batch_id) WHERE batch_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS uq_medicine_stocktake_counts_new
+  ON medicine_stocktake_counts(stocktake_id, item_id, COALESCE(lot_no, ''), COALESCE(expiry_date, 'infinity'::date))
+  WHERE batch_id IS NULL;
+
+-- txn adjust ที่เกิดจากการ commit รอบตรวจนับ
+ALTER TABLE medicine_txns ADD COLUMN IF NOT EXISTS stocktake_id uuid REFERENCES medicine_stocktakes(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_medicine_txns_stocktake ON medicine_txns(stocktake_id) WHERE stocktake_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_medicine_txns_stocktake;
+ALTER TABLE medicine_txns DROP COLUMN IF EXISTS stocktake_id;
+DROP TABLE IF EXISTS medicine_stocktake_counts;
+DROP TABLE IF EXISTS medicine_stocktakes;
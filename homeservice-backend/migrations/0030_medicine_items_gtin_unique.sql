@@ -0,0 +1,25 @@
+-- +goose Up
+-- บาร์โค้ดเดียวกันมี item ที่ยังใช้อยู่ได้ตัวเดียวต่อบ้าน (กันสแกน quick-add พร้อมกันแล้วได้ยาซ้ำสองตัว)
+-- เทียบแบบ GTIN-14 เหมือน idx_medicine_items_gtin14 เดิม; item ที่ archive แล้วไม่นับ (สแกนใหม่ = สร้างตัวใหม่ได้)
+-- ข้อมูลเดิมที่ซ้ำ: เก็บบาร์โค้ดไว้ที่ตัวที่สร้างก่อน ตัวอื่นล้าง gtin ทิ้ง
+UPDATE medicine_items SET gtin = NULL, updated_at = now()
+WHERE id IN (
+  SELECT id FROM (
+    SELECT id, row_number() OVER (
+      PARTITION BY household_id, lpad(regexp_replace(gtin, '[^0-9]', '', 'g'), 14, '0')
+      ORDER BY created_at, id) AS n
+    FROM medicine_items
+    WHERE gtin IS NOT NULL AND is_archived = false
+  ) d WHERE d.n > 1
+);
+
+DROP INDEX IF EXISTS idx_medicine_items_gtin14;
+CREATE UNIQUE INDEX IF NOT EXISTS uq_medicine_items_gtin14
+  ON medicine_items(household_id, lpad(regexp_replace(gtin, '[^0-9]', '', 'g'), 14, '0'))
+  WHERE gtin IS NOT NULL AND is_archived = false;
+
+-- +goose Down
+DROP INDEX IF EXISTS uq_medicine_items_gtin14;
+CREATE INDEX IF NOT EXISTS idx_medicine_items_gtin14
+  ON medicine_items(household_id, lpad(regexp_replace(gtin, '[^0-9]', '', 'g'), 14, '0'))
+  WHERE gtin IS NOT NULL;
@@ -0,0 +1,21 @@
+-- +goose Up
+-- actor_id เป็น FK ON DELETE SET NULL ซึ่ง Postgres ทำเป็น UPDATE บน purchase_events
+-- trigger append-only เดิมปฏิเสธทุก UPDATE ทำให้ลบ user ที่เคยแตะคำขอซื้อไม่ได้
+-- ยอมให้ UPDATE ได้กรณีเดียว: actor_id กลายเป็น NULL และคอลัมน์อื่นไม่เปลี่ยน
+CREATE OR REPLACE FUNCTION purchase_events_append_only() RETURNS TRIGGER AS $$
+BEGIN
+  IF OLD.actor_id IS NOT NULL AND NEW.actor_id IS NULL
+     AND (NEW.id, NEW.purchase_id, NEW.kind, NEW.data, NEW.created_at)
+         IS NOT DISTINCT FROM (OLD.id, OLD.purchase_id, OLD.kind, OLD.data, OLD.created_at) THEN
+    RETURN NEW;
+  END IF;
+  RAISE EXCEPTION 'purchase_events is append-only';
+END; $$ LANGUAGE plpgsql;
+
+-- +goose Down
+-- กลับไปเป็น trigger เดิม: ปลอดภัยต่อข้อมูล (แถวที่ actor_id ถูก NULL ไปแล้วคงอยู่ตามเดิม)
+-- แต่หลัง down จะลบ user ที่มี purchase_events อ้างอยู่ไม่ได้อีก
+CREATE OR REPLACE FUNCTION purchase_events_append_only() RETURNS TRIGGER AS $$
+BEGIN
+  RAISE EXCEPTION 'purchase_events is append-only';
+END; $$ LANGUAGE plpgsql;
@@ -0,0 +1,7 @@
+// Package migrations ฝังไฟล์ SQL ทั้งหมดเข้าไปใน binary (ใช้โดย internal/migrate)
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS